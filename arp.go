@@ -1,7 +1,7 @@
 // @@
 // @ Author       : Eacher
 // @ Date         : 2023-07-01 15:19:37
-// @ LastEditTime : 2023-09-04 09:40:23
+// @ LastEditTime : 2026-10-21 00:33:05
 // @ LastEditors  : Eacher
 // @ --------------------------------------------------------------------------------<
 // @ Description  : 
@@ -44,22 +44,22 @@ const (
 
  */
 type ArpPacket struct {
-	HardwareType 	uint16
-	ProtocolType 	uint16
-	HardwareLen  	uint8
-	IPLen 		 	uint8
-	Operation 	 	uint16
-	SendHardware 	HardwareAddr
-	SendIP 			IPv4
-	TargetHardware 	HardwareAddr
-	TargetIP 		IPv4
+	HardwareType 	uint16 			`json:"hardware_type"`
+	ProtocolType 	uint16 			`json:"protocol_type"`
+	HardwareLen  	uint8 			`json:"hardware_len"`
+	IPLen 		 	uint8 			`json:"ip_len"`
+	Operation 	 	ArpOperation 	`json:"operation"`
+	SendHardware 	HardwareAddr 	`json:"send_hardware"`
+	SendIP 			IPv4 			`json:"send_ip"`
+	TargetHardware 	HardwareAddr 	`json:"target_hardware"`
+	TargetIP 		IPv4 			`json:"target_ip"`
 }
 
 func NewArpPacket(b [SizeofArpPacket]byte) (arp ArpPacket) {
 	arp = *(*ArpPacket)(unsafe.Pointer(&b[0]))
 	arp.HardwareType 	= binary.BigEndian.Uint16(b[0:2])
 	arp.ProtocolType 	= binary.BigEndian.Uint16(b[2:4])
-	arp.Operation 		= binary.BigEndian.Uint16(b[6:8])
+	arp.Operation 		= ArpOperation(binary.BigEndian.Uint16(b[6:8]))
 	return
 }
 
@@ -68,7 +68,7 @@ func (arp ArpPacket) WireFormat() []byte {
 	binary.BigEndian.PutUint16(b[:2], arp.HardwareType)
 	binary.BigEndian.PutUint16(b[2:4], arp.ProtocolType)
 	b[4], b[5] = arp.HardwareLen, arp.IPLen
-	binary.BigEndian.PutUint16(b[6:8], arp.Operation)
+	binary.BigEndian.PutUint16(b[6:8], uint16(arp.Operation))
 	*(*HardwareAddr)(b[8:14]) = arp.SendHardware
 	*(*IPv4)(b[14:18]) = arp.SendIP
 	*(*HardwareAddr)(b[18:24]) = arp.TargetHardware
@@ -76,11 +76,69 @@ func (arp ArpPacket) WireFormat() []byte {
 	return b[:]
 }
 
-func (arp ArpPacket) String() string {
-	str := "OP: request"
-	if 1 != arp.Operation {
-		str = "OP: replay" 
+// 解析以太网帧承载的 ARP 报文, 非 ARP 帧或长度不足时 ok 为 false
+func NewEthernetArp(b []byte) (eth EthernetPacket, arp ArpPacket, ok bool) {
+	if len(b) < SizeofEthernetPacket + SizeofArpPacket {
+		return
+	}
+	if eth = NewEthernetPacket(([SizeofEthernetPacket]byte)(b)); eth.FrameType != ETH_P_ARP {
+		return
+	}
+	arp = NewArpPacket(([SizeofArpPacket]byte)(b[SizeofEthernetPacket:]))
+	ok = arp.HardwareType == ARP_ETHERNETTYPE && arp.ProtocolType == ETH_P_IP
+	return
+}
+
+// 封装为以太网帧, 源地址为 SendHardware, RARP 报文使用 ETH_P_RARP 帧类型
+func (arp ArpPacket) EthernetFrame(dst HardwareAddr) []byte {
+	eth := EthernetPacket{HeadMAC: [2]HardwareAddr{dst, arp.SendHardware}, FrameType: ETH_P_ARP}
+	if arp.Operation.IsRarp() {
+		eth.FrameType = ETH_P_RARP
+	}
+	return append(eth.WireFormat(), arp.WireFormat()...)
+}
+
+/*
+	RFC 5227 2.1.1.  Probe Details
+	The host probes to see if an address is already in use by broadcasting an ARP Request
+	for the desired address.  The client MUST fill in the 'sender hardware address' field
+	of the ARP Request with the hardware address of the interface through which it is
+	sending the packet.  The 'sender IP address' field MUST be set to all zeroes; this is
+	to avoid polluting ARP caches in other hosts on the same link in the case where the
+	address turns out to be already in use by another host.  The 'target hardware address'
+	field is ignored and SHOULD be set to all zeroes.  The 'target IP address' field MUST
+	be set to the address being probed.  An ARP Request constructed this way, with an
+	all-zero 'sender IP address', is referred to as an 'ARP Probe'.
+ */
+func NewArpProbe(mac HardwareAddr, ip IPv4) ArpPacket {
+	return ArpPacket{
+		HardwareType: ARP_ETHERNETTYPE, ProtocolType: ETH_P_IP, HardwareLen: 6, IPLen: 4,
+		Operation: ARP_REQUEST, SendHardware: mac, TargetIP: ip,
 	}
+}
+
+/*
+	RFC 5227 2.3.  Announcing an Address
+	Having probed to determine that a desired address may be used safely, a host
+	implementing this specification MUST then announce that it is commencing to use this
+	address by broadcasting ANNOUNCE_NUM ARP Announcements, spaced ANNOUNCE_INTERVAL
+	seconds apart.  An ARP Announcement is identical to the ARP Probe described above,
+	except that now the sender and target IP addresses are both set to the host's newly
+	selected IPv4 address.
+ */
+func NewArpAnnounce(mac HardwareAddr, ip IPv4) ArpPacket {
+	arp := NewArpProbe(mac, ip)
+	arp.SendIP = ip
+	return arp
+}
+
+// sender IP 为 0.0.0.0 的请求报文
+func (arp ArpPacket) IsProbe() bool {
+	return arp.Operation == ARP_REQUEST && arp.SendIP == IPv4{}
+}
+
+func (arp ArpPacket) String() string {
+	str := "OP: " + arp.Operation.String()
 	str += " Src-MAC: " + arp.SendHardware.String() + " Src-IP: " + arp.SendIP.String()
 	str += " Dst-MAC: " + arp.TargetHardware.String() + " Dst-IP: " + arp.TargetIP.String()
 	return str
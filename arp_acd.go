@@ -0,0 +1,308 @@
+// @@
+// @ Author       : Eacher
+// @ Date         : 2026-10-19 16:05:12
+// @ LastEditTime : 2026-10-19 16:05:12
+// @ LastEditors  : Eacher
+// @ --------------------------------------------------------------------------------<
+// @ Description  : RFC 5227 IPv4 Address Conflict Detection
+// @ --------------------------------------------------------------------------------<
+// @ FilePath     : /20yyq/packet/arp_acd.go
+// @@
+package packet
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"os"
+	"time"
+)
+
+/*
+	RFC 5227 1.1.  Conventions and Terminology Used in This Document
+
+	PROBE_WAIT           1 second   (initial random delay)
+	PROBE_NUM            3          (number of probe packets)
+	PROBE_MIN            1 second   (minimum delay until repeated probe)
+	PROBE_MAX            2 seconds  (maximum delay until repeated probe)
+	ANNOUNCE_WAIT        2 seconds  (delay before announcing)
+	ANNOUNCE_NUM         2          (number of Announcement packets)
+	ANNOUNCE_INTERVAL    2 seconds  (time between Announcement packets)
+	MAX_CONFLICTS       10          (max conflicts before rate-limiting)
+	RATE_LIMIT_INTERVAL 60 seconds  (delay between successive attempts)
+	DEFEND_INTERVAL     10 seconds  (minimum interval between defensive ARPs)
+*/
+const (
+	ARP_PROBE_WAIT          = time.Second
+	ARP_PROBE_NUM           = 3
+	ARP_PROBE_MIN           = time.Second
+	ARP_PROBE_MAX           = 2 * time.Second
+	ARP_ANNOUNCE_WAIT       = 2 * time.Second
+	ARP_ANNOUNCE_NUM        = 2
+	ARP_ANNOUNCE_INTERVAL   = 2 * time.Second
+	ARP_MAX_CONFLICTS       = 10
+	ARP_RATE_LIMIT_INTERVAL = 60 * time.Second
+	ARP_DEFEND_INTERVAL     = 10 * time.Second
+)
+
+var ErrArpConflict = errors.New("arp: address conflict detected")
+
+type ArpConflictState uint8
+
+const (
+	ACD_INIT ArpConflictState = iota
+	ACD_PROBE
+	ACD_ANNOUNCE
+	ACD_DEFEND
+	ACD_CONFLICT
+)
+
+func (s ArpConflictState) String() string {
+	switch s {
+	case ACD_INIT:
+		return "init"
+	case ACD_PROBE:
+		return "probe"
+	case ACD_ANNOUNCE:
+		return "announce"
+	case ACD_DEFEND:
+		return "defend"
+	case ACD_CONFLICT:
+		return "conflict"
+	}
+	return "unknown"
+}
+
+// Handle 对收到的 ARP 报文给出的处理动作
+type ArpConflictAction uint8
+
+const (
+	ACD_ACTION_NONE ArpConflictAction = iota
+	ACD_ACTION_DEFEND
+	ACD_ACTION_CONFLICT
+)
+
+// 时间参数为零值时使用 RFC 5227 默认值
+type ArpConflictDetector struct {
+	Link         FrameReadWriter
+	HardwareAddr HardwareAddr
+	IP           IPv4
+
+	ProbeWait        time.Duration
+	ProbeNum         int
+	ProbeMin         time.Duration
+	ProbeMax         time.Duration
+	AnnounceWait     time.Duration
+	AnnounceNum      int
+	AnnounceInterval time.Duration
+	DefendInterval   time.Duration
+	Rand             *rand.Rand
+
+	// 冲突发生时回调, 参数为冲突方的 ARP 报文
+	OnConflict func(eth EthernetPacket, arp ArpPacket)
+
+	state      ArpConflictState
+	lastDefend time.Time
+}
+
+func (acd *ArpConflictDetector) State() ArpConflictState {
+	return acd.state
+}
+
+// 纯状态机入口, 不做任何 I/O, now 为报文到达时间
+func (acd *ArpConflictDetector) Handle(eth EthernetPacket, arp ArpPacket, now time.Time) ArpConflictAction {
+	if arp.SendHardware == acd.HardwareAddr || eth.HeadMAC[1] == acd.HardwareAddr {
+		return ACD_ACTION_NONE
+	}
+	switch acd.state {
+	case ACD_PROBE:
+		// 2.1.1 sender IP 为探测地址, 或者其他主机同时在探测该地址
+		if arp.SendIP == acd.IP || (arp.IsProbe() && arp.TargetIP == acd.IP) {
+			acd.conflict(eth, arp)
+			return ACD_ACTION_CONFLICT
+		}
+	case ACD_ANNOUNCE, ACD_DEFEND:
+		// 2.4 (b) DEFEND_INTERVAL 内仅防御一次, 再次冲突则放弃地址
+		if arp.SendIP != acd.IP {
+			break
+		}
+		if acd.lastDefend.IsZero() || now.Sub(acd.lastDefend) >= acd.defendInterval() {
+			acd.lastDefend = now
+			return ACD_ACTION_DEFEND
+		}
+		acd.conflict(eth, arp)
+		return ACD_ACTION_CONFLICT
+	}
+	return ACD_ACTION_NONE
+}
+
+func (acd *ArpConflictDetector) conflict(eth EthernetPacket, arp ArpPacket) {
+	acd.state = ACD_CONFLICT
+	if acd.OnConflict != nil {
+		acd.OnConflict(eth, arp)
+	}
+}
+
+// 依次执行探测与公告, 地址可用时返回 nil 并进入 ACD_DEFEND 状态, 地址被占用时返回 ErrArpConflict
+func (acd *ArpConflictDetector) Probe(ctx context.Context) error {
+	lw := watchLink(ctx, acd.Link)
+	defer lw.Close()
+	acd.state, acd.lastDefend = ACD_PROBE, time.Time{}
+	if err := acd.wait(ctx, lw, acd.jitter(0, acd.probeWait())); err != nil {
+		return err
+	}
+	probe := NewArpProbe(acd.HardwareAddr, acd.IP).EthernetFrame(Broadcast)
+	for i, num := 0, acd.probeNum(); i < num; i++ {
+		if err := acd.Link.WriteFrame(probe); err != nil {
+			return err
+		}
+		wait := acd.jitter(acd.probeMin(), acd.probeMax())
+		if i == num-1 {
+			wait = acd.announceWait()
+		}
+		if err := acd.wait(ctx, lw, wait); err != nil {
+			return err
+		}
+	}
+	acd.state = ACD_ANNOUNCE
+	if err := acd.announce(ctx, lw); err != nil {
+		return err
+	}
+	acd.state = ACD_DEFEND
+	return nil
+}
+
+// 广播 ANNOUNCE_NUM 次 ARP Announcement
+func (acd *ArpConflictDetector) Announce(ctx context.Context) error {
+	lw := watchLink(ctx, acd.Link)
+	defer lw.Close()
+	return acd.announce(ctx, lw)
+}
+
+func (acd *ArpConflictDetector) announce(ctx context.Context, lw *linkWatcher) error {
+	announce := NewArpAnnounce(acd.HardwareAddr, acd.IP).EthernetFrame(Broadcast)
+	for i, num := 0, acd.announceNum(); i < num; i++ {
+		if err := acd.Link.WriteFrame(announce); err != nil {
+			return err
+		}
+		if i < num-1 {
+			if err := acd.wait(ctx, lw, acd.announceInterval()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// 持续防御已占用的地址, 直到 ctx 结束或者地址丢失返回 ErrArpConflict
+func (acd *ArpConflictDetector) Defend(ctx context.Context) error {
+	lw := watchLink(ctx, acd.Link)
+	defer lw.Close()
+	acd.state = ACD_DEFEND
+	return acd.wait(ctx, lw, -1)
+}
+
+// 监听链路 d 时长并处理收到的 ARP 报文, d 小于 0 时一直监听
+func (acd *ArpConflictDetector) wait(ctx context.Context, lw *linkWatcher, d time.Duration) error {
+	var deadline time.Time
+	if d >= 0 {
+		deadline = time.Now().Add(d)
+	}
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		lw.setDeadline(deadline)
+		b, err := acd.Link.ReadFrame()
+		if err != nil {
+			if errors.Is(err, os.ErrDeadlineExceeded) {
+				if err = ctx.Err(); err != nil {
+					return err
+				}
+				if !deadline.IsZero() && !time.Now().Before(deadline) {
+					return nil
+				}
+				continue
+			}
+			return err
+		}
+		eth, arp, ok := NewEthernetArp(b)
+		if !ok {
+			continue
+		}
+		switch acd.Handle(eth, arp, time.Now()) {
+		case ACD_ACTION_CONFLICT:
+			return ErrArpConflict
+		case ACD_ACTION_DEFEND:
+			if err = acd.Link.WriteFrame(NewArpAnnounce(acd.HardwareAddr, acd.IP).EthernetFrame(Broadcast)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (acd *ArpConflictDetector) jitter(lo, hi time.Duration) time.Duration {
+	if hi <= lo {
+		return lo
+	}
+	if acd.Rand == nil {
+		acd.Rand = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	return lo + time.Duration(acd.Rand.Int63n(int64(hi-lo)))
+}
+
+func (acd *ArpConflictDetector) probeWait() time.Duration {
+	if acd.ProbeWait > 0 {
+		return acd.ProbeWait
+	}
+	return ARP_PROBE_WAIT
+}
+
+func (acd *ArpConflictDetector) probeNum() int {
+	if acd.ProbeNum > 0 {
+		return acd.ProbeNum
+	}
+	return ARP_PROBE_NUM
+}
+
+func (acd *ArpConflictDetector) probeMin() time.Duration {
+	if acd.ProbeMin > 0 {
+		return acd.ProbeMin
+	}
+	return ARP_PROBE_MIN
+}
+
+func (acd *ArpConflictDetector) probeMax() time.Duration {
+	if acd.ProbeMax > 0 {
+		return acd.ProbeMax
+	}
+	return ARP_PROBE_MAX
+}
+
+func (acd *ArpConflictDetector) announceWait() time.Duration {
+	if acd.AnnounceWait > 0 {
+		return acd.AnnounceWait
+	}
+	return ARP_ANNOUNCE_WAIT
+}
+
+func (acd *ArpConflictDetector) announceNum() int {
+	if acd.AnnounceNum > 0 {
+		return acd.AnnounceNum
+	}
+	return ARP_ANNOUNCE_NUM
+}
+
+func (acd *ArpConflictDetector) announceInterval() time.Duration {
+	if acd.AnnounceInterval > 0 {
+		return acd.AnnounceInterval
+	}
+	return ARP_ANNOUNCE_INTERVAL
+}
+
+func (acd *ArpConflictDetector) defendInterval() time.Duration {
+	if acd.DefendInterval > 0 {
+		return acd.DefendInterval
+	}
+	return ARP_DEFEND_INTERVAL
+}
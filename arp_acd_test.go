@@ -0,0 +1,134 @@
+// @@
+// @ Author       : Eacher
+// @ Date         : 2026-10-21 16:29:54
+// @ LastEditTime : 2026-10-21 16:29:54
+// @ LastEditors  : Eacher
+// @ --------------------------------------------------------------------------------<
+// @ Description  : ARP 地址冲突检测内存链路测试
+// @ --------------------------------------------------------------------------------<
+// @ FilePath     : /20yyq/packet/arp_acd_test.go
+// @@
+package packet
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func newArpTestDetector(link FrameReadWriter, mac HardwareAddr, ip IPv4) *ArpConflictDetector {
+	return &ArpConflictDetector{
+		Link: link, HardwareAddr: mac, IP: ip,
+		ProbeWait: 10 * time.Millisecond, ProbeMin: 10 * time.Millisecond, ProbeMax: 20 * time.Millisecond,
+		AnnounceWait: 20 * time.Millisecond, AnnounceInterval: 10 * time.Millisecond, DefendInterval: time.Second,
+	}
+}
+
+// 读取 link 上的 ARP 报文, 对每个报文调用 reply, 返回值不为 nil 时写回链路
+func serveArpTestPeer(link FrameReadWriter, reply func(ArpPacket) []byte) <-chan ArpPacket {
+	seen := make(chan ArpPacket, 16)
+	go func() {
+		defer close(seen)
+		for {
+			b, err := link.ReadFrame()
+			if err != nil {
+				return
+			}
+			if _, arp, ok := NewEthernetArp(b); ok {
+				seen <- arp
+				if frame := reply(arp); frame != nil {
+					link.WriteFrame(frame)
+				}
+			}
+		}
+	}()
+	return seen
+}
+
+func TestArpConflictDetectorProbe(t *testing.T) {
+	a, b := NewFramePipe()
+	mac, ip := HardwareAddr{0x02, 0, 0, 0, 0, 0x01}, IPv4{10, 0, 0, 5}
+	seen := serveArpTestPeer(b, func(ArpPacket) []byte { return nil })
+	acd := newArpTestDetector(a, mac, ip)
+	if err := acd.Probe(context.Background()); err != nil || acd.State() != ACD_DEFEND {
+		t.Fatalf("Probe() = %v, state %v; want nil, defend", err, acd.State())
+	}
+	a.Close()
+	var got []ArpPacket
+	for arp := range seen {
+		got = append(got, arp)
+	}
+	if len(got) != ARP_PROBE_NUM + ARP_ANNOUNCE_NUM {
+		t.Fatalf("sent %d ARP packets; want %d probes and %d announcements", len(got), ARP_PROBE_NUM, ARP_ANNOUNCE_NUM)
+	}
+	for i, arp := range got {
+		if want := i < ARP_PROBE_NUM; arp.IsProbe() != want || arp.TargetIP != ip || arp.SendHardware != mac {
+			t.Errorf("packet %d = %+v; want probe %v for %v", i, arp, want, ip)
+		}
+		if i >= ARP_PROBE_NUM && arp.SendIP != ip {
+			t.Errorf("announcement %d sender IP = %v; want %v", i, arp.SendIP, ip)
+		}
+	}
+}
+
+// 探测期间其它主机应答该地址
+func TestArpConflictDetectorProbeConflict(t *testing.T) {
+	a, b := NewFramePipe()
+	defer a.Close()
+	mac, peer, ip := HardwareAddr{0x02, 0, 0, 0, 0, 0x01}, HardwareAddr{0x02, 0, 0, 0, 0, 0x02}, IPv4{10, 0, 0, 5}
+	serveArpTestPeer(b, func(arp ArpPacket) []byte {
+		if !arp.IsProbe() {
+			return nil
+		}
+		reply := ArpPacket{
+			HardwareType: ARP_ETHERNETTYPE, ProtocolType: ETH_P_IP, HardwareLen: 6, IPLen: 4, Operation: ARP_REPLY,
+			SendHardware: peer, SendIP: ip, TargetHardware: arp.SendHardware,
+		}
+		return reply.EthernetFrame(arp.SendHardware)
+	})
+	var conflict ArpPacket
+	acd := newArpTestDetector(a, mac, ip)
+	acd.OnConflict = func(eth EthernetPacket, arp ArpPacket) { conflict = arp }
+	ctx, cancel := context.WithTimeout(context.Background(), 5 * time.Second)
+	defer cancel()
+	if err := acd.Probe(ctx); err != ErrArpConflict || acd.State() != ACD_CONFLICT {
+		t.Fatalf("Probe() = %v, state %v; want ErrArpConflict, conflict", err, acd.State())
+	}
+	if conflict.SendHardware != peer {
+		t.Errorf("OnConflict sender = %v; want %v", conflict.SendHardware, peer)
+	}
+}
+
+// DEFEND_INTERVAL 内第一次冲突发送公告防御, 第二次放弃地址
+func TestArpConflictDetectorDefend(t *testing.T) {
+	a, b := NewFramePipe()
+	defer b.Close()
+	mac, peer, ip := HardwareAddr{0x02, 0, 0, 0, 0, 0x01}, HardwareAddr{0x02, 0, 0, 0, 0, 0x02}, IPv4{10, 0, 0, 5}
+	acd := newArpTestDetector(a, mac, ip)
+	ctx, cancel := context.WithTimeout(context.Background(), 5 * time.Second)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- acd.Defend(ctx) }()
+
+	claim := NewArpAnnounce(peer, ip).EthernetFrame(Broadcast)
+	if err := b.WriteFrame(claim); err != nil {
+		t.Fatal(err)
+	}
+	b.SetReadDeadline(time.Now().Add(time.Second))
+	frame, err := b.ReadFrame()
+	if err != nil {
+		t.Fatalf("no defending announcement: %v", err)
+	}
+	if _, arp, ok := NewEthernetArp(frame); !ok || arp.SendHardware != mac || arp.SendIP != ip {
+		t.Errorf("defending packet = %+v; want announcement of %v by %v", arp, ip, mac)
+	}
+	b.WriteFrame(claim)
+	select {
+	case err := <-done:
+		if err != ErrArpConflict || acd.State() != ACD_CONFLICT {
+			t.Errorf("Defend() = %v, state %v; want ErrArpConflict, conflict", err, acd.State())
+		}
+	case <-ctx.Done():
+		t.Fatal("Defend() did not give up the address")
+	}
+}
@@ -0,0 +1,410 @@
+// @@
+// @ Author       : Eacher
+// @ Date         : 2026-10-19 17:26:41
+// @ LastEditTime : 2026-10-21 15:24:17
+// @ LastEditors  : Eacher
+// @ --------------------------------------------------------------------------------<
+// @ Description  : 任意长度硬件/协议地址的 ARP, RARP, InARP
+// @ --------------------------------------------------------------------------------<
+// @ FilePath     : /20yyq/packet/arp_generic.go
+// @@
+package packet
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"strconv"
+)
+
+/*
+	IANA Address Resolution Protocol (ARP) Parameters
+
+	Hardware Type (hrd)
+	 1  Ethernet (10Mb)
+	 6  IEEE 802 Networks
+	15  Frame Relay
+	16  Asynchronous Transmission Mode (ATM)
+	24  IEEE 1394.1995
+	27  EUI-64
+	32  InfiniBand (TM)
+
+	Operation Codes (op)
+	 1  REQUEST                      [RFC826]
+	 2  REPLY                        [RFC826]
+	 3  request Reverse              [RFC903]
+	 4  reply Reverse                [RFC903]
+	 5  DRARP-Request                [RFC1931]
+	 6  DRARP-Reply                  [RFC1931]
+	 7  DRARP-Error                  [RFC1931]
+	 8  InARP-Request                [RFC2390]
+	 9  InARP-Reply                  [RFC2390]
+	10  ARP-NAK                      [RFC1577]
+*/
+const (
+	ARP_IEEE802TYPE    = 0x06
+	ARP_FRAMERELAYTYPE = 0x0f
+	ARP_ATMTYPE        = 0x10
+	ARP_IEEE1394TYPE   = 0x18
+	ARP_EUI64TYPE      = 0x1b
+	ARP_INFINIBANDTYPE = 0x20
+	// RFC 1577 ATMARP, 报文格式与 RFC 826 不同
+	ARP_ATMARPTYPE     = 0x13
+
+	// ar$hrd ar$pro ar$hln ar$pln ar$op
+	SizeofArpHeader = 0x08
+	// ar$hrd ar$pro ar$shtl ar$sstl ar$op ar$spln ar$thtl ar$tstl ar$tpln
+	SizeofAtmArpHeader = 0x0c
+
+	// ATMARP type & length 字段: 第 6 位为 1 时是 E.164 地址, 低 6 位为长度
+	ATMARP_E164        = 0x40
+	ATMARP_LEN_MASK    = 0x3f
+)
+
+type ArpOperation uint16
+
+const (
+	RARP_REQUEST ArpOperation = iota + 3
+	RARP_REPLY
+	DRARP_REQUEST
+	DRARP_REPLY
+	DRARP_ERROR
+	INARP_REQUEST
+	INARP_REPLY
+	ARP_NAK
+)
+
+var (
+	ErrArpTruncated = errors.New("arp: packet too short for address lengths")
+)
+
+func (op ArpOperation) String() string {
+	switch op {
+	case ARP_REQUEST:
+		return "request"
+	case ARP_REPLY:
+		return "reply"
+	case RARP_REQUEST:
+		return "rarp-request"
+	case RARP_REPLY:
+		return "rarp-reply"
+	case DRARP_REQUEST:
+		return "drarp-request"
+	case DRARP_REPLY:
+		return "drarp-reply"
+	case DRARP_ERROR:
+		return "drarp-error"
+	case INARP_REQUEST:
+		return "inarp-request"
+	case INARP_REPLY:
+		return "inarp-reply"
+	case ARP_NAK:
+		return "nak"
+	}
+	return "op(" + strconv.Itoa(int(op)) + ")"
+}
+
+// RFC 903 RARP 报文在以太网上使用 ETH_P_RARP 帧类型
+func (op ArpOperation) IsRarp() bool {
+	return op == RARP_REQUEST || op == RARP_REPLY
+}
+
+/*
+	RFC 2734 4.2.  ARP Packet Format
+	The IEEE 1394 ARP packet has no target hardware address, the sender hardware
+	address is 16 octets long:
+
+	sender_unique_ID      8 octets  EUI-64
+	sender_max_rec        1 octet
+	sspd                  1 octet
+	sender_unicast_FIFO   6 octets
+	sender_IP_address     4 octets
+	target_IP_address     4 octets
+
+	RFC 1577 ATMARP/InATMARP Packet Format
+	ATMARP 的硬件地址为 ATM 号码加可选的 ATM 子地址, 各地址长度相互独立:
+
+	ar$hrd  16 bits  Hardware type (19)
+	ar$pro  16 bits  Protocol type
+	ar$shtl  8 bits  Type & length of source ATM number (q)
+	ar$sstl  8 bits  Type & length of source ATM subaddress (r)
+	ar$op   16 bits  Operation code
+	ar$spln  8 bits  Length of source protocol address (s)
+	ar$thtl  8 bits  Type & length of target ATM number (x)
+	ar$tstl  8 bits  Type & length of target ATM subaddress (y)
+	ar$tpln  8 bits  Length of target protocol address (z)
+	ar$sha  qoctets  source ATM number
+	ar$ssa  roctets  source ATM subaddress
+	ar$spa  soctets  source protocol address
+	ar$tha  xoctets  target ATM number
+	ar$tsa  yoctets  target ATM subaddress
+	ar$tpa  zoctets  target protocol address
+*/
+type ArpGenericPacket struct {
+	HardwareType   uint16
+	ProtocolType   uint16
+	Operation      ArpOperation
+	SendHardware   []byte
+	SendProtocol   []byte
+	TargetHardware []byte
+	TargetProtocol []byte
+
+	// 以下字段只用于 ATMARP, SendHardware/TargetHardware 为 ATM 号码
+	// SendAtmType/TargetAtmType 为 0 (ATM Forum NSAPA) 或 ATMARP_E164, 子地址总是 NSAPA 格式
+	SendAtmType      uint8
+	SendSubaddress   []byte
+	TargetAtmType    uint8
+	TargetSubaddress []byte
+}
+
+/*
+	按 ar$hln/ar$pln 解析地址, b 为不含链路层头部的 ARP 报文
+	ar$hrd 为 ARP_ATMARPTYPE 时按 RFC 1577 的 ATMARP 格式解析, ARP_NAK 即为该格式的应答
+*/
+func NewArpGenericPacket(b []byte) (arp ArpGenericPacket, err error) {
+	if len(b) < SizeofArpHeader {
+		return arp, ErrArpTruncated
+	}
+	arp.HardwareType = binary.BigEndian.Uint16(b[0:2])
+	arp.ProtocolType = binary.BigEndian.Uint16(b[2:4])
+	arp.Operation = ArpOperation(binary.BigEndian.Uint16(b[6:8]))
+	if arp.HardwareType == ARP_ATMARPTYPE {
+		return newAtmArpPacket(b)
+	}
+	hln, pln := int(b[4]), int(b[5])
+	length := SizeofArpHeader + 2*hln + 2*pln
+	if arp.HardwareType == ARP_IEEE1394TYPE {
+		length -= hln
+	}
+	if len(b) < length {
+		return ArpGenericPacket{}, ErrArpTruncated
+	}
+	next := func(n int) []byte {
+		v := make([]byte, n)
+		copy(v, b[:n])
+		b = b[n:]
+		return v
+	}
+	b = b[SizeofArpHeader:]
+	arp.SendHardware, arp.SendProtocol = next(hln), next(pln)
+	if arp.HardwareType != ARP_IEEE1394TYPE {
+		arp.TargetHardware = next(hln)
+	}
+	arp.TargetProtocol = next(pln)
+	return
+}
+
+func newAtmArpPacket(b []byte) (arp ArpGenericPacket, err error) {
+	if len(b) < SizeofAtmArpHeader {
+		return arp, ErrArpTruncated
+	}
+	arp.HardwareType = binary.BigEndian.Uint16(b[0:2])
+	arp.ProtocolType = binary.BigEndian.Uint16(b[2:4])
+	arp.Operation = ArpOperation(binary.BigEndian.Uint16(b[6:8]))
+	shtl, sstl, spln, thtl, tstl, tpln := b[4], b[5], int(b[8]), b[9], b[10], int(b[11])
+	lens := []int{int(shtl & ATMARP_LEN_MASK), int(sstl & ATMARP_LEN_MASK), spln, int(thtl & ATMARP_LEN_MASK), int(tstl & ATMARP_LEN_MASK), tpln}
+	length := SizeofAtmArpHeader
+	for _, n := range lens {
+		length += n
+	}
+	if len(b) < length {
+		return ArpGenericPacket{}, ErrArpTruncated
+	}
+	b = b[SizeofAtmArpHeader:]
+	next := func(n int) []byte {
+		v := make([]byte, n)
+		copy(v, b[:n])
+		b = b[n:]
+		return v
+	}
+	arp.SendAtmType, arp.TargetAtmType = shtl & ATMARP_E164, thtl & ATMARP_E164
+	arp.SendHardware, arp.SendSubaddress, arp.SendProtocol = next(lens[0]), next(lens[1]), next(lens[2])
+	arp.TargetHardware, arp.TargetSubaddress, arp.TargetProtocol = next(lens[3]), next(lens[4]), next(lens[5])
+	return
+}
+
+/*
+	地址长度超过 255 或者目标地址长度与发送方不一致 (为空时填充 0) 时返回 nil
+	IEEE 1394 报文不含目标硬件地址, TargetHardware 必须为空
+	ATMARP 报文的各地址长度相互独立, ATM 号码与子地址不能超过 63 字节
+*/
+func (arp ArpGenericPacket) WireFormat() []byte {
+	if arp.HardwareType == ARP_ATMARPTYPE {
+		return arp.atmArpWireFormat()
+	}
+	hln, pln := len(arp.SendHardware), len(arp.SendProtocol)
+	switch {
+	case hln > 255 || pln > 255:
+		return nil
+	case arp.HardwareType == ARP_IEEE1394TYPE && len(arp.TargetHardware) != 0:
+		return nil
+	case len(arp.TargetHardware) != 0 && len(arp.TargetHardware) != hln:
+		return nil
+	case len(arp.TargetProtocol) != 0 && len(arp.TargetProtocol) != pln:
+		return nil
+	}
+	b := make([]byte, SizeofArpHeader, SizeofArpHeader+2*len(arp.SendHardware)+2*len(arp.SendProtocol))
+	binary.BigEndian.PutUint16(b[0:2], arp.HardwareType)
+	binary.BigEndian.PutUint16(b[2:4], arp.ProtocolType)
+	b[4], b[5] = uint8(hln), uint8(pln)
+	binary.BigEndian.PutUint16(b[6:8], uint16(arp.Operation))
+	b = append(b, arp.SendHardware...)
+	b = append(b, arp.SendProtocol...)
+	if arp.HardwareType != ARP_IEEE1394TYPE {
+		target := make([]byte, hln)
+		copy(target, arp.TargetHardware)
+		b = append(b, target...)
+	}
+	target := make([]byte, pln)
+	copy(target, arp.TargetProtocol)
+	return append(b, target...)
+}
+
+func (arp ArpGenericPacket) atmArpWireFormat() []byte {
+	addrs := [][]byte{arp.SendHardware, arp.SendSubaddress, arp.SendProtocol, arp.TargetHardware, arp.TargetSubaddress, arp.TargetProtocol}
+	length := SizeofAtmArpHeader
+	for i, v := range addrs {
+		if (i % 3 == 2 && len(v) > 255) || (i % 3 != 2 && len(v) > ATMARP_LEN_MASK) {
+			return nil
+		}
+		length += len(v)
+	}
+	if arp.SendAtmType & ^uint8(ATMARP_E164) != 0 || arp.TargetAtmType & ^uint8(ATMARP_E164) != 0 {
+		return nil
+	}
+	b := make([]byte, SizeofAtmArpHeader, length)
+	binary.BigEndian.PutUint16(b[0:2], arp.HardwareType)
+	binary.BigEndian.PutUint16(b[2:4], arp.ProtocolType)
+	b[4], b[5] = arp.SendAtmType | uint8(len(arp.SendHardware)), uint8(len(arp.SendSubaddress))
+	binary.BigEndian.PutUint16(b[6:8], uint16(arp.Operation))
+	b[8] = uint8(len(arp.SendProtocol))
+	b[9], b[10], b[11] = arp.TargetAtmType | uint8(len(arp.TargetHardware)), uint8(len(arp.TargetSubaddress)), uint8(len(arp.TargetProtocol))
+	for _, v := range addrs {
+		b = append(b, v...)
+	}
+	return b
+}
+
+// 以太网 IPv4 报文转换为定长 ArpPacket
+func (arp ArpGenericPacket) ArpPacket() (ArpPacket, bool) {
+	if len(arp.SendHardware) != 6 || len(arp.SendProtocol) != 4 || len(arp.TargetHardware) != 6 || len(arp.TargetProtocol) != 4 {
+		return ArpPacket{}, false
+	}
+	return ArpPacket{
+		HardwareType: arp.HardwareType, ProtocolType: arp.ProtocolType, HardwareLen: 6, IPLen: 4,
+		Operation: arp.Operation, SendHardware: HardwareAddr(arp.SendHardware), SendIP: IPv4(arp.SendProtocol),
+		TargetHardware: HardwareAddr(arp.TargetHardware), TargetIP: IPv4(arp.TargetProtocol),
+	}, true
+}
+
+func (arp ArpPacket) ArpGenericPacket() ArpGenericPacket {
+	return ArpGenericPacket{
+		HardwareType: arp.HardwareType, ProtocolType: arp.ProtocolType, Operation: arp.Operation,
+		SendHardware: append([]byte(nil), arp.SendHardware[:]...), SendProtocol: append([]byte(nil), arp.SendIP[:]...),
+		TargetHardware: append([]byte(nil), arp.TargetHardware[:]...), TargetProtocol: append([]byte(nil), arp.TargetIP[:]...),
+	}
+}
+
+func (arp ArpGenericPacket) String() string {
+	str := "OP: " + arp.Operation.String() + " HW: " + arpHardwareString(arp.HardwareType)
+	str += " Src-HW: " + arpHardwareAddrString(arp.SendHardware) + " Src-PA: " + arpProtocolAddrString(arp.SendProtocol)
+	if arp.HardwareType != ARP_IEEE1394TYPE {
+		str += " Dst-HW: " + arpHardwareAddrString(arp.TargetHardware)
+	}
+	str += " Dst-PA: " + arpProtocolAddrString(arp.TargetProtocol)
+	if arp.HardwareType == ARP_ATMARPTYPE {
+		str += " Src-SA: " + arpHardwareAddrString(arp.SendSubaddress) + " Dst-SA: " + arpHardwareAddrString(arp.TargetSubaddress)
+	}
+	return str
+}
+
+/*
+	RFC 1577 ATMARP Server Operational Requirements
+	ARP_NAK 为 ATMARP 服务器无法解析请求时的应答, 除操作码外原样复制请求报文
+*/
+func NewAtmArpNak(req ArpGenericPacket) ArpGenericPacket {
+	req.Operation = ARP_NAK
+	return req
+}
+
+/*
+	RFC 903 A Reverse Address Resolution Protocol
+	"Request reverse" is a request for the protocol address corresponding to the
+	hardware address in the target hardware address field.  In the typical case, the
+	sender hardware address and the target hardware address are the same, and the
+	protocol address fields are undefined.
+*/
+func NewRarpRequest(mac HardwareAddr) ArpPacket {
+	return ArpPacket{
+		HardwareType: ARP_ETHERNETTYPE, ProtocolType: ETH_P_IP, HardwareLen: 6, IPLen: 4,
+		Operation: RARP_REQUEST, SendHardware: mac, TargetHardware: mac,
+	}
+}
+
+// RARP 服务器应答, TargetIP 为分配给 req.TargetHardware 的地址
+func NewRarpReply(req ArpPacket, mac HardwareAddr, ip, target IPv4) ArpPacket {
+	return ArpPacket{
+		HardwareType: req.HardwareType, ProtocolType: req.ProtocolType, HardwareLen: 6, IPLen: 4,
+		Operation: RARP_REPLY, SendHardware: mac, SendIP: ip, TargetHardware: req.TargetHardware, TargetIP: target,
+	}
+}
+
+/*
+	RFC 2390 7.1.  InARP Request and Response Packet Formats
+	The InARP request packet carries the requester's hardware and protocol addresses
+	and the target's hardware address; the target protocol address is zero.  The
+	responder fills in its protocol address and swaps sender and target.
+*/
+func NewInArpRequest(htype, ptype uint16, sha, spa, tha []byte) ArpGenericPacket {
+	return ArpGenericPacket{
+		HardwareType: htype, ProtocolType: ptype, Operation: INARP_REQUEST,
+		SendHardware: sha, SendProtocol: spa, TargetHardware: tha, TargetProtocol: make([]byte, len(spa)),
+	}
+}
+
+func NewInArpReply(req ArpGenericPacket, pa []byte) ArpGenericPacket {
+	return ArpGenericPacket{
+		HardwareType: req.HardwareType, ProtocolType: req.ProtocolType, Operation: INARP_REPLY,
+		SendHardware: req.TargetHardware, SendProtocol: pa, TargetHardware: req.SendHardware, TargetProtocol: req.SendProtocol,
+	}
+}
+
+func arpHardwareString(t uint16) string {
+	switch t {
+	case ARP_ETHERNETTYPE:
+		return "ethernet"
+	case ARP_IEEE802TYPE:
+		return "ieee802"
+	case ARP_FRAMERELAYTYPE:
+		return "frame-relay"
+	case ARP_ATMTYPE:
+		return "atm"
+	case ARP_ATMARPTYPE:
+		return "atmarp"
+	case ARP_IEEE1394TYPE:
+		return "ieee1394"
+	case ARP_EUI64TYPE:
+		return "eui64"
+	case ARP_INFINIBANDTYPE:
+		return "infiniband"
+	}
+	return "hrd(" + strconv.Itoa(int(t)) + ")"
+}
+
+func arpHardwareAddrString(b []byte) string {
+	buf := make([]byte, 0, len(b)*3)
+	for i, v := range b {
+		if i > 0 {
+			buf = append(buf, ':')
+		}
+		buf = append(buf, hexDigit[v>>4], hexDigit[v&0xF])
+	}
+	return string(buf)
+}
+
+func arpProtocolAddrString(b []byte) string {
+	if len(b) == net.IPv4len || len(b) == net.IPv6len {
+		return net.IP(b).String()
+	}
+	return arpHardwareAddrString(b)
+}
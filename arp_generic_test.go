@@ -0,0 +1,60 @@
+// @@
+// @ Author       : Eacher
+// @ Date         : 2026-10-21 15:24:17
+// @ LastEditTime : 2026-10-21 15:24:17
+// @ LastEditors  : Eacher
+// @ --------------------------------------------------------------------------------<
+// @ Description  : 任意长度地址 ARP 与 ATMARP 编解码测试
+// @ --------------------------------------------------------------------------------<
+// @ FilePath     : /20yyq/packet/arp_generic_test.go
+// @@
+package packet
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestArpGenericAtmArp(t *testing.T) {
+	nsap := bytes.Repeat([]byte{0x47}, 20)
+	req := ArpGenericPacket{
+		HardwareType: ARP_ATMARPTYPE, ProtocolType: ETH_P_IP, Operation: ARP_REQUEST,
+		SendHardware: nsap, SendProtocol: []byte{10, 0, 0, 1},
+		TargetHardware: []byte{}, TargetSubaddress: []byte{}, SendSubaddress: []byte{}, TargetProtocol: []byte{10, 0, 0, 2},
+	}
+	b := req.WireFormat()
+	want := []byte{0x00, 0x13, 0x08, 0x00, 20, 0, 0x00, 0x01, 4, 0, 0, 4}
+	if !bytes.HasPrefix(b, want) || len(b) != SizeofAtmArpHeader + 20 + 4 + 4 {
+		t.Fatalf("WireFormat() = % x; want header % x and %d bytes", b, want, SizeofAtmArpHeader + 28)
+	}
+	nak := NewAtmArpNak(req)
+	nak.SendAtmType, nak.TargetSubaddress = ATMARP_E164, []byte{1, 2, 3}
+	got, err := NewArpGenericPacket(nak.WireFormat())
+	if err != nil || !reflect.DeepEqual(got, nak) {
+		t.Errorf("NewArpGenericPacket(nak) = %+v, %v; want %+v", got, err, nak)
+	}
+	if got.Operation.String() != "nak" {
+		t.Errorf("Operation = %q; want nak", got.Operation.String())
+	}
+	if _, err := NewArpGenericPacket(b[:len(b) - 1]); err != ErrArpTruncated {
+		t.Errorf("truncated ATMARP error = %v; want ErrArpTruncated", err)
+	}
+	if req.SendHardware = make([]byte, 64); req.WireFormat() != nil {
+		t.Errorf("WireFormat() with 64 byte ATM number != nil")
+	}
+}
+
+func TestArpGenericRoundTrip(t *testing.T) {
+	tests := []ArpGenericPacket{
+		NewInArpRequest(ARP_INFINIBANDTYPE, ETH_P_IP, bytes.Repeat([]byte{1}, 20), []byte{10, 0, 0, 1}, bytes.Repeat([]byte{2}, 20)),
+		{HardwareType: ARP_IEEE1394TYPE, ProtocolType: ETH_P_IP, Operation: ARP_REQUEST, SendHardware: bytes.Repeat([]byte{3}, 16), SendProtocol: []byte{10, 0, 0, 1}, TargetProtocol: []byte{10, 0, 0, 2}},
+		NewRarpRequest(HardwareAddr{0x02, 0, 0, 0, 0, 1}).ArpGenericPacket(),
+	}
+	for _, tt := range tests {
+		got, err := NewArpGenericPacket(tt.WireFormat())
+		if err != nil || !reflect.DeepEqual(got, tt) {
+			t.Errorf("round trip %v = %+v, %v", tt, got, err)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+// @@
+// @ Author       : Eacher
+// @ Date         : 2026-10-19 16:48:30
+// @ LastEditTime : 2026-10-21 16:36:12
+// @ LastEditors  : Eacher
+// @ --------------------------------------------------------------------------------<
+// @ Description  : RFC 3927 IPv4 Link-Local 地址自动配置
+// @ --------------------------------------------------------------------------------<
+// @ FilePath     : /20yyq/packet/arp_ipv4ll.go
+// @@
+package packet
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"math/rand"
+	"time"
+)
+
+/*
+	RFC 3927 2.1.  Link-Local Address Selection
+	When a host wishes to configure an IPv4 Link-Local address, it selects an address
+	using a pseudo-random number generator with a uniform distribution in the range from
+	169.254.1.0 to 169.254.254.255 inclusive.
+
+	The IPv4 prefix 169.254/16 is registered with the IANA for this purpose.  The first
+	256 and last 256 addresses in the 169.254/16 prefix are reserved for future use and
+	MUST NOT be selected by a host using this dynamic configuration mechanism.
+
+	The pseudo-random number generation algorithm MUST be chosen so that different hosts
+	do not generate the same sequence of numbers.  If the host has access to persistent
+	information that is different for each host, such as its IEEE 802 MAC address, then
+	the pseudo-random number generator SHOULD be seeded using a value derived from this
+	information.  This means that even without using any other persistent storage, a
+	host will usually select the same IPv4 Link-Local address each time it is booted,
+	which can be convenient for debugging and other operational reasons.
+*/
+var IPv4LinkLocalPrefix = IPv4{169, 254, 0, 0}
+
+const (
+	ipv4LLFirst = 0x0100
+	ipv4LLLast  = 0xfeff
+)
+
+// 是否为 169.254/16 地址
+func IsIPv4LinkLocal(ip IPv4) bool {
+	return ip[0] == IPv4LinkLocalPrefix[0] && ip[1] == IPv4LinkLocalPrefix[1]
+}
+
+// 由 MAC 地址派生的伪随机数生成器, 同一主机每次启动得到相同的候选地址序列
+func NewIPv4LinkLocalRand(mac HardwareAddr) *rand.Rand {
+	h := fnv.New64a()
+	h.Write(mac[:])
+	return rand.New(rand.NewSource(int64(h.Sum64())))
+}
+
+// 在 169.254.1.0 - 169.254.254.255 中均匀选取候选地址
+func NewIPv4LinkLocalCandidate(r *rand.Rand) IPv4 {
+	n := ipv4LLFirst + r.Intn(ipv4LLLast-ipv4LLFirst+1)
+	return IPv4{IPv4LinkLocalPrefix[0], IPv4LinkLocalPrefix[1], byte(n >> 8), byte(n)}
+}
+
+// Detector 作为探测参数模板, 其 Link/HardwareAddr/IP 字段由分配器填充
+type IPv4LinkLocal struct {
+	Link         FrameReadWriter
+	HardwareAddr HardwareAddr
+	// 优先尝试的地址, 例如上次启动成功占用的地址
+	Preferred         IPv4
+	Detector          ArpConflictDetector
+	MaxConflicts      int
+	RateLimitInterval time.Duration
+	Rand              *rand.Rand
+
+	// 地址占用成功
+	OnClaim func(ip IPv4)
+	// 探测或防御过程中地址冲突
+	OnConflict func(ip IPv4)
+
+	ip        IPv4
+	conflicts int
+}
+
+// 当前已占用的地址, 未占用时为 0.0.0.0
+func (ll *IPv4LinkLocal) IP() IPv4 {
+	return ll.ip
+}
+
+// 上次成功占用地址之后的冲突次数
+func (ll *IPv4LinkLocal) Conflicts() int {
+	return ll.conflicts
+}
+
+/*
+	探测候选地址直到成功占用一个地址, 冲突超过 MAX_CONFLICTS 次后每 RATE_LIMIT_INTERVAL 最多尝试一次
+	成功占用地址后冲突计数清零, 之后的冲突重新开始计数 (RFC 3927 2.2.1)
+*/
+func (ll *IPv4LinkLocal) Claim(ctx context.Context) (IPv4, error) {
+	if ll.Rand == nil {
+		ll.Rand = NewIPv4LinkLocalRand(ll.HardwareAddr)
+	}
+	ll.ip = IPv4{}
+	candidate := ll.Preferred
+	if !IsIPv4LinkLocal(candidate) || candidate[2] == 0 || candidate[2] == 0xff {
+		candidate = NewIPv4LinkLocalCandidate(ll.Rand)
+	}
+	for {
+		if ll.conflicts >= ll.maxConflicts() {
+			if err := sleepContext(ctx, ll.rateLimitInterval()); err != nil {
+				return IPv4{}, err
+			}
+		}
+		acd := ll.detector(candidate)
+		err := acd.Probe(ctx)
+		if err == nil {
+			ll.ip, ll.Preferred, ll.conflicts = candidate, candidate, 0
+			if ll.OnClaim != nil {
+				ll.OnClaim(candidate)
+			}
+			return candidate, nil
+		}
+		if !errors.Is(err, ErrArpConflict) {
+			return IPv4{}, err
+		}
+		ll.conflict(candidate)
+		candidate = NewIPv4LinkLocalCandidate(ll.Rand)
+	}
+}
+
+// 占用地址并持续防御, 地址丢失后重新选择地址, 直到 ctx 结束
+func (ll *IPv4LinkLocal) Run(ctx context.Context) error {
+	for {
+		ip, err := ll.Claim(ctx)
+		if err != nil {
+			return err
+		}
+		acd := ll.detector(ip)
+		if err = acd.Defend(ctx); !errors.Is(err, ErrArpConflict) {
+			return err
+		}
+		ll.conflict(ip)
+		ll.ip, ll.Preferred = IPv4{}, IPv4{}
+	}
+}
+
+func (ll *IPv4LinkLocal) conflict(ip IPv4) {
+	ll.conflicts++
+	if ll.OnConflict != nil {
+		ll.OnConflict(ip)
+	}
+}
+
+func (ll *IPv4LinkLocal) detector(ip IPv4) *ArpConflictDetector {
+	acd := ll.Detector
+	acd.Link, acd.HardwareAddr, acd.IP = ll.Link, ll.HardwareAddr, ip
+	if acd.Rand == nil {
+		acd.Rand = ll.Rand
+	}
+	return &acd
+}
+
+func (ll *IPv4LinkLocal) maxConflicts() int {
+	if ll.MaxConflicts > 0 {
+		return ll.MaxConflicts
+	}
+	return ARP_MAX_CONFLICTS
+}
+
+func (ll *IPv4LinkLocal) rateLimitInterval() time.Duration {
+	if ll.RateLimitInterval > 0 {
+		return ll.RateLimitInterval
+	}
+	return ARP_RATE_LIMIT_INTERVAL
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
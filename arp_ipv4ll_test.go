@@ -0,0 +1,63 @@
+// @@
+// @ Author       : Eacher
+// @ Date         : 2026-10-21 16:36:12
+// @ LastEditTime : 2026-10-21 16:36:12
+// @ LastEditors  : Eacher
+// @ --------------------------------------------------------------------------------<
+// @ Description  : IPv4 Link-Local 地址分配测试
+// @ --------------------------------------------------------------------------------<
+// @ FilePath     : /20yyq/packet/arp_ipv4ll_test.go
+// @@
+package packet
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// 冲突计数在成功占用地址后清零, 之后的 Claim 不再受速率限制
+func TestIPv4LinkLocalConflictReset(t *testing.T) {
+	a, b := NewFramePipe()
+	defer a.Close()
+	mac, peer := HardwareAddr{0x02, 0, 0, 0, 0, 0x01}, HardwareAddr{0x02, 0, 0, 0, 0, 0x02}
+	var mu sync.Mutex
+	taken := map[IPv4]bool{}
+	serveArpTestPeer(b, func(arp ArpPacket) []byte {
+		mu.Lock()
+		defer mu.Unlock()
+		// 前两个候选地址已被占用
+		if !arp.IsProbe() || (len(taken) >= 2 && !taken[arp.TargetIP]) {
+			return nil
+		}
+		taken[arp.TargetIP] = true
+		reply := ArpPacket{
+			HardwareType: ARP_ETHERNETTYPE, ProtocolType: ETH_P_IP, HardwareLen: 6, IPLen: 4, Operation: ARP_REPLY,
+			SendHardware: peer, SendIP: arp.TargetIP, TargetHardware: arp.SendHardware,
+		}
+		return reply.EthernetFrame(arp.SendHardware)
+	})
+	var conflicts []IPv4
+	ll := &IPv4LinkLocal{
+		Link: a, HardwareAddr: mac, Detector: *newArpTestDetector(nil, HardwareAddr{}, IPv4{}),
+		MaxConflicts: 2, RateLimitInterval: 300 * time.Millisecond,
+		OnConflict: func(ip IPv4) { conflicts = append(conflicts, ip) },
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5 * time.Second)
+	defer cancel()
+	ip, err := ll.Claim(ctx)
+	if err != nil || !IsIPv4LinkLocal(ip) || taken[ip] {
+		t.Fatalf("Claim() = %v, %v; want a free link-local address", ip, err)
+	}
+	if len(conflicts) != 2 || ll.Conflicts() != 0 {
+		t.Errorf("conflicts = %v, Conflicts() = %d; want 2 conflicts then 0", conflicts, ll.Conflicts())
+	}
+	start := time.Now()
+	if again, err := ll.Claim(ctx); err != nil || again != ip {
+		t.Errorf("second Claim() = %v, %v; want %v", again, err, ip)
+	}
+	if d := time.Since(start); d >= ll.RateLimitInterval {
+		t.Errorf("second Claim() took %v; want no rate limit wait", d)
+	}
+}
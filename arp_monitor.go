@@ -0,0 +1,390 @@
+// @@
+// @ Author       : Eacher
+// @ Date         : 2026-10-19 18:10:05
+// @ LastEditTime : 2026-10-21 17:03:19
+// @ LastEditors  : Eacher
+// @ --------------------------------------------------------------------------------<
+// @ Description  : ARP 欺骗与异常检测
+// @ --------------------------------------------------------------------------------<
+// @ FilePath     : /20yyq/packet/arp_monitor.go
+// @@
+package packet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	// 同一 IP 在该时间窗口内切换回曾经使用过的 MAC 视为 flip-flop
+	ARP_MONITOR_FLIPFLOP_WINDOW = 5 * time.Minute
+	// 请求发出后等待应答的时长, 超时后的应答视为未请求应答
+	ARP_MONITOR_REQUEST_TIMEOUT = 5 * time.Second
+	// 同一 MAC 在 GRATUITOUS_WINDOW 内发送超过 GRATUITOUS_THRESHOLD 个免费 ARP 视为风暴
+	ARP_MONITOR_GRATUITOUS_WINDOW    = time.Second
+	ARP_MONITOR_GRATUITOUS_THRESHOLD = 10
+	// 超过该时长未出现的 IP 移出绑定表
+	ARP_MONITOR_BINDING_TIMEOUT = 4 * time.Hour
+	// 绑定表容量, 已满时不再学习新的 IP, 已有绑定照常检测
+	ARP_MONITOR_MAX_BINDINGS = 1 << 16
+	// 每个 IP 保留的历史 MAC 数
+	arpMonitorHistory = 4
+)
+
+type ArpAlertType uint8
+
+const (
+	// IP→MAC 绑定变更
+	ARP_ALERT_BINDING_CHANGE ArpAlertType = iota + 1
+	// IP 在多个 MAC 之间来回切换
+	ARP_ALERT_FLIP_FLOP
+	// 没有对应请求的应答
+	ARP_ALERT_UNSOLICITED_REPLY
+	// 免费 ARP 风暴
+	ARP_ALERT_GRATUITOUS_STORM
+	// 以太网源地址与 ARP 发送方硬件地址不一致
+	ARP_ALERT_ETHERNET_MISMATCH
+)
+
+func (t ArpAlertType) String() string {
+	switch t {
+	case ARP_ALERT_BINDING_CHANGE:
+		return "binding-change"
+	case ARP_ALERT_FLIP_FLOP:
+		return "flip-flop"
+	case ARP_ALERT_UNSOLICITED_REPLY:
+		return "unsolicited-reply"
+	case ARP_ALERT_GRATUITOUS_STORM:
+		return "gratuitous-storm"
+	case ARP_ALERT_ETHERNET_MISMATCH:
+		return "ethernet-mismatch"
+	}
+	return "unknown"
+}
+
+type ArpAlert struct {
+	Type ArpAlertType
+	Time time.Time
+	IP   IPv4
+	// 绑定变更前的 MAC, 以太网不一致时为以太网源地址
+	Old HardwareAddr
+	New HardwareAddr
+	// 风暴告警时为窗口内的报文数
+	Count    int
+	Ethernet EthernetPacket
+	Arp      ArpPacket
+}
+
+func (a ArpAlert) String() string {
+	str := a.Time.Format(time.RFC3339Nano) + " " + a.Type.String() + " IP: " + a.IP.String()
+	switch a.Type {
+	case ARP_ALERT_BINDING_CHANGE, ARP_ALERT_FLIP_FLOP, ARP_ALERT_ETHERNET_MISMATCH:
+		str += " Old-MAC: " + a.Old.String() + " New-MAC: " + a.New.String()
+	case ARP_ALERT_GRATUITOUS_STORM:
+		str += " MAC: " + a.New.String() + fmt.Sprintf(" Count: %d", a.Count)
+	default:
+		str += " MAC: " + a.New.String()
+	}
+	return str
+}
+
+type ArpMonitorStats struct {
+	Packets    uint64
+	Requests   uint64
+	Replies    uint64
+	Gratuitous uint64
+	Probes     uint64
+	Alerts     map[ArpAlertType]uint64
+	First      time.Time
+	Last       time.Time
+}
+
+// 每秒报文数, 以首尾报文时间计算, 离线分析时与抓包时间一致
+func (s ArpMonitorStats) PacketRate() float64 {
+	return s.rate(s.Packets)
+}
+
+// 每秒告警数
+func (s ArpMonitorStats) AlertRate(t ArpAlertType) float64 {
+	return s.rate(s.Alerts[t])
+}
+
+func (s ArpMonitorStats) rate(n uint64) float64 {
+	d := s.Last.Sub(s.First).Seconds()
+	if d <= 0 {
+		return float64(n)
+	}
+	return float64(n) / d
+}
+
+type arpBinding struct {
+	mac     HardwareAddr
+	updated time.Time
+	// 历史 MAC 与其最后一次出现时间, 不含当前 MAC
+	history []arpBindingHistory
+}
+
+type arpBindingHistory struct {
+	mac  HardwareAddr
+	seen time.Time
+}
+
+// 零值参数使用 ARP_MONITOR_* 默认值, 可并发调用
+type ArpMonitor struct {
+	FlipFlopWindow      time.Duration
+	RequestTimeout      time.Duration
+	GratuitousWindow    time.Duration
+	GratuitousThreshold int
+	BindingTimeout      time.Duration
+	MaxBindings         int
+	// 已知合法绑定, 与之不符的报文总是产生绑定变更告警
+	Static  map[IPv4]HardwareAddr
+	OnAlert func(ArpAlert)
+
+	mu         sync.Mutex
+	bindings   map[IPv4]*arpBinding
+	pending    map[[8]byte]time.Time
+	gratuitous map[HardwareAddr][]time.Time
+	expired    time.Time
+	stats      ArpMonitorStats
+}
+
+/*
+	处理一个以太网帧, 非 ARP 帧返回 nil
+	802.1Q/802.1ad 标签由 StripVLAN 去除, 绑定不区分 VLAN, 各 VLAN 地址重叠时需按 VLAN 使用不同的 ArpMonitor
+ */
+func (m *ArpMonitor) HandleFrame(b []byte, ts time.Time) []ArpAlert {
+	_, b = StripVLAN(b)
+	eth, arp, ok := NewEthernetArp(b)
+	if !ok {
+		return nil
+	}
+	return m.Handle(eth, arp, ts)
+}
+
+func (m *ArpMonitor) Handle(eth EthernetPacket, arp ArpPacket, ts time.Time) (alerts []ArpAlert) {
+	m.mu.Lock()
+	if m.bindings == nil {
+		m.bindings, m.pending = make(map[IPv4]*arpBinding), make(map[[8]byte]time.Time)
+		m.gratuitous, m.stats.Alerts = make(map[HardwareAddr][]time.Time), make(map[ArpAlertType]uint64)
+	}
+	alert := func(t ArpAlertType, ip IPv4, old, cur HardwareAddr, count int) {
+		alerts = append(alerts, ArpAlert{Type: t, Time: ts, IP: ip, Old: old, New: cur, Count: count, Ethernet: eth, Arp: arp})
+		m.stats.Alerts[t]++
+	}
+	m.stats.Packets++
+	if m.stats.First.IsZero() {
+		m.stats.First = ts
+	}
+	m.stats.Last = ts
+	// 先清理过期条目, 绑定表已满时为新 IP 腾出空间
+	m.expire(ts)
+
+	if eth.HeadMAC[1] != arp.SendHardware {
+		alert(ARP_ALERT_ETHERNET_MISMATCH, arp.SendIP, eth.HeadMAC[1], arp.SendHardware, 0)
+	}
+	switch arp.Operation {
+	case ARP_REQUEST:
+		m.stats.Requests++
+		if arp.IsProbe() {
+			m.stats.Probes++
+		}
+		m.pending[arpPendingKey(arp.SendIP, arp.TargetIP)] = ts
+	case ARP_REPLY:
+		m.stats.Replies++
+		// 同一请求允许多个应答, 由 expire 统一清理
+		at, ok := m.pending[arpPendingKey(arp.TargetIP, arp.SendIP)]
+		if (!ok || ts.Sub(at) > m.requestTimeout()) && arp.SendIP != arp.TargetIP {
+			alert(ARP_ALERT_UNSOLICITED_REPLY, arp.SendIP, HardwareAddr{}, arp.SendHardware, 0)
+		}
+	}
+	if arp.SendIP == arp.TargetIP && arp.SendIP != (IPv4{}) {
+		m.stats.Gratuitous++
+		window, list := m.gratuitousWindow(), m.gratuitous[arp.SendHardware]
+		for len(list) > 0 && ts.Sub(list[0]) > window {
+			list = list[1:]
+		}
+		list = append(list, ts)
+		if m.gratuitous[arp.SendHardware] = list; len(list) == m.gratuitousThreshold()+1 {
+			alert(ARP_ALERT_GRATUITOUS_STORM, arp.SendIP, HardwareAddr{}, arp.SendHardware, len(list))
+		}
+	}
+	if arp.SendIP != (IPv4{}) {
+		m.bind(arp.SendIP, arp.SendHardware, ts, alert)
+	}
+	m.mu.Unlock()
+	if m.OnAlert != nil {
+		for _, a := range alerts {
+			m.OnAlert(a)
+		}
+	}
+	return
+}
+
+func (m *ArpMonitor) bind(ip IPv4, mac HardwareAddr, ts time.Time, alert func(ArpAlertType, IPv4, HardwareAddr, HardwareAddr, int)) {
+	if static, ok := m.Static[ip]; ok && static != mac {
+		alert(ARP_ALERT_BINDING_CHANGE, ip, static, mac, 0)
+		return
+	}
+	bind := m.bindings[ip]
+	if bind == nil {
+		if len(m.bindings) < m.maxBindings() {
+			m.bindings[ip] = &arpBinding{mac: mac, updated: ts}
+		}
+		return
+	}
+	if bind.mac == mac {
+		bind.updated = ts
+		return
+	}
+	typ := ARP_ALERT_BINDING_CHANGE
+	history := bind.history[:0]
+	for _, h := range bind.history {
+		if h.mac == mac {
+			if ts.Sub(h.seen) <= m.flipFlopWindow() {
+				typ = ARP_ALERT_FLIP_FLOP
+			}
+			continue
+		}
+		history = append(history, h)
+	}
+	alert(typ, ip, bind.mac, mac, 0)
+	if history = append(history, arpBindingHistory{bind.mac, ts}); len(history) > arpMonitorHistory {
+		history = history[1:]
+	}
+	bind.mac, bind.updated, bind.history = mac, ts, history
+}
+
+func (m *ArpMonitor) expire(ts time.Time) {
+	timeout := m.requestTimeout()
+	if ts.Sub(m.expired) < timeout {
+		return
+	}
+	m.expired = ts
+	for k, at := range m.pending {
+		if ts.Sub(at) > timeout {
+			delete(m.pending, k)
+		}
+	}
+	window := m.gratuitousWindow()
+	for mac, list := range m.gratuitous {
+		if len(list) == 0 || ts.Sub(list[len(list)-1]) > window {
+			delete(m.gratuitous, mac)
+		}
+	}
+	timeout = m.bindingTimeout()
+	for ip, bind := range m.bindings {
+		if ts.Sub(bind.updated) > timeout {
+			delete(m.bindings, ip)
+		}
+	}
+}
+
+// 当前 IP→MAC 绑定表
+func (m *ArpMonitor) Bindings() map[IPv4]HardwareAddr {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	table := make(map[IPv4]HardwareAddr, len(m.bindings))
+	for ip, bind := range m.bindings {
+		table[ip] = bind.mac
+	}
+	return table
+}
+
+func (m *ArpMonitor) Stats() ArpMonitorStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stats := m.stats
+	stats.Alerts = make(map[ArpAlertType]uint64, len(m.stats.Alerts))
+	for k, v := range m.stats.Alerts {
+		stats.Alerts[k] = v
+	}
+	return stats
+}
+
+// 实时监听链路直到 ctx 结束
+func (m *ArpMonitor) Run(ctx context.Context, link FrameReadWriter) error {
+	lw := watchLink(ctx, link)
+	defer lw.Close()
+	for {
+		b, err := link.ReadFrame()
+		if err != nil {
+			if errors.Is(err, os.ErrDeadlineExceeded) && ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+		m.HandleFrame(b, time.Now())
+	}
+}
+
+// 离线分析抓包文件, 使用抓包时间戳
+func (m *ArpMonitor) ReadPcap(pr *PcapReader) error {
+	if pr.Header.LinkType != PCAP_LINKTYPE_ETHERNET {
+		return ErrPcapFormat
+	}
+	for {
+		b, ts, err := pr.Next()
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		m.HandleFrame(b, ts)
+	}
+}
+
+func (m *ArpMonitor) flipFlopWindow() time.Duration {
+	if m.FlipFlopWindow > 0 {
+		return m.FlipFlopWindow
+	}
+	return ARP_MONITOR_FLIPFLOP_WINDOW
+}
+
+func (m *ArpMonitor) requestTimeout() time.Duration {
+	if m.RequestTimeout > 0 {
+		return m.RequestTimeout
+	}
+	return ARP_MONITOR_REQUEST_TIMEOUT
+}
+
+func (m *ArpMonitor) gratuitousWindow() time.Duration {
+	if m.GratuitousWindow > 0 {
+		return m.GratuitousWindow
+	}
+	return ARP_MONITOR_GRATUITOUS_WINDOW
+}
+
+func (m *ArpMonitor) gratuitousThreshold() int {
+	if m.GratuitousThreshold > 0 {
+		return m.GratuitousThreshold
+	}
+	return ARP_MONITOR_GRATUITOUS_THRESHOLD
+}
+
+func (m *ArpMonitor) bindingTimeout() time.Duration {
+	if m.BindingTimeout > 0 {
+		return m.BindingTimeout
+	}
+	return ARP_MONITOR_BINDING_TIMEOUT
+}
+
+func (m *ArpMonitor) maxBindings() int {
+	if m.MaxBindings > 0 {
+		return m.MaxBindings
+	}
+	return ARP_MONITOR_MAX_BINDINGS
+}
+
+// 请求方 IP 与被请求 IP
+func arpPendingKey(requester, target IPv4) (key [8]byte) {
+	copy(key[:4], requester[:])
+	copy(key[4:], target[:])
+	return
+}
@@ -0,0 +1,56 @@
+// @@
+// @ Author       : Eacher
+// @ Date         : 2026-10-21 17:03:19
+// @ LastEditTime : 2026-10-21 17:03:19
+// @ LastEditors  : Eacher
+// @ --------------------------------------------------------------------------------<
+// @ Description  : ARP 监测绑定表测试
+// @ --------------------------------------------------------------------------------<
+// @ FilePath     : /20yyq/packet/arp_monitor_test.go
+// @@
+package packet
+
+import (
+	"testing"
+	"time"
+)
+
+// 在以太网首部后插入 802.1Q 标签
+func arpTestTagged(frame []byte, vlan uint16) []byte {
+	tag := []byte{0x81, 0x00, uint8(vlan >> 8), uint8(vlan)}
+	return append(append(append([]byte{}, frame[:12]...), tag...), frame[12:]...)
+}
+
+func TestArpMonitorVLAN(t *testing.T) {
+	m := &ArpMonitor{}
+	ip := IPv4{10, 0, 0, 5}
+	first, second := HardwareAddr{0x02, 0, 0, 0, 0, 0x05}, HardwareAddr{0x02, 0, 0, 0, 0, 0x06}
+	now := time.Now()
+	m.HandleFrame(arpTestTagged(newArpTestRequest(first, ip, IPv4{10, 0, 0, 1}).EthernetFrame(Broadcast), 10), now)
+	if mac := m.Bindings()[ip]; mac != first {
+		t.Fatalf("binding after tagged frame = %v; want %v", mac, first)
+	}
+	alerts := m.HandleFrame(arpTestTagged(newArpTestRequest(second, ip, IPv4{10, 0, 0, 1}).EthernetFrame(Broadcast), 10), now)
+	if len(alerts) != 1 || alerts[0].Type != ARP_ALERT_BINDING_CHANGE || alerts[0].Old != first {
+		t.Errorf("tagged binding change alerts = %v", alerts)
+	}
+}
+
+func TestArpMonitorBindingLimits(t *testing.T) {
+	m := &ArpMonitor{MaxBindings: 2, BindingTimeout: time.Minute}
+	now := time.Now()
+	for i := uint8(1); i <= 3; i++ {
+		m.Handle(EthernetPacket{}, newArpTestRequest(HardwareAddr{0x02, 0, 0, 0, 0, i}, IPv4{10, 0, 0, i}, IPv4{10, 0, 0, 100}), now)
+	}
+	if table := m.Bindings(); len(table) != 2 {
+		t.Errorf("bindings over MaxBindings = %v; want 2 entries", table)
+	}
+	// 超过 BindingTimeout 后旧绑定被移除, 腾出空间学习新 IP
+	later := now.Add(2 * time.Minute)
+	m.Handle(EthernetPacket{}, newArpTestRequest(HardwareAddr{0x02, 0, 0, 0, 0, 4}, IPv4{10, 0, 0, 4}, IPv4{10, 0, 0, 100}), later)
+	m.Handle(EthernetPacket{}, newArpTestRequest(HardwareAddr{0x02, 0, 0, 0, 0, 5}, IPv4{10, 0, 0, 5}, IPv4{10, 0, 0, 100}), later)
+	table := m.Bindings()
+	if _, ok := table[IPv4{10, 0, 0, 1}]; ok || len(table) != 2 || table[IPv4{10, 0, 0, 5}] != (HardwareAddr{0x02, 0, 0, 0, 0, 5}) {
+		t.Errorf("bindings after expiry = %v; want 10.0.0.4 and 10.0.0.5", table)
+	}
+}
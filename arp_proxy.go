@@ -0,0 +1,213 @@
+// @@
+// @ Author       : Eacher
+// @ Date         : 2026-10-19 18:52:17
+// @ LastEditTime : 2026-10-21 15:02:44
+// @ LastEditors  : Eacher
+// @ --------------------------------------------------------------------------------<
+// @ Description  : Proxy ARP 应答
+// @ --------------------------------------------------------------------------------<
+// @ FilePath     : /20yyq/packet/arp_proxy.go
+// @@
+package packet
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"time"
+)
+
+/*
+	RFC 1027 Using ARP to Implement Transparent Subnet Gateways
+
+	The gateway answers ARP requests for hosts on other subnets with its own hardware
+	address, so that hosts which are not subnet aware send their packets to the gateway.
+
+	RFC 1122 2.3.2.1  ARP Cache Validation
+	(2) Unicast Poll -- Actively poll the remote host by periodically sending a
+	    point-to-point ARP Request to it, and delete the entry if no ARP Reply is
+	    received from N successive polls.
+*/
+const (
+	// 同一请求方在 PROXY_ARP_RATE_INTERVAL 内最多获得 PROXY_ARP_RATE_LIMIT 个应答
+	PROXY_ARP_RATE_LIMIT    = 0x10
+	PROXY_ARP_RATE_INTERVAL = time.Second
+)
+
+type proxyArpEntry struct {
+	prefix IPv4Prefix
+	mac    HardwareAddr
+}
+
+// HardwareAddr 为本机网卡地址, Add 未指定 MAC 的条目使用该地址应答
+type ProxyArp struct {
+	Link         FrameReadWriter
+	HardwareAddr HardwareAddr
+	RateLimit    int
+	RateInterval time.Duration
+	// 每次应答后回调
+	OnReply func(req, reply ArpPacket)
+
+	mu      sync.Mutex
+	entries []proxyArpEntry
+	exclude []IPv4Prefix
+	limiter map[HardwareAddr][]time.Time
+}
+
+// 代理 prefix 内的地址, mac 为零值时使用 HardwareAddr, prefix 的主机位被忽略
+func (p *ProxyArp) Add(prefix IPv4Prefix, mac HardwareAddr) {
+	prefix = prefix.Masked()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i, e := range p.entries {
+		if e.prefix == prefix {
+			p.entries[i].mac = mac
+			return
+		}
+	}
+	p.entries = append(p.entries, proxyArpEntry{prefix, mac})
+}
+
+func (p *ProxyArp) AddAddr(ip IPv4, mac HardwareAddr) {
+	p.Add(IPv4Prefix{IP: ip, Bits: 32}, mac)
+}
+
+func (p *ProxyArp) Remove(prefix IPv4Prefix) {
+	prefix = prefix.Masked()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i, e := range p.entries {
+		if e.prefix == prefix {
+			p.entries = append(p.entries[:i], p.entries[i+1:]...)
+			return
+		}
+	}
+}
+
+// 排除列表中的地址即使位于代理前缀内也不应答
+func (p *ProxyArp) Exclude(prefix IPv4Prefix) {
+	p.mu.Lock()
+	p.exclude = append(p.exclude, prefix)
+	p.mu.Unlock()
+}
+
+// 最长前缀匹配
+func (p *ProxyArp) Lookup(ip IPv4) (HardwareAddr, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	e, ok := p.lookup(ip)
+	return e.mac, ok
+}
+
+// 返回匹配的条目, mac 为零值时已替换为 HardwareAddr
+func (p *ProxyArp) lookup(ip IPv4) (entry proxyArpEntry, ok bool) {
+	for _, e := range p.exclude {
+		if e.Contains(ip) {
+			return
+		}
+	}
+	for _, e := range p.entries {
+		if (!ok || e.prefix.Bits > entry.prefix.Bits) && e.prefix.Contains(ip) {
+			entry, ok = e, true
+		}
+	}
+	if ok && entry.mac == (HardwareAddr{}) {
+		entry.mac = p.HardwareAddr
+	}
+	return
+}
+
+// 对需要代理的 ARP 请求返回以太网应答帧, OnReply 在释放锁后调用
+func (p *ProxyArp) Handle(eth EthernetPacket, arp ArpPacket, now time.Time) ([]byte, bool) {
+	reply, ok := p.handle(eth, arp, now)
+	if !ok {
+		return nil, false
+	}
+	if p.OnReply != nil {
+		p.OnReply(arp, reply)
+	}
+	return reply.EthernetFrame(arp.SendHardware), true
+}
+
+func (p *ProxyArp) handle(eth EthernetPacket, arp ArpPacket, now time.Time) (ArpPacket, bool) {
+	if arp.Operation != ARP_REQUEST || arp.SendIP == arp.TargetIP {
+		return ArpPacket{}, false
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	target, ok := p.lookup(arp.TargetIP)
+	if !ok || target.mac == arp.SendHardware {
+		return ArpPacket{}, false
+	}
+	// 只应答广播请求与发给本机/代理地址的单播轮询
+	if eth.HeadMAC[0] != Broadcast && eth.HeadMAC[0] != target.mac && eth.HeadMAC[0] != p.HardwareAddr {
+		return ArpPacket{}, false
+	}
+	// 请求方与目标位于同一代理前缀时由目标自己应答, 不同前缀之间的请求由本机代答
+	if sender, ok := p.lookup(arp.SendIP); ok && sender.prefix == target.prefix && arp.SendIP != (IPv4{}) {
+		return ArpPacket{}, false
+	}
+	if !p.allow(arp.SendHardware, now) {
+		return ArpPacket{}, false
+	}
+	return ArpPacket{
+		HardwareType: ARP_ETHERNETTYPE, ProtocolType: ETH_P_IP, HardwareLen: 6, IPLen: 4,
+		Operation: ARP_REPLY, SendHardware: target.mac, SendIP: arp.TargetIP,
+		TargetHardware: arp.SendHardware, TargetIP: arp.SendIP,
+	}, true
+}
+
+func (p *ProxyArp) allow(mac HardwareAddr, now time.Time) bool {
+	limit, interval := p.RateLimit, p.RateInterval
+	if limit <= 0 {
+		limit = PROXY_ARP_RATE_LIMIT
+	}
+	if interval <= 0 {
+		interval = PROXY_ARP_RATE_INTERVAL
+	}
+	if p.limiter == nil {
+		p.limiter = make(map[HardwareAddr][]time.Time)
+	}
+	list := p.limiter[mac]
+	for len(list) > 0 && now.Sub(list[0]) >= interval {
+		list = list[1:]
+	}
+	if len(list) >= limit {
+		p.limiter[mac] = list
+		return false
+	}
+	if list = append(list, now); len(p.limiter) > 0x1000 {
+		for k, v := range p.limiter {
+			if len(v) == 0 || now.Sub(v[len(v)-1]) >= interval {
+				delete(p.limiter, k)
+			}
+		}
+	}
+	p.limiter[mac] = list
+	return true
+}
+
+// 监听 Link 并应答, 直到 ctx 结束
+func (p *ProxyArp) Serve(ctx context.Context) error {
+	lw := watchLink(ctx, p.Link)
+	defer lw.Close()
+	for {
+		b, err := p.Link.ReadFrame()
+		if err != nil {
+			if errors.Is(err, os.ErrDeadlineExceeded) && ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+		eth, arp, ok := NewEthernetArp(b)
+		if !ok {
+			continue
+		}
+		if reply, ok := p.Handle(eth, arp, time.Now()); ok {
+			if err = p.Link.WriteFrame(reply); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,118 @@
+// @@
+// @ Author       : Eacher
+// @ Date         : 2026-10-21 15:02:44
+// @ LastEditTime : 2026-10-21 15:02:44
+// @ LastEditors  : Eacher
+// @ --------------------------------------------------------------------------------<
+// @ Description  : Proxy ARP 内存链路测试
+// @ --------------------------------------------------------------------------------<
+// @ FilePath     : /20yyq/packet/arp_proxy_test.go
+// @@
+package packet
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func newArpTestRequest(mac HardwareAddr, send, target IPv4) ArpPacket {
+	return ArpPacket{
+		HardwareType: ARP_ETHERNETTYPE, ProtocolType: ETH_P_IP, HardwareLen: 6, IPLen: 4,
+		Operation: ARP_REQUEST, SendHardware: mac, SendIP: send, TargetIP: target,
+	}
+}
+
+func TestProxyArpServe(t *testing.T) {
+	a, b := NewFramePipe()
+	defer b.Close()
+	local, peer := HardwareAddr{0x02, 0, 0, 0, 0, 0x01}, HardwareAddr{0x02, 0, 0, 0, 0, 0x42}
+	p := &ProxyArp{Link: a, HardwareAddr: local}
+	// 主机位被忽略, 与 10.1.0.0/16 为同一前缀
+	p.Add(IPv4Prefix{IPv4{10, 1, 2, 3}, 16}, HardwareAddr{0x02, 0, 0, 0, 0, 0x99})
+	p.Add(IPv4Prefix{IPv4{10, 1, 0, 0}, 16}, HardwareAddr{})
+	p.Add(IPv4Prefix{IPv4{10, 2, 0, 0}, 16}, HardwareAddr{})
+	p.Add(IPv4Prefix{IPv4{10, 3, 0, 0}, 16}, HardwareAddr{0x02, 0, 0, 0, 0, 0x33})
+	p.Exclude(IPv4Prefix{IPv4{10, 2, 9, 0}, 24})
+	replies := make(chan ArpPacket, 8)
+	p.OnReply = func(req, reply ArpPacket) {
+		// 回调中可以调用 ProxyArp 的方法
+		if _, ok := p.Lookup(reply.SendIP); !ok {
+			t.Errorf("Lookup(%v) in OnReply failed", reply.SendIP)
+		}
+		replies <- reply
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- p.Serve(ctx) }()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	tests := []struct {
+		name 	string
+		send 	IPv4
+		target 	IPv4
+		mac 	HardwareAddr
+		ok 		bool
+	}{
+		{"other prefix", IPv4{10, 1, 0, 5}, IPv4{10, 2, 0, 7}, local, true},
+		{"reverse", IPv4{10, 2, 0, 7}, IPv4{10, 1, 0, 5}, local, true},
+		{"entry mac", IPv4{10, 1, 0, 5}, IPv4{10, 3, 0, 1}, HardwareAddr{0x02, 0, 0, 0, 0, 0x33}, true},
+		{"unproxied sender", IPv4{192, 168, 0, 2}, IPv4{10, 2, 0, 7}, local, true},
+		{"same prefix", IPv4{10, 1, 0, 5}, IPv4{10, 1, 0, 6}, HardwareAddr{}, false},
+		{"excluded", IPv4{10, 1, 0, 5}, IPv4{10, 2, 9, 1}, HardwareAddr{}, false},
+		{"not proxied", IPv4{10, 1, 0, 5}, IPv4{192, 168, 0, 1}, HardwareAddr{}, false},
+		{"gratuitous", IPv4{10, 2, 0, 7}, IPv4{10, 2, 0, 7}, HardwareAddr{}, false},
+	}
+	for _, tt := range tests {
+		if err := b.WriteFrame(newArpTestRequest(peer, tt.send, tt.target).EthernetFrame(Broadcast)); err != nil {
+			t.Fatal(err)
+		}
+		b.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+		frame, err := b.ReadFrame()
+		if !tt.ok {
+			if err == nil {
+				t.Errorf("%s: unexpected reply % x", tt.name, frame)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: no reply: %v", tt.name, err)
+			continue
+		}
+		eth, reply, ok := NewEthernetArp(frame)
+		if !ok || reply.Operation != ARP_REPLY || reply.SendIP != tt.target || reply.SendHardware != tt.mac ||
+			reply.TargetIP != tt.send || reply.TargetHardware != peer || eth.HeadMAC[0] != peer {
+			t.Errorf("%s: reply = %+v; want %v is-at %v", tt.name, reply, tt.target, tt.mac)
+		}
+		select {
+		case r := <-replies:
+			if r.SendIP != tt.target {
+				t.Errorf("%s: OnReply reply for %v; want %v", tt.name, r.SendIP, tt.target)
+			}
+		default:
+			t.Errorf("%s: OnReply not called", tt.name)
+		}
+	}
+	if p.Remove(IPv4Prefix{IPv4{10, 3, 4, 5}, 16}); len(p.entries) != 2 {
+		t.Errorf("entries after Remove(10.3.4.5/16) = %v; want 2", p.entries)
+	}
+}
+
+func TestProxyArpRateLimit(t *testing.T) {
+	p := &ProxyArp{HardwareAddr: HardwareAddr{0x02, 0, 0, 0, 0, 0x01}, RateLimit: 2, RateInterval: time.Second}
+	p.Add(IPv4Prefix{IPv4{10, 2, 0, 0}, 16}, HardwareAddr{})
+	peer := HardwareAddr{0x02, 0, 0, 0, 0, 0x42}
+	eth := EthernetPacket{HeadMAC: [2]HardwareAddr{Broadcast, peer}, FrameType: ETH_P_ARP}
+	now := time.Unix(1000, 0)
+	for i, want := range []bool{true, true, false} {
+		if _, ok := p.Handle(eth, newArpTestRequest(peer, IPv4{10, 1, 0, 5}, IPv4{10, 2, 0, 7}), now); ok != want {
+			t.Errorf("request %d: ok = %v; want %v", i, ok, want)
+		}
+	}
+	if _, ok := p.Handle(eth, newArpTestRequest(peer, IPv4{10, 1, 0, 5}, IPv4{10, 2, 0, 7}), now.Add(time.Second)); !ok {
+		t.Errorf("request after interval not answered")
+	}
+}
@@ -1,7 +1,7 @@
 // @@
 // @ Author       : Eacher
 // @ Date         : 2023-09-06 10:48:53
-// @ LastEditTime : 2023-09-11 08:01:05
+// @ LastEditTime : 2026-10-21 00:47:31
 // @ LastEditors  : Eacher
 // @ --------------------------------------------------------------------------------<
 // @ Description  : 
@@ -13,6 +13,9 @@ package can
 import (
 	"fmt"
 	"unsafe"
+	"strings"
+	"encoding/hex"
+	"encoding/json"
 )
 
 const (
@@ -119,3 +122,58 @@ func (f Frame) String() string {
 	format := "%d\t%-4X\t[%d]\t% -24X\t%s\t%d\t%d\t%d\n"
 	return fmt.Sprintf(format, f.id, f.ID(), f.Len, f.Data[:f.Len], f.Data[:f.Len], f.Flags, f.Res0, f.Res1)
 }
+
+/*
+	JSON 格式, id 不含 EFF/RTR/ERR 标志位, data 只保留前 len 字节:
+	{"id": 291, "extended": false, "remote": false, "error": false, "fd": false, "len": 2, "flags": 0, "data": "de:ad"}
+ */
+type frameJSON struct {
+	ID 			uint32 	`json:"id"`
+	Extended 	bool 	`json:"extended"`
+	Remote 		bool 	`json:"remote"`
+	Error 		bool 	`json:"error"`
+	CanFd 		bool 	`json:"fd"`
+	Len 		uint8 	`json:"len"`
+	Flags 		uint8 	`json:"flags"`
+	Res0 		uint8 	`json:"res0,omitempty"`
+	Res1 		uint8 	`json:"res1,omitempty"`
+	Data 		string 	`json:"data"`
+}
+
+func (f Frame) MarshalJSON() ([]byte, error) {
+	n := int(f.Len)
+	if n > CanFDDataLength {
+		n = CanFDDataLength
+	}
+	data := make([]string, n)
+	for i := range data {
+		data[i] = hex.EncodeToString(f.Data[i:i+1])
+	}
+	return json.Marshal(frameJSON{
+		f.ID(), f.Extended, f.Remote, f.Error, f.CanFd, f.Len, f.Flags, f.Res0, f.Res1, strings.Join(data, ":"),
+	})
+}
+
+func (f *Frame) UnmarshalJSON(b []byte) error {
+	var v frameJSON
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	data, err := hex.DecodeString(strings.ReplaceAll(v.Data, ":", ""))
+	if err != nil {
+		return err
+	}
+	max := CanDataLength
+	if v.CanFd {
+		max = CanFDDataLength
+	}
+	if n := len(data); n > max || int(v.Len) > max {
+		if int(v.Len) > n {
+			n = int(v.Len)
+		}
+		return fmt.Errorf("invalid Can data length: %v exceeds %v bytes", n, max)
+	}
+	*f = Frame{Len: v.Len, Flags: v.Flags, Res0: v.Res0, Res1: v.Res1, CanFd: v.CanFd, Extended: v.Extended, Remote: v.Remote, Error: v.Error}
+	copy(f.Data[:], data)
+	return f.SetID(v.ID)
+}
@@ -1,7 +1,7 @@
 // @@
 // @ Author       : Eacher
 // @ Date         : 2023-07-04 08:48:44
-// @ LastEditTime : 2023-09-04 09:40:22
+// @ LastEditTime : 2026-10-21 16:58:44
 // @ LastEditors  : Eacher
 // @ --------------------------------------------------------------------------------<
 // @ Description  : 
@@ -12,7 +12,6 @@ package packet
 
 import (
 	"time"
-	"unsafe"
 	"encoding/binary"
 )
 
@@ -26,9 +25,15 @@ const (
 	DHCP_BOOTREPLY 		= 0x02
 	DHCP_Ethernet_TYPE 	= 0x01
 	DHCP_Ethernet_LEN 	= 0x06
+	DHCP_BROADCAST_FLAG = 0x8000
 
 	SizeofDhcpV4Packet 	= 0xf0
 	SizeofOptionsPacket = 0x02
+	// RFC 951 不含 vend 的固定首部
+	SizeofBootpPacket 	= 0xec
+	// RFC 951 vend 区域长度, RFC 1542 3.1.2 要求报文不少于 300 bytes
+	BOOTP_VEND_LEN 		= 0x40
+	BOOTP_MIN_LEN 		= SizeofBootpPacket + BOOTP_VEND_LEN
 )
 
 /*
@@ -70,8 +75,13 @@ type DhcpV4Packet struct {
 	FileName 		[128]byte
 	cookie   		[4]byte
 	Options  		[]OptionsPacket
+	// vend 区域不以 MagicCookie 开头时的原始内容 (RFC 951 BOOTP), 此时 Options 为空
+	Vend 			[]byte
+	// 解码时选项溢出到 file/sname 的原报文长度 (含 IP/UDP 首部), 未溢出为 0
+	overload 		int
 }
 
+// Value 超过 255 bytes 时 (RFC 3396 长选项) Length 为 255, 编码以 len(Value) 为准, 实际长度使用 Len
 type OptionsPacket struct {
 	Code 		uint8
 	Length 		uint8
@@ -81,63 +91,98 @@ type OptionsPacket struct {
 // 14.byte  EthernetPacket
 // 20.byte  IPv4Packet 或者 IPv6Packet
 func NewDhcpV4Packet(b []byte) (dhcp DhcpV4Packet) {
-	if len(b) > SizeofDhcpV4Packet {
-		dhcp = *(*DhcpV4Packet)(unsafe.Pointer((*[SizeofDhcpV4Packet]byte)(b)))
+	if len(b) >= SizeofBootpPacket {
+		dhcp.Op, dhcp.HardwareType, dhcp.HardwareLen, dhcp.Hops = b[0], b[1], b[2], b[3]
+		dhcp.CIAddr, dhcp.YIAddr = *(*IPv4)(b[12:16]), *(*IPv4)(b[16:20])
+		dhcp.SIAddr, dhcp.GIAddr = *(*IPv4)(b[20:24]), *(*IPv4)(b[24:28])
+		dhcp.ChHardware, dhcp.HostName = *(*[16]byte)(b[28:44]), *(*[64]byte)(b[44:108])
+		dhcp.FileName = *(*[128]byte)(b[108:236])
 		dhcp.XID = binary.BigEndian.Uint32(b[4:8])
 		dhcp.Secs = binary.BigEndian.Uint16(b[8:10])
 		dhcp.Flags 	 = binary.BigEndian.Uint16(b[10:12])
-		dhcp.Options = NewOptionsPacket(b[SizeofDhcpV4Packet:])
+		if len(b) >= SizeofDhcpV4Packet && *(*[4]byte)(b[236:240]) == MagicCookie {
+			dhcp.cookie = MagicCookie
+			dhcp.Options = dhcp.decodeOptions(NewOptionsPacket(b[SizeofDhcpV4Packet:]), len(b))
+		} else {
+			dhcp.Vend = append([]byte{}, b[SizeofBootpPacket:]...)
+		}
 	}
 	return
 }
 
+/*
+	Options 不为空或者 Vend 为 nil 时 vend 区域为 MagicCookie, 选项与 END (RFC 1048), 否则为 Vend 原始内容
+	不足 BOOTP_MIN_LEN 时以 0 (PAD) 补齐
+ */
 func (dhcp DhcpV4Packet) WireFormat() []byte {
-	var opb []byte
-	if 0 < len(dhcp.Options) {
+	vend := dhcp.Vend
+	if 0 < len(dhcp.Options) || vend == nil {
+		vend = MagicCookie[:]
 		for _, val := range dhcp.Options {
-			opb = append(opb, val.WireFormat()...)
+			vend = append(vend, val.WireFormat()...)
 		}
-		b := make([]byte, len(opb) + SizeofDhcpV4Packet + 1)
-		copy(b[SizeofDhcpV4Packet:], opb)
-		opb, b[len(b) - 1] = b, 255
-		b[0], b[1], b[2], b[3] 	= dhcp.Op, dhcp.HardwareType, dhcp.HardwareLen, dhcp.Hops
-		binary.BigEndian.PutUint32(b[4:8], dhcp.XID)
-		binary.BigEndian.PutUint16(b[8:10], dhcp.Secs)
-		binary.BigEndian.PutUint16(b[10:12], dhcp.Flags)
-		*(*IPv4)(b[12:16]) 	= dhcp.CIAddr
-		*(*IPv4)(b[16:20]) 	= dhcp.YIAddr
-		*(*IPv4)(b[20:24]) 	= dhcp.SIAddr
-		*(*IPv4)(b[24:28]) 	= dhcp.GIAddr
-		*(*[16]byte)(b[28:44]) 	= dhcp.ChHardware
-		*(*[64]byte)(b[44:108]) = dhcp.HostName
-		*(*[128]byte)(b[108:236]) = dhcp.FileName
-		*(*[4]byte)(b[236:240]) 	= MagicCookie
+		vend = append(vend, 255)
 	}
-	return opb
+	size := SizeofBootpPacket + len(vend)
+	if size < BOOTP_MIN_LEN {
+		size = BOOTP_MIN_LEN
+	}
+	b := make([]byte, size)
+	copy(b[SizeofBootpPacket:], vend)
+	b[0], b[1], b[2], b[3] 	= dhcp.Op, dhcp.HardwareType, dhcp.HardwareLen, dhcp.Hops
+	binary.BigEndian.PutUint32(b[4:8], dhcp.XID)
+	binary.BigEndian.PutUint16(b[8:10], dhcp.Secs)
+	binary.BigEndian.PutUint16(b[10:12], dhcp.Flags)
+	*(*IPv4)(b[12:16]) 	= dhcp.CIAddr
+	*(*IPv4)(b[16:20]) 	= dhcp.YIAddr
+	*(*IPv4)(b[20:24]) 	= dhcp.SIAddr
+	*(*IPv4)(b[24:28]) 	= dhcp.GIAddr
+	*(*[16]byte)(b[28:44]) 	= dhcp.ChHardware
+	*(*[64]byte)(b[44:108]) = dhcp.HostName
+	*(*[128]byte)(b[108:236]) = dhcp.FileName
+	return b
 }
 
 func NewOptionsPacket(b []byte) (list []OptionsPacket) {
-	var idx, next uint8
-	if len(b) > SizeofOptionsPacket {
-		opp := OptionsPacket{b[idx], b[idx+1], nil}
-		idx = 2
-		for opp.Code != 255 {
-			opp.Length = b[idx-1]
-			next = idx + opp.Length
-			opp.Value = make([]byte, opp.Length)
-			copy(opp.Value, b[idx:next])
-			list = append(list, opp)
-			opp = OptionsPacket{b[next], 0, nil}
-			idx = next + 2
+	for idx := 0; idx < len(b); {
+		code := b[idx]
+		if code == 0 {
+			idx++
+			continue
+		}
+		if code == 255 || idx + SizeofOptionsPacket > len(b) {
+			break
+		}
+		opp := OptionsPacket{code, b[idx+1], nil}
+		next := idx + SizeofOptionsPacket + int(opp.Length)
+		if next > len(b) {
+			break
 		}
+		opp.Value = make([]byte, opp.Length)
+		copy(opp.Value, b[idx+SizeofOptionsPacket:next])
+		list, idx = append(list, opp), next
 	}
 	return
 }
 
+// Value 超过 255 bytes 时按 RFC 3396 拆分为多个实例
 func (opp OptionsPacket) WireFormat() []byte {
-	b := make([]byte, SizeofOptionsPacket)
-	b[0], b[1] = opp.Code, opp.Length
-	return append(b, opp.Value...)
+	b := make([]byte, 0, SizeofOptionsPacket + len(opp.Value))
+	v := opp.Value
+	for first := true; first || len(v) > 0; first = false {
+		n := len(v)
+		if n > 255 {
+			n = 255
+		}
+		b = append(append(b, opp.Code, uint8(n)), v[:n]...)
+		v = v[n:]
+	}
+	return b
+}
+
+// 选项数据的实际长度, RFC 3396 长选项合并后可能超过 255
+func (opp OptionsPacket) Len() int {
+	return len(opp.Value)
 }
 
 /*
@@ -156,6 +201,14 @@ func (opp OptionsPacket) WireFormat() []byte {
 	         6     DHCPNAK
 	         7     DHCPRELEASE
 	         8     DHCPINFORM
+
+	RFC 3203 与 RFC 4388 扩展的报文类型:
+
+	         9     DHCPFORCERENEW
+	        10     DHCPLEASEQUERY
+	        11     DHCPLEASEUNASSIGNED
+	        12     DHCPLEASEUNKNOWN
+	        13     DHCPLEASEACTIVE
  */
 type DHCP_Message_Type uint8
 
@@ -168,10 +221,15 @@ const (
 	DHCP_NAK
 	DHCP_RELEASE
 	DHCP_INFORM
+	DHCP_FORCERENEW
+	DHCP_LEASEQUERY
+	DHCP_LEASEUNASSIGNED
+	DHCP_LEASEUNKNOWN
+	DHCP_LEASEACTIVE
 )
 
 func SetDHCPMessage(t DHCP_Message_Type) OptionsPacket {
-	return OptionsPacket{53, 1, []byte{byte(t)}}
+	return OptionsPacket{DHCP_Message_Type_Code, 1, []byte{byte(t)}}
 }
 
 /*
@@ -194,7 +252,7 @@ func SetDHCPMessage(t DHCP_Message_Type) OptionsPacket {
 	+-----+-----+-----+-----+---
  */
 func SetDHCPOptionsRequestList(codes ...uint8) OptionsPacket {
-	return OptionsPacket{55, uint8(len(codes)), codes}
+	return OptionsPacket{DHCP_Parameter_Request_List, uint8(len(codes)), codes}
 }
 
 /*
@@ -217,7 +275,25 @@ func SetDHCPMaximumMessageSize(size uint16) OptionsPacket {
 	if size < 576 {
 		size = 576
 	}
-	return OptionsPacket{57, 2, binary.BigEndian.AppendUint16(nil, size)}
+	return OptionsPacket{DHCP_Maximum_Message_Size, 2, binary.BigEndian.AppendUint16(nil, size)}
+}
+
+/*
+5.1. Interface MTU Option
+	This option specifies the MTU to use on this interface.  The MTU is
+	specified as a 16-bit unsigned integer.  The minimum legal value for
+	the MTU is 68.
+
+	Code   Len      MTU
+	+-----+-----+-----+-----+
+	|  26 |  2  |  m1 |  m2 |
+	+-----+-----+-----+-----+
+ */
+func SetDHCPInterfaceMTU(mtu uint16) OptionsPacket {
+	if mtu < 68 {
+		return OptionsPacket{}
+	}
+	return OptionsPacket{DHCP_Interface_MTU, 2, binary.BigEndian.AppendUint16(nil, mtu)}
 }
 
 /*
@@ -318,15 +394,13 @@ const (
 	DHCP_Bootfile_Name DHCP_STRING_TYPE								= 67
 	DHCP_Error_Message DHCP_STRING_TYPE								= 56
 	DHCP_Vendor_Class_Identifier DHCP_STRING_TYPE 				= 60
+	DHCP_Host_Name DHCP_STRING_TYPE 									= 12
+	DHCP_Domain_Name DHCP_STRING_TYPE 								= 15
 )
 
 func SetDHCPString(t DHCP_STRING_TYPE, s string) OptionsPacket {
-	length := len(s) + 1
-	if length > 255 {
-		return OptionsPacket{}
-	}
 	s += string([]byte{0})
-	return OptionsPacket{uint8(t), uint8(length), []byte(s)}
+	return SetDHCPOption(uint8(t), []byte(s))
 }
 
 /*
@@ -725,15 +799,14 @@ const (
 )
 
 func SetDHCPIPv4(t DHCP_IPv4_TYPE, ip ...IPv4) OptionsPacket {
-	length := len(ip)*4
-	if length > 255 || length < 1 {
+	if len(ip) < 1 {
 		return OptionsPacket{}
 	}
-	b := make([]byte, length)
+	b := make([]byte, len(ip)*4)
 	for i, v := range ip {
 		copy(b[i*4:], v[:])
 	}
-	return OptionsPacket{uint8(t), uint8(length), b}
+	return SetDHCPOption(uint8(t), b)
 }
 
 /*
@@ -764,7 +837,7 @@ const (
 )
 
 func SetDHCPNetBIOSNodeType(t DHCP_NetBIOS_Node_Type) OptionsPacket {
-	return OptionsPacket{46, 1, []byte{byte(t)}}
+	return OptionsPacket{DHCP_NetBIOS_Node_Type_Code, 1, []byte{byte(t)}}
 }
 
 /*
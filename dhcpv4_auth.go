@@ -0,0 +1,333 @@
+// @@
+// @ Author       : Eacher
+// @ Date         : 2026-10-20 21:16:33
+// @ LastEditTime : 2026-10-20 21:52:07
+// @ LastEditors  : Eacher
+// @ --------------------------------------------------------------------------------<
+// @ Description  : RFC 3118 选项 90 Authentication, 延迟认证与 Reconfigure Key 协议
+// @ --------------------------------------------------------------------------------<
+// @ FilePath     : /20yyq/packet/dhcpv4_auth.go
+// @@
+package packet
+
+import (
+	"crypto/hmac"
+	"crypto/md5"
+	"encoding/binary"
+	"errors"
+	"sync"
+	"time"
+)
+
+/*
+RFC 3118 2  Format of an authentication option
+	The code for the authentication option is 90, and its length is N.
+
+	 0                   1                   2                   3
+	 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
+	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+	|     Code      |    Length     |  Protocol     | Algorithm     |
+	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+	|     RDM       | Replay Detection (64 bits)                    |
+	+-+-+-+-+-+-+-+-+                                               |
+	|                                                               |
+	|               +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+	|               |  Authentication Information                   |
+	+-+-+-+-+-+-+-+-+                                               |
+	|                                                               |
+	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+
+	The value 0 in the RDM field indicates the use of a monotonically increasing value
+	in the replay detection field.
+
+	To compute the MAC, the 'hops' and 'giaddr' fields and the authentication
+	information field are set to zero.
+
+	MAC 位于认证信息的最后 16 bytes, 按 WireFormat 的编码计算; 接收方由解码后的报文重新编码,
+	因此 decodeOptions 合并的长选项与选项 52 溢出的报文需要发送方同样按本包的编码方式签名
+ */
+const (
+	DHCP_Authentication = 90
+
+	DHCP_AUTH_PROTOCOL_TOKEN 			= 0
+	DHCP_AUTH_PROTOCOL_DELAYED 			= 1
+	// RFC 3315 21.5 Reconfigure Key, RFC 6704 用于 DHCPFORCERENEW
+	DHCP_AUTH_PROTOCOL_RECONFIGURE_KEY 	= 3
+
+	DHCP_AUTH_ALGORITHM_HMAC_MD5 		= 1
+
+	DHCP_AUTH_RDM_MONOTONIC 			= 0
+
+	// Protocol, Algorithm, RDM 与 Replay Detection
+	dhcpV4AuthHeaderLength 				= 0x0b
+)
+
+var (
+	ErrDhcpAuthFormat 		= errors.New("dhcp: malformed authentication option")
+	ErrDhcpAuthMissing 		= errors.New("dhcp: missing authentication option")
+	ErrDhcpAuthUnsupported 	= errors.New("dhcp: unsupported authentication protocol")
+	ErrDhcpAuthKey 			= errors.New("dhcp: unknown authentication key")
+	ErrDhcpAuthFailed 		= errors.New("dhcp: authentication failed")
+	ErrDhcpAuthReplay 		= errors.New("dhcp: replayed authentication")
+)
+
+type DhcpV4Authentication struct {
+	Protocol 		uint8
+	Algorithm 		uint8
+	RDM 			uint8
+	ReplayDetection uint64
+	Info 			[]byte
+}
+
+func NewDhcpV4Authentication(b []byte) (a DhcpV4Authentication, err error) {
+	if len(b) < dhcpV4AuthHeaderLength {
+		return a, ErrDhcpAuthFormat
+	}
+	a.Protocol, a.Algorithm, a.RDM = b[0], b[1], b[2]
+	a.ReplayDetection = binary.BigEndian.Uint64(b[3:11])
+	a.Info = append([]byte{}, b[dhcpV4AuthHeaderLength:]...)
+	return
+}
+
+func (a DhcpV4Authentication) Encode() []byte {
+	b := append([]byte{a.Protocol, a.Algorithm, a.RDM}, binary.BigEndian.AppendUint64(nil, a.ReplayDetection)...)
+	return append(b, a.Info...)
+}
+
+func SetDHCPAuthentication(a DhcpV4Authentication) OptionsPacket {
+	if dhcpV4AuthHeaderLength + len(a.Info) > 0xff {
+		return OptionsPacket{}
+	}
+	return SetDHCPOption(DHCP_Authentication, a.Encode())
+}
+
+func (dhcp DhcpV4Packet) Authentication() (DhcpV4Authentication, bool) {
+	if opp, ok := dhcp.Option(DHCP_Authentication); ok {
+		a, err := NewDhcpV4Authentication(opp.Value)
+		return a, err == nil
+	}
+	return DhcpV4Authentication{}, false
+}
+
+/*
+	计算 HMAC-MD5, hops, giaddr 与选项 90 认证信息最后 16 bytes 置零, 不修改 dhcp
+	报文不含选项 90 或认证信息不足 16 bytes 时返回 nil
+ */
+func dhcpV4AuthHMACMD5(dhcp DhcpV4Packet, key []byte) []byte {
+	options := make([]OptionsPacket, len(dhcp.Options))
+	copy(options, dhcp.Options)
+	found := false
+	for i, opp := range options {
+		if opp.Code != DHCP_Authentication {
+			continue
+		}
+		if len(opp.Value) < dhcpV4AuthHeaderLength + md5.Size {
+			return nil
+		}
+		value := append([]byte{}, opp.Value...)
+		for j := len(value) - md5.Size; j < len(value); j++ {
+			value[j] = 0
+		}
+		options[i].Value, found = value, true
+		break
+	}
+	if !found {
+		return nil
+	}
+	dhcp.Options, dhcp.Hops, dhcp.GIAddr = options, 0, IPv4{}
+	mac := hmac.New(md5.New, key)
+	mac.Write(dhcp.WireFormat())
+	return mac.Sum(nil)
+}
+
+// 将 HMAC-MD5 写入选项 90 认证信息的最后 16 bytes
+func dhcpV4AuthSign(dhcp *DhcpV4Packet, key []byte) bool {
+	sum := dhcpV4AuthHMACMD5(*dhcp, key)
+	if sum == nil {
+		return false
+	}
+	options := DhcpV4Options(dhcp.Options)
+	opp, _ := options.Get(DHCP_Authentication)
+	value := append([]byte{}, opp.Value...)
+	copy(value[len(value) - md5.Size:], sum)
+	options.Set(SetDHCPOption(DHCP_Authentication, value))
+	dhcp.Options = options
+	return true
+}
+
+func dhcpV4AuthVerify(dhcp DhcpV4Packet, key []byte) bool {
+	opp, ok := dhcp.Option(DHCP_Authentication)
+	sum := dhcpV4AuthHMACMD5(dhcp, key)
+	return ok && sum != nil && hmac.Equal(sum, opp.Value[len(opp.Value) - md5.Size:])
+}
+
+/*
+RFC 3118 4  Configuration token protocol
+	If the protocol field is 0, the authentication information field holds a simple
+	authentication token. The token is transmitted in plaintext and provides only weak
+	entity authentication and no message authentication.
+ */
+func SetDHCPAuthToken(token []byte) OptionsPacket {
+	return SetDHCPAuthentication(DhcpV4Authentication{Protocol: DHCP_AUTH_PROTOCOL_TOKEN, Info: token})
+}
+
+/*
+RFC 3118 5  Delayed authentication protocol
+	 0                   1                   2                   3
+	 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
+	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+	|     Code      |    Length     |0 0 0 0 0 0 0 1| Algorithm     |
+	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+	|     RDM       | Replay Detection (64 bits)                    |
+	+-+-+-+-+-+-+-+-+                                               |
+	|                                                               |
+	|               +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+	|               | Secret ID (32 bits)                           |
+	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+	|               |   HMAC-MD5 (128 bits) ....
+	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+
+	The client requests authentication by including the authentication option in its
+	DHCPDISCOVER message, with no authentication information.
+ */
+func SetDHCPAuthDelayedRequest(replay uint64) OptionsPacket {
+	return SetDHCPAuthentication(DhcpV4Authentication{
+		Protocol: DHCP_AUTH_PROTOCOL_DELAYED, Algorithm: DHCP_AUTH_ALGORITHM_HMAC_MD5,
+		RDM: DHCP_AUTH_RDM_MONOTONIC, ReplayDetection: replay,
+	})
+}
+
+// 协议 1 的 Secret ID, 不含认证信息 (DHCPDISCOVER 中的请求) 时 ok 为 false
+func (a DhcpV4Authentication) SecretID() (uint32, bool) {
+	if a.Protocol != DHCP_AUTH_PROTOCOL_DELAYED || len(a.Info) != 4 + md5.Size {
+		return 0, false
+	}
+	return binary.BigEndian.Uint32(a.Info), true
+}
+
+/*
+	添加或替换选项 90 并写入 HMAC-MD5, 须在其它字段与选项确定之后调用
+	之后由中继添加的选项 82 (RFC 3046) 与 Overload 都会使 MAC 失效
+ */
+func SignDhcpV4Delayed(dhcp *DhcpV4Packet, id uint32, key []byte, replay uint64) bool {
+	info := binary.BigEndian.AppendUint32(make([]byte, 0, 4 + md5.Size), id)
+	return dhcpV4AuthSetAndSign(dhcp, DHCP_AUTH_PROTOCOL_DELAYED, append(info, make([]byte, md5.Size)...), key, replay)
+}
+
+// RFC 3315 21.5 / RFC 6704: 认证信息为类型 2 与 HMAC-MD5, key 为 reconfigure key (nonce)
+func SignDhcpV4ReconfigureKey(dhcp *DhcpV4Packet, key []byte, replay uint64) bool {
+	return dhcpV4AuthSetAndSign(dhcp, DHCP_AUTH_PROTOCOL_RECONFIGURE_KEY, append([]byte{DHCP_FORCERENEW_HMAC_MD5}, make([]byte, md5.Size)...), key, replay)
+}
+
+func dhcpV4AuthSetAndSign(dhcp *DhcpV4Packet, protocol uint8, info, key []byte, replay uint64) bool {
+	options := DhcpV4Options(append([]OptionsPacket(nil), dhcp.Options...))
+	options.Set(SetDHCPAuthentication(DhcpV4Authentication{
+		Protocol: protocol, Algorithm: DHCP_AUTH_ALGORITHM_HMAC_MD5, RDM: DHCP_AUTH_RDM_MONOTONIC, ReplayDetection: replay, Info: info,
+	}))
+	dhcp.Options = options
+	return dhcpV4AuthSign(dhcp, key)
+}
+
+// RDM 0 的发送方计数, 以纳秒时间为下限, 重启后仍然递增
+type DhcpV4ReplayCounter struct {
+	mu 		sync.Mutex
+	last 	uint64
+}
+
+func (c *DhcpV4ReplayCounter) Next(now time.Time) uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if v := uint64(now.UnixNano()); v > c.last {
+		c.last = v
+	} else {
+		c.last++
+	}
+	return c.last
+}
+
+/*
+	RFC 3118 3  The receiver MUST check that the replay detection value is greater
+	than the value in the last message from the same sender.
+
+	RDM 0 的接收方状态, 以对端标识为键记录最近一次通过校验的值, 零值可以直接使用
+ */
+type DhcpV4ReplayState struct {
+	mu 		sync.Mutex
+	last 	map[string]uint64
+}
+
+// v 大于该对端上次的值时记录并返回 true
+func (r *DhcpV4ReplayState) Check(peer string, v uint64) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if last, ok := r.last[peer]; ok && v <= last {
+		return false
+	}
+	if r.last == nil {
+		r.last = make(map[string]uint64)
+	}
+	r.last[peer] = v
+	return true
+}
+
+func (r *DhcpV4ReplayState) Reset(peer string) {
+	r.mu.Lock()
+	delete(r.last, peer)
+	r.mu.Unlock()
+}
+
+/*
+	按协议校验选项 90, 各协议的密钥为空时拒绝该协议
+	MAC 校验通过后再检查 Replay Detection, peer 通常为客户端的 ClientID/ChHardware 或服务端标识
+ */
+type DhcpV4Authenticator struct {
+	// 协议 0 的令牌
+	Token 			[]byte
+	// 协议 1 的密钥, 以 Secret ID 为键
+	Keys 			map[uint32][]byte
+	// 协议 3 的 reconfigure key (RFC 6704 nonce)
+	ReconfigureKey 	[]byte
+	Replay 			DhcpV4ReplayState
+}
+
+func (a *DhcpV4Authenticator) Verify(dhcp DhcpV4Packet, peer string) error {
+	auth, ok := dhcp.Authentication()
+	if !ok {
+		return ErrDhcpAuthMissing
+	}
+	var key []byte
+	switch auth.Protocol {
+	case DHCP_AUTH_PROTOCOL_TOKEN:
+		if len(a.Token) == 0 || auth.Algorithm != 0 || !hmac.Equal(auth.Info, a.Token) {
+			return ErrDhcpAuthFailed
+		}
+		return nil
+	case DHCP_AUTH_PROTOCOL_DELAYED:
+		id, ok := auth.SecretID()
+		if !ok {
+			return ErrDhcpAuthFormat
+		}
+		if key, ok = a.Keys[id]; !ok {
+			return ErrDhcpAuthKey
+		}
+	case DHCP_AUTH_PROTOCOL_RECONFIGURE_KEY:
+		if len(auth.Info) != md5.Size + 1 || auth.Info[0] != DHCP_FORCERENEW_HMAC_MD5 {
+			return ErrDhcpAuthFormat
+		}
+		if key = a.ReconfigureKey; len(key) == 0 {
+			return ErrDhcpAuthKey
+		}
+	default:
+		return ErrDhcpAuthUnsupported
+	}
+	if auth.Algorithm != DHCP_AUTH_ALGORITHM_HMAC_MD5 || auth.RDM != DHCP_AUTH_RDM_MONOTONIC {
+		return ErrDhcpAuthUnsupported
+	}
+	if !dhcpV4AuthVerify(dhcp, key) {
+		return ErrDhcpAuthFailed
+	}
+	if !a.Replay.Check(peer, auth.ReplayDetection) {
+		return ErrDhcpAuthReplay
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+// @@
+// @ Author       : Eacher
+// @ Date         : 2026-10-20 20:41:18
+// @ LastEditTime : 2026-10-20 21:16:33
+// @ LastEditors  : Eacher
+// @ --------------------------------------------------------------------------------<
+// @ Description  : RFC 951/1542 BOOTP 兼容, RFC 1534 BOOTP 客户端永久分配
+// @ --------------------------------------------------------------------------------<
+// @ FilePath     : /20yyq/packet/dhcpv4_bootp.go
+// @@
+package packet
+
+import (
+	"context"
+	"time"
+)
+
+/*
+	RFC 1048  BOOTP Vendor Information Extensions
+
+	The vendor information field has been implicitly defined to be of
+	fixed length, 64 bytes... The first four octets of the vendor field
+	contain the "magic cookie" 99.130.83.99. The rest of the field is a
+	list of tagged data items. The remainder of the field is to be filled
+	with "pad" items (tag 0) and an "end" item (tag 255) terminates the list.
+
+	RFC 1542 3.1.2  The BOOTP client SHOULD... set the vend field to a
+	minimum of 64 octets, so that a BOOTREQUEST is at least 300 octets.
+
+	RFC 1534 2  A DHCP server MAY... allocate permanent addresses to BOOTP
+	clients. The server responds with a BOOTREPLY without DHCP options.
+
+	不含选项 53 的报文视为 BOOTP; vend 不以 MagicCookie 开头时原样保存在 Vend 中
+ */
+
+// vend 区域是否以 MagicCookie 开头, 与 WireFormat 的编码一致
+func (dhcp DhcpV4Packet) HasMagicCookie() bool {
+	return 0 < len(dhcp.Options) || dhcp.Vend == nil
+}
+
+// 不含选项 53 的 BOOTREQUEST/BOOTREPLY
+func (dhcp DhcpV4Packet) IsBOOTP() bool {
+	_, ok := dhcp.MessageType()
+	return !ok
+}
+
+/*
+	构造 BOOTREQUEST, options 为 RFC 1048 扩展, 按顺序放入 64 bytes 的 vend 区域, 放不下的选项被丢弃
+	需要不含 MagicCookie 的请求时设置 Vend 并清空 Options
+ */
+func NewBootpRequest(mac HardwareAddr, xid uint32, options ...OptionsPacket) DhcpV4Packet {
+	dhcp := DhcpV4Packet{Op: DHCP_BOOTREQUEST, HardwareType: DHCP_Ethernet_TYPE, HardwareLen: DHCP_Ethernet_LEN, XID: xid}
+	copy(dhcp.ChHardware[:], mac[:])
+	dhcp.Options = bootpFit(options, BOOTP_VEND_LEN - len(MagicCookie) - 1)
+	return dhcp
+}
+
+// 按顺序保留编码后总长度不超过 room 的选项
+func bootpFit(options []OptionsPacket, room int) (list []OptionsPacket) {
+	for _, opp := range options {
+		if n := len(opp.WireFormat()); n <= room {
+			list, room = append(list, opp), room - n
+		}
+	}
+	return
+}
+
+/*
+	RFC 1534 3  BOOTP clients and DHCP servers
+	CIAddr 为零时分配永久地址 (BOUND, Expiry 为零), 否则只返回启动参数
+	应答不含选项 53/54/51, 请求不含 MagicCookie 时应答的 vend 区域全为 0
+	选项按 vend 区域 64 bytes 截断, 请求含选项 57 时按其长度
+ */
+func (s *DhcpV4Server) bootp(ctx context.Context, sn *DhcpV4Subnet, req DhcpV4Packet, now time.Time) (DhcpV4Packet, bool, error) {
+	reply := DhcpV4Packet{
+		Op: DHCP_BOOTREPLY, HardwareType: req.HardwareType, HardwareLen: req.HardwareLen, XID: req.XID,
+		Flags: req.Flags, CIAddr: req.CIAddr, SIAddr: s.serverID(req), GIAddr: req.GIAddr, ChHardware: req.ChHardware,
+	}
+	if req.CIAddr == (IPv4{}) {
+		ip, err := s.pick(ctx, sn, req, now)
+		if err != nil || ip == (IPv4{}) {
+			return DhcpV4Packet{}, false, err
+		}
+		mac, cid := dhcpV4Client(req)
+		old, ok := s.leases[ip]
+		r := DhcpV4LeaseRecord{IP: ip, HardwareAddr: mac, ClientID: cid, State: DHCP_LEASE_BOUND}
+		if ok && old.Owner(mac, cid) {
+			r.HostName = old.HostName
+		}
+		name, hasName := req.HostNameOption()
+		if hasName {
+			r.HostName = name
+		}
+		// 重传的请求不再写入 Store
+		if !ok || !old.Owner(mac, cid) || old.State != DHCP_LEASE_BOUND || !old.Expiry.IsZero() || old.HostName != r.HostName {
+			r.Updated = now
+			if err = s.put(0, r); err != nil {
+				return DhcpV4Packet{}, false, err
+			}
+			if s.OnLease != nil {
+				s.OnLease(0, r)
+			}
+		}
+		reply.YIAddr = ip
+	}
+	reply.Options = s.options(sn, req)
+	if sn.Boot != nil {
+		sn.Boot.Apply(req, &reply)
+	}
+	if !req.HasMagicCookie() {
+		reply.Options, reply.Vend = nil, []byte{}
+		return reply, true, nil
+	}
+	room := BOOTP_VEND_LEN - len(MagicCookie) - 1
+	if max, ok := req.MaximumMessageSize(); ok && int(max) - SizeofIPv4Packet - SizeofDUPPacket - SizeofDhcpV4Packet - 1 > room {
+		room = int(max) - SizeofIPv4Packet - SizeofDUPPacket - SizeofDhcpV4Packet - 1
+	}
+	// RFC 3046 2.2: 回显选项 82, 作为最后一个选项
+	agent, hasAgent := req.Option(DHCP_Relay_Agent_Information)
+	if hasAgent {
+		room -= len(agent.WireFormat())
+	}
+	reply.Options = bootpFit(reply.Options, room)
+	if hasAgent {
+		reply.Options = append(reply.Options, agent)
+	}
+	return reply, true, nil
+}
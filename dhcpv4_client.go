@@ -0,0 +1,742 @@
+// @@
+// @ Author       : Eacher
+// @ Date         : 2026-10-20 09:12:40
+// @ LastEditTime : 2026-10-21 15:46:03
+// @ LastEditors  : Eacher
+// @ --------------------------------------------------------------------------------<
+// @ Description  : RFC 2131 DHCPv4 客户端状态机
+// @ --------------------------------------------------------------------------------<
+// @ FilePath     : /20yyq/packet/dhcpv4_client.go
+// @@
+package packet
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"math/rand"
+	"os"
+	"sync"
+	"time"
+)
+
+/*
+	RFC 2131 4.4  DHCP client behavior
+
+	 --------                               -------
+	|        | +-------------------------->|       |<-------------------+
+	| INIT-  | |     +-------------------->| INIT  |                    |
+	| REBOOT |DHCPNAK/         +---------->|       |<---+               |
+	|        |Restart|         |            -------     |               |
+	 --------  |  DHCPNAK/     |               |                        |
+	    |      Discard offer   |      -/Send DHCPDISCOVER               |
+	-/Send DHCPREQUEST         |               |                        |
+	    |      |     |      DHCPACK            v        |               |
+	 -----------     |   (not accept.)/   -----------   |               |
+	|           |    |  Send DHCPDECLINE |           |                  |
+	| REBOOTING |    |         |         | SELECTING |<----+            |
+	|           |    |        /          |           |     |DHCPOFFER/  |
+	 -----------     |       /            -----------   |  |Collect     |
+	    |            |      /                  |   |       |  replies   |
+	DHCPACK/         |     /  +----------------+   +-------+            |
+	Record lease, set|    |   v   Select offer/                         |
+	timers T1, T2   ------------  send DHCPREQUEST      |               |
+	    |   +----->|            |             DHCPNAK, Lease expired/   |
+	    |   |      | REQUESTING |                  Halt network         |
+	    DHCPOFFER/ |            |                       |               |
+	    Discard     ------------                        |               |
+	    |   |        |        |                   -----------           |
+	    |   +--------+     DHCPACK/              |           |          |
+	    |              Record lease, set    -----| REBINDING |          |
+	    |                timers T1, T2     /     |           |          |
+	    |                     |        DHCPACK/   -----------           |
+	    |                     v     Record lease, set   ^               |
+	    +----------------> -------      /timers T1,T2   |               |
+	               +----->|       |<---+                |               |
+	               |      | BOUND |<---+                |               |
+	  DHCPOFFER, DHCPACK, |       |    |            T2 expires/   DHCPNAK/
+	   DHCPNAK/Discard     -------     |             Broadcast  Halt network
+	               |       | |         |            DHCPREQUEST         |
+	               +-------+ |        DHCPACK/          |               |
+	                    T1 expires/   Record lease, set |               |
+	                 Send DHCPREQUEST timers T1, T2     |               |
+	                 to leasing server |                |               |
+	                         |   ----------             |               |
+	                         |  |          |------------+               |
+	                         +->| RENEWING |                            |
+	                            |          |----------------------------+
+	                             ----------
+ */
+type DhcpV4ClientState uint8
+
+const (
+	DHCP_STATE_INIT DhcpV4ClientState = iota
+	DHCP_STATE_SELECTING
+	DHCP_STATE_REQUESTING
+	DHCP_STATE_BOUND
+	DHCP_STATE_RENEWING
+	DHCP_STATE_REBINDING
+	DHCP_STATE_INIT_REBOOT
+	DHCP_STATE_REBOOTING
+)
+
+func (s DhcpV4ClientState) String() string {
+	switch s {
+	case DHCP_STATE_INIT:
+		return "INIT"
+	case DHCP_STATE_SELECTING:
+		return "SELECTING"
+	case DHCP_STATE_REQUESTING:
+		return "REQUESTING"
+	case DHCP_STATE_BOUND:
+		return "BOUND"
+	case DHCP_STATE_RENEWING:
+		return "RENEWING"
+	case DHCP_STATE_REBINDING:
+		return "REBINDING"
+	case DHCP_STATE_INIT_REBOOT:
+		return "INIT-REBOOT"
+	case DHCP_STATE_REBOOTING:
+		return "REBOOTING"
+	}
+	return "UNKNOWN"
+}
+
+/*
+	RFC 2131 4.1  Constructing and sending DHCP messages
+	For example, in a 10Mb/sec Ethernet internetwork, the delay before the first
+	retransmission SHOULD be 4 seconds randomized by the value of a uniform random number
+	chosen from the range -1 to +1.  The delay before the next retransmission SHOULD be
+	8 seconds randomized by the value of a uniform number chosen from the range -1 to +1.
+	The retransmission delay SHOULD be doubled with subsequent retransmissions up to a
+	maximum of 64 seconds.
+
+	RFC 2131 4.4.5  Reacquisition and expiration
+	In both RENEWING and REBINDING states, if the client receives no response to its
+	DHCPREQUEST message, the client SHOULD wait one-half of the remaining time until T2
+	(in RENEWING state) and one-half of the remaining lease time (in REBINDING state),
+	down to a minimum of 60 seconds, before retransmitting the DHCPREQUEST message.
+ */
+const (
+	DHCP_CLIENT_BACKOFF 		= 4 * time.Second
+	DHCP_CLIENT_MAX_BACKOFF 	= 64 * time.Second
+	DHCP_CLIENT_RENEW_MIN 		= 60 * time.Second
+	DHCP_CLIENT_REQUEST_RETRIES = 4
+	// 3.1.5 发送 DHCPDECLINE 后至少等待 10 秒再重新申请
+	DHCP_CLIENT_DECLINE_WAIT 	= 10 * time.Second
+
+	// 租约时间为 0xffffffff 表示永久
+	DHCP_INFINITE_LEASE 		= time.Duration(0xffffffff) * time.Second
+)
+
+var (
+	ErrDhcpNak 		= errors.New("dhcp: server replied DHCPNAK")
+	ErrDhcpTimeout 	= errors.New("dhcp: no response from server")
+)
+
+type DhcpV4Lease struct {
+	IP 			IPv4
+	ServerID 	IPv4
+	SubnetMask 	IPv4
+	Routers 	[]IPv4
+	// 已按 RFC 3442 处理选项 121/33/3 的优先级
+	Routes 		[]DhcpV4Route
+	DNS 		[]IPv4
+	DomainName 	string
+	// 选项 119
+	DomainSearch []string
+	LeaseTime 	time.Duration
+	T1 			time.Duration
+	T2 			time.Duration
+	// 发送获得该租约的 DHCPREQUEST 的时间, 各定时器由此起算
+	Start 		time.Time
+	// RFC 6704 服务端下发的 nonce, 用于认证 DHCPFORCERENEW
+	ForcerenewNonce []byte
+	// 服务端 DHCPACK 原文
+	Ack 		DhcpV4Packet
+}
+
+func (l DhcpV4Lease) Expiry() time.Time {
+	if l.LeaseTime >= DHCP_INFINITE_LEASE {
+		return time.Time{}
+	}
+	return l.Start.Add(l.LeaseTime)
+}
+
+func (l DhcpV4Lease) Valid(now time.Time) bool {
+	if l.IP == (IPv4{}) {
+		return false
+	}
+	expiry := l.Expiry()
+	return expiry.IsZero() || now.Before(expiry)
+}
+
+type DhcpV4EventType uint8
+
+const (
+	DHCP_EVENT_BOUND DhcpV4EventType = iota + 1
+	DHCP_EVENT_RENEWED
+	DHCP_EVENT_REBOUND
+	DHCP_EVENT_EXPIRED
+	DHCP_EVENT_NAK
+	DHCP_EVENT_DECLINED
+	DHCP_EVENT_RELEASED
+)
+
+func (t DhcpV4EventType) String() string {
+	switch t {
+	case DHCP_EVENT_BOUND:
+		return "bound"
+	case DHCP_EVENT_RENEWED:
+		return "renewed"
+	case DHCP_EVENT_REBOUND:
+		return "rebound"
+	case DHCP_EVENT_EXPIRED:
+		return "expired"
+	case DHCP_EVENT_NAK:
+		return "nak"
+	case DHCP_EVENT_DECLINED:
+		return "declined"
+	case DHCP_EVENT_RELEASED:
+		return "released"
+	}
+	return "unknown"
+}
+
+type DhcpV4Event struct {
+	Type 	DhcpV4EventType
+	State 	DhcpV4ClientState
+	Lease 	DhcpV4Lease
+}
+
+type DhcpV4Client struct {
+	HardwareAddr 	HardwareAddr
+	// 获得地址前使用的传输, 通常为 NewDhcpV4RawTransport
+	Transport 		DhcpV4Transport
+	// 获得地址后 RENEWING 单播使用的传输, 为 nil 时使用 Transport
+	BoundTransport 	DhcpV4Transport
+	HostName 		string
+	ClientID 		DhcpV4ClientID
+	RequestList 	[]uint8
+	// 附加在 DHCPDISCOVER/DHCPREQUEST 中的选项
+	Options 		[]OptionsPacket
+	// 要求服务端广播应答
+	Broadcast 		bool
+	// 上次获得的租约, 有效时从 INIT-REBOOT 开始
+	Lease 			DhcpV4Lease
+	// 不为 nil 时在 DHCPACK 后检测地址冲突, 冲突则发送 DHCPDECLINE, 参见 NewDhcpV4ArpConflict
+	Conflict 		func(ctx context.Context, ip IPv4) (bool, error)
+	// Run 结束时不发送 DHCPRELEASE
+	KeepLease 		bool
+	// DHCPDISCOVER 携带选项 80, 接受服务端直接回复的 DHCPACK (RFC 4039)
+	RapidCommit 	bool
+	// 携带选项 145 并在 BOUND 状态接收经 nonce 认证的 DHCPFORCERENEW (RFC 3203, RFC 6704)
+	Forcerenew 		bool
+
+	Backoff 		time.Duration
+	MaxBackoff 		time.Duration
+	RenewMin 		time.Duration
+	RequestRetries 	int
+	DeclineWait 	time.Duration
+	Rand 			*rand.Rand
+
+	// 事件回调与事件通道, 通道发送会阻塞直到被读取或者 ctx 结束
+	// Release (包括 Run 结束时) 发送的 DHCP_EVENT_RELEASED 不阻塞, 通道无人读取时丢弃
+	OnEvent 		func(DhcpV4Event)
+	Events 			chan<- DhcpV4Event
+
+	// state 只由 Run 所在的 goroutine 修改, mu 保护 State 的并发读取
+	mu 				sync.Mutex
+	state 			DhcpV4ClientState
+	xid 			uint32
+	start 			time.Time
+	// 以服务端标识记录 DHCPFORCERENEW 的 Replay Detection
+	auth 			DhcpV4Authenticator
+}
+
+// RFC 3442: 选项 121 位于选项 3 之前
+var dhcpV4DefaultRequestList = []uint8{1, 121, 3, 6, 12, 15, 28, 33, 42, 51, 54, 58, 59, 119}
+
+// 基于 ARP 冲突检测的 Conflict 实现, link 需接收 ETH_P_ARP 帧
+func NewDhcpV4ArpConflict(link FrameReadWriter, mac HardwareAddr) func(ctx context.Context, ip IPv4) (bool, error) {
+	return func(ctx context.Context, ip IPv4) (bool, error) {
+		acd := &ArpConflictDetector{Link: link, HardwareAddr: mac, IP: ip}
+		err := acd.Probe(ctx)
+		if errors.Is(err, ErrArpConflict) {
+			return true, nil
+		}
+		return false, err
+	}
+}
+
+// 可以在 Run 运行时从其它 goroutine 调用
+func (c *DhcpV4Client) State() DhcpV4ClientState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.state
+}
+
+func (c *DhcpV4Client) setState(state DhcpV4ClientState) {
+	c.mu.Lock()
+	c.state = state
+	c.mu.Unlock()
+}
+
+// 运行状态机直到 ctx 结束, 结束时若持有租约则发送 DHCPRELEASE
+func (c *DhcpV4Client) Run(ctx context.Context) error {
+	c.random()
+	c.setState(DHCP_STATE_INIT)
+	if c.Lease.Valid(time.Now()) {
+		c.setState(DHCP_STATE_INIT_REBOOT)
+	}
+	for {
+		err := c.step(ctx)
+		if ctx.Err() != nil {
+			if !c.KeepLease && c.bound() {
+				c.Release()
+			}
+			return ctx.Err()
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (c *DhcpV4Client) bound() bool {
+	return c.state == DHCP_STATE_BOUND || c.state == DHCP_STATE_RENEWING || c.state == DHCP_STATE_REBINDING
+}
+
+func (c *DhcpV4Client) step(ctx context.Context) error {
+	switch c.state {
+	case DHCP_STATE_INIT, DHCP_STATE_SELECTING:
+		return c.selecting(ctx)
+	case DHCP_STATE_INIT_REBOOT, DHCP_STATE_REBOOTING:
+		return c.rebooting(ctx)
+	case DHCP_STATE_BOUND:
+		return c.waitRenew(ctx)
+	case DHCP_STATE_RENEWING, DHCP_STATE_REBINDING:
+		return c.renewing(ctx)
+	}
+	return nil
+}
+
+// BOUND -> RENEWING, 等待 T1 或者 DHCPFORCERENEW
+func (c *DhcpV4Client) waitRenew(ctx context.Context) error {
+	var t1 time.Time
+	if c.Lease.LeaseTime < DHCP_INFINITE_LEASE {
+		t1 = c.Lease.Start.Add(c.Lease.T1)
+	}
+	if !c.Forcerenew {
+		if t1.IsZero() {
+			<-ctx.Done()
+			return nil
+		}
+		if err := sleepContext(ctx, time.Until(t1)); err == nil {
+			c.setState(DHCP_STATE_RENEWING)
+		}
+		return nil
+	}
+	tr := c.Transport
+	if c.BoundTransport != nil {
+		tr = c.BoundTransport
+	}
+	lw := watchLink(ctx, tr)
+	defer lw.Close()
+	lw.setDeadline(t1)
+	for {
+		dhcp, _, err := tr.Receive()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			if errors.Is(err, os.ErrDeadlineExceeded) {
+				c.setState(DHCP_STATE_RENEWING)
+				return nil
+			}
+			return err
+		}
+		if c.forcerenewed(dhcp) {
+			c.setState(DHCP_STATE_RENEWING)
+			return nil
+		}
+	}
+}
+
+// INIT -> SELECTING -> REQUESTING -> BOUND, RFC 4039 SELECTING -> BOUND
+func (c *DhcpV4Client) selecting(ctx context.Context) error {
+	c.setState(DHCP_STATE_SELECTING)
+	c.xid, c.start = c.Rand.Uint32(), time.Now()
+	types := []DHCP_Message_Type{DHCP_OFFER}
+	if c.RapidCommit {
+		types = append(types, DHCP_ACK)
+	}
+	var start time.Time
+	offer, err := c.exchange(ctx, c.Transport, IPv4Broadcast, func() DhcpV4Packet {
+		start = time.Now()
+		return c.packet(DHCP_DISCOVER, IPv4{})
+	}, time.Time{}, -1, types...)
+	if err != nil {
+		return err
+	}
+	if t, _ := offer.MessageType(); t == DHCP_ACK {
+		return c.acknowledged(ctx, offer, start, DHCP_EVENT_BOUND)
+	}
+	c.setState(DHCP_STATE_REQUESTING)
+	server, _ := offer.Option(uint8(DHCP_Server_Identifier))
+	ack, err := c.exchange(ctx, c.Transport, IPv4Broadcast, func() DhcpV4Packet {
+		start = time.Now()
+		dhcp := c.packet(DHCP_REQUEST, IPv4{})
+		options := DhcpV4Options(dhcp.Options)
+		options.Set(SetDHCPIPv4(DHCP_Requested_IP_Address, offer.YIAddr))
+		options.Set(server)
+		dhcp.Options = options
+		return dhcp
+	}, time.Time{}, c.requestRetries(), DHCP_ACK, DHCP_NAK)
+	if err != nil {
+		if errors.Is(err, ErrDhcpTimeout) {
+			c.setState(DHCP_STATE_INIT)
+			return nil
+		}
+		return err
+	}
+	return c.acknowledged(ctx, ack, start, DHCP_EVENT_BOUND)
+}
+
+// INIT-REBOOT -> REBOOTING -> BOUND
+func (c *DhcpV4Client) rebooting(ctx context.Context) error {
+	c.setState(DHCP_STATE_REBOOTING)
+	c.xid, c.start = c.Rand.Uint32(), time.Now()
+	var start time.Time
+	ack, err := c.exchange(ctx, c.Transport, IPv4Broadcast, func() DhcpV4Packet {
+		start = time.Now()
+		dhcp := c.packet(DHCP_REQUEST, IPv4{})
+		dhcp.Options = append(dhcp.Options, SetDHCPIPv4(DHCP_Requested_IP_Address, c.Lease.IP))
+		return dhcp
+	}, c.Lease.Expiry(), c.requestRetries(), DHCP_ACK, DHCP_NAK)
+	if err != nil {
+		if errors.Is(err, ErrDhcpTimeout) {
+			c.setState(DHCP_STATE_INIT)
+			c.Lease = DhcpV4Lease{}
+			return nil
+		}
+		return err
+	}
+	return c.acknowledged(ctx, ack, start, DHCP_EVENT_BOUND)
+}
+
+// RENEWING -> REBINDING -> BOUND/INIT
+func (c *DhcpV4Client) renewing(ctx context.Context) error {
+	c.xid, c.start = c.Rand.Uint32(), time.Now()
+	tr, dst, until, event := c.Transport, c.Lease.ServerID, c.Lease.Start.Add(c.Lease.T2), DHCP_EVENT_RENEWED
+	if c.state == DHCP_STATE_RENEWING && c.BoundTransport != nil {
+		tr = c.BoundTransport
+	}
+	if c.state == DHCP_STATE_REBINDING {
+		dst, until, event = IPv4Broadcast, c.Lease.Expiry(), DHCP_EVENT_REBOUND
+	}
+	var start time.Time
+	ack, err := c.exchange(ctx, tr, dst, func() DhcpV4Packet {
+		start = time.Now()
+		return c.packet(DHCP_REQUEST, c.Lease.IP)
+	}, until, -1, DHCP_ACK, DHCP_NAK)
+	if err == nil {
+		return c.acknowledged(ctx, ack, start, event)
+	}
+	if !errors.Is(err, ErrDhcpTimeout) {
+		return err
+	}
+	if c.state == DHCP_STATE_RENEWING {
+		c.setState(DHCP_STATE_REBINDING)
+		return nil
+	}
+	lease := c.Lease
+	c.setState(DHCP_STATE_INIT)
+	c.Lease = DhcpV4Lease{}
+	c.emit(ctx, DHCP_EVENT_EXPIRED, lease)
+	return nil
+}
+
+// 处理 DHCPACK/DHCPNAK
+func (c *DhcpV4Client) acknowledged(ctx context.Context, ack DhcpV4Packet, start time.Time, event DhcpV4EventType) error {
+	if t, _ := ack.MessageType(); t == DHCP_NAK {
+		lease := c.Lease
+		c.setState(DHCP_STATE_INIT)
+		c.Lease = DhcpV4Lease{}
+		c.emit(ctx, DHCP_EVENT_NAK, lease)
+		return nil
+	}
+	lease := NewDhcpV4Lease(ack, start)
+	if lease.ForcerenewNonce == nil && lease.ServerID == c.Lease.ServerID {
+		lease.ForcerenewNonce = c.Lease.ForcerenewNonce
+	}
+	if event == DHCP_EVENT_BOUND && c.Conflict != nil {
+		conflict, err := c.Conflict(ctx, lease.IP)
+		if err != nil {
+			return err
+		}
+		if conflict {
+			err = c.decline(lease)
+			c.setState(DHCP_STATE_INIT)
+			c.Lease = DhcpV4Lease{}
+			c.emit(ctx, DHCP_EVENT_DECLINED, lease)
+			if err != nil {
+				return err
+			}
+			return sleepContext(ctx, c.declineWait())
+		}
+	}
+	c.setState(DHCP_STATE_BOUND)
+	c.Lease = lease
+	c.emit(ctx, event, lease)
+	return nil
+}
+
+// 由 DHCPACK 生成租约, 缺省 T1 为租期的 0.5, T2 为租期的 0.875
+func NewDhcpV4Lease(ack DhcpV4Packet, start time.Time) DhcpV4Lease {
+	lease := DhcpV4Lease{IP: ack.YIAddr, Start: start, Ack: ack, LeaseTime: DHCP_INFINITE_LEASE}
+	if lease.IP == (IPv4{}) {
+		lease.IP = ack.CIAddr
+	}
+	lease.ServerID, _ = ack.ServerIdentifier()
+	lease.SubnetMask, _ = ack.SubnetMask()
+	lease.Routers, _ = ack.Routers()
+	lease.Routes = ack.EffectiveRoutes()
+	lease.DNS, _ = ack.DNSServers()
+	lease.DomainName, _ = ack.DomainName()
+	lease.DomainSearch, _ = ack.DomainSearch()
+	lease.ForcerenewNonce, _ = ack.ForcerenewNonce()
+	if d, ok := ack.LeaseTime(); ok {
+		lease.LeaseTime = d
+	}
+	lease.T1, lease.T2 = lease.LeaseTime / 2, lease.LeaseTime * 7 / 8
+	if d, ok := ack.RenewalTime(); ok && d < lease.LeaseTime {
+		lease.T1 = d
+	}
+	if d, ok := ack.RebindingTime(); ok && d < lease.LeaseTime {
+		lease.T2 = d
+	}
+	if lease.T1 > lease.T2 {
+		lease.T1 = lease.T2
+	}
+	return lease
+}
+
+/*
+	发送请求并等待应答, 超时按指数退避重传
+	retries 小于 0 时不限次数; until 不为零值时, 到达该时间返回 ErrDhcpTimeout, 并按 4.4.5 计算重传间隔
+ */
+func (c *DhcpV4Client) exchange(ctx context.Context, tr DhcpV4Transport, dst IPv4, build func() DhcpV4Packet, until time.Time, retries int, types ...DHCP_Message_Type) (DhcpV4Packet, error) {
+	lw := watchLink(ctx, tr)
+	defer lw.Close()
+	backoff := c.backoff()
+	for attempt := 0; retries < 0 || attempt <= retries; attempt++ {
+		if !until.IsZero() && !time.Now().Before(until) {
+			break
+		}
+		if err := tr.Send(build(), dst); err != nil {
+			return DhcpV4Packet{}, err
+		}
+		var wait time.Duration
+		if until.IsZero() {
+			// 4.1: 随机 -1 到 +1 秒, 不小于 backoff 的一半
+			if wait = backoff; backoff > time.Second {
+				wait += time.Duration(c.Rand.Int63n(int64(2 * time.Second))) - time.Second
+			}
+			if wait < backoff / 2 {
+				wait = backoff / 2
+			}
+			if backoff *= 2; backoff > c.maxBackoff() {
+				backoff = c.maxBackoff()
+			}
+		} else if wait = time.Until(until) / 2; wait < c.renewMin() {
+			wait = c.renewMin()
+		}
+		deadline := time.Now().Add(wait)
+		if !until.IsZero() && deadline.After(until) {
+			deadline = until
+		}
+		for {
+			lw.setDeadline(deadline)
+			dhcp, _, err := tr.Receive()
+			if err != nil {
+				if ctx.Err() != nil {
+					return DhcpV4Packet{}, ctx.Err()
+				}
+				if errors.Is(err, os.ErrDeadlineExceeded) {
+					break
+				}
+				return DhcpV4Packet{}, err
+			}
+			if c.accept(dhcp, types) {
+				return dhcp, nil
+			}
+		}
+	}
+	return DhcpV4Packet{}, ErrDhcpTimeout
+}
+
+func (c *DhcpV4Client) accept(dhcp DhcpV4Packet, types []DHCP_Message_Type) bool {
+	if dhcp.Op != DHCP_BOOTREPLY || dhcp.XID != c.xid || HardwareAddr(dhcp.ChHardware[:6]) != c.HardwareAddr {
+		return false
+	}
+	t, _ := dhcp.MessageType()
+	// RFC 4039: SELECTING 中的 DHCPACK 必须带有选项 80
+	if t == DHCP_ACK && c.state == DHCP_STATE_SELECTING && !dhcp.RapidCommit() {
+		return false
+	}
+	// DHCPREQUEST 必须以选项 54 选择服务端, 没有服务端标识的 DHCPOFFER 无法使用
+	if _, ok := dhcp.ServerIdentifier(); t == DHCP_OFFER && !ok {
+		return false
+	}
+	for _, v := range types {
+		if v == t {
+			return true
+		}
+	}
+	return false
+}
+
+// ciaddr 仅在 BOUND/RENEWING/REBINDING 中填写
+func (c *DhcpV4Client) packet(t DHCP_Message_Type, ciaddr IPv4) DhcpV4Packet {
+	dhcp := DhcpV4Packet{
+		Op: DHCP_BOOTREQUEST, HardwareType: DHCP_Ethernet_TYPE, HardwareLen: DHCP_Ethernet_LEN,
+		XID: c.xid, CIAddr: ciaddr,
+	}
+	copy(dhcp.ChHardware[:], c.HardwareAddr[:])
+	if t == DHCP_DISCOVER || t == DHCP_REQUEST {
+		if secs := time.Since(c.start) / time.Second; secs < 0xffff {
+			dhcp.Secs = uint16(secs)
+		} else {
+			dhcp.Secs = 0xffff
+		}
+		if c.Broadcast {
+			dhcp.Flags = DHCP_BROADCAST_FLAG
+		}
+	}
+	dhcp.Options = append(dhcp.Options, SetDHCPMessage(t))
+	if opp := SetDHCPClientID(c.ClientID); opp.Code != 0 {
+		dhcp.Options = append(dhcp.Options, opp)
+	}
+	if t != DHCP_DISCOVER && t != DHCP_REQUEST {
+		return dhcp
+	}
+	if c.HostName != "" && len(c.HostName) < 256 {
+		dhcp.Options = append(dhcp.Options, OptionsPacket{uint8(DHCP_Host_Name), uint8(len(c.HostName)), []byte(c.HostName)})
+	}
+	list := c.RequestList
+	if len(list) == 0 {
+		list = dhcpV4DefaultRequestList
+	}
+	options := append(DhcpV4Options(dhcp.Options), SetDHCPOptionsRequestList(list...), SetDHCPMaximumMessageSize(1500))
+	if t == DHCP_DISCOVER && c.RapidCommit {
+		options.Set(SetDHCPRapidCommit())
+	}
+	if c.Forcerenew {
+		options.Set(SetDHCPForcerenewNonceCapable(DHCP_AUTH_ALGORITHM_HMAC_MD5))
+	}
+	for _, opp := range c.Options {
+		options.Set(opp)
+	}
+	dhcp.Options = options
+	return dhcp
+}
+
+/*
+	RFC 2131 4.4.4  Use of broadcast and unicast
+	If the client receives a DHCPACK that the client determines is already in use, the
+	client MUST send a DHCPDECLINE message to the server.  The client SHOULD wait a
+	minimum of ten seconds before restarting the configuration process to avoid
+	excessive network traffic in case of looping.
+ */
+func (c *DhcpV4Client) decline(lease DhcpV4Lease) error {
+	dhcp := c.packet(DHCP_DECLINE, IPv4{})
+	dhcp.Options = append(dhcp.Options, SetDHCPIPv4(DHCP_Requested_IP_Address, lease.IP), SetDHCPIPv4(DHCP_Server_Identifier, lease.ServerID))
+	return c.Transport.Send(dhcp, IPv4Broadcast)
+}
+
+// 向服务端单播 DHCPRELEASE 并清除租约
+func (c *DhcpV4Client) Release() error {
+	lease := c.Lease
+	if lease.IP == (IPv4{}) {
+		return nil
+	}
+	c.xid = c.random().Uint32()
+	dhcp := c.packet(DHCP_RELEASE, lease.IP)
+	dhcp.Options = append(dhcp.Options, SetDHCPIPv4(DHCP_Server_Identifier, lease.ServerID))
+	tr := c.Transport
+	if c.BoundTransport != nil {
+		tr = c.BoundTransport
+	}
+	err := tr.Send(dhcp, lease.ServerID)
+	c.setState(DHCP_STATE_INIT)
+	c.Lease = DhcpV4Lease{}
+	// Run 的 ctx 结束后调用方可能已不再读取 Events
+	if event := c.event(DHCP_EVENT_RELEASED, lease); c.Events != nil {
+		select {
+		case c.Events <- event:
+		default:
+		}
+	}
+	return err
+}
+
+func (c *DhcpV4Client) emit(ctx context.Context, t DhcpV4EventType, lease DhcpV4Lease) {
+	if event := c.event(t, lease); c.Events != nil {
+		select {
+		case c.Events <- event:
+		case <-ctx.Done():
+		}
+	}
+}
+
+// 调用 OnEvent 并返回事件
+func (c *DhcpV4Client) event(t DhcpV4EventType, lease DhcpV4Lease) DhcpV4Event {
+	event := DhcpV4Event{Type: t, State: c.state, Lease: lease}
+	if c.OnEvent != nil {
+		c.OnEvent(event)
+	}
+	return event
+}
+
+func (c *DhcpV4Client) random() *rand.Rand {
+	if c.Rand == nil {
+		c.Rand = rand.New(rand.NewSource(time.Now().UnixNano() ^ int64(binary.BigEndian.Uint32(c.HardwareAddr[2:]))))
+	}
+	return c.Rand
+}
+
+func (c *DhcpV4Client) backoff() time.Duration {
+	if c.Backoff > 0 {
+		return c.Backoff
+	}
+	return DHCP_CLIENT_BACKOFF
+}
+
+func (c *DhcpV4Client) maxBackoff() time.Duration {
+	if c.MaxBackoff > 0 {
+		return c.MaxBackoff
+	}
+	return DHCP_CLIENT_MAX_BACKOFF
+}
+
+func (c *DhcpV4Client) renewMin() time.Duration {
+	if c.RenewMin > 0 {
+		return c.RenewMin
+	}
+	return DHCP_CLIENT_RENEW_MIN
+}
+
+func (c *DhcpV4Client) requestRetries() int {
+	if c.RequestRetries > 0 {
+		return c.RequestRetries
+	}
+	return DHCP_CLIENT_REQUEST_RETRIES
+}
+
+func (c *DhcpV4Client) declineWait() time.Duration {
+	if c.DeclineWait > 0 {
+		return c.DeclineWait
+	}
+	return DHCP_CLIENT_DECLINE_WAIT
+}
@@ -0,0 +1,92 @@
+// @@
+// @ Author       : Eacher
+// @ Date         : 2026-10-21 15:46:03
+// @ LastEditTime : 2026-10-21 15:46:03
+// @ LastEditors  : Eacher
+// @ --------------------------------------------------------------------------------<
+// @ Description  : DHCPv4 客户端与进程内服务端测试
+// @ --------------------------------------------------------------------------------<
+// @ FilePath     : /20yyq/packet/dhcpv4_client_test.go
+// @@
+package packet
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestDhcpV4ClientServer(t *testing.T) {
+	mac := HardwareAddr{0x02, 0, 0, 0, 0, 0x30}
+	s := &DhcpV4Server{
+		ServerID: 	dhcpV4TestServerIP,
+		Subnets: 	[]*DhcpV4Subnet{newDhcpV4TestSubnet()},
+		Store: 		NewDhcpV4MemoryStore(),
+		LeaseTime: 	time.Hour,
+	}
+	events := make(chan DhcpV4Event, 4)
+	c := &DhcpV4Client{HardwareAddr: mac, Transport: startDhcpV4TestServer(t, s), Backoff: 200 * time.Millisecond, Events: events}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- c.Run(ctx) }()
+	select {
+	case e := <-events:
+		if e.Type != DHCP_EVENT_BOUND || e.Lease.IP != (IPv4{10, 0, 0, 10}) || e.Lease.ServerID != dhcpV4TestServerIP || e.Lease.LeaseTime != time.Hour {
+			t.Errorf("event = %v %+v; want bound 10.0.0.10 from %v", e.Type, e.Lease, dhcpV4TestServerIP)
+		}
+		if routers, _ := e.Lease.Ack.IPv4ListOption(DHCP_Router); len(routers) != 1 || routers[0] != dhcpV4TestServerIP {
+			t.Errorf("lease routers = %v; want [%v]", routers, dhcpV4TestServerIP)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("no lease, client state %v", c.State())
+	}
+	// Run 运行时读取状态
+	if st := c.State(); st != DHCP_STATE_BOUND {
+		t.Errorf("State() = %v; want bound", st)
+	}
+	cancel()
+	<-done
+	// Run 结束时发送 DHCPRELEASE
+	for i := 0; ; i++ {
+		if leases := s.Leases(); len(leases) == 1 && leases[0].State == DHCP_LEASE_RELEASED {
+			break
+		} else if i == 50 {
+			t.Fatalf("leases after Run = %+v; want one released", leases)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// 没有服务端标识的 DHCPOFFER 不能进入 REQUESTING
+func TestDhcpV4ClientOfferWithoutServerID(t *testing.T) {
+	a, b := NewFramePipe()
+	defer b.Close()
+	server := NewDhcpV4RawTransport(b, dhcpV4TestServerMAC, dhcpV4TestServerIP, DHCP_ServerPort, DHCP_ClientPort)
+	requests := make(chan DHCP_Message_Type, 16)
+	go func() {
+		for {
+			req, _, err := server.Receive()
+			if err != nil {
+				return
+			}
+			mt, _ := req.MessageType()
+			requests <- mt
+			offer := DhcpV4Packet{Op: DHCP_BOOTREPLY, HardwareType: req.HardwareType, HardwareLen: req.HardwareLen, XID: req.XID, YIAddr: IPv4{10, 0, 0, 10},
+				ChHardware: req.ChHardware, Options: []OptionsPacket{SetDHCPMessage(DHCP_OFFER)}}
+			server.Send(offer, IPv4Broadcast)
+		}
+	}()
+	c := &DhcpV4Client{HardwareAddr: HardwareAddr{0x02, 0, 0, 0, 0, 0x31}, Transport: NewDhcpV4RawTransport(a, HardwareAddr{0x02, 0, 0, 0, 0, 0x31}, IPv4{}, DHCP_ClientPort, DHCP_ServerPort),
+		Backoff: 100 * time.Millisecond, KeepLease: true}
+	ctx, cancel := context.WithTimeout(context.Background(), 500 * time.Millisecond)
+	defer cancel()
+	c.Run(ctx)
+	if st := c.State(); st != DHCP_STATE_SELECTING {
+		t.Errorf("State() = %v; want selecting", st)
+	}
+	for len(requests) > 0 {
+		if mt := <-requests; mt != DHCP_DISCOVER {
+			t.Errorf("client sent message type %d; want only DHCPDISCOVER", mt)
+		}
+	}
+}
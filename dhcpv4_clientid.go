@@ -0,0 +1,306 @@
+// @@
+// @ Author       : Eacher
+// @ Date         : 2026-10-20 17:52:14
+// @ LastEditTime : 2026-10-21 16:12:50
+// @ LastEditors  : Eacher
+// @ --------------------------------------------------------------------------------<
+// @ Description  : 选项 61 客户端标识与 RFC 4361 DUID/IAID
+// @ --------------------------------------------------------------------------------<
+// @ FilePath     : /20yyq/packet/dhcpv4_clientid.go
+// @@
+package packet
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"hash/fnv"
+	"net"
+	"strings"
+	"time"
+)
+
+/*
+9.14. Client-identifier
+	The code for this option is 61, and its minimum length is 2.
+
+	Code   Len   Type  Client-Identifier
+	+-----+-----+-----+-----+-----+---
+	| 61  |  n  |  t1 |  i1 |  i2 | ...
+	+-----+-----+-----+-----+-----+---
+
+	Type 为 ARP 硬件类型时其后为硬件地址, 为 0 时其后为不透明标识
+
+RFC 4361 6.1  Node-specific Client Identifier
+
+	    Code  Len  Type  IAID                DUID
+	   +----+----+-----+----+----+----+----+----+----+---
+	   | 61 | n  | 255 | i1 | i2 | i3 | i4 | d1 | d2 |...
+	   +----+----+-----+----+----+----+----+----+----+---
+
+RFC 8415 11  DHCP Unique Identifier (DUID)
+	DUID-LLT  type 1: hardware type (16), time (32), link-layer address
+	DUID-EN   type 2: enterprise-number (32), identifier
+	DUID-LL   type 3: hardware type (16), link-layer address
+	DUID-UUID type 4 (RFC 6355): UUID (128)
+ */
+const (
+	DHCP_Client_Identifier = 61
+
+	DHCP_CLIENT_ID_OPAQUE = 0x00
+	DHCP_CLIENT_ID_DUID   = 0xff
+
+	DUID_LLT 	= 0x01
+	DUID_EN 	= 0x02
+	DUID_LL 	= 0x03
+	DUID_UUID 	= 0x04
+
+	// RFC 8415 11.1: DUID 最长 128 bytes (不含类型)
+	duidMaxLength = 0x82
+)
+
+var (
+	ErrDUIDFormat 		= errors.New("dhcp: malformed DUID")
+	ErrClientIDFormat 	= errors.New("dhcp: malformed client identifier")
+
+	// DUID-LLT 时间起点 2000-01-01 00:00:00 UTC
+	duidEpoch = time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+)
+
+// DUID 原始编码, 文本形式为以 ':' 分隔的十六进制, 便于持久化
+type DUID []byte
+
+func NewDUIDLLT(htype uint16, t time.Time, addr []byte) DUID {
+	b := binary.BigEndian.AppendUint16(nil, DUID_LLT)
+	b = binary.BigEndian.AppendUint16(b, htype)
+	b = binary.BigEndian.AppendUint32(b, uint32(t.Sub(duidEpoch) / time.Second))
+	return append(b, addr...)
+}
+
+func NewDUIDEN(enterprise uint32, id []byte) DUID {
+	b := binary.BigEndian.AppendUint16(nil, DUID_EN)
+	b = binary.BigEndian.AppendUint32(b, enterprise)
+	return append(b, id...)
+}
+
+func NewDUIDLL(htype uint16, addr []byte) DUID {
+	b := binary.BigEndian.AppendUint16(nil, DUID_LL)
+	b = binary.BigEndian.AppendUint16(b, htype)
+	return append(b, addr...)
+}
+
+func NewDUIDUUID(uuid [16]byte) DUID {
+	return append(binary.BigEndian.AppendUint16(nil, DUID_UUID), uuid[:]...)
+}
+
+// 以网卡地址生成 DUID-LL, 适合没有稳定存储的设备; 有存储时应生成一次 DUID-LLT 并保存
+func NewDUIDFromInterface(ifi *net.Interface) (DUID, error) {
+	if len(ifi.HardwareAddr) == 0 {
+		return nil, ErrDUIDFormat
+	}
+	return NewDUIDLL(ARP_ETHERNETTYPE, ifi.HardwareAddr), nil
+}
+
+func (d DUID) Type() uint16 {
+	if len(d) < 2 {
+		return 0
+	}
+	return binary.BigEndian.Uint16(d)
+}
+
+// 检查各类型的最小长度与总长度
+func (d DUID) Validate() error {
+	min := 0
+	switch d.Type() {
+	case DUID_LLT:
+		min = 8
+	case DUID_EN:
+		min = 6
+	case DUID_LL:
+		min = 4
+	case DUID_UUID:
+		min = 18
+		if len(d) != min {
+			return ErrDUIDFormat
+		}
+	default:
+		min = 3
+	}
+	if len(d) < min || len(d) > duidMaxLength {
+		return ErrDUIDFormat
+	}
+	return nil
+}
+
+// DUID-LLT/DUID-LL 的硬件类型与链路层地址
+func (d DUID) LinkLayerAddr() (uint16, []byte, bool) {
+	switch d.Type() {
+	case DUID_LLT:
+		if len(d) >= 8 {
+			return binary.BigEndian.Uint16(d[2:]), d[8:], true
+		}
+	case DUID_LL:
+		if len(d) >= 4 {
+			return binary.BigEndian.Uint16(d[2:]), d[4:], true
+		}
+	}
+	return 0, nil, false
+}
+
+func (d DUID) Time() (time.Time, bool) {
+	if d.Type() != DUID_LLT || len(d) < 8 {
+		return time.Time{}, false
+	}
+	return duidEpoch.Add(time.Duration(binary.BigEndian.Uint32(d[4:])) * time.Second), true
+}
+
+func (d DUID) EnterpriseNumber() (uint32, []byte, bool) {
+	if d.Type() != DUID_EN || len(d) < 6 {
+		return 0, nil, false
+	}
+	return binary.BigEndian.Uint32(d[2:]), d[6:], true
+}
+
+func (d DUID) UUID() (uuid [16]byte, ok bool) {
+	if d.Type() != DUID_UUID || len(d) != 18 {
+		return
+	}
+	return [16]byte(d[2:]), true
+}
+
+func (d DUID) String() string {
+	return hexColon(d)
+}
+
+func (d DUID) MarshalText() ([]byte, error) {
+	return []byte(hexColon(d)), nil
+}
+
+func (d *DUID) UnmarshalText(b []byte) error {
+	v, err := parseHexColon(string(b))
+	if err != nil {
+		return ErrDUIDFormat
+	}
+	*d = v
+	return nil
+}
+
+// RFC 4361 6.1: IAID 在同一客户端的各接口间唯一且重启后保持不变, 由接口名称生成
+func NewIAID(ifname string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(ifname))
+	return h.Sum32()
+}
+
+// 选项 61 的 Value
+type DhcpV4ClientID []byte
+
+func NewDhcpV4ClientIDHardware(htype uint8, addr []byte) DhcpV4ClientID {
+	return append(DhcpV4ClientID{htype}, addr...)
+}
+
+func NewDhcpV4ClientIDOpaque(id []byte) DhcpV4ClientID {
+	return append(DhcpV4ClientID{DHCP_CLIENT_ID_OPAQUE}, id...)
+}
+
+func NewDhcpV4ClientIDDUID(iaid uint32, duid DUID) DhcpV4ClientID {
+	b := binary.BigEndian.AppendUint32(DhcpV4ClientID{DHCP_CLIENT_ID_DUID}, iaid)
+	return append(b, duid...)
+}
+
+// RFC 4361 节点标识: IAID 由接口名称生成, duid 为 nil 时使用接口的 DUID-LL
+func NewDhcpV4ClientIDFromInterface(ifi *net.Interface, duid DUID) (DhcpV4ClientID, error) {
+	if duid == nil {
+		var err error
+		if duid, err = NewDUIDFromInterface(ifi); err != nil {
+			return nil, err
+		}
+	}
+	return NewDhcpV4ClientIDDUID(NewIAID(ifi.Name), duid), nil
+}
+
+func (id DhcpV4ClientID) Type() uint8 {
+	if len(id) < 1 {
+		return 0
+	}
+	return id[0]
+}
+
+func (id DhcpV4ClientID) Validate() error {
+	if len(id) < 2 || len(id) > 255 {
+		return ErrClientIDFormat
+	}
+	if id.Type() == DHCP_CLIENT_ID_DUID {
+		if len(id) < 5 {
+			return ErrClientIDFormat
+		}
+		return DUID(id[5:]).Validate()
+	}
+	return nil
+}
+
+// 硬件类型为以太网时的硬件地址
+func (id DhcpV4ClientID) HardwareAddr() (mac HardwareAddr, ok bool) {
+	if id.Type() != ARP_ETHERNETTYPE || len(id) != 7 {
+		return
+	}
+	return HardwareAddr(id[1:]), true
+}
+
+func (id DhcpV4ClientID) IAID() (uint32, bool) {
+	if id.Type() != DHCP_CLIENT_ID_DUID || len(id) < 5 {
+		return 0, false
+	}
+	return binary.BigEndian.Uint32(id[1:]), true
+}
+
+func (id DhcpV4ClientID) DUID() (DUID, bool) {
+	if id.Type() != DHCP_CLIENT_ID_DUID || len(id) < 5 {
+		return nil, false
+	}
+	return DUID(id[5:]), true
+}
+
+func (id DhcpV4ClientID) String() string {
+	return hexColon(id)
+}
+
+func (id DhcpV4ClientID) MarshalText() ([]byte, error) {
+	return []byte(hexColon(id)), nil
+}
+
+// 接受 MarshalText 的 "01:02:0a" 形式与不带 ':' 的十六进制
+func (id *DhcpV4ClientID) UnmarshalText(b []byte) error {
+	v, err := parseHexColon(string(b))
+	if err != nil {
+		return ErrClientIDFormat
+	}
+	*id = v
+	return nil
+}
+
+func SetDHCPClientID(id DhcpV4ClientID) OptionsPacket {
+	if id.Validate() != nil {
+		return OptionsPacket{}
+	}
+	return SetDHCPOption(DHCP_Client_Identifier, id)
+}
+
+func hexColon(b []byte) string {
+	s := make([]byte, 0, len(b) * 3)
+	for i, v := range b {
+		if i > 0 {
+			s = append(s, ':')
+		}
+		s = append(s, "0123456789abcdef"[v >> 4], "0123456789abcdef"[v & 0x0f])
+	}
+	return string(s)
+}
+
+// 接受 "01:02:0a" 与 "01020a" 两种形式
+func parseHexColon(s string) ([]byte, error) {
+	if s == "" {
+		return nil, nil
+	}
+	return hex.DecodeString(strings.ReplaceAll(s, ":", ""))
+}
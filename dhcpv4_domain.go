@@ -0,0 +1,290 @@
+// @@
+// @ Author       : Eacher
+// @ Date         : 2026-10-20 17:08:52
+// @ LastEditTime : 2026-10-20 17:08:52
+// @ LastEditors  : Eacher
+// @ --------------------------------------------------------------------------------<
+// @ Description  : DHCPv4 域名选项 15/81/119 与 DNS 名称编码
+// @ --------------------------------------------------------------------------------<
+// @ FilePath     : /20yyq/packet/dhcpv4_domain.go
+// @@
+package packet
+
+import (
+	"errors"
+	"strings"
+)
+
+/*
+RFC 1035 4.1.4  Message compression
+	    +--+--+--+--+--+--+--+--+--+--+--+--+--+--+--+--+
+	    | 1  1|                OFFSET                   |
+	    +--+--+--+--+--+--+--+--+--+--+--+--+--+--+--+--+
+
+	The first two bits are ones.  This allows a pointer to be distinguished from a
+	label, since the label must begin with two zero bits because labels are restricted
+	to 63 octets or less.
+
+RFC 3397  Dynamic Host Configuration Protocol (DHCP) Domain Search Option
+	The code for this option is 119.
+
+	    Code  Len         Data
+	   +-----+-----+-----+-----+-----+-----+-----+-----+--
+	   | 119 | Len |  s1 |  s2 |  s3 |  s4 |  s5 |  s6 |  ...
+	   +-----+-----+-----+-----+-----+-----+-----+-----+--
+
+	The list of domain names is encoded as specified in RFC 1035 section 3.1 and may
+	use the compression of section 4.1.4.  Pointers are offsets from the beginning of
+	the concatenated option data.  Long lists are split across instances per RFC 3396.
+ */
+const (
+	DHCP_Client_FQDN 	= 81
+	DHCP_Domain_Search 	= 119
+
+	// RFC 1035 2.3.4 Size limits
+	dnsMaxLabel 	= 63
+	dnsMaxName 		= 255
+	dnsPointer 		= 0xc0
+)
+
+var ErrDomainName = errors.New("dhcp: malformed domain name")
+
+// 校验并返回去除末尾 '.' 的标签, 不允许空标签
+func domainLabels(name string) ([]string, error) {
+	name = strings.TrimSuffix(name, ".")
+	if name == "" {
+		return nil, ErrDomainName
+	}
+	labels := strings.Split(name, ".")
+	size := 1
+	for _, l := range labels {
+		if len(l) < 1 || len(l) > dnsMaxLabel {
+			return nil, ErrDomainName
+		}
+		size += len(l) + 1
+	}
+	if size > dnsMaxName {
+		return nil, ErrDomainName
+	}
+	return labels, nil
+}
+
+/*
+	编码域名, compress 不为 nil 时使用并记录压缩指针, 键为小写后缀, 值为相对 b 起始的偏移
+	partial 为 true 时不写入结束的根标签 (RFC 4702 部分域名)
+ */
+func appendDomainName(b []byte, name string, compress map[string]int, partial bool) ([]byte, error) {
+	labels, err := domainLabels(name)
+	if err != nil {
+		return nil, err
+	}
+	for i := range labels {
+		if compress != nil {
+			suffix := strings.ToLower(strings.Join(labels[i:], "."))
+			if off, ok := compress[suffix]; ok {
+				return append(b, dnsPointer | uint8(off >> 8), uint8(off)), nil
+			}
+			if len(b) < 0x3fff {
+				compress[suffix] = len(b)
+			}
+		}
+		b = append(append(b, uint8(len(labels[i]))), labels[i]...)
+	}
+	if partial {
+		return b, nil
+	}
+	return append(b, 0), nil
+}
+
+/*
+	从 b[off:] 解码一个域名, 返回域名 (不含末尾 '.'), 下一个域名的偏移, 是否以根标签结束
+	指针只能指向当前域名之前的位置, 防止循环
+ */
+func readDomainName(b []byte, off int) (string, int, bool, error) {
+	var labels []string
+	next, start, size := -1, off, 1
+	for {
+		if off >= len(b) {
+			if next < 0 && len(labels) > 0 {
+				// 没有根标签的部分域名
+				return strings.Join(labels, "."), off, false, nil
+			}
+			return "", 0, false, ErrDomainName
+		}
+		switch c := b[off]; {
+		case c == 0:
+			if next < 0 {
+				next = off + 1
+			}
+			if len(labels) == 0 {
+				return "", 0, false, ErrDomainName
+			}
+			return strings.Join(labels, "."), next, true, nil
+		case c & dnsPointer == dnsPointer:
+			if off + 1 >= len(b) {
+				return "", 0, false, ErrDomainName
+			}
+			ptr := int(c & ^uint8(dnsPointer)) << 8 | int(b[off+1])
+			if ptr >= start {
+				return "", 0, false, ErrDomainName
+			}
+			if next < 0 {
+				next = off + 2
+			}
+			off, start = ptr, ptr
+		case c & dnsPointer != 0:
+			return "", 0, false, ErrDomainName
+		default:
+			if off + 1 + int(c) > len(b) {
+				return "", 0, false, ErrDomainName
+			}
+			if size += int(c) + 1; size > dnsMaxName {
+				return "", 0, false, ErrDomainName
+			}
+			labels, off = append(labels, string(b[off+1:off+1+int(c)])), off + 1 + int(c)
+		}
+	}
+}
+
+// RFC 3397 编码, 名称之间共享压缩指针
+func EncodeDomainSearch(names ...string) ([]byte, error) {
+	var b []byte
+	var err error
+	compress := make(map[string]int)
+	for _, name := range names {
+		if b, err = appendDomainName(b, name, compress, false); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+// b 为合并后的选项 119 数据
+func DecodeDomainSearch(b []byte) ([]string, error) {
+	var names []string
+	for off := 0; off < len(b); {
+		name, next, full, err := readDomainName(b, off)
+		if err != nil || !full {
+			return nil, ErrDomainName
+		}
+		names, off = append(names, name), next
+	}
+	return names, nil
+}
+
+// 超过 255 bytes 时由 WireFormat 按 RFC 3396 拆分
+func SetDHCPDomainSearch(names ...string) OptionsPacket {
+	b, err := EncodeDomainSearch(names...)
+	if err != nil || len(b) < 1 {
+		return OptionsPacket{}
+	}
+	return SetDHCPOption(DHCP_Domain_Search, b)
+}
+
+func (dhcp DhcpV4Packet) DomainSearch() ([]string, bool) {
+	if opp, ok := dhcp.Option(DHCP_Domain_Search); ok {
+		names, err := DecodeDomainSearch(opp.Value)
+		return names, err == nil && len(names) > 0
+	}
+	return nil, false
+}
+
+/*
+3.17. Domain Name
+	This option specifies the domain name that client should use when
+	resolving hostnames via the Domain Name System.
+
+	The code for this option is 15.  Its minimum length is 1.
+
+	选项 15 为不含结束符的 ASCII 字符串, 与选项 119 使用相同的名称校验
+ */
+func SetDHCPDomainName(name string) OptionsPacket {
+	if _, err := domainLabels(name); err != nil {
+		return OptionsPacket{}
+	}
+	return SetDHCPOption(uint8(DHCP_Domain_Name), []byte(strings.TrimSuffix(name, ".")))
+}
+
+/*
+RFC 4702  The DHCP Client FQDN Option
+
+	    Code   Len    Flags  RCODE1 RCODE2   Domain Name
+	   +------+------+------+------+------+------+--
+	   |  81  |   n  |      |      |      |       ...
+	   +------+------+------+------+------+------+--
+
+	    0 1 2 3 4 5 6 7
+	   +-+-+-+-+-+-+-+-+
+	   |  MBZ  |N|E|O|S|
+	   +-+-+-+-+-+-+-+-+
+
+	S: 客户端请求服务端更新 A 记录; O: 服务端覆盖了客户端的 S 设置
+	E: 域名使用 DNS 编码 (否则为已弃用的 ASCII 编码); N: 服务端不应更新任何记录
+	RCODE1/RCODE2 已弃用, 客户端发送 0, 服务端发送 255
+ */
+const (
+	DHCP_FQDN_S = 0x01
+	DHCP_FQDN_O = 0x02
+	DHCP_FQDN_E = 0x04
+	DHCP_FQDN_N = 0x08
+)
+
+// Name 以 '.' 结尾表示完整域名, 否则为部分域名 (由服务端补全)
+type DhcpV4ClientFQDN struct {
+	Flags 	uint8
+	RCode1 	uint8
+	RCode2 	uint8
+	Name 	string
+}
+
+func NewDhcpV4ClientFQDN(b []byte) (fqdn DhcpV4ClientFQDN, err error) {
+	if len(b) < 3 || b[0] & 0xf0 != 0 {
+		return fqdn, ErrDomainName
+	}
+	fqdn.Flags, fqdn.RCode1, fqdn.RCode2 = b[0], b[1], b[2]
+	if len(b) == 3 {
+		return
+	}
+	if fqdn.Flags & DHCP_FQDN_E == 0 {
+		fqdn.Name = strings.TrimRight(string(b[3:]), "\x00")
+		return
+	}
+	name, next, full, err := readDomainName(b[3:], 0)
+	if err != nil || next != len(b) - 3 {
+		return fqdn, ErrDomainName
+	}
+	if fqdn.Name = name; full {
+		fqdn.Name += "."
+	}
+	return
+}
+
+func (fqdn DhcpV4ClientFQDN) Encode() ([]byte, error) {
+	b := []byte{fqdn.Flags, fqdn.RCode1, fqdn.RCode2}
+	if fqdn.Name == "" {
+		return b, nil
+	}
+	if fqdn.Flags & DHCP_FQDN_E == 0 {
+		if _, err := domainLabels(fqdn.Name); err != nil {
+			return nil, err
+		}
+		return append(b, fqdn.Name...), nil
+	}
+	return appendDomainName(b, fqdn.Name, nil, !strings.HasSuffix(fqdn.Name, "."))
+}
+
+func SetDHCPClientFQDN(fqdn DhcpV4ClientFQDN) OptionsPacket {
+	b, err := fqdn.Encode()
+	if err != nil {
+		return OptionsPacket{}
+	}
+	return SetDHCPOption(DHCP_Client_FQDN, b)
+}
+
+func (dhcp DhcpV4Packet) ClientFQDN() (DhcpV4ClientFQDN, bool) {
+	if opp, ok := dhcp.Option(DHCP_Client_FQDN); ok {
+		fqdn, err := NewDhcpV4ClientFQDN(opp.Value)
+		return fqdn, err == nil
+	}
+	return DhcpV4ClientFQDN{}, false
+}
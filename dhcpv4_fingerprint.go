@@ -0,0 +1,246 @@
+// @@
+// @ Author       : Eacher
+// @ Date         : 2026-10-20 22:24:15
+// @ LastEditTime : 2026-10-21 16:55:02
+// @ LastEditors  : Eacher
+// @ --------------------------------------------------------------------------------<
+// @ Description  : DHCP 指纹提取与设备分类
+// @ --------------------------------------------------------------------------------<
+// @ FilePath     : /20yyq/packet/dhcpv4_fingerprint.go
+// @@
+package packet
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+/*
+	客户端报文的指纹, 通常取自 DHCPDISCOVER/DHCPREQUEST
+	Fingerprint 为 fingerbank 格式, 即选项 55 的十进制列表, 以 ',' 分隔, 例如 "1,3,6,15,31,33,43,44,46,47,119,121,249,252"
+	Vendor 为选项 60, 即 fingerbank 的 dhcp_vendor
+ */
+type DhcpV4Fingerprint struct {
+	MessageType 	DHCP_Message_Type
+	// 报文中选项的出现顺序, 不含 PAD/END, 同 Code 的多个实例按首次出现计
+	OptionOrder 	[]uint8
+	RequestList 	[]uint8
+	VendorClass 	string
+	MaxMessageSize 	uint16
+	HostName 		string
+}
+
+func NewDhcpV4Fingerprint(dhcp DhcpV4Packet) DhcpV4Fingerprint {
+	f := DhcpV4Fingerprint{OptionOrder: DhcpV4Options(dhcp.Options).Codes()}
+	f.MessageType, _ = dhcp.MessageType()
+	f.RequestList, _ = dhcp.ParameterRequestList()
+	f.VendorClass, _ = dhcp.VendorClassIdentifier()
+	f.MaxMessageSize, _ = dhcp.MaximumMessageSize()
+	f.HostName, _ = dhcp.HostNameOption()
+	return f
+}
+
+// fingerbank 格式的选项 55 列表
+func (f DhcpV4Fingerprint) String() string {
+	return joinDhcpV4Codes(f.RequestList)
+}
+
+// 选项出现顺序, 格式与 String 相同
+func (f DhcpV4Fingerprint) Options() string {
+	return joinDhcpV4Codes(f.OptionOrder)
+}
+
+func joinDhcpV4Codes(codes []uint8) string {
+	list := make([]string, len(codes))
+	for i, c := range codes {
+		list[i] = strconv.Itoa(int(c))
+	}
+	return strings.Join(list, ",")
+}
+
+// 解析 String/Options 格式的列表, 忽略空白, 空字符串返回 nil
+func ParseDhcpV4Codes(s string) ([]uint8, error) {
+	if s = strings.TrimSpace(s); s == "" {
+		return nil, nil
+	}
+	fields := strings.Split(s, ",")
+	codes := make([]uint8, len(fields))
+	for i, v := range fields {
+		n, err := strconv.ParseUint(strings.TrimSpace(v), 10, 8)
+		if err != nil {
+			return nil, ErrDhcpFingerprintRule
+		}
+		codes[i] = uint8(n)
+	}
+	return codes, nil
+}
+
+var ErrDhcpFingerprintRule = errors.New("dhcp: invalid fingerprint rule")
+
+// 各条件的权重, 匹配的条件越多得分越高
+const (
+	DHCP_FINGERPRINT_SCORE_REQUEST_LIST = 0x10
+	DHCP_FINGERPRINT_SCORE_OPTIONS 		= 0x08
+	DHCP_FINGERPRINT_SCORE_VENDOR 		= 0x04
+	DHCP_FINGERPRINT_SCORE_HOST_NAME 	= 0x02
+	DHCP_FINGERPRINT_SCORE_MAX_SIZE 	= 0x01
+)
+
+/*
+	条件为空时不参与匹配, 至少需要一个条件; 全部条件都满足时规则命中
+	Fingerprint 与 Options 精确匹配, VendorClass 与 HostName 为 path.Match 通配符 (区分大小写)
+	JSON 同时接受 fingerbank 的字段名 dhcp_fingerprint/dhcp_vendor/hostname/device_name, 与本包字段名同时出现时以本包为准
+ */
+type DhcpV4FingerprintRule struct {
+	Fingerprint 	string 	`json:"fingerprint,omitempty"`
+	Options 		string 	`json:"options,omitempty"`
+	VendorClass 	string 	`json:"vendor_class,omitempty"`
+	HostName 		string 	`json:"host_name,omitempty"`
+	MaxMessageSize 	uint16 	`json:"max_message_size,omitempty"`
+
+	OS 				string 	`json:"os"`
+	// 设备类别, 例如 "Phone", "Printer", "Windows"
+	Class 			string 	`json:"class,omitempty"`
+}
+
+func (r DhcpV4FingerprintRule) Match(f DhcpV4Fingerprint) (score int, ok bool) {
+	if r.Fingerprint != "" {
+		if r.Fingerprint != f.String() {
+			return 0, false
+		}
+		score += DHCP_FINGERPRINT_SCORE_REQUEST_LIST
+	}
+	if r.Options != "" {
+		if r.Options != f.Options() {
+			return 0, false
+		}
+		score += DHCP_FINGERPRINT_SCORE_OPTIONS
+	}
+	if r.VendorClass != "" {
+		if matched, _ := path.Match(r.VendorClass, f.VendorClass); !matched {
+			return 0, false
+		}
+		score += DHCP_FINGERPRINT_SCORE_VENDOR
+	}
+	if r.HostName != "" {
+		if matched, _ := path.Match(r.HostName, f.HostName); !matched {
+			return 0, false
+		}
+		score += DHCP_FINGERPRINT_SCORE_HOST_NAME
+	}
+	if r.MaxMessageSize != 0 {
+		if r.MaxMessageSize != f.MaxMessageSize {
+			return 0, false
+		}
+		score += DHCP_FINGERPRINT_SCORE_MAX_SIZE
+	}
+	return score, score > 0
+}
+
+func (r *DhcpV4FingerprintRule) UnmarshalJSON(b []byte) error {
+	type rule DhcpV4FingerprintRule
+	var v struct {
+		rule
+		DhcpFingerprint string `json:"dhcp_fingerprint"`
+		DhcpVendor 		string `json:"dhcp_vendor"`
+		Hostname 		string `json:"hostname"`
+		DeviceName 		string `json:"device_name"`
+	}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	*r = DhcpV4FingerprintRule(v.rule)
+	for _, f := range []struct{ dst *string; src string }{
+		{&r.Fingerprint, v.DhcpFingerprint}, {&r.VendorClass, v.DhcpVendor},
+		{&r.HostName, v.Hostname}, {&r.OS, v.DeviceName},
+	} {
+		if *f.dst == "" {
+			*f.dst = f.src
+		}
+	}
+	return nil
+}
+
+// 规范化 Fingerprint/Options 并检查通配符
+func (r *DhcpV4FingerprintRule) normalize() error {
+	for _, s := range []*string{&r.Fingerprint, &r.Options} {
+		codes, err := ParseDhcpV4Codes(*s)
+		if err != nil {
+			return err
+		}
+		*s = joinDhcpV4Codes(codes)
+	}
+	for _, pattern := range []string{r.VendorClass, r.HostName} {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return ErrDhcpFingerprintRule
+		}
+	}
+	if r.Fingerprint == "" && r.Options == "" && r.VendorClass == "" && r.HostName == "" && r.MaxMessageSize == 0 {
+		return ErrDhcpFingerprintRule
+	}
+	return nil
+}
+
+type DhcpV4DeviceGuess struct {
+	OS 		string
+	Class 	string
+	Score 	int
+	// 命中的规则
+	Rule 	DhcpV4FingerprintRule
+}
+
+type DhcpV4FingerprintDB struct {
+	Rules []DhcpV4FingerprintRule
+}
+
+/*
+	从 JSON 数组加载规则, 例如:
+	[
+		{"fingerprint": "1,3,6,15,31,33,43,44,46,47,119,121,249,252", "vendor_class": "MSFT 5.0", "os": "Windows 10", "class": "Windows"},
+		{"vendor_class": "android-dhcp-*", "os": "Android", "class": "Phone"},
+		{"dhcp_fingerprint": "1,121,3,6,15,119,252", "device_name": "Mac OS X"}
+	]
+ */
+func LoadDhcpV4FingerprintDB(r io.Reader) (*DhcpV4FingerprintDB, error) {
+	var rules []DhcpV4FingerprintRule
+	if err := json.NewDecoder(r).Decode(&rules); err != nil {
+		return nil, err
+	}
+	db := &DhcpV4FingerprintDB{}
+	for _, rule := range rules {
+		if err := db.Add(rule); err != nil {
+			return nil, err
+		}
+	}
+	return db, nil
+}
+
+func (db *DhcpV4FingerprintDB) Add(rule DhcpV4FingerprintRule) error {
+	if err := rule.normalize(); err != nil {
+		return err
+	}
+	db.Rules = append(db.Rules, rule)
+	return nil
+}
+
+// 返回命中的规则, 按得分从高到低排列, 得分相同时保持规则顺序
+func (db *DhcpV4FingerprintDB) Match(f DhcpV4Fingerprint) []DhcpV4DeviceGuess {
+	var list []DhcpV4DeviceGuess
+	for _, r := range db.Rules {
+		if score, ok := r.Match(f); ok {
+			list = append(list, DhcpV4DeviceGuess{OS: r.OS, Class: r.Class, Score: score, Rule: r})
+		}
+	}
+	sort.SliceStable(list, func(i, j int) bool { return list[i].Score > list[j].Score })
+	return list
+}
+
+func (db *DhcpV4FingerprintDB) Classify(dhcp DhcpV4Packet) (DhcpV4Fingerprint, []DhcpV4DeviceGuess) {
+	f := NewDhcpV4Fingerprint(dhcp)
+	return f, db.Match(f)
+}
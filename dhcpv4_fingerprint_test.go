@@ -0,0 +1,57 @@
+// @@
+// @ Author       : Eacher
+// @ Date         : 2026-10-21 16:55:02
+// @ LastEditTime : 2026-10-21 16:55:02
+// @ LastEditors  : Eacher
+// @ --------------------------------------------------------------------------------<
+// @ Description  : DHCP 指纹规则加载与匹配测试
+// @ --------------------------------------------------------------------------------<
+// @ FilePath     : /20yyq/packet/dhcpv4_fingerprint_test.go
+// @@
+package packet
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDhcpV4FingerprintDB(t *testing.T) {
+	db, err := LoadDhcpV4FingerprintDB(strings.NewReader(`[
+		{"vendor_class": "MSFT *", "os": "Windows", "class": "Windows"},
+		{"dhcp_fingerprint": "1, 3,6,15", "dhcp_vendor": "MSFT 5.0", "device_name": "Windows 10"},
+		{"host_name": "printer-*", "max_message_size": 1500, "os": "Printer"}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := db.Rules[1]; r.Fingerprint != "1,3,6,15" || r.VendorClass != "MSFT 5.0" || r.OS != "Windows 10" {
+		t.Errorf("fingerbank rule = %+v", r)
+	}
+	dhcp := newDhcpV4TestRequest(HardwareAddr{2, 0, 0, 0, 0, 1}, 1,
+		SetDHCPMessage(DHCP_DISCOVER),
+		SetDHCPOptionsRequestList(1, 3, 6, 15),
+		SetDHCPString(DHCP_Vendor_Class_Identifier, "MSFT 5.0"),
+		SetDHCPString(DHCP_Host_Name, "printer-1"),
+		SetDHCPMaximumMessageSize(1500),
+	)
+	f, guesses := db.Classify(dhcp)
+	if f.String() != "1,3,6,15" || f.Options() != "53,55,60,12,57" {
+		t.Errorf("fingerprint = %q options %q", f.String(), f.Options())
+	}
+	want := []struct{ os string; score int }{
+		{"Windows 10", DHCP_FINGERPRINT_SCORE_REQUEST_LIST | DHCP_FINGERPRINT_SCORE_VENDOR},
+		{"Windows", DHCP_FINGERPRINT_SCORE_VENDOR},
+		{"Printer", DHCP_FINGERPRINT_SCORE_HOST_NAME | DHCP_FINGERPRINT_SCORE_MAX_SIZE},
+	}
+	if len(guesses) != len(want) {
+		t.Fatalf("Classify() = %+v; want %d guesses", guesses, len(want))
+	}
+	for i, w := range want {
+		if guesses[i].OS != w.os || guesses[i].Score != w.score {
+			t.Errorf("guess %d = %s/%#x; want %s/%#x", i, guesses[i].OS, guesses[i].Score, w.os, w.score)
+		}
+	}
+	if _, err := LoadDhcpV4FingerprintDB(strings.NewReader(`[{"os": "Empty"}]`)); err != ErrDhcpFingerprintRule {
+		t.Errorf("rule without conditions: err = %v; want ErrDhcpFingerprintRule", err)
+	}
+}
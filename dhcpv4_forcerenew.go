@@ -0,0 +1,168 @@
+// @@
+// @ Author       : Eacher
+// @ Date         : 2026-10-20 21:16:33
+// @ LastEditTime : 2026-10-21 16:42:27
+// @ LastEditors  : Eacher
+// @ --------------------------------------------------------------------------------<
+// @ Description  : RFC 3203 DHCPFORCERENEW 与 RFC 6704 nonce 认证
+// @ --------------------------------------------------------------------------------<
+// @ FilePath     : /20yyq/packet/dhcpv4_forcerenew.go
+// @@
+package packet
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"time"
+)
+
+/*
+RFC 3203  DHCP reconfigure extension
+	The DHCPFORCERENEW message makes it possible for the DHCP server to force the
+	client to enter the RENEWING state.  A client MUST discard any DHCPFORCERENEW
+	that is not authenticated.
+
+RFC 6704 3  Forcerenew Nonce Protocol Capability Option
+	 0                   1                   2                   3
+	 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
+	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+	|  option-code  |  option-len   | Algorithm 1   | Algorithm 2   |
+	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+	option-code  145
+
+RFC 6704 4  Forcerenew Nonce Authentication
+	The server sends the nonce in the DHCPACK using the Authentication option with
+	Protocol 3, Algorithm 1 (HMAC-MD5) and RDM 0, authentication information type 1
+	followed by the 128-bit nonce.  The DHCPFORCERENEW carries type 2 followed by the
+	HMAC-MD5 of the message keyed with the nonce.
+
+	客户端在 DHCPDISCOVER/DHCPREQUEST 中携带选项 145, 服务端在每个 DHCPACK 中下发新的 nonce,
+	客户端只接受 nonce 签名且 Replay Detection 递增的 DHCPFORCERENEW
+ */
+const (
+	DHCP_Forcerenew_Nonce_Capable 	= 145
+
+	// 选项 90 认证信息类型
+	DHCP_FORCERENEW_NONCE_VALUE 	= 1
+	DHCP_FORCERENEW_HMAC_MD5 		= 2
+	DHCP_FORCERENEW_NONCE_LEN 		= 0x10
+)
+
+var (
+	ErrDhcpNoLease 				= errors.New("dhcp: no bound lease")
+	ErrDhcpNoForcerenewNonce 	= errors.New("dhcp: lease has no forcerenew nonce")
+)
+
+func SetDHCPForcerenewNonceCapable(algorithms ...uint8) OptionsPacket {
+	if len(algorithms) < 1 {
+		return OptionsPacket{}
+	}
+	return SetDHCPOption(DHCP_Forcerenew_Nonce_Capable, algorithms)
+}
+
+func (dhcp DhcpV4Packet) ForcerenewNonceCapable() ([]uint8, bool) {
+	if opp, ok := dhcp.Option(DHCP_Forcerenew_Nonce_Capable); ok && len(opp.Value) > 0 {
+		return opp.Value, true
+	}
+	return nil, false
+}
+
+// DHCPACK 中选项 90 下发的 nonce
+func (dhcp DhcpV4Packet) ForcerenewNonce() ([]byte, bool) {
+	a, ok := dhcp.Authentication()
+	if !ok || a.Protocol != DHCP_AUTH_PROTOCOL_RECONFIGURE_KEY || a.Algorithm != DHCP_AUTH_ALGORITHM_HMAC_MD5 {
+		return nil, false
+	}
+	if len(a.Info) != DHCP_FORCERENEW_NONCE_LEN + 1 || a.Info[0] != DHCP_FORCERENEW_NONCE_VALUE {
+		return nil, false
+	}
+	return a.Info[1:], true
+}
+
+// 客户端声明支持 HMAC-MD5 时生成 nonce
+func (s *DhcpV4Server) forcerenewNonce(req DhcpV4Packet) []byte {
+	if !s.ForcerenewNonce {
+		return nil
+	}
+	algorithms, _ := req.ForcerenewNonceCapable()
+	for _, v := range algorithms {
+		if v != DHCP_AUTH_ALGORITHM_HMAC_MD5 {
+			continue
+		}
+		nonce := make([]byte, DHCP_FORCERENEW_NONCE_LEN)
+		if _, err := rand.Read(nonce); err != nil {
+			return nil
+		}
+		return nonce
+	}
+	return nil
+}
+
+func (s *DhcpV4Server) nonceAuthentication(info []byte, now time.Time) OptionsPacket {
+	return SetDHCPAuthentication(DhcpV4Authentication{
+		Protocol: DHCP_AUTH_PROTOCOL_RECONFIGURE_KEY, Algorithm: DHCP_AUTH_ALGORITHM_HMAC_MD5,
+		RDM: DHCP_AUTH_RDM_MONOTONIC, ReplayDetection: s.replay.Next(now), Info: info,
+	})
+}
+
+/*
+	构造发给已绑定地址 ip 的 DHCPFORCERENEW 并按 RFC 6704 签名
+	客户端丢弃未经认证的 DHCPFORCERENEW, 租约没有 nonce (客户端未携带选项 145) 时返回 ErrDhcpNoForcerenewNonce
+*/
+func (s *DhcpV4Server) ForcerenewPacket(ip IPv4, now time.Time) (DhcpV4Packet, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return DhcpV4Packet{}, err
+	}
+	r, ok := s.leases[ip]
+	if !ok || r.State != DHCP_LEASE_BOUND || r.Expired(now) {
+		return DhcpV4Packet{}, ErrDhcpNoLease
+	}
+	if len(r.ForcerenewNonce) == 0 {
+		return DhcpV4Packet{}, ErrDhcpNoForcerenewNonce
+	}
+	var xid [4]byte
+	if _, err := rand.Read(xid[:]); err != nil {
+		return DhcpV4Packet{}, err
+	}
+	dhcp := DhcpV4Packet{
+		Op: DHCP_BOOTREPLY, HardwareType: DHCP_Ethernet_TYPE, HardwareLen: DHCP_Ethernet_LEN,
+		XID: binary.BigEndian.Uint32(xid[:]), CIAddr: ip,
+	}
+	copy(dhcp.ChHardware[:], r.HardwareAddr[:])
+	dhcp.Options = append(dhcp.Options, SetDHCPMessage(DHCP_FORCERENEW), SetDHCPIPv4(DHCP_Server_Identifier, s.ServerID))
+	if opp := SetDHCPClientID(r.ClientID); opp.Code != 0 {
+		dhcp.Options = append(dhcp.Options, opp)
+	}
+	SignDhcpV4ReconfigureKey(&dhcp, r.ForcerenewNonce, s.replay.Next(now))
+	return dhcp, nil
+}
+
+// 单播 DHCPFORCERENEW 使客户端进入 RENEWING
+func (s *DhcpV4Server) Forcerenew(ip IPv4) error {
+	dhcp, err := s.ForcerenewPacket(ip, time.Now())
+	if err != nil {
+		return err
+	}
+	return s.Transport.Send(dhcp, ip)
+}
+
+// 校验服务端标识, nonce 签名与 Replay Detection
+func (c *DhcpV4Client) forcerenewed(dhcp DhcpV4Packet) bool {
+	if dhcp.Op != DHCP_BOOTREPLY || HardwareAddr(dhcp.ChHardware[:6]) != c.HardwareAddr {
+		return false
+	}
+	if t, _ := dhcp.MessageType(); t != DHCP_FORCERENEW {
+		return false
+	}
+	if server, _ := dhcp.ServerIdentifier(); server != c.Lease.ServerID {
+		return false
+	}
+	if a, _ := dhcp.Authentication(); a.Protocol != DHCP_AUTH_PROTOCOL_RECONFIGURE_KEY {
+		return false
+	}
+	c.auth.ReconfigureKey = c.Lease.ForcerenewNonce
+	return c.auth.Verify(dhcp, string(c.Lease.ServerID[:])) == nil
+}
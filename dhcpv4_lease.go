@@ -0,0 +1,193 @@
+// @@
+// @ Author       : Eacher
+// @ Date         : 2026-10-20 10:36:05
+// @ LastEditTime : 2026-10-20 21:16:33
+// @ LastEditors  : Eacher
+// @ --------------------------------------------------------------------------------<
+// @ Description  : DHCPv4 服务端租约存储
+// @ --------------------------------------------------------------------------------<
+// @ FilePath     : /20yyq/packet/dhcpv4_lease.go
+// @@
+package packet
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+type DhcpV4LeaseState uint8
+
+const (
+	// 已发送 DHCPOFFER, 等待 DHCPREQUEST
+	DHCP_LEASE_OFFERED DhcpV4LeaseState = iota + 1
+	DHCP_LEASE_BOUND
+	// 客户端 DHCPDECLINE 或者探测到冲突, 到期前不再分配
+	DHCP_LEASE_DECLINED
+	// 客户端 DHCPRELEASE, 保留绑定关系以便再次分配给同一客户端
+	DHCP_LEASE_RELEASED
+)
+
+func (s DhcpV4LeaseState) String() string {
+	switch s {
+	case DHCP_LEASE_OFFERED:
+		return "offered"
+	case DHCP_LEASE_BOUND:
+		return "bound"
+	case DHCP_LEASE_DECLINED:
+		return "declined"
+	case DHCP_LEASE_RELEASED:
+		return "released"
+	}
+	return "unknown"
+}
+
+// 服务端租约记录, 以 IP 为键
+type DhcpV4LeaseRecord struct {
+	IP 				IPv4 				`json:"ip"`
+	HardwareAddr 	HardwareAddr 		`json:"hardware_addr"`
+	ClientID 		DhcpV4ClientID 		`json:"client_id,omitempty"`
+	HostName 		string 				`json:"host_name,omitempty"`
+	State 			DhcpV4LeaseState 	`json:"state"`
+	Expiry 			time.Time 			`json:"expiry"`
+	// 最后一次客户端交互的时间, 用于 RFC 4388 选项 91
+	Updated 		time.Time 			`json:"updated"`
+	// RFC 6704 最近一次 DHCPACK 下发的 nonce
+	ForcerenewNonce []byte 				`json:"forcerenew_nonce,omitempty"`
+}
+
+func (r DhcpV4LeaseRecord) Expired(now time.Time) bool {
+	return !r.Expiry.IsZero() && !now.Before(r.Expiry)
+}
+
+// 客户端标识优先使用选项 61, 否则使用 ChHardware
+func (r DhcpV4LeaseRecord) Owner(mac HardwareAddr, clientID DhcpV4ClientID) bool {
+	if len(clientID) > 0 || len(r.ClientID) > 0 {
+		return bytes.Equal(r.ClientID, clientID)
+	}
+	return r.HardwareAddr == mac
+}
+
+// 租约持久化接口, 服务端在每次变更后调用 Put/Delete
+type DhcpV4LeaseStore interface {
+	Load() ([]DhcpV4LeaseRecord, error)
+	Put(r DhcpV4LeaseRecord) error
+	Delete(ip IPv4) error
+}
+
+type dhcpV4MemoryStore struct {
+	mu 		sync.Mutex
+	leases 	map[IPv4]DhcpV4LeaseRecord
+}
+
+// 仅保存在内存中, 进程退出后丢失
+func NewDhcpV4MemoryStore() DhcpV4LeaseStore {
+	return &dhcpV4MemoryStore{leases: make(map[IPv4]DhcpV4LeaseRecord)}
+}
+
+func (ms *dhcpV4MemoryStore) Load() ([]DhcpV4LeaseRecord, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	return ms.list(), nil
+}
+
+func (ms *dhcpV4MemoryStore) Put(r DhcpV4LeaseRecord) error {
+	ms.mu.Lock()
+	ms.leases[r.IP] = r
+	ms.mu.Unlock()
+	return nil
+}
+
+func (ms *dhcpV4MemoryStore) Delete(ip IPv4) error {
+	ms.mu.Lock()
+	delete(ms.leases, ip)
+	ms.mu.Unlock()
+	return nil
+}
+
+func (ms *dhcpV4MemoryStore) list() []DhcpV4LeaseRecord {
+	list := make([]DhcpV4LeaseRecord, 0, len(ms.leases))
+	for _, r := range ms.leases {
+		list = append(list, r)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].IP.Uint32() < list[j].IP.Uint32() })
+	return list
+}
+
+type dhcpV4FileStore struct {
+	dhcpV4MemoryStore
+	path 	string
+}
+
+// 以 JSON 数组保存到 path, 每次变更写入临时文件后原子替换
+func NewDhcpV4FileStore(path string) DhcpV4LeaseStore {
+	return &dhcpV4FileStore{dhcpV4MemoryStore{leases: make(map[IPv4]DhcpV4LeaseRecord)}, path}
+}
+
+func (fst *dhcpV4FileStore) Load() ([]DhcpV4LeaseRecord, error) {
+	fst.mu.Lock()
+	defer fst.mu.Unlock()
+	b, err := os.ReadFile(fst.path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var list []DhcpV4LeaseRecord
+	if err = json.Unmarshal(b, &list); err != nil {
+		return nil, err
+	}
+	fst.leases = make(map[IPv4]DhcpV4LeaseRecord, len(list))
+	for _, r := range list {
+		fst.leases[r.IP] = r
+	}
+	return fst.list(), nil
+}
+
+func (fst *dhcpV4FileStore) Put(r DhcpV4LeaseRecord) error {
+	fst.mu.Lock()
+	defer fst.mu.Unlock()
+	fst.leases[r.IP] = r
+	return fst.save()
+}
+
+func (fst *dhcpV4FileStore) Delete(ip IPv4) error {
+	fst.mu.Lock()
+	defer fst.mu.Unlock()
+	if _, ok := fst.leases[ip]; !ok {
+		return nil
+	}
+	delete(fst.leases, ip)
+	return fst.save()
+}
+
+func (fst *dhcpV4FileStore) save() error {
+	b, err := json.MarshalIndent(fst.list(), "", "\t")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(fst.path), filepath.Base(fst.path) + ".*")
+	if err != nil {
+		return err
+	}
+	if _, err = tmp.Write(b); err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), fst.path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
@@ -0,0 +1,152 @@
+// @@
+// @ Author       : Eacher
+// @ Date         : 2026-10-20 21:16:33
+// @ LastEditTime : 2026-10-20 21:16:33
+// @ LastEditors  : Eacher
+// @ --------------------------------------------------------------------------------<
+// @ Description  : RFC 4388 DHCPLEASEQUERY
+// @ --------------------------------------------------------------------------------<
+// @ FilePath     : /20yyq/packet/dhcpv4_leasequery.go
+// @@
+package packet
+
+import (
+	"bytes"
+	"sort"
+	"time"
+)
+
+/*
+RFC 4388 6.1  Sending a DHCPLEASEQUERY Message
+	The DHCPLEASEQUERY message is typically sent by an access concentrator.  The
+	DHCPLEASEQUERY message uses the DHCP message format, with the giaddr set to the
+	IP address of the requestor.
+
+	Query by IP address:         ciaddr set, htype/hlen/chaddr zero.
+	Query by MAC address:        ciaddr zero, htype/hlen/chaddr set.
+	Query by Client-identifier:  ciaddr zero, htype/hlen/chaddr zero, option 61 set.
+
+RFC 4388 6.2  Receiving a DHCPLEASEQUERY Message
+	DHCPLEASEUNASSIGNED: the server is authoritative for the IP address but has no
+	active lease.  DHCPLEASEUNKNOWN: the server has no information about the IP
+	address or client.  DHCPLEASEACTIVE: ciaddr carries the (most recent) IP address,
+	option 51 the remaining lease time, option 91 the time since the last client
+	transaction and option 92 all addresses associated with the client.
+ */
+const (
+	DHCP_Client_Last_Transaction_Time DHCP_TIME_TYPE 	= 91
+	DHCP_Associated_IP DHCP_IPv4_TYPE 					= 92
+)
+
+var dhcpV4LeasequeryRequestList = []uint8{51, 54, 61, 82, 91, 92}
+
+func newDhcpV4Leasequery(requestor IPv4, xid uint32) DhcpV4Packet {
+	return DhcpV4Packet{Op: DHCP_BOOTREQUEST, XID: xid, GIAddr: requestor, Options: []OptionsPacket{
+		SetDHCPMessage(DHCP_LEASEQUERY), SetDHCPOptionsRequestList(dhcpV4LeasequeryRequestList...),
+	}}
+}
+
+// requestor 为请求者地址 (GIAddr), 应答发往其 DHCP_ServerPort
+func NewDhcpV4LeasequeryByIP(requestor IPv4, xid uint32, ip IPv4) DhcpV4Packet {
+	dhcp := newDhcpV4Leasequery(requestor, xid)
+	dhcp.CIAddr = ip
+	return dhcp
+}
+
+func NewDhcpV4LeasequeryByMAC(requestor IPv4, xid uint32, mac HardwareAddr) DhcpV4Packet {
+	dhcp := newDhcpV4Leasequery(requestor, xid)
+	dhcp.HardwareType, dhcp.HardwareLen = DHCP_Ethernet_TYPE, DHCP_Ethernet_LEN
+	copy(dhcp.ChHardware[:], mac[:])
+	return dhcp
+}
+
+func NewDhcpV4LeasequeryByClientID(requestor IPv4, xid uint32, id DhcpV4ClientID) DhcpV4Packet {
+	dhcp := newDhcpV4Leasequery(requestor, xid)
+	dhcp.Options = append(dhcp.Options, SetDHCPClientID(id))
+	return dhcp
+}
+
+func (dhcp DhcpV4Packet) ClientLastTransactionTime() (time.Duration, bool) {
+	return dhcp.TimeOption(DHCP_Client_Last_Transaction_Time)
+}
+
+func (dhcp DhcpV4Packet) AssociatedIP() ([]IPv4, bool) {
+	return dhcp.IPv4ListOption(DHCP_Associated_IP)
+}
+
+// RFC 4388 6.4  不带 GIAddr 的请求被丢弃
+func (s *DhcpV4Server) leasequery(req DhcpV4Packet, now time.Time) (DhcpV4Packet, bool) {
+	if req.Op != DHCP_BOOTREQUEST || req.GIAddr == (IPv4{}) || s.Leasequery == nil || !s.Leasequery(req.GIAddr) {
+		return DhcpV4Packet{}, false
+	}
+	dhcp := DhcpV4Packet{
+		Op: DHCP_BOOTREPLY, HardwareType: req.HardwareType, HardwareLen: req.HardwareLen,
+		XID: req.XID, CIAddr: req.CIAddr, GIAddr: req.GIAddr, ChHardware: req.ChHardware,
+	}
+	active := func(r DhcpV4LeaseRecord) bool {
+		return r.State == DHCP_LEASE_BOUND && !r.Expired(now)
+	}
+	cid, hasCID := req.ClientIdentifier()
+	var found []DhcpV4LeaseRecord
+	switch {
+	case req.CIAddr != (IPv4{}):
+		if r, ok := s.leases[req.CIAddr]; ok && active(r) {
+			found = append(found, r)
+			break
+		}
+		for _, sn := range s.Subnets {
+			if sn.Prefix.Contains(req.CIAddr) {
+				dhcp.Options = append(dhcp.Options, SetDHCPMessage(DHCP_LEASEUNASSIGNED), SetDHCPIPv4(DHCP_Server_Identifier, s.ServerID))
+				return dhcp, true
+			}
+		}
+	case hasCID:
+		for _, r := range s.leases {
+			if active(r) && bytes.Equal(r.ClientID, cid) {
+				found = append(found, r)
+			}
+		}
+	case req.HardwareLen > 0:
+		var mac HardwareAddr
+		copy(mac[:], req.ChHardware[:6])
+		for _, r := range s.leases {
+			if active(r) && r.HardwareAddr == mac {
+				found = append(found, r)
+			}
+		}
+	default:
+		return DhcpV4Packet{}, false
+	}
+	if len(found) == 0 {
+		dhcp.Options = append(dhcp.Options, SetDHCPMessage(DHCP_LEASEUNKNOWN), SetDHCPIPv4(DHCP_Server_Identifier, s.ServerID))
+		return dhcp, true
+	}
+	// 最近交互的租约在前
+	sort.Slice(found, func(i, j int) bool {
+		return found[i].Updated.After(found[j].Updated)
+	})
+	r := found[0]
+	dhcp.CIAddr, dhcp.HardwareType, dhcp.HardwareLen = r.IP, DHCP_Ethernet_TYPE, DHCP_Ethernet_LEN
+	dhcp.ChHardware = [16]byte{}
+	copy(dhcp.ChHardware[:], r.HardwareAddr[:])
+	dhcp.Options = append(dhcp.Options, SetDHCPMessage(DHCP_LEASEACTIVE), SetDHCPIPv4(DHCP_Server_Identifier, s.ServerID))
+	lease := DHCP_INFINITE_LEASE
+	if !r.Expiry.IsZero() {
+		lease = r.Expiry.Sub(now)
+	}
+	dhcp.Options = append(dhcp.Options, SetDHCPTime(DHCP_IP_Address_Lease, lease))
+	if opp := SetDHCPClientID(r.ClientID); opp.Code != 0 {
+		dhcp.Options = append(dhcp.Options, opp)
+	}
+	if !r.Updated.IsZero() {
+		dhcp.Options = append(dhcp.Options, SetDHCPTime(DHCP_Client_Last_Transaction_Time, now.Sub(r.Updated)))
+	}
+	if req.CIAddr == (IPv4{}) {
+		list := make([]IPv4, len(found))
+		for i, v := range found {
+			list[i] = v.IP
+		}
+		dhcp.Options = append(dhcp.Options, SetDHCPIPv4(DHCP_Associated_IP, list...))
+	}
+	return dhcp, true
+}
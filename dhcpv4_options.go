@@ -0,0 +1,213 @@
+// @@
+// @ Author       : Eacher
+// @ Date         : 2026-10-20 14:21:33
+// @ LastEditTime : 2026-10-21 00:12:40
+// @ LastEditors  : Eacher
+// @ --------------------------------------------------------------------------------<
+// @ Description  : DHCPv4 选项容器与类型化读取
+// @ --------------------------------------------------------------------------------<
+// @ FilePath     : /20yyq/packet/dhcpv4_options.go
+// @@
+package packet
+
+import (
+	"encoding/binary"
+	"strings"
+	"time"
+)
+
+// 保持插入顺序的选项集合, 与 DhcpV4Packet.Options 可直接互相转换
+type DhcpV4Options []OptionsPacket
+
+func (o DhcpV4Options) Get(code uint8) (OptionsPacket, bool) {
+	for _, opp := range o {
+		if opp.Code == code {
+			return opp, true
+		}
+	}
+	return OptionsPacket{}, false
+}
+
+// 替换已有的同 Code 选项并保持其位置, 否则追加到末尾, Code 为 0 的选项被忽略
+func (o *DhcpV4Options) Set(opp OptionsPacket) {
+	if opp.Code == 0 {
+		return
+	}
+	for i, v := range *o {
+		if v.Code == opp.Code {
+			(*o)[i] = opp
+			return
+		}
+	}
+	*o = append(*o, opp)
+}
+
+// 删除所有 Code 相同的选项
+func (o *DhcpV4Options) Del(code uint8) {
+	list := (*o)[:0]
+	for _, opp := range *o {
+		if opp.Code != code {
+			list = append(list, opp)
+		}
+	}
+	*o = list
+}
+
+func (o DhcpV4Options) Codes() []uint8 {
+	codes := make([]uint8, len(o))
+	for i, opp := range o {
+		codes[i] = opp.Code
+	}
+	return codes
+}
+
+func (dhcp DhcpV4Packet) Option(code uint8) (OptionsPacket, bool) {
+	return DhcpV4Options(dhcp.Options).Get(code)
+}
+
+func (dhcp DhcpV4Packet) MessageType() (DHCP_Message_Type, bool) {
+	if opp, ok := dhcp.Option(DHCP_Message_Type_Code); ok && len(opp.Value) == 1 {
+		return DHCP_Message_Type(opp.Value[0]), true
+	}
+	return 0, false
+}
+
+// SetDHCPIPv4 类选项的全部地址
+func (dhcp DhcpV4Packet) IPv4ListOption(t DHCP_IPv4_TYPE) ([]IPv4, bool) {
+	opp, ok := dhcp.Option(uint8(t))
+	if !ok || len(opp.Value) < 4 || len(opp.Value) % 4 != 0 {
+		return nil, false
+	}
+	list := make([]IPv4, len(opp.Value) / 4)
+	for i := range list {
+		list[i] = IPv4(opp.Value[i*4:])
+	}
+	return list, true
+}
+
+// SetDHCPIPv4 类选项的第一个地址
+func (dhcp DhcpV4Packet) IPv4Option(t DHCP_IPv4_TYPE) (IPv4, bool) {
+	if list, ok := dhcp.IPv4ListOption(t); ok {
+		return list[0], true
+	}
+	return IPv4{}, false
+}
+
+func (dhcp DhcpV4Packet) TimeOption(t DHCP_TIME_TYPE) (time.Duration, bool) {
+	if opp, ok := dhcp.Option(uint8(t)); ok && len(opp.Value) == 4 {
+		return time.Duration(binary.BigEndian.Uint32(opp.Value)) * time.Second, true
+	}
+	return 0, false
+}
+
+// 去除 SetDHCPString 添加的 NUL 结束符
+func (dhcp DhcpV4Packet) StringOption(t DHCP_STRING_TYPE) (string, bool) {
+	if opp, ok := dhcp.Option(uint8(t)); ok {
+		return strings.TrimRight(string(opp.Value), "\x00"), true
+	}
+	return "", false
+}
+
+func (dhcp DhcpV4Packet) ServerIdentifier() (IPv4, bool) {
+	return dhcp.IPv4Option(DHCP_Server_Identifier)
+}
+
+func (dhcp DhcpV4Packet) RequestedIPAddress() (IPv4, bool) {
+	return dhcp.IPv4Option(DHCP_Requested_IP_Address)
+}
+
+func (dhcp DhcpV4Packet) SubnetMask() (IPv4, bool) {
+	return dhcp.IPv4Option(DHCP_Subnet_Mask)
+}
+
+func (dhcp DhcpV4Packet) Routers() ([]IPv4, bool) {
+	return dhcp.IPv4ListOption(DHCP_Router)
+}
+
+func (dhcp DhcpV4Packet) DNSServers() ([]IPv4, bool) {
+	return dhcp.IPv4ListOption(DHCP_Domain_Name_Server)
+}
+
+func (dhcp DhcpV4Packet) LeaseTime() (time.Duration, bool) {
+	return dhcp.TimeOption(DHCP_IP_Address_Lease)
+}
+
+func (dhcp DhcpV4Packet) RenewalTime() (time.Duration, bool) {
+	return dhcp.TimeOption(DHCP_Renewal_Time)
+}
+
+func (dhcp DhcpV4Packet) RebindingTime() (time.Duration, bool) {
+	return dhcp.TimeOption(DHCP_Rebinding_Time)
+}
+
+// 选项 12, 与 sname 字段 HostName 区分
+func (dhcp DhcpV4Packet) HostNameOption() (string, bool) {
+	return dhcp.StringOption(DHCP_Host_Name)
+}
+
+func (dhcp DhcpV4Packet) DomainName() (string, bool) {
+	return dhcp.StringOption(DHCP_Domain_Name)
+}
+
+func (dhcp DhcpV4Packet) VendorClassIdentifier() (string, bool) {
+	return dhcp.StringOption(DHCP_Vendor_Class_Identifier)
+}
+
+func (dhcp DhcpV4Packet) ErrorMessage() (string, bool) {
+	return dhcp.StringOption(DHCP_Error_Message)
+}
+
+func (dhcp DhcpV4Packet) ParameterRequestList() ([]uint8, bool) {
+	if opp, ok := dhcp.Option(DHCP_Parameter_Request_List); ok && len(opp.Value) > 0 {
+		return opp.Value, true
+	}
+	return nil, false
+}
+
+func (dhcp DhcpV4Packet) ClientIdentifier() (DhcpV4ClientID, bool) {
+	if opp, ok := dhcp.Option(DHCP_Client_Identifier); ok && len(opp.Value) > 0 {
+		return DhcpV4ClientID(opp.Value), true
+	}
+	return nil, false
+}
+
+func (dhcp DhcpV4Packet) MaximumMessageSize() (uint16, bool) {
+	if opp, ok := dhcp.Option(DHCP_Maximum_Message_Size); ok && len(opp.Value) == 2 {
+		return binary.BigEndian.Uint16(opp.Value), true
+	}
+	return 0, false
+}
+
+func (dhcp DhcpV4Packet) NetBIOSNodeType() (DHCP_NetBIOS_Node_Type, bool) {
+	if opp, ok := dhcp.Option(DHCP_NetBIOS_Node_Type_Code); ok && len(opp.Value) == 1 {
+		return DHCP_NetBIOS_Node_Type(opp.Value[0]), true
+	}
+	return 0, false
+}
+
+func (dhcp DhcpV4Packet) InterfaceMTU() (uint16, bool) {
+	if opp, ok := dhcp.Option(DHCP_Interface_MTU); ok && len(opp.Value) == 2 {
+		return binary.BigEndian.Uint16(opp.Value), true
+	}
+	return 0, false
+}
+
+func (dhcp DhcpV4Packet) BroadcastAddress() (IPv4, bool) {
+	return dhcp.IPv4Option(DHCP_Broadcast_Address)
+}
+
+func (dhcp DhcpV4Packet) NTPServers() ([]IPv4, bool) {
+	return dhcp.IPv4ListOption(DHCP_NTP_Servers)
+}
+
+func (dhcp DhcpV4Packet) CaptivePortal() (string, bool) {
+	return dhcp.StringOption(DHCP_Captive_Portal)
+}
+
+func (dhcp DhcpV4Packet) TFTPServerAddress() ([]IPv4, bool) {
+	return dhcp.IPv4ListOption(DHCP_TFTP_Server_Address)
+}
+
+func (dhcp DhcpV4Packet) WPAD() (string, bool) {
+	return dhcp.StringOption(DHCP_WPAD)
+}
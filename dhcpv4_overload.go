@@ -0,0 +1,177 @@
+// @@
+// @ Author       : Eacher
+// @ Date         : 2026-10-20 15:47:10
+// @ LastEditTime : 2026-10-21 16:58:44
+// @ LastEditors  : Eacher
+// @ --------------------------------------------------------------------------------<
+// @ Description  : RFC 3396 长选项与选项 52 Option Overload
+// @ --------------------------------------------------------------------------------<
+// @ FilePath     : /20yyq/packet/dhcpv4_overload.go
+// @@
+package packet
+
+/*
+9.3. Option Overload
+	This option is used to indicate that the DHCP 'sname' or 'file'
+	fields are being overloaded by using them to carry DHCP options. A
+	DHCP server inserts this option if the returned parameters will
+	exceed the usual space allotted for options.
+
+	If this option is present, the client interprets the specified
+	additional fields after it concludes interpretation of the standard
+	option fields.
+
+	The code for this option is 52, and its length is 1.  Legal values
+	for this option are:
+
+	        Value   Meaning
+	        -----   --------
+	          1     the 'file' field is used to hold options
+	          2     the 'sname' field is used to hold options
+	          3     both fields are used to hold options
+
+	 Code   Len  Value
+	+-----+-----+-----+
+	|  52 |  1  |1/2/3|
+	+-----+-----+-----+
+
+RFC 3396  Encoding Long Options in DHCPv4
+	When a DHCP agent needs to send an option whose data exceeds 255 octets, it splits
+	the option into multiple instances of the same code.  The receiving agent concatenates
+	all instances in the order: 'options' field, then 'file' field, then 'sname' field.
+ */
+const (
+	DHCP_Option_Overload = 52
+
+	DHCP_OVERLOAD_FILE 	= 0x01
+	DHCP_OVERLOAD_SNAME = 0x02
+	DHCP_OVERLOAD_BOTH 	= DHCP_OVERLOAD_FILE | DHCP_OVERLOAD_SNAME
+)
+
+// 任意 Code 的选项, Value 超过 255 bytes 时编码为多个实例
+func SetDHCPOption(code uint8, value []byte) OptionsPacket {
+	length := len(value)
+	if length > 255 {
+		length = 255
+	}
+	return OptionsPacket{code, uint8(length), value}
+}
+
+func SetDHCPOptionOverload(v uint8) OptionsPacket {
+	return OptionsPacket{DHCP_Option_Overload, 1, []byte{v}}
+}
+
+/*
+	按选项 52 从 file/sname 读取选项并清空这两个字段, 然后合并同 Code 的多个实例
+	解码结果不含选项 52, WireFormat 不会再次溢出, 需要时使用 Overload; size 为报文长度, 发生溢出时记录以便 reoverload
+ */
+func (dhcp *DhcpV4Packet) decodeOptions(list []OptionsPacket, size int) []OptionsPacket {
+	options := DhcpV4Options(list)
+	if opp, ok := options.Get(DHCP_Option_Overload); ok && len(opp.Value) == 1 {
+		dhcp.overload = SizeofIPv4Packet + SizeofDUPPacket + size
+		if opp.Value[0] & DHCP_OVERLOAD_FILE != 0 {
+			options, dhcp.FileName = append(options, NewOptionsPacket(dhcp.FileName[:])...), [128]byte{}
+		}
+		if opp.Value[0] & DHCP_OVERLOAD_SNAME != 0 {
+			options, dhcp.HostName = append(options, NewOptionsPacket(dhcp.HostName[:])...), [64]byte{}
+		}
+		options.Del(DHCP_Option_Overload)
+	}
+	return ConcatOptions(options)
+}
+
+// RFC 3396: 合并同 Code 的多个实例, 保持首次出现的位置
+func ConcatOptions(list []OptionsPacket) []OptionsPacket {
+	out := make([]OptionsPacket, 0, len(list))
+	index := make(map[uint8]int, len(list))
+	for _, opp := range list {
+		i, ok := index[opp.Code]
+		if !ok {
+			index[opp.Code] = len(out)
+			out = append(out, opp)
+			continue
+		}
+		value := make([]byte, 0, len(out[i].Value) + len(opp.Value))
+		out[i] = SetDHCPOption(opp.Code, append(append(value, out[i].Value...), opp.Value...))
+	}
+	return out
+}
+
+/*
+	RFC 2132 9.10 Maximum DHCP Message Size 为包含 IP/UDP 首部的最大报文长度
+	编码后超过 max 时将后续选项依次放入 file 与 sname 并添加选项 52
+	不超过 255 bytes 的选项不会被拆分, 长选项按剩余空间拆分
+	file 或 sname 已被使用时不使用该字段, 仍然放不下时 ok 为 false, 返回原报文
+ */
+func (dhcp DhcpV4Packet) Overload(max int) (DhcpV4Packet, bool) {
+	room := max - SizeofIPv4Packet - SizeofDUPPacket - SizeofDhcpV4Packet - 1
+	total := 0
+	for _, opp := range dhcp.Options {
+		total += len(opp.WireFormat())
+	}
+	if total <= room {
+		return dhcp, true
+	}
+	// 选项区保留选项 52, file 与 sname 各保留 END
+	size := [3]int{room - SizeofOptionsPacket - 1, len(dhcp.FileName) - 1, len(dhcp.HostName) - 1}
+	if dhcp.FileName != ([128]byte{}) {
+		size[1] = 0
+	}
+	if dhcp.HostName != ([64]byte{}) {
+		size[2] = 0
+	}
+	var used [3]int
+	var areas [3][]OptionsPacket
+	r := 0
+	for _, opp := range dhcp.Options {
+		v, long := opp.Value, len(opp.Value) > 255
+		for {
+			if r >= len(size) {
+				return dhcp, false
+			}
+			free := size[r] - used[r] - SizeofOptionsPacket
+			if free < len(v) && (!long || free < 1) {
+				r++
+				continue
+			}
+			n := len(v)
+			if n > free {
+				n = free
+			}
+			if n > 255 {
+				n = 255
+			}
+			areas[r], used[r] = append(areas[r], SetDHCPOption(opp.Code, v[:n])), used[r] + SizeofOptionsPacket + n
+			if v = v[n:]; len(v) == 0 {
+				break
+			}
+		}
+	}
+	var flag uint8
+	if len(areas[1]) > 0 {
+		flag |= DHCP_OVERLOAD_FILE
+		copy(dhcp.FileName[:], overloadArea(areas[1]))
+	}
+	if len(areas[2]) > 0 {
+		flag |= DHCP_OVERLOAD_SNAME
+		copy(dhcp.HostName[:], overloadArea(areas[2]))
+	}
+	dhcp.Options = append(areas[0], SetDHCPOptionOverload(flag))
+	return dhcp, true
+}
+
+// 解码时发生过溢出的报文按原报文长度再次溢出, 选项区预留 reserve bytes; 未溢出过的报文原样返回
+func (dhcp DhcpV4Packet) reoverload(reserve int) (DhcpV4Packet, bool) {
+	if dhcp.overload == 0 {
+		return dhcp, true
+	}
+	return dhcp.Overload(dhcp.overload - reserve)
+}
+
+func overloadArea(list []OptionsPacket) []byte {
+	var b []byte
+	for _, opp := range list {
+		b = append(b, opp.WireFormat()...)
+	}
+	return append(b, 255)
+}
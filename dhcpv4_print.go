@@ -0,0 +1,162 @@
+// @@
+// @ Author       : Eacher
+// @ Date         : 2026-10-20 23:31:08
+// @ LastEditTime : 2026-10-21 00:09:17
+// @ LastEditors  : Eacher
+// @ --------------------------------------------------------------------------------<
+// @ Description  : DHCPv4 报文的可读输出 (类似 dhcpdump)
+// @ --------------------------------------------------------------------------------<
+// @ FilePath     : /20yyq/packet/dhcpv4_print.go
+// @@
+package packet
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var dhcpV4MessageNames = [...]string{
+	DHCP_DISCOVER: "DISCOVER", DHCP_OFFER: "OFFER", DHCP_REQUEST: "REQUEST", DHCP_DECLINE: "DECLINE",
+	DHCP_ACK: "ACK", DHCP_NAK: "NAK", DHCP_RELEASE: "RELEASE", DHCP_INFORM: "INFORM",
+	DHCP_FORCERENEW: "FORCERENEW", DHCP_LEASEQUERY: "LEASEQUERY", DHCP_LEASEUNASSIGNED: "LEASEUNASSIGNED",
+	DHCP_LEASEUNKNOWN: "LEASEUNKNOWN", DHCP_LEASEACTIVE: "LEASEACTIVE",
+}
+
+func (t DHCP_Message_Type) String() string {
+	if int(t) < len(dhcpV4MessageNames) && dhcpV4MessageNames[t] != "" {
+		return dhcpV4MessageNames[t]
+	}
+	return "UNKNOWN(" + strconv.Itoa(int(t)) + ")"
+}
+
+// 按选项类型显示值, 长度不符时以十六进制显示
+func (opp OptionsPacket) String() string {
+	return strings.TrimRight(fmt.Sprintf("%3d (%3d) %-28s %s", opp.Code, len(opp.Value), DhcpV4OptionName(opp.Code), dhcpV4OptionValue(opp)), " ")
+}
+
+func dhcpV4OptionValue(opp OptionsPacket) string {
+	def, _ := LookupDhcpV4Option(opp.Code)
+	value, err := DecodeDhcpV4Option(opp)
+	if err != nil {
+		return hexColon(opp.Value)
+	}
+	switch def.Type {
+	case DHCP_DATA_OPAQUE:
+		return hexColon(opp.Value)
+	case DHCP_DATA_IPV4_LIST:
+		list := make([]string, len(value.([]IPv4)))
+		for i, ip := range value.([]IPv4) {
+			list[i] = ip.String()
+		}
+		return strings.Join(list, ", ")
+	case DHCP_DATA_STRING:
+		return strconv.Quote(value.(string))
+	case DHCP_DATA_UINT8_LIST, DHCP_DATA_OPTION_CODES:
+		list := make([]string, len(opp.Value))
+		for i, c := range opp.Value {
+			list[i] = strconv.Itoa(int(c))
+			if def, ok := LookupDhcpV4Option(c); ok && opp.Code == DHCP_Parameter_Request_List {
+				list[i] += " (" + def.Name + ")"
+			}
+		}
+		return strings.Join(list, ", ")
+	case DHCP_DATA_UINT16_LIST:
+		list := make([]string, len(value.([]uint16)))
+		for i, n := range value.([]uint16) {
+			list[i] = strconv.Itoa(int(n))
+		}
+		return strings.Join(list, ", ")
+	case DHCP_DATA_DURATION:
+		d := value.(time.Duration)
+		if d >= DHCP_INFINITE_LEASE {
+			return "infinite"
+		}
+		return strconv.FormatUint(uint64(d / time.Second), 10) + " (" + d.String() + ")"
+	case DHCP_DATA_FLAG:
+		return ""
+	case DHCP_DATA_NESTED:
+		list := make([]string, len(value.([]OptionsPacket)))
+		for i, sub := range value.([]OptionsPacket) {
+			list[i] = strconv.Itoa(int(sub.Code)) + "=" + hexColon(sub.Value)
+		}
+		return strings.Join(list, " ")
+	case DHCP_DATA_MESSAGE_TYPE:
+		return strconv.Itoa(int(opp.Value[0])) + " (" + value.(DHCP_Message_Type).String() + ")"
+	case DHCP_DATA_DOMAIN_LIST:
+		return strings.Join(value.([]string), ", ")
+	case DHCP_DATA_CLASSLESS_ROUTES, DHCP_DATA_STATIC_ROUTES:
+		return dhcpV4Routes(value.([]DhcpV4Route))
+	case DHCP_DATA_CLIENT_FQDN:
+		fqdn := value.(DhcpV4ClientFQDN)
+		return fmt.Sprintf("flags 0x%02x %q", fqdn.Flags, fqdn.Name)
+	case DHCP_DATA_AUTHENTICATION:
+		a := value.(DhcpV4Authentication)
+		return fmt.Sprintf("protocol %d algorithm %d rdm %d replay 0x%016x info %s", a.Protocol, a.Algorithm, a.RDM, a.ReplayDetection, hexColon(a.Info))
+	}
+	return fmt.Sprint(value)
+}
+
+/*
+	多行输出, 格式参考 dhcpdump:
+	    OP: 1 (BOOTREQUEST)
+	 HTYPE: 1
+	  HLEN: 6
+	  ...
+	OPTION:  53 (  1) DHCP Message Type            1 (DISCOVER)
+ */
+func (dhcp DhcpV4Packet) String() string {
+	var b strings.Builder
+	op := "BOOTREQUEST"
+	if dhcp.Op == DHCP_BOOTREPLY {
+		op = "BOOTREPLY"
+	} else if dhcp.Op != DHCP_BOOTREQUEST {
+		op = "unknown"
+	}
+	fmt.Fprintf(&b, "    OP: %d (%s)\n", dhcp.Op, op)
+	fmt.Fprintf(&b, " HTYPE: %d\n  HLEN: %d\n  HOPS: %d\n   XID: 0x%08x\n  SECS: %d\n", dhcp.HardwareType, dhcp.HardwareLen, dhcp.Hops, dhcp.XID, dhcp.Secs)
+	flags := fmt.Sprintf(" FLAGS: 0x%04x", dhcp.Flags)
+	if dhcp.Flags & DHCP_BROADCAST_FLAG != 0 {
+		flags += " (broadcast)"
+	}
+	b.WriteString(flags + "\n")
+	fmt.Fprintf(&b, "CIADDR: %s\nYIADDR: %s\nSIADDR: %s\nGIADDR: %s\n", dhcp.CIAddr, dhcp.YIAddr, dhcp.SIAddr, dhcp.GIAddr)
+	chaddr := dhcp.ChHardware[:]
+	if n := int(dhcp.HardwareLen); n > 0 && n < len(chaddr) && dhcpV4Zero(chaddr[n:]) {
+		chaddr = chaddr[:n]
+	}
+	fmt.Fprintf(&b, "CHADDR: %s\n", hexColon(chaddr))
+	fmt.Fprintf(&b, " SNAME: %q\n FNAME: %q\n", dhcpV4CString(dhcp.HostName[:]), dhcpV4CString(dhcp.FileName[:]))
+	if !dhcp.HasMagicCookie() {
+		fmt.Fprintf(&b, "  VEND: %s\n", hexColon(dhcp.Vend))
+	}
+	for _, opp := range dhcp.Options {
+		b.WriteString("OPTION: " + opp.String() + "\n")
+	}
+	return b.String()
+}
+
+func dhcpV4Routes(routes []DhcpV4Route) string {
+	list := make([]string, len(routes))
+	for i, r := range routes {
+		list[i] = r.Dst.String() + "/" + strconv.Itoa(int(r.PrefixLen)) + " via " + r.Gateway.String()
+	}
+	return strings.Join(list, ", ")
+}
+
+func dhcpV4CString(b []byte) string {
+	if i := strings.IndexByte(string(b), 0); i >= 0 {
+		b = b[:i]
+	}
+	return string(b)
+}
+
+func dhcpV4Zero(b []byte) bool {
+	for _, v := range b {
+		if v != 0 {
+			return false
+		}
+	}
+	return true
+}
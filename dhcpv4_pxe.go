@@ -0,0 +1,379 @@
+// @@
+// @ Author       : Eacher
+// @ Date         : 2026-10-20 19:12:36
+// @ LastEditTime : 2026-10-21 16:16:31
+// @ LastEditors  : Eacher
+// @ --------------------------------------------------------------------------------<
+// @ Description  : PXE/UEFI HTTP 网络启动选项 93/94/97 与启动策略
+// @ --------------------------------------------------------------------------------<
+// @ FilePath     : /20yyq/packet/dhcpv4_pxe.go
+// @@
+package packet
+
+import (
+	"encoding/binary"
+	"strconv"
+	"strings"
+)
+
+/*
+RFC 4578  DHCP Options for the Intel Preboot eXecution Environment (PXE)
+
+2.1. Client System Architecture Type Option Definition
+
+	 Code  Len  16-bit Type
+	+----+-----+-----+-----+
+	| 93 |  n  | n1  | n2  |
+	+----+-----+-----+-----+
+
+2.2. Client Network Interface Identifier Option Definition
+
+	 Code  Len  Type  Major Minor
+	+----+-----+----+-----+-----+
+	| 94 |  3  |  t |  M  |  m  |
+	+----+-----+----+-----+-----+
+
+	Type 为 1 (UNDI), Major/Minor 为 UNDI 版本
+
+2.5. Client Machine Identifier Option Definition
+
+	 Code  Len  Type  Machine Identifier
+	+----+-----+----+-----+ . . . +-----+
+	| 97 | n   |  t |     | . . . |     |
+	+----+-----+----+-----+ . . . +-----+
+
+	Type 为 0 时其后为 16 bytes 的 GUID
+
+	PXE 客户端的选项 60 为 "PXEClient:Arch:xxxxx:UNDI:yyyzzz"
+	UEFI HTTP Boot (UEFI 2.5 24.7) 客户端为 "HTTPClient:Arch:xxxxx:UNDI:yyyzzz", 服务端应答必须包含
+	选项 60 "HTTPClient" 且启动文件为 URL
+ */
+const (
+	DHCP_Client_System_Architecture 	= 93
+	DHCP_Client_Network_Interface_ID 	= 94
+	DHCP_Client_Machine_ID 				= 97
+	DHCP_User_Class 					= 77
+
+	DHCP_VENDOR_CLASS_HTTP = "HTTPClient"
+)
+
+// IANA Processor Architecture Types
+type DHCP_PXE_ARCH uint16
+
+const (
+	PXE_ARCH_X86_BIOS DHCP_PXE_ARCH = iota
+	PXE_ARCH_NEC_PC98
+	PXE_ARCH_ITANIUM
+	PXE_ARCH_DEC_ALPHA
+	PXE_ARCH_ARC_X86
+	PXE_ARCH_INTEL_LEAN
+	PXE_ARCH_X86_UEFI
+	PXE_ARCH_X64_UEFI
+	PXE_ARCH_EFI_XSCALE
+	PXE_ARCH_EBC
+	PXE_ARCH_ARM32_UEFI
+	PXE_ARCH_ARM64_UEFI
+	PXE_ARCH_POWERPC_OPEN_FIRMWARE
+	PXE_ARCH_POWERPC_EPAPR
+	PXE_ARCH_POWER_OPAL_V3
+	PXE_ARCH_X86_UEFI_HTTP
+	PXE_ARCH_X64_UEFI_HTTP
+	PXE_ARCH_EBC_HTTP
+	PXE_ARCH_ARM32_UEFI_HTTP
+	PXE_ARCH_ARM64_UEFI_HTTP
+	PXE_ARCH_X86_BIOS_HTTP
+	PXE_ARCH_ARM32_UBOOT
+	PXE_ARCH_ARM64_UBOOT
+	PXE_ARCH_ARM32_UBOOT_HTTP
+	PXE_ARCH_ARM64_UBOOT_HTTP
+	PXE_ARCH_RISCV32_UEFI
+	PXE_ARCH_RISCV32_UEFI_HTTP
+	PXE_ARCH_RISCV64_UEFI
+	PXE_ARCH_RISCV64_UEFI_HTTP
+)
+
+func (a DHCP_PXE_ARCH) String() string {
+	switch a {
+	case PXE_ARCH_X86_BIOS:
+		return "x86 BIOS"
+	case PXE_ARCH_ITANIUM:
+		return "Itanium"
+	case PXE_ARCH_X86_UEFI:
+		return "x86 UEFI"
+	case PXE_ARCH_X64_UEFI:
+		return "x64 UEFI"
+	case PXE_ARCH_EBC:
+		return "EBC"
+	case PXE_ARCH_ARM32_UEFI:
+		return "ARM 32-bit UEFI"
+	case PXE_ARCH_ARM64_UEFI:
+		return "ARM 64-bit UEFI"
+	case PXE_ARCH_X86_UEFI_HTTP:
+		return "x86 UEFI HTTP"
+	case PXE_ARCH_X64_UEFI_HTTP:
+		return "x64 UEFI HTTP"
+	case PXE_ARCH_EBC_HTTP:
+		return "EBC HTTP"
+	case PXE_ARCH_ARM32_UEFI_HTTP:
+		return "ARM 32-bit UEFI HTTP"
+	case PXE_ARCH_ARM64_UEFI_HTTP:
+		return "ARM 64-bit UEFI HTTP"
+	case PXE_ARCH_X86_BIOS_HTTP:
+		return "x86 BIOS HTTP"
+	case PXE_ARCH_ARM32_UBOOT:
+		return "ARM 32-bit U-Boot"
+	case PXE_ARCH_ARM64_UBOOT:
+		return "ARM 64-bit U-Boot"
+	case PXE_ARCH_RISCV32_UEFI:
+		return "RISC-V 32-bit UEFI"
+	case PXE_ARCH_RISCV64_UEFI:
+		return "RISC-V 64-bit UEFI"
+	}
+	return "arch " + strconv.Itoa(int(a))
+}
+
+// 是否为从 HTTP 启动的架构类型
+func (a DHCP_PXE_ARCH) HTTP() bool {
+	switch a {
+	case PXE_ARCH_X86_UEFI_HTTP, PXE_ARCH_X64_UEFI_HTTP, PXE_ARCH_EBC_HTTP, PXE_ARCH_ARM32_UEFI_HTTP, PXE_ARCH_ARM64_UEFI_HTTP,
+		PXE_ARCH_X86_BIOS_HTTP, PXE_ARCH_ARM32_UBOOT_HTTP, PXE_ARCH_ARM64_UBOOT_HTTP, PXE_ARCH_RISCV32_UEFI_HTTP, PXE_ARCH_RISCV64_UEFI_HTTP:
+		return true
+	}
+	return false
+}
+
+func SetDHCPClientArch(arch ...DHCP_PXE_ARCH) OptionsPacket {
+	if len(arch) < 1 || len(arch) > 127 {
+		return OptionsPacket{}
+	}
+	b := make([]byte, 0, len(arch) * 2)
+	for _, a := range arch {
+		b = binary.BigEndian.AppendUint16(b, uint16(a))
+	}
+	return OptionsPacket{DHCP_Client_System_Architecture, uint8(len(b)), b}
+}
+
+func (dhcp DhcpV4Packet) ClientArch() ([]DHCP_PXE_ARCH, bool) {
+	opp, ok := dhcp.Option(DHCP_Client_System_Architecture)
+	if !ok || len(opp.Value) < 2 || len(opp.Value) % 2 != 0 {
+		return nil, false
+	}
+	list := make([]DHCP_PXE_ARCH, len(opp.Value) / 2)
+	for i := range list {
+		list[i] = DHCP_PXE_ARCH(binary.BigEndian.Uint16(opp.Value[i*2:]))
+	}
+	return list, true
+}
+
+// 选项 94, UNDI 版本
+func SetDHCPClientNDI(major, minor uint8) OptionsPacket {
+	return OptionsPacket{DHCP_Client_Network_Interface_ID, 3, []byte{1, major, minor}}
+}
+
+func (dhcp DhcpV4Packet) ClientNDI() (major, minor uint8, ok bool) {
+	if opp, found := dhcp.Option(DHCP_Client_Network_Interface_ID); found && len(opp.Value) == 3 && opp.Value[0] == 1 {
+		return opp.Value[1], opp.Value[2], true
+	}
+	return
+}
+
+func SetDHCPClientMachineID(uuid [16]byte) OptionsPacket {
+	return OptionsPacket{DHCP_Client_Machine_ID, 17, append([]byte{0}, uuid[:]...)}
+}
+
+func (dhcp DhcpV4Packet) ClientMachineID() (uuid [16]byte, ok bool) {
+	if opp, found := dhcp.Option(DHCP_Client_Machine_ID); found && len(opp.Value) == 17 && opp.Value[0] == 0 {
+		return [16]byte(opp.Value[1:]), true
+	}
+	return
+}
+
+// 选项 77, 兼容 RFC 3004 的 (len, data) 列表与 iPXE 等客户端发送的单个字符串
+func (dhcp DhcpV4Packet) UserClass() ([]string, bool) {
+	opp, ok := dhcp.Option(DHCP_User_Class)
+	if !ok || len(opp.Value) < 1 {
+		return nil, false
+	}
+	var list []string
+	for idx := 0; idx < len(opp.Value); {
+		n := int(opp.Value[idx])
+		if n < 1 || idx + 1 + n > len(opp.Value) {
+			return []string{string(opp.Value)}, true
+		}
+		list, idx = append(list, string(opp.Value[idx+1:idx+1+n])), idx + 1 + n
+	}
+	return list, true
+}
+
+// 选项 60 中的网络启动客户端信息
+type DhcpV4NetbootClass struct {
+	// DHCP_VENDOR_CLASS_PXE 或 DHCP_VENDOR_CLASS_HTTP
+	Class 		string
+	Arch 		DHCP_PXE_ARCH
+	HasArch 	bool
+	UNDIMajor 	uint8
+	UNDIMinor 	uint8
+}
+
+// 解析 "PXEClient:Arch:00007:UNDI:003016", 只有前缀时 HasArch 为 false
+func NewDhcpV4NetbootClass(s string) (c DhcpV4NetbootClass, ok bool) {
+	fields := strings.Split(strings.TrimRight(s, "\x00"), ":")
+	if c.Class = fields[0]; c.Class != DHCP_VENDOR_CLASS_PXE && c.Class != DHCP_VENDOR_CLASS_HTTP {
+		return DhcpV4NetbootClass{}, false
+	}
+	for i := 1; i + 1 < len(fields); i += 2 {
+		switch fields[i] {
+		case "Arch":
+			if n, err := strconv.ParseUint(fields[i+1], 10, 16); err == nil {
+				c.Arch, c.HasArch = DHCP_PXE_ARCH(n), true
+			}
+		case "UNDI":
+			if v := fields[i+1]; len(v) == 6 {
+				major, err1 := strconv.ParseUint(v[:3], 10, 8)
+				minor, err2 := strconv.ParseUint(v[3:], 10, 8)
+				if err1 == nil && err2 == nil {
+					c.UNDIMajor, c.UNDIMinor = uint8(major), uint8(minor)
+				}
+			}
+		}
+	}
+	return c, true
+}
+
+func (c DhcpV4NetbootClass) String() string {
+	s := c.Class
+	if c.HasArch {
+		s += ":Arch:" + strconv.FormatUint(uint64(c.Arch) + 100000, 10)[1:]
+		s += ":UNDI:" + strconv.FormatUint(uint64(c.UNDIMajor) + 1000, 10)[1:] + strconv.FormatUint(uint64(c.UNDIMinor) + 1000, 10)[1:]
+	}
+	return s
+}
+
+func (c DhcpV4NetbootClass) HTTP() bool {
+	return c.Class == DHCP_VENDOR_CLASS_HTTP
+}
+
+// 网络启动客户端, 合并选项 60/93/94/97/77
+type DhcpV4NetbootClient struct {
+	DhcpV4NetbootClass
+	// 选项 93 的全部架构, 选项 93 缺失时取自选项 60
+	Archs 		[]DHCP_PXE_ARCH
+	UUID 		[16]byte
+	HasUUID 	bool
+	UserClass 	[]string
+}
+
+// 选项 60 不是 PXEClient/HTTPClient 时 ok 为 false
+func (dhcp DhcpV4Packet) NetbootClient() (c DhcpV4NetbootClient, ok bool) {
+	class, _ := dhcp.VendorClassIdentifier()
+	if c.DhcpV4NetbootClass, ok = NewDhcpV4NetbootClass(class); !ok {
+		return
+	}
+	if c.Archs, ok = dhcp.ClientArch(); ok {
+		if !c.HasArch {
+			c.Arch, c.HasArch = c.Archs[0], true
+		}
+	} else if c.HasArch {
+		c.Archs = []DHCP_PXE_ARCH{c.Arch}
+	}
+	if major, minor, found := dhcp.ClientNDI(); found {
+		c.UNDIMajor, c.UNDIMinor = major, minor
+	}
+	c.UUID, c.HasUUID = dhcp.ClientMachineID()
+	c.UserClass, _ = dhcp.UserClass()
+	return c, true
+}
+
+/*
+	按架构选择启动文件, Archs 与 UserClass 为空时匹配任意客户端
+	HTTPClient 的 FileName 应为 URL, 例如 "http://10.0.0.1/boot/bootx64.efi"
+ */
+type DhcpV4BootRule struct {
+	Archs 		[]DHCP_PXE_ARCH
+	// 匹配选项 77, 例如 "iPXE" 用于链式加载后的第二次请求
+	UserClass 	string
+	// 下一个服务器, 写入 SIAddr
+	NextServer 	IPv4
+	// 选项 66, 为空时不下发
+	ServerName 	string
+	// 写入 file 字段, 超过 127 bytes 时使用选项 67, 见 DhcpV4BootPolicy.Apply
+	FileName 	string
+	// 附加选项, 例如 SetDHCPVendorSpecific(...)
+	Options 	[]OptionsPacket
+}
+
+func (r DhcpV4BootRule) Match(c DhcpV4NetbootClient) bool {
+	if r.UserClass != "" {
+		found := false
+		for _, uc := range c.UserClass {
+			found = found || uc == r.UserClass
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(r.Archs) == 0 {
+		return true
+	}
+	for _, a := range r.Archs {
+		for _, b := range c.Archs {
+			if a == b {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// 规则按顺序匹配, 带 UserClass 的规则应放在前面
+type DhcpV4BootPolicy struct {
+	Rules []DhcpV4BootRule
+}
+
+func (p DhcpV4BootPolicy) Match(req DhcpV4Packet) (DhcpV4BootRule, DhcpV4NetbootClient, bool) {
+	c, ok := req.NetbootClient()
+	if !ok {
+		return DhcpV4BootRule{}, c, false
+	}
+	for _, r := range p.Rules {
+		if r.Match(c) {
+			return r, c, true
+		}
+	}
+	return DhcpV4BootRule{}, c, false
+}
+
+/*
+	为网络启动客户端填写应答: SIAddr, file/选项 67, 选项 66, 回显选项 60 类别
+	PXEClient 附加选项 43 子选项 6 = 0x08, 直接下载 file 而不进行启动服务器发现
+	不是网络启动客户端或没有匹配的规则时返回 false, reply 不变, 否则返回 true
+	FileName 短于 128 字节时写入 file 字段 (旧的 PXE ROM 只读取 file), 否则清空 file 并改用选项 67,
+	例如较长的 UEFI HTTP 启动 URL
+	file 字段写入后 reply.Overload 不能再把选项溢出到 file/sname, 选项放不下时返回 false,
+	因此应答需要 Overload 时规则的 Options 应尽量精简
+ */
+func (p DhcpV4BootPolicy) Apply(req DhcpV4Packet, reply *DhcpV4Packet) bool {
+	r, c, ok := p.Match(req)
+	if !ok {
+		return false
+	}
+	options := DhcpV4Options(reply.Options)
+	reply.SIAddr, reply.FileName = r.NextServer, [128]byte{}
+	if len(r.FileName) < len(reply.FileName) {
+		copy(reply.FileName[:], r.FileName)
+	} else {
+		options.Set(SetDHCPString(DHCP_Bootfile_Name, r.FileName))
+	}
+	if r.ServerName != "" {
+		options.Set(SetDHCPString(DHCP_TFTP_Server_Name, r.ServerName))
+	}
+	options.Set(SetDHCPOption(uint8(DHCP_Vendor_Class_Identifier), []byte(c.Class)))
+	if !c.HTTP() {
+		options.Set(SetDHCPVendorSpecific(OptionsPacket{PXE_DISCOVERY_CONTROL, 1, []byte{0x08}}))
+	}
+	for _, opp := range r.Options {
+		options.Set(opp)
+	}
+	reply.Options = options
+	return true
+}
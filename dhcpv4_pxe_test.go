@@ -0,0 +1,180 @@
+// @@
+// @ Author       : Eacher
+// @ Date         : 2026-10-21 10:36:18
+// @ LastEditTime : 2026-10-21 10:36:18
+// @ LastEditors  : Eacher
+// @ --------------------------------------------------------------------------------<
+// @ Description  : PXE/UEFI HTTP 网络启动选项与启动策略测试
+// @ --------------------------------------------------------------------------------<
+// @ FilePath     : /20yyq/packet/dhcpv4_pxe_test.go
+// @@
+package packet
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestNewDhcpV4NetbootClass(t *testing.T) {
+	tests := []struct {
+		in 		string
+		want 	DhcpV4NetbootClass
+		ok 		bool
+		str 	string
+	}{
+		{"PXEClient:Arch:00007:UNDI:003016", DhcpV4NetbootClass{DHCP_VENDOR_CLASS_PXE, PXE_ARCH_X64_UEFI, true, 3, 16}, true, "PXEClient:Arch:00007:UNDI:003016"},
+		{"HTTPClient:Arch:00016:UNDI:003001", DhcpV4NetbootClass{DHCP_VENDOR_CLASS_HTTP, PXE_ARCH_X64_UEFI_HTTP, true, 3, 1}, true, "HTTPClient:Arch:00016:UNDI:003001"},
+		{"PXEClient:Arch:00000:UNDI:002001\x00", DhcpV4NetbootClass{DHCP_VENDOR_CLASS_PXE, PXE_ARCH_X86_BIOS, true, 2, 1}, true, "PXEClient:Arch:00000:UNDI:002001"},
+		{"PXEClient", DhcpV4NetbootClass{Class: DHCP_VENDOR_CLASS_PXE}, true, "PXEClient"},
+		{"PXEClient:Arch:xyz:UNDI:12", DhcpV4NetbootClass{Class: DHCP_VENDOR_CLASS_PXE}, true, "PXEClient"},
+		{"MSFT 5.0", DhcpV4NetbootClass{}, false, ""},
+		{"", DhcpV4NetbootClass{}, false, ""},
+	}
+	for _, tt := range tests {
+		c, ok := NewDhcpV4NetbootClass(tt.in)
+		if ok != tt.ok || c != tt.want {
+			t.Errorf("NewDhcpV4NetbootClass(%q) = %+v, %v; want %+v, %v", tt.in, c, ok, tt.want, tt.ok)
+			continue
+		}
+		if ok && c.String() != tt.str {
+			t.Errorf("NewDhcpV4NetbootClass(%q).String() = %q; want %q", tt.in, c.String(), tt.str)
+		}
+	}
+}
+
+func TestDhcpV4ClientArch(t *testing.T) {
+	tests := []struct {
+		opp 	OptionsPacket
+		want 	[]DHCP_PXE_ARCH
+		ok 		bool
+	}{
+		{SetDHCPClientArch(PXE_ARCH_X64_UEFI), []DHCP_PXE_ARCH{PXE_ARCH_X64_UEFI}, true},
+		{SetDHCPClientArch(PXE_ARCH_X86_BIOS, PXE_ARCH_ARM64_UEFI_HTTP), []DHCP_PXE_ARCH{PXE_ARCH_X86_BIOS, PXE_ARCH_ARM64_UEFI_HTTP}, true},
+		{OptionsPacket{DHCP_Client_System_Architecture, 3, []byte{0, 7, 0}}, nil, false},
+		{OptionsPacket{DHCP_Client_System_Architecture, 1, []byte{7}}, nil, false},
+		{SetDHCPClientArch(), nil, false},
+	}
+	for i, tt := range tests {
+		dhcp := NewDhcpV4Packet(DhcpV4Packet{Options: []OptionsPacket{tt.opp}}.WireFormat())
+		list, ok := dhcp.ClientArch()
+		if ok != tt.ok || !reflect.DeepEqual(list, tt.want) {
+			t.Errorf("%d: ClientArch() = %v, %v; want %v, %v", i, list, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestDhcpV4ClientNDI(t *testing.T) {
+	tests := []struct {
+		opp 			OptionsPacket
+		major, minor 	uint8
+		ok 				bool
+	}{
+		{SetDHCPClientNDI(3, 16), 3, 16, true},
+		{SetDHCPClientNDI(2, 1), 2, 1, true},
+		{OptionsPacket{DHCP_Client_Network_Interface_ID, 3, []byte{2, 3, 16}}, 0, 0, false},
+		{OptionsPacket{DHCP_Client_Network_Interface_ID, 2, []byte{1, 3}}, 0, 0, false},
+	}
+	for i, tt := range tests {
+		dhcp := NewDhcpV4Packet(DhcpV4Packet{Options: []OptionsPacket{tt.opp}}.WireFormat())
+		major, minor, ok := dhcp.ClientNDI()
+		if major != tt.major || minor != tt.minor || ok != tt.ok {
+			t.Errorf("%d: ClientNDI() = %d, %d, %v; want %d, %d, %v", i, major, minor, ok, tt.major, tt.minor, tt.ok)
+		}
+	}
+}
+
+func TestDhcpV4ClientMachineID(t *testing.T) {
+	uuid := [16]byte{0x12, 0x34, 0x56, 0x78, 0x9a, 0xbc, 0xde, 0xf0, 1, 2, 3, 4, 5, 6, 7, 8}
+	tests := []struct {
+		opp 	OptionsPacket
+		want 	[16]byte
+		ok 		bool
+	}{
+		{SetDHCPClientMachineID(uuid), uuid, true},
+		{OptionsPacket{DHCP_Client_Machine_ID, 17, append([]byte{1}, uuid[:]...)}, [16]byte{}, false},
+		{OptionsPacket{DHCP_Client_Machine_ID, 16, append([]byte{0}, uuid[:15]...)}, [16]byte{}, false},
+	}
+	for i, tt := range tests {
+		dhcp := NewDhcpV4Packet(DhcpV4Packet{Options: []OptionsPacket{tt.opp}}.WireFormat())
+		id, ok := dhcp.ClientMachineID()
+		if id != tt.want || ok != tt.ok {
+			t.Errorf("%d: ClientMachineID() = %x, %v; want %x, %v", i, id, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestDhcpV4BootPolicyApply(t *testing.T) {
+	longURL := "http://10.0.0.1/" + string(bytes.Repeat([]byte("a"), 128)) + ".efi"
+	policy := DhcpV4BootPolicy{Rules: []DhcpV4BootRule{
+		{UserClass: "iPXE", NextServer: IPv4{10, 0, 0, 1}, FileName: "http://10.0.0.1/boot.ipxe"},
+		{Archs: []DHCP_PXE_ARCH{PXE_ARCH_X86_BIOS}, NextServer: IPv4{10, 0, 0, 2}, ServerName: "tftp.local", FileName: "undionly.kpxe"},
+		{Archs: []DHCP_PXE_ARCH{PXE_ARCH_X64_UEFI}, NextServer: IPv4{10, 0, 0, 3}, FileName: "ipxe.efi"},
+		{Archs: []DHCP_PXE_ARCH{PXE_ARCH_X64_UEFI_HTTP}, FileName: longURL},
+	}}
+	tests := []struct {
+		name 		string
+		options 	[]OptionsPacket
+		ok 			bool
+		next 		IPv4
+		file 		string
+		option67 	string
+		server 		string
+		class 		string
+		discovery 	bool
+	}{
+		{"bios", []OptionsPacket{
+			SetDHCPString(DHCP_Vendor_Class_Identifier, "PXEClient:Arch:00000:UNDI:002001"),
+		}, true, IPv4{10, 0, 0, 2}, "undionly.kpxe", "", "tftp.local", "PXEClient", true},
+		{"uefi option 93", []OptionsPacket{
+			SetDHCPString(DHCP_Vendor_Class_Identifier, "PXEClient"),
+			SetDHCPClientArch(PXE_ARCH_X64_UEFI), SetDHCPClientNDI(3, 16),
+		}, true, IPv4{10, 0, 0, 3}, "ipxe.efi", "", "", "PXEClient", true},
+		{"ipxe chain", []OptionsPacket{
+			SetDHCPString(DHCP_Vendor_Class_Identifier, "PXEClient:Arch:00007:UNDI:003016"),
+			{DHCP_User_Class, 5, []byte{4, 'i', 'P', 'X', 'E'}},
+		}, true, IPv4{10, 0, 0, 1}, "http://10.0.0.1/boot.ipxe", "", "", "PXEClient", true},
+		{"http boot", []OptionsPacket{
+			SetDHCPString(DHCP_Vendor_Class_Identifier, "HTTPClient:Arch:00016:UNDI:003001"),
+		}, true, IPv4{}, "", longURL, "", "HTTPClient", false},
+		{"no rule", []OptionsPacket{
+			SetDHCPString(DHCP_Vendor_Class_Identifier, "PXEClient:Arch:00011:UNDI:003016"),
+		}, false, IPv4{}, "", "", "", "", false},
+		{"not netboot", []OptionsPacket{
+			SetDHCPString(DHCP_Vendor_Class_Identifier, "MSFT 5.0"),
+		}, false, IPv4{}, "", "", "", "", false},
+	}
+	for _, tt := range tests {
+		req := NewDhcpV4Packet(DhcpV4Packet{Op: 1, HardwareType: 1, HardwareLen: 6, XID: 0x1234, Options: tt.options}.WireFormat())
+		reply := DhcpV4Packet{Op: 2, HardwareType: 1, HardwareLen: 6, XID: req.XID, YIAddr: IPv4{10, 0, 0, 100}}
+		if ok := policy.Apply(req, &reply); ok != tt.ok {
+			t.Errorf("%s: Apply() = %v; want %v", tt.name, ok, tt.ok)
+			continue
+		} else if !ok {
+			if !reflect.DeepEqual(reply, DhcpV4Packet{Op: 2, HardwareType: 1, HardwareLen: 6, XID: req.XID, YIAddr: IPv4{10, 0, 0, 100}}) {
+				t.Errorf("%s: Apply() changed reply: %+v", tt.name, reply)
+			}
+			continue
+		}
+		reply = NewDhcpV4Packet(reply.WireFormat())
+		if reply.SIAddr != tt.next {
+			t.Errorf("%s: SIAddr = %v; want %v", tt.name, reply.SIAddr, tt.next)
+		}
+		if file := string(bytes.TrimRight(reply.FileName[:], "\x00")); file != tt.file {
+			t.Errorf("%s: file = %q; want %q", tt.name, file, tt.file)
+		}
+		if s, _ := reply.StringOption(DHCP_Bootfile_Name); s != tt.option67 {
+			t.Errorf("%s: option 67 = %q; want %q", tt.name, s, tt.option67)
+		}
+		if s, _ := reply.StringOption(DHCP_TFTP_Server_Name); s != tt.server {
+			t.Errorf("%s: option 66 = %q; want %q", tt.name, s, tt.server)
+		}
+		if s, _ := reply.VendorClassIdentifier(); s != tt.class {
+			t.Errorf("%s: option 60 = %q; want %q", tt.name, s, tt.class)
+		}
+		o, _ := reply.VendorSpecific("")
+		if v, ok := o.Get(PXE_DISCOVERY_CONTROL); ok != tt.discovery || (ok && !bytes.Equal(v, []byte{0x08})) {
+			t.Errorf("%s: option 43 discovery-control = %x, %v; want 08, %v", tt.name, v, ok, tt.discovery)
+		}
+	}
+}
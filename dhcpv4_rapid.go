@@ -0,0 +1,35 @@
+// @@
+// @ Author       : Eacher
+// @ Date         : 2026-10-20 21:16:33
+// @ LastEditTime : 2026-10-20 21:16:33
+// @ LastEditors  : Eacher
+// @ --------------------------------------------------------------------------------<
+// @ Description  : RFC 4039 选项 80 Rapid Commit
+// @ --------------------------------------------------------------------------------<
+// @ FilePath     : /20yyq/packet/dhcpv4_rapid.go
+// @@
+package packet
+
+/*
+RFC 4039 4  Rapid Commit Option Format
+	     Code  Len
+	   +-----+-----+
+	   |  80 |  0  |
+	   +-----+-----+
+
+	A client preparing a DHCPDISCOVER message that includes the Rapid Commit option
+	SHOULD include the Rapid Commit option in the DHCPDISCOVER message.  A server that
+	is configured to commit addresses immediately responds with a DHCPACK that includes
+	the Rapid Commit option; a client MUST NOT accept a DHCPACK in the SELECTING state
+	unless it contains the Rapid Commit option.
+ */
+const DHCP_Rapid_Commit = 80
+
+func SetDHCPRapidCommit() OptionsPacket {
+	return OptionsPacket{DHCP_Rapid_Commit, 0, nil}
+}
+
+func (dhcp DhcpV4Packet) RapidCommit() bool {
+	_, ok := dhcp.Option(DHCP_Rapid_Commit)
+	return ok
+}
@@ -0,0 +1,961 @@
+// @@
+// @ Author       : Eacher
+// @ Date         : 2026-10-20 23:58:46
+// @ LastEditTime : 2026-10-21 00:44:10
+// @ LastEditors  : Eacher
+// @ --------------------------------------------------------------------------------<
+// @ Description  : DHCPv4 选项注册表 (IANA BOOTP/DHCP Options), 通用编解码与 JSON
+// @ --------------------------------------------------------------------------------<
+// @ FilePath     : /20yyq/packet/dhcpv4_registry.go
+// @@
+package packet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	DHCP_NetBIOS_Node_Type_Code 	= 46
+	DHCP_Message_Type_Code 			= 53
+	DHCP_Parameter_Request_List 	= 55
+	DHCP_Maximum_Message_Size 		= 57
+
+	DHCP_Interface_MTU 							= 26
+	DHCP_Broadcast_Address DHCP_IPv4_TYPE 		= 28
+	DHCP_NTP_Servers DHCP_IPv4_TYPE 			= 42
+	// RFC 8910 Captive-Portal URI
+	DHCP_Captive_Portal DHCP_STRING_TYPE 		= 114
+	// RFC 5859 TFTP Server Address
+	DHCP_TFTP_Server_Address DHCP_IPv4_TYPE 	= 150
+	// Web Proxy Auto-Discovery URL, 非 IANA 注册
+	DHCP_WPAD DHCP_STRING_TYPE 					= 252
+)
+
+var (
+	ErrDhcpOptionUnknown 	= errors.New("dhcp: unknown option")
+	ErrDhcpOptionValue 		= errors.New("dhcp: invalid option value")
+)
+
+type DHCP_OPTION_DATA_TYPE uint8
+
+const (
+	// 原始字节, JSON 为 "01:02:03"
+	DHCP_DATA_OPAQUE DHCP_OPTION_DATA_TYPE = iota
+	DHCP_DATA_IPV4
+	DHCP_DATA_IPV4_LIST
+	// NVT ASCII, 解码时去除末尾的 NUL
+	DHCP_DATA_STRING
+	DHCP_DATA_UINT8
+	DHCP_DATA_UINT16
+	DHCP_DATA_UINT32
+	DHCP_DATA_INT32
+	DHCP_DATA_UINT8_LIST
+	DHCP_DATA_UINT16_LIST
+	// 1 byte, 0 或 1
+	DHCP_DATA_BOOL
+	// 32 位秒数
+	DHCP_DATA_DURATION
+	// 长度为 0 的选项, 例如 Rapid Commit
+	DHCP_DATA_FLAG
+	// 与选项相同编码的子选项, 例如选项 43, 82
+	DHCP_DATA_NESTED
+	DHCP_DATA_MESSAGE_TYPE
+	// 选项代码列表 (选项 55)
+	DHCP_DATA_OPTION_CODES
+	DHCP_DATA_CLIENT_ID
+	// RFC 1035 域名列表, 可以带压缩指针
+	DHCP_DATA_DOMAIN_LIST
+	// RFC 3442 无类路由
+	DHCP_DATA_CLASSLESS_ROUTES
+	// RFC 2132 5.8 目的地址/路由器地址对
+	DHCP_DATA_STATIC_ROUTES
+	DHCP_DATA_CLIENT_FQDN
+	DHCP_DATA_AUTHENTICATION
+)
+
+var dhcpV4DataTypeNames = [...]string{
+	"opaque", "ipv4", "ipv4-list", "string", "uint8", "uint16", "uint32", "int32", "uint8-list", "uint16-list",
+	"bool", "duration", "flag", "nested", "message-type", "option-codes", "client-id", "domain-list",
+	"classless-routes", "static-routes", "client-fqdn", "authentication",
+}
+
+func (t DHCP_OPTION_DATA_TYPE) String() string {
+	if int(t) < len(dhcpV4DataTypeNames) {
+		return dhcpV4DataTypeNames[t]
+	}
+	return "type(" + strconv.Itoa(int(t)) + ")"
+}
+
+type DhcpV4OptionDef struct {
+	Code 	uint8 					`json:"code"`
+	Name 	string 					`json:"name"`
+	Type 	DHCP_OPTION_DATA_TYPE 	`json:"type"`
+	// 定义该选项的文档, 例如 "RFC 2132"
+	RFC 	string 					`json:"rfc"`
+}
+
+/*
+	IANA Dynamic Host Configuration Protocol (DHCP) and Bootstrap Protocol (BOOTP) Parameters
+	https://www.iana.org/assignments/bootp-dhcp-parameters
+	不含 PAD (0) 与 END (255), 已废弃或未分配的代码不在表中
+ */
+var dhcpV4OptionDefs = []DhcpV4OptionDef{
+	{1, "Subnet Mask", DHCP_DATA_IPV4, "RFC 2132"},
+	{2, "Time Offset", DHCP_DATA_INT32, "RFC 2132"},
+	{3, "Router", DHCP_DATA_IPV4_LIST, "RFC 2132"},
+	{4, "Time Server", DHCP_DATA_IPV4_LIST, "RFC 2132"},
+	{5, "Name Server", DHCP_DATA_IPV4_LIST, "RFC 2132"},
+	{6, "Domain Name Server", DHCP_DATA_IPV4_LIST, "RFC 2132"},
+	{7, "Log Server", DHCP_DATA_IPV4_LIST, "RFC 2132"},
+	{8, "Cookie Server", DHCP_DATA_IPV4_LIST, "RFC 2132"},
+	{9, "LPR Server", DHCP_DATA_IPV4_LIST, "RFC 2132"},
+	{10, "Impress Server", DHCP_DATA_IPV4_LIST, "RFC 2132"},
+	{11, "Resource Location Server", DHCP_DATA_IPV4_LIST, "RFC 2132"},
+	{12, "Host Name", DHCP_DATA_STRING, "RFC 2132"},
+	{13, "Boot File Size", DHCP_DATA_UINT16, "RFC 2132"},
+	{14, "Merit Dump File", DHCP_DATA_STRING, "RFC 2132"},
+	{15, "Domain Name", DHCP_DATA_STRING, "RFC 2132"},
+	{16, "Swap Server", DHCP_DATA_IPV4, "RFC 2132"},
+	{17, "Root Path", DHCP_DATA_STRING, "RFC 2132"},
+	{18, "Extensions Path", DHCP_DATA_STRING, "RFC 2132"},
+	{19, "IP Forwarding", DHCP_DATA_BOOL, "RFC 2132"},
+	{20, "Non-Local Source Routing", DHCP_DATA_BOOL, "RFC 2132"},
+	{21, "Policy Filter", DHCP_DATA_IPV4_LIST, "RFC 2132"},
+	{22, "Maximum Datagram Reassembly Size", DHCP_DATA_UINT16, "RFC 2132"},
+	{23, "Default IP TTL", DHCP_DATA_UINT8, "RFC 2132"},
+	{24, "Path MTU Aging Timeout", DHCP_DATA_DURATION, "RFC 2132"},
+	{25, "Path MTU Plateau Table", DHCP_DATA_UINT16_LIST, "RFC 2132"},
+	{26, "Interface MTU", DHCP_DATA_UINT16, "RFC 2132"},
+	{27, "All Subnets Are Local", DHCP_DATA_BOOL, "RFC 2132"},
+	{28, "Broadcast Address", DHCP_DATA_IPV4, "RFC 2132"},
+	{29, "Perform Mask Discovery", DHCP_DATA_BOOL, "RFC 2132"},
+	{30, "Mask Supplier", DHCP_DATA_BOOL, "RFC 2132"},
+	{31, "Perform Router Discovery", DHCP_DATA_BOOL, "RFC 2132"},
+	{32, "Router Solicitation Address", DHCP_DATA_IPV4, "RFC 2132"},
+	{33, "Static Route", DHCP_DATA_STATIC_ROUTES, "RFC 2132"},
+	{34, "Trailer Encapsulation", DHCP_DATA_BOOL, "RFC 2132"},
+	{35, "ARP Cache Timeout", DHCP_DATA_DURATION, "RFC 2132"},
+	{36, "Ethernet Encapsulation", DHCP_DATA_BOOL, "RFC 2132"},
+	{37, "TCP Default TTL", DHCP_DATA_UINT8, "RFC 2132"},
+	{38, "TCP Keepalive Interval", DHCP_DATA_DURATION, "RFC 2132"},
+	{39, "TCP Keepalive Garbage", DHCP_DATA_BOOL, "RFC 2132"},
+	{40, "NIS Domain", DHCP_DATA_STRING, "RFC 2132"},
+	{41, "NIS Servers", DHCP_DATA_IPV4_LIST, "RFC 2132"},
+	{42, "NTP Servers", DHCP_DATA_IPV4_LIST, "RFC 2132"},
+	{43, "Vendor Specific Information", DHCP_DATA_NESTED, "RFC 2132"},
+	{44, "NetBIOS Name Server", DHCP_DATA_IPV4_LIST, "RFC 2132"},
+	{45, "NetBIOS Datagram Distribution Server", DHCP_DATA_IPV4_LIST, "RFC 2132"},
+	{46, "NetBIOS Node Type", DHCP_DATA_UINT8, "RFC 2132"},
+	{47, "NetBIOS Scope", DHCP_DATA_STRING, "RFC 2132"},
+	{48, "X Window Font Server", DHCP_DATA_IPV4_LIST, "RFC 2132"},
+	{49, "X Window Display Manager", DHCP_DATA_IPV4_LIST, "RFC 2132"},
+	{50, "Requested IP Address", DHCP_DATA_IPV4, "RFC 2132"},
+	{51, "IP Address Lease Time", DHCP_DATA_DURATION, "RFC 2132"},
+	{52, "Option Overload", DHCP_DATA_UINT8, "RFC 2132"},
+	{53, "DHCP Message Type", DHCP_DATA_MESSAGE_TYPE, "RFC 2132"},
+	{54, "Server Identifier", DHCP_DATA_IPV4, "RFC 2132"},
+	{55, "Parameter Request List", DHCP_DATA_OPTION_CODES, "RFC 2132"},
+	{56, "Message", DHCP_DATA_STRING, "RFC 2132"},
+	{57, "Maximum DHCP Message Size", DHCP_DATA_UINT16, "RFC 2132"},
+	{58, "Renewal (T1) Time", DHCP_DATA_DURATION, "RFC 2132"},
+	{59, "Rebinding (T2) Time", DHCP_DATA_DURATION, "RFC 2132"},
+	{60, "Vendor Class Identifier", DHCP_DATA_STRING, "RFC 2132"},
+	{61, "Client Identifier", DHCP_DATA_CLIENT_ID, "RFC 2132"},
+	{62, "NetWare/IP Domain Name", DHCP_DATA_STRING, "RFC 2242"},
+	{63, "NetWare/IP Information", DHCP_DATA_NESTED, "RFC 2242"},
+	{64, "NIS+ Domain", DHCP_DATA_STRING, "RFC 2132"},
+	{65, "NIS+ Servers", DHCP_DATA_IPV4_LIST, "RFC 2132"},
+	{66, "TFTP Server Name", DHCP_DATA_STRING, "RFC 2132"},
+	{67, "Bootfile Name", DHCP_DATA_STRING, "RFC 2132"},
+	{68, "Mobile IP Home Agent", DHCP_DATA_IPV4_LIST, "RFC 2132"},
+	{69, "SMTP Server", DHCP_DATA_IPV4_LIST, "RFC 2132"},
+	{70, "POP3 Server", DHCP_DATA_IPV4_LIST, "RFC 2132"},
+	{71, "NNTP Server", DHCP_DATA_IPV4_LIST, "RFC 2132"},
+	{72, "WWW Server", DHCP_DATA_IPV4_LIST, "RFC 2132"},
+	{73, "Finger Server", DHCP_DATA_IPV4_LIST, "RFC 2132"},
+	{74, "IRC Server", DHCP_DATA_IPV4_LIST, "RFC 2132"},
+	{75, "StreetTalk Server", DHCP_DATA_IPV4_LIST, "RFC 2132"},
+	{76, "STDA Server", DHCP_DATA_IPV4_LIST, "RFC 2132"},
+	{77, "User Class", DHCP_DATA_OPAQUE, "RFC 3004"},
+	{78, "SLP Directory Agent", DHCP_DATA_OPAQUE, "RFC 2610"},
+	{79, "SLP Service Scope", DHCP_DATA_OPAQUE, "RFC 2610"},
+	{80, "Rapid Commit", DHCP_DATA_FLAG, "RFC 4039"},
+	{81, "Client FQDN", DHCP_DATA_CLIENT_FQDN, "RFC 4702"},
+	{82, "Relay Agent Information", DHCP_DATA_NESTED, "RFC 3046"},
+	{83, "iSNS", DHCP_DATA_OPAQUE, "RFC 4174"},
+	{85, "NDS Servers", DHCP_DATA_IPV4_LIST, "RFC 2241"},
+	{86, "NDS Tree Name", DHCP_DATA_STRING, "RFC 2241"},
+	{87, "NDS Context", DHCP_DATA_STRING, "RFC 2241"},
+	{88, "BCMCS Controller Domain Name List", DHCP_DATA_DOMAIN_LIST, "RFC 4280"},
+	{89, "BCMCS Controller IPv4 Address", DHCP_DATA_IPV4_LIST, "RFC 4280"},
+	{90, "Authentication", DHCP_DATA_AUTHENTICATION, "RFC 3118"},
+	{91, "Client Last Transaction Time", DHCP_DATA_DURATION, "RFC 4388"},
+	{92, "Associated IP", DHCP_DATA_IPV4_LIST, "RFC 4388"},
+	{93, "Client System Architecture", DHCP_DATA_UINT16_LIST, "RFC 4578"},
+	{94, "Client Network Interface Identifier", DHCP_DATA_OPAQUE, "RFC 4578"},
+	{95, "LDAP Servers", DHCP_DATA_OPAQUE, "RFC 3679"},
+	{97, "Client Machine Identifier", DHCP_DATA_OPAQUE, "RFC 4578"},
+	{98, "User Authentication", DHCP_DATA_STRING, "RFC 2485"},
+	{99, "GeoConf Civic", DHCP_DATA_OPAQUE, "RFC 4776"},
+	{100, "IEEE 1003.1 TZ String", DHCP_DATA_STRING, "RFC 4833"},
+	{101, "Reference to the TZ Database", DHCP_DATA_STRING, "RFC 4833"},
+	{108, "IPv6-Only Preferred", DHCP_DATA_DURATION, "RFC 8925"},
+	{109, "DHCPv4o6 Softwire Source Address", DHCP_DATA_OPAQUE, "RFC 8539"},
+	{112, "NetInfo Parent Server Address", DHCP_DATA_IPV4_LIST, "RFC 3679"},
+	{113, "NetInfo Parent Server Tag", DHCP_DATA_STRING, "RFC 3679"},
+	{114, "Captive-Portal", DHCP_DATA_STRING, "RFC 8910"},
+	{116, "Auto-Configure", DHCP_DATA_UINT8, "RFC 2563"},
+	{117, "Name Service Search", DHCP_DATA_UINT16_LIST, "RFC 2937"},
+	{118, "Subnet Selection", DHCP_DATA_IPV4, "RFC 3011"},
+	{119, "Domain Search", DHCP_DATA_DOMAIN_LIST, "RFC 3397"},
+	{120, "SIP Servers", DHCP_DATA_OPAQUE, "RFC 3361"},
+	{121, "Classless Static Route", DHCP_DATA_CLASSLESS_ROUTES, "RFC 3442"},
+	{122, "CableLabs Client Configuration", DHCP_DATA_NESTED, "RFC 3495"},
+	{123, "GeoConf", DHCP_DATA_OPAQUE, "RFC 6225"},
+	{124, "V-I Vendor Class", DHCP_DATA_OPAQUE, "RFC 3925"},
+	{125, "V-I Vendor Specific Information", DHCP_DATA_OPAQUE, "RFC 3925"},
+	{128, "PXE Undefined (128)", DHCP_DATA_OPAQUE, "RFC 4578"},
+	{129, "PXE Undefined (129)", DHCP_DATA_OPAQUE, "RFC 4578"},
+	{130, "PXE Undefined (130)", DHCP_DATA_OPAQUE, "RFC 4578"},
+	{131, "PXE Undefined (131)", DHCP_DATA_OPAQUE, "RFC 4578"},
+	{132, "PXE Undefined (132)", DHCP_DATA_OPAQUE, "RFC 4578"},
+	{133, "PXE Undefined (133)", DHCP_DATA_OPAQUE, "RFC 4578"},
+	{134, "PXE Undefined (134)", DHCP_DATA_OPAQUE, "RFC 4578"},
+	{135, "PXE Undefined (135)", DHCP_DATA_OPAQUE, "RFC 4578"},
+	{136, "PANA Agent", DHCP_DATA_IPV4_LIST, "RFC 5192"},
+	{137, "LoST Server", DHCP_DATA_DOMAIN_LIST, "RFC 5223"},
+	{138, "CAPWAP Access Controller", DHCP_DATA_IPV4_LIST, "RFC 5417"},
+	{139, "MoS IPv4 Address", DHCP_DATA_OPAQUE, "RFC 5678"},
+	{140, "MoS IPv4 FQDN", DHCP_DATA_OPAQUE, "RFC 5678"},
+	{141, "SIP UA Configuration Service Domains", DHCP_DATA_DOMAIN_LIST, "RFC 6011"},
+	{142, "ANDSF IPv4 Address", DHCP_DATA_IPV4_LIST, "RFC 6153"},
+	{143, "SZTP Redirect", DHCP_DATA_OPAQUE, "RFC 8572"},
+	{144, "GeoLoc", DHCP_DATA_OPAQUE, "RFC 6225"},
+	{145, "Forcerenew Nonce Capable", DHCP_DATA_UINT8_LIST, "RFC 6704"},
+	{146, "RDNSS Selection", DHCP_DATA_OPAQUE, "RFC 6731"},
+	{147, "DOTS Reference Identifier", DHCP_DATA_DOMAIN_LIST, "RFC 8973"},
+	{148, "DOTS Address", DHCP_DATA_IPV4_LIST, "RFC 8973"},
+	{150, "TFTP Server Address", DHCP_DATA_IPV4_LIST, "RFC 5859"},
+	{151, "Bulk Leasequery Status Code", DHCP_DATA_OPAQUE, "RFC 6926"},
+	{152, "Base Time", DHCP_DATA_UINT32, "RFC 6926"},
+	{153, "Start Time of State", DHCP_DATA_UINT32, "RFC 6926"},
+	{154, "Query Start Time", DHCP_DATA_UINT32, "RFC 6926"},
+	{155, "Query End Time", DHCP_DATA_UINT32, "RFC 6926"},
+	{156, "DHCP State", DHCP_DATA_UINT8, "RFC 6926"},
+	{157, "Data Source", DHCP_DATA_UINT8, "RFC 6926"},
+	{158, "PCP Server", DHCP_DATA_OPAQUE, "RFC 7291"},
+	{159, "Port Parameters", DHCP_DATA_OPAQUE, "RFC 7618"},
+	{161, "MUD URL", DHCP_DATA_STRING, "RFC 8520"},
+	{162, "Encrypted DNS Resolver", DHCP_DATA_OPAQUE, "RFC 9463"},
+	{208, "PXELINUX Magic", DHCP_DATA_OPAQUE, "RFC 5071"},
+	{209, "PXELINUX Configuration File", DHCP_DATA_STRING, "RFC 5071"},
+	{210, "PXELINUX Path Prefix", DHCP_DATA_STRING, "RFC 5071"},
+	{211, "PXELINUX Reboot Time", DHCP_DATA_DURATION, "RFC 5071"},
+	{212, "6RD", DHCP_DATA_OPAQUE, "RFC 5969"},
+	{213, "Access Network Domain Name", DHCP_DATA_DOMAIN_LIST, "RFC 5986"},
+	{220, "Subnet Allocation", DHCP_DATA_OPAQUE, "RFC 6656"},
+	{221, "Virtual Subnet Selection", DHCP_DATA_OPAQUE, "RFC 6607"},
+	{249, "Classless Static Route (Microsoft)", DHCP_DATA_CLASSLESS_ROUTES, "Microsoft"},
+	{252, "WPAD", DHCP_DATA_STRING, "draft-ietf-wrec-wpad"},
+}
+
+var dhcpV4Registry struct {
+	sync.RWMutex
+	codes 	[256]*DhcpV4OptionDef
+	names 	map[string]*DhcpV4OptionDef
+}
+
+func init() {
+	for _, def := range dhcpV4OptionDefs {
+		RegisterDhcpV4Option(def)
+	}
+}
+
+/*
+	注册或替换选项定义, 用于站点私有选项 (224-254) 或厂商扩展
+	名称不区分大小写
+ */
+func RegisterDhcpV4Option(def DhcpV4OptionDef) error {
+	if def.Code == 0 || def.Code == 255 || def.Name == "" {
+		return ErrDhcpOptionUnknown
+	}
+	dhcpV4Registry.Lock()
+	defer dhcpV4Registry.Unlock()
+	if dhcpV4Registry.names == nil {
+		dhcpV4Registry.names = make(map[string]*DhcpV4OptionDef)
+	}
+	if old := dhcpV4Registry.codes[def.Code]; old != nil {
+		delete(dhcpV4Registry.names, strings.ToLower(old.Name))
+	}
+	dhcpV4Registry.codes[def.Code] = &def
+	dhcpV4Registry.names[strings.ToLower(def.Name)] = &def
+	return nil
+}
+
+func LookupDhcpV4Option(code uint8) (DhcpV4OptionDef, bool) {
+	dhcpV4Registry.RLock()
+	defer dhcpV4Registry.RUnlock()
+	if def := dhcpV4Registry.codes[code]; def != nil {
+		return *def, true
+	}
+	return DhcpV4OptionDef{Code: code, Name: "Option " + strconv.Itoa(int(code))}, false
+}
+
+func LookupDhcpV4OptionByName(name string) (DhcpV4OptionDef, bool) {
+	dhcpV4Registry.RLock()
+	defer dhcpV4Registry.RUnlock()
+	if def := dhcpV4Registry.names[strings.ToLower(name)]; def != nil {
+		return *def, true
+	}
+	return DhcpV4OptionDef{}, false
+}
+
+// 全部已注册的选项, 按 Code 排序
+func DhcpV4OptionDefs() []DhcpV4OptionDef {
+	dhcpV4Registry.RLock()
+	defer dhcpV4Registry.RUnlock()
+	var list []DhcpV4OptionDef
+	for _, def := range dhcpV4Registry.codes {
+		if def != nil {
+			list = append(list, *def)
+		}
+	}
+	return list
+}
+
+// 未注册的选项返回 "Option <code>"
+func DhcpV4OptionName(code uint8) string {
+	def, _ := LookupDhcpV4Option(code)
+	return def.Name
+}
+
+/*
+	按注册的类型解码选项值, 未注册的选项按 DHCP_DATA_OPAQUE 处理, 返回值类型:
+	OPAQUE []byte, IPV4 IPv4, IPV4_LIST []IPv4, STRING string, UINT8 uint8, UINT16 uint16, UINT32 uint32,
+	INT32 int32, UINT8_LIST []uint8, UINT16_LIST []uint16, BOOL bool, DURATION time.Duration, FLAG bool,
+	NESTED []OptionsPacket, MESSAGE_TYPE DHCP_Message_Type, OPTION_CODES []uint8, CLIENT_ID DhcpV4ClientID,
+	DOMAIN_LIST []string, CLASSLESS_ROUTES/STATIC_ROUTES []DhcpV4Route, CLIENT_FQDN DhcpV4ClientFQDN,
+	AUTHENTICATION DhcpV4Authentication
+ */
+func DecodeDhcpV4Option(opp OptionsPacket) (interface{}, error) {
+	def, _ := LookupDhcpV4Option(opp.Code)
+	v, n := opp.Value, len(opp.Value)
+	switch def.Type {
+	case DHCP_DATA_OPAQUE:
+		return append([]byte(nil), v...), nil
+	case DHCP_DATA_IPV4:
+		if n == 4 {
+			return IPv4(v), nil
+		}
+	case DHCP_DATA_IPV4_LIST:
+		if n % 4 == 0 {
+			list := make([]IPv4, n / 4)
+			for i := range list {
+				list[i] = IPv4(v[i*4:])
+			}
+			return list, nil
+		}
+	case DHCP_DATA_STRING:
+		return strings.TrimRight(string(v), "\x00"), nil
+	case DHCP_DATA_UINT8, DHCP_DATA_MESSAGE_TYPE:
+		if n == 1 && def.Type == DHCP_DATA_UINT8 {
+			return v[0], nil
+		}
+		if n == 1 {
+			return DHCP_Message_Type(v[0]), nil
+		}
+	case DHCP_DATA_UINT16:
+		if n == 2 {
+			return binary.BigEndian.Uint16(v), nil
+		}
+	case DHCP_DATA_UINT32:
+		if n == 4 {
+			return binary.BigEndian.Uint32(v), nil
+		}
+	case DHCP_DATA_INT32:
+		if n == 4 {
+			return int32(binary.BigEndian.Uint32(v)), nil
+		}
+	case DHCP_DATA_UINT8_LIST, DHCP_DATA_OPTION_CODES:
+		if n > 0 {
+			return append([]uint8(nil), v...), nil
+		}
+	case DHCP_DATA_UINT16_LIST:
+		if n > 0 && n % 2 == 0 {
+			list := make([]uint16, n / 2)
+			for i := range list {
+				list[i] = binary.BigEndian.Uint16(v[i*2:])
+			}
+			return list, nil
+		}
+	case DHCP_DATA_BOOL:
+		if n == 1 && v[0] <= 1 {
+			return v[0] == 1, nil
+		}
+	case DHCP_DATA_DURATION:
+		if n == 4 {
+			return time.Duration(binary.BigEndian.Uint32(v)) * time.Second, nil
+		}
+	case DHCP_DATA_FLAG:
+		if n == 0 {
+			return true, nil
+		}
+	case DHCP_DATA_NESTED:
+		return NewOptionsPacket(v), nil
+	case DHCP_DATA_CLIENT_ID:
+		if n > 0 {
+			return DhcpV4ClientID(append([]byte(nil), v...)), nil
+		}
+	case DHCP_DATA_DOMAIN_LIST:
+		if names, err := DecodeDomainSearch(v); err == nil && len(names) > 0 {
+			return names, nil
+		}
+	case DHCP_DATA_CLASSLESS_ROUTES:
+		if routes, err := NewDhcpV4ClasslessRoutes(v); err == nil {
+			return routes, nil
+		}
+	case DHCP_DATA_STATIC_ROUTES:
+		if routes, err := NewDhcpV4StaticRoutes(v); err == nil {
+			return routes, nil
+		}
+	case DHCP_DATA_CLIENT_FQDN:
+		if fqdn, err := NewDhcpV4ClientFQDN(v); err == nil {
+			return fqdn, nil
+		}
+	case DHCP_DATA_AUTHENTICATION:
+		if a, err := NewDhcpV4Authentication(v); err == nil {
+			return a, nil
+		}
+	}
+	return nil, ErrDhcpOptionValue
+}
+
+// DecodeDhcpV4Option 的逆过程, value 的类型必须与注册的类型一致
+func EncodeDhcpV4Option(code uint8, value interface{}) (OptionsPacket, error) {
+	def, _ := LookupDhcpV4Option(code)
+	var b []byte
+	var err error
+	switch v := value.(type) {
+	case []byte:
+		switch def.Type {
+		case DHCP_DATA_OPAQUE, DHCP_DATA_UINT8_LIST, DHCP_DATA_OPTION_CODES:
+			b = v
+		default:
+			return OptionsPacket{}, ErrDhcpOptionValue
+		}
+	case IPv4:
+		if def.Type != DHCP_DATA_IPV4 && def.Type != DHCP_DATA_IPV4_LIST {
+			return OptionsPacket{}, ErrDhcpOptionValue
+		}
+		b = v[:]
+	case []IPv4:
+		if def.Type != DHCP_DATA_IPV4_LIST || len(v) < 1 {
+			return OptionsPacket{}, ErrDhcpOptionValue
+		}
+		for _, ip := range v {
+			b = append(b, ip[:]...)
+		}
+	case string:
+		if def.Type != DHCP_DATA_STRING {
+			return OptionsPacket{}, ErrDhcpOptionValue
+		}
+		b = []byte(v)
+	case uint8:
+		if def.Type != DHCP_DATA_UINT8 {
+			return OptionsPacket{}, ErrDhcpOptionValue
+		}
+		b = []byte{v}
+	case uint16:
+		if def.Type != DHCP_DATA_UINT16 {
+			return OptionsPacket{}, ErrDhcpOptionValue
+		}
+		b = binary.BigEndian.AppendUint16(nil, v)
+	case uint32:
+		if def.Type != DHCP_DATA_UINT32 {
+			return OptionsPacket{}, ErrDhcpOptionValue
+		}
+		b = binary.BigEndian.AppendUint32(nil, v)
+	case int32:
+		if def.Type != DHCP_DATA_INT32 {
+			return OptionsPacket{}, ErrDhcpOptionValue
+		}
+		b = binary.BigEndian.AppendUint32(nil, uint32(v))
+	case []uint16:
+		if def.Type != DHCP_DATA_UINT16_LIST || len(v) < 1 {
+			return OptionsPacket{}, ErrDhcpOptionValue
+		}
+		for _, n := range v {
+			b = binary.BigEndian.AppendUint16(b, n)
+		}
+	case bool:
+		switch {
+		case def.Type == DHCP_DATA_FLAG && v:
+			b = []byte{}
+		case def.Type == DHCP_DATA_BOOL && v:
+			b = []byte{1}
+		case def.Type == DHCP_DATA_BOOL:
+			b = []byte{0}
+		default:
+			return OptionsPacket{}, ErrDhcpOptionValue
+		}
+	case time.Duration:
+		if def.Type != DHCP_DATA_DURATION {
+			return OptionsPacket{}, ErrDhcpOptionValue
+		}
+		b = binary.BigEndian.AppendUint32(nil, dhcpSeconds(v))
+	case []OptionsPacket:
+		if def.Type != DHCP_DATA_NESTED {
+			return OptionsPacket{}, ErrDhcpOptionValue
+		}
+		for _, sub := range v {
+			b = append(b, sub.WireFormat()...)
+		}
+	case DHCP_Message_Type:
+		if def.Type != DHCP_DATA_MESSAGE_TYPE {
+			return OptionsPacket{}, ErrDhcpOptionValue
+		}
+		b = []byte{uint8(v)}
+	case DhcpV4ClientID:
+		if def.Type != DHCP_DATA_CLIENT_ID || len(v) < 1 {
+			return OptionsPacket{}, ErrDhcpOptionValue
+		}
+		b = v
+	case []string:
+		if def.Type != DHCP_DATA_DOMAIN_LIST {
+			return OptionsPacket{}, ErrDhcpOptionValue
+		}
+		b, err = EncodeDomainSearch(v...)
+	case []DhcpV4Route:
+		switch def.Type {
+		case DHCP_DATA_CLASSLESS_ROUTES:
+			b, err = EncodeDhcpV4ClasslessRoutes(v...)
+		case DHCP_DATA_STATIC_ROUTES:
+			opp := SetDHCPStaticRoutes(v...)
+			if opp.Code == 0 {
+				err = ErrDhcpOptionValue
+			}
+			b = opp.Value
+		default:
+			return OptionsPacket{}, ErrDhcpOptionValue
+		}
+	case DhcpV4ClientFQDN:
+		if def.Type != DHCP_DATA_CLIENT_FQDN {
+			return OptionsPacket{}, ErrDhcpOptionValue
+		}
+		b, err = v.Encode()
+	case DhcpV4Authentication:
+		if def.Type != DHCP_DATA_AUTHENTICATION {
+			return OptionsPacket{}, ErrDhcpOptionValue
+		}
+		b = v.Encode()
+	default:
+		return OptionsPacket{}, ErrDhcpOptionValue
+	}
+	if err != nil {
+		return OptionsPacket{}, err
+	}
+	return SetDHCPOption(code, append([]byte{}, b...)), nil
+}
+
+/*
+	JSON 格式: {"code": 51, "name": "IP Address Lease Time", "value": 3600}
+	value 按注册类型表示: 地址为 "192.168.1.1", 时间为秒数, 原始字节与子选项值为 "01:02:03",
+	消息类型为名称, 路由为 {"prefix": "10.0.0.0/8", "gateway": "192.168.1.1"}
+	值与注册类型不符或重新编码不能得到相同字节时以 "raw" 保存原始字节, 保证可以原样还原
+	解码时 code 为 0 则按 name 查找
+ */
+type dhcpV4OptionJSON struct {
+	Code 	uint8 			`json:"code"`
+	Name 	string 			`json:"name,omitempty"`
+	Value 	json.RawMessage `json:"value,omitempty"`
+	Raw 	string 			`json:"raw,omitempty"`
+	NUL 	bool 			`json:"nul,omitempty"`
+}
+
+type dhcpV4SubOptionJSON struct {
+	Code 	uint8 	`json:"code"`
+	Value 	string 	`json:"value"`
+}
+
+type dhcpV4RouteJSON struct {
+	Prefix 	string 	`json:"prefix"`
+	Gateway string 	`json:"gateway"`
+}
+
+type dhcpV4FQDNJSON struct {
+	Flags 	uint8 	`json:"flags"`
+	RCode1 	uint8 	`json:"rcode1"`
+	RCode2 	uint8 	`json:"rcode2"`
+	Name 	string 	`json:"name"`
+}
+
+type dhcpV4AuthJSON struct {
+	Protocol 		uint8 	`json:"protocol"`
+	Algorithm 		uint8 	`json:"algorithm"`
+	RDM 			uint8 	`json:"rdm"`
+	ReplayDetection uint64 	`json:"replay_detection"`
+	Info 			string 	`json:"info"`
+}
+
+func (opp OptionsPacket) MarshalJSON() ([]byte, error) {
+	def, _ := LookupDhcpV4Option(opp.Code)
+	out := dhcpV4OptionJSON{Code: opp.Code, Name: def.Name}
+	value, err := DecodeDhcpV4Option(opp)
+	if err == nil {
+		// 重新编码必须得到相同的字节, SetDHCPString 添加的 NUL 以 "nul" 标记
+		var check OptionsPacket
+		if check, err = EncodeDhcpV4Option(opp.Code, value); err == nil && !bytes.Equal(check.Value, opp.Value) {
+			if out.NUL = def.Type == DHCP_DATA_STRING && bytes.Equal(append(check.Value, 0), opp.Value); !out.NUL {
+				err = ErrDhcpOptionValue
+			}
+		}
+	}
+	if err == nil {
+		if value, err = dhcpV4JSONValue(def.Type, value); err == nil {
+			out.Value, err = json.Marshal(value)
+		}
+	}
+	if err != nil {
+		out.NUL = false
+		out.Value, out.Raw = nil, hexColon(opp.Value)
+	}
+	return json.Marshal(out)
+}
+
+func dhcpV4JSONValue(t DHCP_OPTION_DATA_TYPE, value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case []byte:
+		if t == DHCP_DATA_OPAQUE {
+			return hexColon(v), nil
+		}
+		list := make([]int, len(v))
+		for i, n := range v {
+			list[i] = int(n)
+		}
+		return list, nil
+	case IPv4:
+		return v.String(), nil
+	case []IPv4:
+		list := make([]string, len(v))
+		for i, ip := range v {
+			list[i] = ip.String()
+		}
+		return list, nil
+	case time.Duration:
+		return dhcpSeconds(v), nil
+	case []OptionsPacket:
+		list := make([]dhcpV4SubOptionJSON, len(v))
+		for i, sub := range v {
+			list[i] = dhcpV4SubOptionJSON{sub.Code, hexColon(sub.Value)}
+		}
+		return list, nil
+	case DHCP_Message_Type:
+		return v.String(), nil
+	case DhcpV4ClientID:
+		return v.String(), nil
+	case []DhcpV4Route:
+		list := make([]dhcpV4RouteJSON, len(v))
+		for i, r := range v {
+			list[i] = dhcpV4RouteJSON{r.Dst.String() + "/" + strconv.Itoa(int(r.PrefixLen)), r.Gateway.String()}
+		}
+		return list, nil
+	case DhcpV4ClientFQDN:
+		return dhcpV4FQDNJSON{v.Flags, v.RCode1, v.RCode2, v.Name}, nil
+	case DhcpV4Authentication:
+		return dhcpV4AuthJSON{v.Protocol, v.Algorithm, v.RDM, v.ReplayDetection, hexColon(v.Info)}, nil
+	}
+	return value, nil
+}
+
+func (opp *OptionsPacket) UnmarshalJSON(b []byte) error {
+	var in dhcpV4OptionJSON
+	if err := json.Unmarshal(b, &in); err != nil {
+		return err
+	}
+	if in.Code == 0 {
+		def, ok := LookupDhcpV4OptionByName(in.Name)
+		if !ok {
+			return ErrDhcpOptionUnknown
+		}
+		in.Code = def.Code
+	}
+	if in.Raw != "" || in.Value == nil {
+		raw, err := parseHexColon(in.Raw)
+		if err != nil {
+			return err
+		}
+		*opp = SetDHCPOption(in.Code, raw)
+		return nil
+	}
+	value, err := dhcpV4ParseJSONValue(in.Code, in.Value)
+	if err != nil {
+		return err
+	}
+	if *opp, err = EncodeDhcpV4Option(in.Code, value); err == nil && in.NUL {
+		*opp = SetDHCPOption(in.Code, append(opp.Value, 0))
+	}
+	return err
+}
+
+/*
+	DhcpV4Packet JSON 格式, options 中每个选项的格式同 OptionsPacket:
+	{"op": 1, "htype": 1, "hlen": 6, "hops": 0, "xid": 305419896, "secs": 0, "flags": 32768,
+	 "ciaddr": "0.0.0.0", "yiaddr": "0.0.0.0", "siaddr": "0.0.0.0", "giaddr": "0.0.0.0",
+	 "chaddr": "00:11:22:33:44:55", "sname": "", "file": "", "options": [...]}
+	chaddr 只保留前 hlen 字节, 其后不全为 0 时保留全部 16 字节
+	sname 与 file 不是以 NUL 结尾的 UTF-8 字符串时以 "sname_raw", "file_raw" 保存原始字节
+	vend 仅用于 BOOTP 报文 (Vend 不为 nil)
+ */
+type dhcpV4PacketJSON struct {
+	Op 				uint8 			`json:"op"`
+	HardwareType 	uint8 			`json:"htype"`
+	HardwareLen 	uint8 			`json:"hlen"`
+	Hops 			uint8 			`json:"hops"`
+	XID 			uint32 			`json:"xid"`
+	Secs 			uint16 			`json:"secs"`
+	Flags 			uint16 			`json:"flags"`
+	CIAddr 			IPv4 			`json:"ciaddr"`
+	YIAddr 			IPv4 			`json:"yiaddr"`
+	SIAddr 			IPv4 			`json:"siaddr"`
+	GIAddr 			IPv4 			`json:"giaddr"`
+	ChHardware 		string 			`json:"chaddr"`
+	HostName 		string 			`json:"sname"`
+	HostNameRaw 	string 			`json:"sname_raw,omitempty"`
+	FileName 		string 			`json:"file"`
+	FileNameRaw 	string 			`json:"file_raw,omitempty"`
+	Options 		[]OptionsPacket `json:"options,omitempty"`
+	Vend 			*string 		`json:"vend,omitempty"`
+}
+
+func (dhcp DhcpV4Packet) MarshalJSON() ([]byte, error) {
+	out := dhcpV4PacketJSON{
+		Op: dhcp.Op, HardwareType: dhcp.HardwareType, HardwareLen: dhcp.HardwareLen, Hops: dhcp.Hops,
+		XID: dhcp.XID, Secs: dhcp.Secs, Flags: dhcp.Flags,
+		CIAddr: dhcp.CIAddr, YIAddr: dhcp.YIAddr, SIAddr: dhcp.SIAddr, GIAddr: dhcp.GIAddr,
+		Options: dhcp.Options,
+	}
+	chaddr := dhcp.ChHardware[:]
+	if n := int(dhcp.HardwareLen); n < len(chaddr) && dhcpV4Zero(chaddr[n:]) {
+		chaddr = chaddr[:n]
+	}
+	out.ChHardware = hexColon(chaddr)
+	out.HostName, out.HostNameRaw = dhcpV4JSONCString(dhcp.HostName[:])
+	out.FileName, out.FileNameRaw = dhcpV4JSONCString(dhcp.FileName[:])
+	if dhcp.Vend != nil {
+		vend := hexColon(dhcp.Vend)
+		out.Vend = &vend
+	}
+	return json.Marshal(out)
+}
+
+func (dhcp *DhcpV4Packet) UnmarshalJSON(b []byte) error {
+	var in dhcpV4PacketJSON
+	if err := json.Unmarshal(b, &in); err != nil {
+		return err
+	}
+	*dhcp = DhcpV4Packet{
+		Op: in.Op, HardwareType: in.HardwareType, HardwareLen: in.HardwareLen, Hops: in.Hops,
+		XID: in.XID, Secs: in.Secs, Flags: in.Flags,
+		CIAddr: in.CIAddr, YIAddr: in.YIAddr, SIAddr: in.SIAddr, GIAddr: in.GIAddr,
+		Options: in.Options,
+	}
+	chaddr, err := parseHexColon(in.ChHardware)
+	if err != nil || len(chaddr) > len(dhcp.ChHardware) {
+		return ErrMACFormat
+	}
+	copy(dhcp.ChHardware[:], chaddr)
+	if err = dhcpV4ParseJSONCString(dhcp.HostName[:], in.HostName, in.HostNameRaw); err != nil {
+		return err
+	}
+	if err = dhcpV4ParseJSONCString(dhcp.FileName[:], in.FileName, in.FileNameRaw); err != nil {
+		return err
+	}
+	if in.Vend == nil {
+		dhcp.cookie = MagicCookie
+		return nil
+	}
+	if dhcp.Vend, err = parseHexColon(*in.Vend); err == nil && dhcp.Vend == nil {
+		dhcp.Vend = []byte{}
+	}
+	return err
+}
+
+// 字符串之后不全为 0 或不是 UTF-8 时返回原始字节
+func dhcpV4JSONCString(b []byte) (string, string) {
+	s := dhcpV4CString(b)
+	if dhcpV4Zero(b[len(s):]) && utf8.ValidString(s) {
+		return s, ""
+	}
+	return "", hexColon(b)
+}
+
+func dhcpV4ParseJSONCString(dst []byte, s, raw string) error {
+	b := []byte(s)
+	if raw != "" {
+		var err error
+		if b, err = parseHexColon(raw); err != nil {
+			return err
+		}
+	}
+	if len(b) > len(dst) {
+		return ErrDhcpOptionValue
+	}
+	copy(dst, b)
+	return nil
+}
+
+func dhcpV4ParseJSONValue(code uint8, raw json.RawMessage) (interface{}, error) {
+	def, _ := LookupDhcpV4Option(code)
+	var err error
+	switch def.Type {
+	case DHCP_DATA_IPV4:
+		var s string
+		if err = json.Unmarshal(raw, &s); err == nil {
+			return ParseIPv4(s)
+		}
+	case DHCP_DATA_IPV4_LIST:
+		var list []string
+		if err = json.Unmarshal(raw, &list); err == nil {
+			ips := make([]IPv4, len(list))
+			for i, s := range list {
+				if ips[i], err = ParseIPv4(s); err != nil {
+					return nil, err
+				}
+			}
+			return ips, nil
+		}
+	case DHCP_DATA_STRING:
+		var s string
+		err = json.Unmarshal(raw, &s)
+		return s, err
+	case DHCP_DATA_UINT8:
+		var n uint8
+		err = json.Unmarshal(raw, &n)
+		return n, err
+	case DHCP_DATA_UINT16:
+		var n uint16
+		err = json.Unmarshal(raw, &n)
+		return n, err
+	case DHCP_DATA_UINT32:
+		var n uint32
+		err = json.Unmarshal(raw, &n)
+		return n, err
+	case DHCP_DATA_INT32:
+		var n int32
+		err = json.Unmarshal(raw, &n)
+		return n, err
+	case DHCP_DATA_UINT8_LIST, DHCP_DATA_OPTION_CODES:
+		var list []uint8
+		// []uint8 按 base64 解码, 先读取为整数列表
+		var ints []uint16
+		if err = json.Unmarshal(raw, &ints); err == nil {
+			for _, n := range ints {
+				if n > 255 {
+					return nil, ErrDhcpOptionValue
+				}
+				list = append(list, uint8(n))
+			}
+			return list, nil
+		}
+	case DHCP_DATA_UINT16_LIST:
+		var list []uint16
+		err = json.Unmarshal(raw, &list)
+		return list, err
+	case DHCP_DATA_BOOL, DHCP_DATA_FLAG:
+		var v bool
+		err = json.Unmarshal(raw, &v)
+		return v, err
+	case DHCP_DATA_DURATION:
+		var n uint32
+		err = json.Unmarshal(raw, &n)
+		return time.Duration(n) * time.Second, err
+	case DHCP_DATA_NESTED:
+		var list []dhcpV4SubOptionJSON
+		if err = json.Unmarshal(raw, &list); err == nil {
+			subs := make([]OptionsPacket, len(list))
+			for i, sub := range list {
+				v, err := parseHexColon(sub.Value)
+				if err != nil {
+					return nil, err
+				}
+				subs[i] = SetDHCPOption(sub.Code, v)
+			}
+			return subs, nil
+		}
+	case DHCP_DATA_MESSAGE_TYPE:
+		var s string
+		if err = json.Unmarshal(raw, &s); err == nil {
+			for t := range dhcpV4MessageNames {
+				if dhcpV4MessageNames[t] != "" && strings.EqualFold(dhcpV4MessageNames[t], s) {
+					return DHCP_Message_Type(t), nil
+				}
+			}
+			return nil, ErrDhcpOptionValue
+		}
+		var n uint8
+		if json.Unmarshal(raw, &n) == nil {
+			return DHCP_Message_Type(n), nil
+		}
+	case DHCP_DATA_CLIENT_ID:
+		var id DhcpV4ClientID
+		err = json.Unmarshal(raw, &id)
+		return id, err
+	case DHCP_DATA_DOMAIN_LIST:
+		var names []string
+		err = json.Unmarshal(raw, &names)
+		return names, err
+	case DHCP_DATA_CLASSLESS_ROUTES, DHCP_DATA_STATIC_ROUTES:
+		var list []dhcpV4RouteJSON
+		if err = json.Unmarshal(raw, &list); err == nil {
+			routes := make([]DhcpV4Route, len(list))
+			for i, r := range list {
+				if routes[i], err = parseDhcpV4Route(r); err != nil {
+					return nil, err
+				}
+			}
+			return routes, nil
+		}
+	case DHCP_DATA_CLIENT_FQDN:
+		var v dhcpV4FQDNJSON
+		err = json.Unmarshal(raw, &v)
+		return DhcpV4ClientFQDN{v.Flags, v.RCode1, v.RCode2, v.Name}, err
+	case DHCP_DATA_AUTHENTICATION:
+		var v dhcpV4AuthJSON
+		if err = json.Unmarshal(raw, &v); err == nil {
+			info, err := parseHexColon(v.Info)
+			return DhcpV4Authentication{v.Protocol, v.Algorithm, v.RDM, v.ReplayDetection, info}, err
+		}
+	default:
+		var s string
+		if err = json.Unmarshal(raw, &s); err == nil {
+			return parseHexColon(s)
+		}
+	}
+	if err == nil {
+		err = ErrDhcpOptionValue
+	}
+	return nil, err
+}
+
+func parseDhcpV4Route(r dhcpV4RouteJSON) (DhcpV4Route, error) {
+	var route DhcpV4Route
+	dst, bits, ok := strings.Cut(r.Prefix, "/")
+	n, err := strconv.ParseUint(bits, 10, 8)
+	if !ok || err != nil || n > 32 {
+		return route, ErrDhcpOptionValue
+	}
+	if route.Dst, err = ParseIPv4(dst); err != nil {
+		return route, err
+	}
+	route.PrefixLen = uint8(n)
+	route.Gateway, err = ParseIPv4(r.Gateway)
+	return route, err
+}
@@ -0,0 +1,348 @@
+// @@
+// @ Author       : Eacher
+// @ Date         : 2026-10-20 13:04:51
+// @ LastEditTime : 2026-10-21 16:58:44
+// @ LastEditors  : Eacher
+// @ --------------------------------------------------------------------------------<
+// @ Description  : DHCP 中继代理与选项 82
+// @ --------------------------------------------------------------------------------<
+// @ FilePath     : /20yyq/packet/dhcpv4_relay.go
+// @@
+package packet
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"sync"
+)
+
+/*
+	RFC 3046  DHCP Relay Agent Information Option
+
+	       Code   Len     Agent Information Field
+	      +------+------+------+------+------+------+--...-+------+
+	      |  82  |   N  |  i1  |  i2  |  i3  |  i4  |      |  iN  |
+	      +------+------+------+------+------+------+--...-+------+
+
+	The Agent Information field consists of a sequence of SubOpt/Length/Value tuples
+	for each sub-option, encoded in the following manner:
+
+	       SubOpt  Len     Sub-option Value
+	      +------+------+------+------+------+------+--...-+------+
+	      |  1   |   N  |  s1  |  s2  |  s3  |  s4  |      |  sN  |
+	      +------+------+------+------+------+------+--...-+------+
+
+	RFC 3527  Link Selection sub-option (5), length 4
+	RFC 3993  Subscriber-ID sub-option (6), NVT ASCII
+	RFC 5107  Server Identifier Override sub-option (11), length 4
+ */
+const (
+	DHCP_Relay_Agent_Information = 82
+
+	// RFC 1542 4.1.1: hops 的缺省上限, hops 大于该值的请求被丢弃
+	DHCP_RELAY_MAX_HOPS = 0x04
+)
+
+type DHCP_RELAY_SUBOPTION_TYPE uint8
+
+const (
+	DHCP_Relay_Circuit_ID DHCP_RELAY_SUBOPTION_TYPE = iota + 1
+	DHCP_Relay_Remote_ID
+	DHCP_Relay_Link_Selection DHCP_RELAY_SUBOPTION_TYPE = 5
+	DHCP_Relay_Subscriber_ID DHCP_RELAY_SUBOPTION_TYPE = 6
+	DHCP_Relay_Server_ID_Override DHCP_RELAY_SUBOPTION_TYPE = 11
+)
+
+// 选项 82 的子选项列表, 子选项与 OptionsPacket 编码相同
+type DhcpV4RelayAgentInfo []OptionsPacket
+
+// 解析选项 82 的 Value, 子选项没有 pad/end, 截断时丢弃剩余部分
+func NewDhcpV4RelayAgentInfo(b []byte) (info DhcpV4RelayAgentInfo) {
+	for idx := 0; idx + SizeofOptionsPacket <= len(b); {
+		next := idx + SizeofOptionsPacket + int(b[idx+1])
+		if next > len(b) {
+			break
+		}
+		value := make([]byte, b[idx+1])
+		copy(value, b[idx+SizeofOptionsPacket:next])
+		info, idx = append(info, OptionsPacket{b[idx], b[idx+1], value}), next
+	}
+	return
+}
+
+func SetDHCPRelayAgentInfo(subs ...OptionsPacket) OptionsPacket {
+	var b []byte
+	for _, opp := range subs {
+		if opp.Code != 0 {
+			b = append(b, opp.WireFormat()...)
+		}
+	}
+	if len(b) < SizeofOptionsPacket || len(b) > 255 {
+		return OptionsPacket{}
+	}
+	return OptionsPacket{DHCP_Relay_Agent_Information, uint8(len(b)), b}
+}
+
+func (info DhcpV4RelayAgentInfo) OptionsPacket() OptionsPacket {
+	return SetDHCPRelayAgentInfo(info...)
+}
+
+func (info DhcpV4RelayAgentInfo) Get(t DHCP_RELAY_SUBOPTION_TYPE) ([]byte, bool) {
+	for _, opp := range info {
+		if opp.Code == uint8(t) {
+			return opp.Value, true
+		}
+	}
+	return nil, false
+}
+
+func (info DhcpV4RelayAgentInfo) CircuitID() ([]byte, bool) {
+	return info.Get(DHCP_Relay_Circuit_ID)
+}
+
+func (info DhcpV4RelayAgentInfo) RemoteID() ([]byte, bool) {
+	return info.Get(DHCP_Relay_Remote_ID)
+}
+
+func (info DhcpV4RelayAgentInfo) LinkSelection() (IPv4, bool) {
+	return info.ipv4(DHCP_Relay_Link_Selection)
+}
+
+func (info DhcpV4RelayAgentInfo) ServerIDOverride() (IPv4, bool) {
+	return info.ipv4(DHCP_Relay_Server_ID_Override)
+}
+
+func (info DhcpV4RelayAgentInfo) SubscriberID() (string, bool) {
+	b, ok := info.Get(DHCP_Relay_Subscriber_ID)
+	return string(b), ok
+}
+
+func (info DhcpV4RelayAgentInfo) ipv4(t DHCP_RELAY_SUBOPTION_TYPE) (IPv4, bool) {
+	if b, ok := info.Get(t); ok && len(b) == 4 {
+		return IPv4(b), true
+	}
+	return IPv4{}, false
+}
+
+func SetDHCPRelaySubOption(t DHCP_RELAY_SUBOPTION_TYPE, b []byte) OptionsPacket {
+	if len(b) < 1 || len(b) > 255 {
+		return OptionsPacket{}
+	}
+	return OptionsPacket{uint8(t), uint8(len(b)), b}
+}
+
+func SetDHCPRelayCircuitID(b []byte) OptionsPacket {
+	return SetDHCPRelaySubOption(DHCP_Relay_Circuit_ID, b)
+}
+
+func SetDHCPRelayRemoteID(b []byte) OptionsPacket {
+	return SetDHCPRelaySubOption(DHCP_Relay_Remote_ID, b)
+}
+
+func SetDHCPRelayLinkSelection(ip IPv4) OptionsPacket {
+	return SetDHCPRelaySubOption(DHCP_Relay_Link_Selection, ip[:])
+}
+
+func SetDHCPRelayServerIDOverride(ip IPv4) OptionsPacket {
+	return SetDHCPRelaySubOption(DHCP_Relay_Server_ID_Override, ip[:])
+}
+
+func SetDHCPRelaySubscriberID(s string) OptionsPacket {
+	return SetDHCPRelaySubOption(DHCP_Relay_Subscriber_ID, []byte(s))
+}
+
+func (dhcp DhcpV4Packet) RelayAgentInfo() (DhcpV4RelayAgentInfo, bool) {
+	if opp, ok := dhcp.Option(DHCP_Relay_Agent_Information); ok {
+		return NewDhcpV4RelayAgentInfo(opp.Value), true
+	}
+	return nil, false
+}
+
+// 面向客户端的中继接口
+type DhcpV4RelayInterface struct {
+	// 面向客户端的传输, 通常为 NewDhcpV4RawTransport(link, mac, IP, DHCP_ServerPort, DHCP_ClientPort)
+	Transport 			DhcpV4Transport
+	// 接口地址, 作为 GIAddr
+	IP 					IPv4
+	CircuitID 			[]byte
+	RemoteID 			[]byte
+	SubscriberID 		string
+	// RFC 3527: 客户端子网与 GIAddr 不同时填写客户端子网地址
+	LinkSelection 		IPv4
+	// RFC 5107: 插入以 IP 为值的 Server Identifier Override, 客户端续约将发往本中继
+	ServerIDOverride 	bool
+	// 信任客户端侧收到的选项 82 (GIAddr 为零), RFC 3046 2.1 缺省丢弃
+	Trusted 			bool
+}
+
+func (ri *DhcpV4RelayInterface) agentInfo() OptionsPacket {
+	subs := []OptionsPacket{SetDHCPRelayCircuitID(ri.CircuitID), SetDHCPRelayRemoteID(ri.RemoteID)}
+	if ri.LinkSelection != (IPv4{}) {
+		subs = append(subs, SetDHCPRelayLinkSelection(ri.LinkSelection))
+	}
+	if ri.SubscriberID != "" {
+		subs = append(subs, SetDHCPRelaySubscriberID(ri.SubscriberID))
+	}
+	if ri.ServerIDOverride {
+		subs = append(subs, SetDHCPRelayServerIDOverride(ri.IP))
+	}
+	return SetDHCPRelayAgentInfo(subs...)
+}
+
+/*
+	RFC 1542 4.1  BOOTP Relay Agent behavior
+	RFC 2131 4.1 / RFC 3046 2.1  Relay Agent Operation
+
+	客户端请求: 检查并递增 Hops, GIAddr 为零时填写接口地址并追加选项 82, 转发给所有 Servers
+	服务端应答: 按 GIAddr (及 Circuit ID) 找到接口, 去除选项 82, 按广播标志广播或者单播给客户端
+	收到的报文使用了选项 52 时转发前按原报文长度再次溢出, 不会比收到的报文更长
+ */
+type DhcpV4Relay struct {
+	Interfaces 	[]*DhcpV4RelayInterface
+	// 面向服务端的传输, 通常为绑定 DHCP_ServerPort 的 NewDhcpV4UDPTransport(conn, DHCP_ServerPort)
+	Upstream 	DhcpV4Transport
+	Servers 	[]IPv4
+	MaxHops 	uint8
+	// 插入选项 82
+	AgentInfo 	bool
+	// Serve 转发单个报文出错时回调, 出错的报文被丢弃, Serve 继续运行
+	OnError 	func(err error)
+}
+
+var ErrDhcpRelayNoServer = errors.New("dhcp: relay has no server")
+
+// 处理客户端请求, 返回需要转发给服务端的报文
+func (r *DhcpV4Relay) HandleRequest(ri *DhcpV4RelayInterface, req DhcpV4Packet) (DhcpV4Packet, bool) {
+	maxHops := r.MaxHops
+	if maxHops == 0 {
+		maxHops = DHCP_RELAY_MAX_HOPS
+	}
+	if req.Op != DHCP_BOOTREQUEST || req.Hops > maxHops {
+		return DhcpV4Packet{}, false
+	}
+	req.Hops++
+	if req.GIAddr != (IPv4{}) {
+		// 已经过其它中继, 保留原有 GIAddr 与选项 82
+		req, _ = req.reoverload(0)
+		return req, true
+	}
+	_, has := req.Option(DHCP_Relay_Agent_Information)
+	if has && !ri.Trusted {
+		return DhcpV4Packet{}, false
+	}
+	req.GIAddr = ri.IP
+	// 不含 MagicCookie 的 BOOTP 请求没有选项区域
+	if r.AgentInfo && !has && req.HasMagicCookie() {
+		// RFC 3046 2.1: 选项 82 为选项区的最后一个选项, 再次溢出后放不下时不添加
+		if opp := ri.agentInfo(); opp.Code != 0 {
+			if dhcp, ok := req.reoverload(len(opp.WireFormat())); ok {
+				dhcp.Options = append(append(DhcpV4Options(nil), dhcp.Options...), opp)
+				return dhcp, true
+			}
+		}
+	}
+	req, _ = req.reoverload(0)
+	return req, true
+}
+
+// 处理服务端应答, 返回客户端侧接口, 去除选项 82 后的报文与目的地址
+func (r *DhcpV4Relay) HandleReply(reply DhcpV4Packet) (*DhcpV4RelayInterface, DhcpV4Packet, IPv4, bool) {
+	if reply.Op != DHCP_BOOTREPLY || reply.GIAddr == (IPv4{}) {
+		return nil, DhcpV4Packet{}, IPv4{}, false
+	}
+	info, has := reply.RelayAgentInfo()
+	circuit, _ := info.CircuitID()
+	var ri *DhcpV4RelayInterface
+	for _, v := range r.Interfaces {
+		if v.IP != reply.GIAddr {
+			continue
+		}
+		if ri == nil || has && bytes.Equal(v.CircuitID, circuit) {
+			ri = v
+		}
+	}
+	if ri == nil {
+		return nil, DhcpV4Packet{}, IPv4{}, false
+	}
+	if has {
+		options := append(DhcpV4Options(nil), reply.Options...)
+		options.Del(DHCP_Relay_Agent_Information)
+		reply.Options = options
+	}
+	reply, _ = reply.reoverload(0)
+	dst := reply.YIAddr
+	switch t, _ := reply.MessageType(); {
+	case t == DHCP_NAK || reply.Flags & DHCP_BROADCAST_FLAG != 0:
+		dst = IPv4Broadcast
+	case reply.CIAddr != (IPv4{}):
+		dst = reply.CIAddr
+	case dst == (IPv4{}):
+		dst = IPv4Broadcast
+	}
+	return ri, reply, dst, true
+}
+
+// 转发直到 ctx 结束或者任一传输读取出错, 发送失败交给 OnError 后继续
+func (r *DhcpV4Relay) Serve(ctx context.Context) error {
+	if len(r.Servers) == 0 {
+		return ErrDhcpRelayNoServer
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var wg sync.WaitGroup
+	var once sync.Once
+	var first error
+	run := func(tr DhcpV4Transport, handle func(DhcpV4Packet)) {
+		defer wg.Done()
+		err := dhcpV4Receive(ctx, tr, handle)
+		once.Do(func() { first = err })
+		cancel()
+	}
+	wg.Add(len(r.Interfaces) + 1)
+	for _, ri := range r.Interfaces {
+		ri := ri
+		go run(ri.Transport, func(dhcp DhcpV4Packet) {
+			req, ok := r.HandleRequest(ri, dhcp)
+			if !ok {
+				return
+			}
+			for _, server := range r.Servers {
+				if err := r.Upstream.Send(req, server); err != nil {
+					r.error(err)
+				}
+			}
+		})
+	}
+	go run(r.Upstream, func(dhcp DhcpV4Packet) {
+		if ri, reply, dst, ok := r.HandleReply(dhcp); ok {
+			if err := ri.Transport.Send(reply, dst); err != nil {
+				r.error(err)
+			}
+		}
+	})
+	wg.Wait()
+	return first
+}
+
+func (r *DhcpV4Relay) error(err error) {
+	if r.OnError != nil {
+		r.OnError(err)
+	}
+}
+
+func dhcpV4Receive(ctx context.Context, tr DhcpV4Transport, handle func(DhcpV4Packet)) error {
+	lw := watchLink(ctx, tr)
+	defer lw.Close()
+	for {
+		dhcp, _, err := tr.Receive()
+		if err != nil {
+			if errors.Is(err, os.ErrDeadlineExceeded) && ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+		handle(dhcp)
+	}
+}
@@ -0,0 +1,178 @@
+// @@
+// @ Author       : Eacher
+// @ Date         : 2026-10-21 15:58:26
+// @ LastEditTime : 2026-10-21 16:58:44
+// @ LastEditors  : Eacher
+// @ --------------------------------------------------------------------------------<
+// @ Description  : DHCP 中继测试
+// @ --------------------------------------------------------------------------------<
+// @ FilePath     : /20yyq/packet/dhcpv4_relay_test.go
+// @@
+package packet
+
+import (
+	"bytes"
+	"context"
+	"testing"
+)
+
+var dhcpV4TestRelayIP = IPv4{10, 0, 0, 2}
+
+func TestDhcpV4RelayHandleRequest(t *testing.T) {
+	ri := &DhcpV4RelayInterface{IP: dhcpV4TestRelayIP, CircuitID: []byte("eth1"), RemoteID: []byte("sw1")}
+	r := &DhcpV4Relay{Interfaces: []*DhcpV4RelayInterface{ri}, AgentInfo: true}
+	mac := HardwareAddr{0x02, 0, 0, 0, 0, 0x40}
+
+	req, ok := r.HandleRequest(ri, newDhcpV4TestRequest(mac, 1, SetDHCPMessage(DHCP_DISCOVER)))
+	if !ok || req.GIAddr != dhcpV4TestRelayIP || req.Hops != 1 {
+		t.Fatalf("HandleRequest() = giaddr %v hops %d, %v; want %v, 1", req.GIAddr, req.Hops, ok, dhcpV4TestRelayIP)
+	}
+	info, has := NewDhcpV4Packet(req.WireFormat()).RelayAgentInfo()
+	circuit, _ := info.CircuitID()
+	remote, _ := info.RemoteID()
+	if !has || !bytes.Equal(circuit, ri.CircuitID) || !bytes.Equal(remote, ri.RemoteID) {
+		t.Errorf("option 82 = %v, %v; want circuit %q remote %q", info, has, ri.CircuitID, ri.RemoteID)
+	}
+
+	tests := []struct {
+		name 	string
+		hops 	uint8
+		giaddr 	IPv4
+		options []OptionsPacket
+		ok 		bool
+	}{
+		{"max hops", DHCP_RELAY_MAX_HOPS, IPv4{}, nil, true},
+		{"over max hops", DHCP_RELAY_MAX_HOPS + 1, IPv4{}, nil, false},
+		{"relayed", 1, IPv4{10, 9, 0, 1}, nil, true},
+		{"untrusted option 82", 0, IPv4{}, []OptionsPacket{SetDHCPRelayAgentInfo(SetDHCPRelayCircuitID([]byte("x")))}, false},
+	}
+	for _, tt := range tests {
+		req := newDhcpV4TestRequest(mac, 2, append([]OptionsPacket{SetDHCPMessage(DHCP_REQUEST)}, tt.options...)...)
+		req.Hops, req.GIAddr = tt.hops, tt.giaddr
+		got, ok := r.HandleRequest(ri, req)
+		if ok != tt.ok {
+			t.Errorf("%s: HandleRequest() ok = %v; want %v", tt.name, ok, tt.ok)
+			continue
+		}
+		if ok && tt.giaddr != (IPv4{}) && (got.GIAddr != tt.giaddr || len(got.Options) != 1) {
+			t.Errorf("%s: relayed request changed: giaddr %v options %v", tt.name, got.GIAddr, got.Options)
+		}
+	}
+}
+
+// 选项 52 溢出的报文转发后仍然溢出, 长度不超过收到的报文
+func TestDhcpV4RelayOverload(t *testing.T) {
+	ri := &DhcpV4RelayInterface{IP: dhcpV4TestRelayIP, CircuitID: []byte("eth1"), RemoteID: []byte("sw1")}
+	r := &DhcpV4Relay{Interfaces: []*DhcpV4RelayInterface{ri}, AgentInfo: true}
+	long := bytes.Repeat([]byte{0xab}, 400)
+	req, ok := newDhcpV4TestRequest(HardwareAddr{0x02, 0, 0, 0, 0, 0x41}, 3, SetDHCPMessage(DHCP_DISCOVER), SetDHCPOption(224, long)).Overload(576)
+	if !ok {
+		t.Fatal("Overload(576) = false")
+	}
+	b := req.WireFormat()
+	if opp, ok := NewDhcpV4Packet(b).Option(224); !ok || opp.Len() != len(long) || opp.Length != 255 {
+		t.Errorf("long option Len() = %d Length %d; want %d, 255", opp.Len(), opp.Length, len(long))
+	}
+
+	got, ok := r.HandleRequest(ri, NewDhcpV4Packet(b))
+	out := got.WireFormat()
+	if !ok || len(out) > len(b) {
+		t.Fatalf("HandleRequest() = %d bytes, %v; want at most %d", len(out), ok, len(b))
+	}
+	relayed := NewDhcpV4Packet(out)
+	if opp, _ := relayed.Option(224); !bytes.Equal(opp.Value, long) {
+		t.Errorf("relayed long option = %d bytes; want %d", opp.Len(), len(long))
+	}
+	if _, has := relayed.RelayAgentInfo(); !has {
+		t.Error("relayed request has no option 82")
+	}
+
+	reply, _ := relayed.Overload(576)
+	reply.Op = DHCP_BOOTREPLY
+	b = reply.WireFormat()
+	_, got, _, ok = r.HandleReply(NewDhcpV4Packet(b))
+	if out = got.WireFormat(); !ok || len(out) > len(b) {
+		t.Fatalf("HandleReply() = %d bytes, %v; want at most %d", len(out), ok, len(b))
+	}
+	if opp, _ := NewDhcpV4Packet(out).Option(224); !bytes.Equal(opp.Value, long) {
+		t.Errorf("reply long option = %d bytes; want %d", opp.Len(), len(long))
+	}
+}
+
+// 记录服务端收到的请求
+type dhcpV4RecordTransport struct {
+	DhcpV4Transport
+	requests 	chan DhcpV4Packet
+}
+
+func (rt *dhcpV4RecordTransport) Receive() (DhcpV4Packet, IPv4, error) {
+	dhcp, src, err := rt.DhcpV4Transport.Receive()
+	if err == nil {
+		select {
+		case rt.requests <- dhcp:
+		default:
+		}
+	}
+	return dhcp, src, err
+}
+
+// 客户端 <-> 中继 <-> 服务端, 均运行在内存链路上
+func TestDhcpV4RelayServe(t *testing.T) {
+	mac := HardwareAddr{0x02, 0, 0, 0, 0, 0x41}
+	relayMAC := HardwareAddr{0x02, 0, 0, 0, 0, 0x02}
+	sa, sb := NewFramePipe()
+	server := &dhcpV4RecordTransport{NewDhcpV4RawTransport(sb, dhcpV4TestServerMAC, dhcpV4TestServerIP, DHCP_ServerPort, DHCP_ClientPort), make(chan DhcpV4Packet, 4)}
+	s := &DhcpV4Server{
+		ServerID: 	dhcpV4TestServerIP,
+		Subnets: 	[]*DhcpV4Subnet{newDhcpV4TestSubnet()},
+		Store: 		NewDhcpV4MemoryStore(),
+		Transport: 	server,
+	}
+	ca, cb := NewFramePipe()
+	ri := &DhcpV4RelayInterface{
+		Transport: 	NewDhcpV4RawTransport(cb, relayMAC, dhcpV4TestRelayIP, DHCP_ServerPort, DHCP_ClientPort),
+		IP: 		dhcpV4TestRelayIP,
+		CircuitID: 	[]byte("eth1"),
+	}
+	r := &DhcpV4Relay{
+		Interfaces: []*DhcpV4RelayInterface{ri},
+		Upstream: 	NewDhcpV4RawTransport(sa, relayMAC, dhcpV4TestRelayIP, DHCP_ServerPort, DHCP_ServerPort),
+		Servers: 	[]IPv4{dhcpV4TestServerIP},
+		AgentInfo: 	true,
+		OnError: 	func(err error) { t.Error(err) },
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 2)
+	go func() { done <- s.Serve(ctx) }()
+	go func() { done <- r.Serve(ctx) }()
+	defer func() {
+		cancel()
+		<-done
+		<-done
+		ca.Close()
+	}()
+	client := NewDhcpV4RawTransport(ca, HardwareAddr{}, IPv4{}, DHCP_ClientPort, DHCP_ServerPort)
+
+	offer := dhcpV4TestExchange(t, client, newDhcpV4TestRequest(mac, 1, SetDHCPMessage(DHCP_DISCOVER)), DHCP_OFFER)
+	if offer.GIAddr != dhcpV4TestRelayIP || offer.YIAddr != (IPv4{10, 0, 0, 10}) {
+		t.Errorf("OFFER giaddr %v yiaddr %v; want %v, 10.0.0.10", offer.GIAddr, offer.YIAddr, dhcpV4TestRelayIP)
+	}
+	// 服务端回显的选项 82 在转发给客户端前被去除
+	if _, has := offer.RelayAgentInfo(); has {
+		t.Errorf("OFFER to client still carries option 82")
+	}
+	select {
+	case req := <-server.requests:
+		info, _ := req.RelayAgentInfo()
+		if circuit, _ := info.CircuitID(); req.GIAddr != dhcpV4TestRelayIP || req.Hops != 1 || !bytes.Equal(circuit, ri.CircuitID) {
+			t.Errorf("server got giaddr %v hops %d circuit %q; want %v, 1, %q", req.GIAddr, req.Hops, circuit, dhcpV4TestRelayIP, ri.CircuitID)
+		}
+	default:
+		t.Errorf("server recorded no request")
+	}
+	dhcpV4TestExchange(t, client, newDhcpV4TestRequest(mac, 2, SetDHCPMessage(DHCP_REQUEST),
+		SetDHCPIPv4(DHCP_Server_Identifier, dhcpV4TestServerIP), SetDHCPIPv4(DHCP_Requested_IP_Address, offer.YIAddr)), DHCP_ACK)
+	if leases := s.Leases(); len(leases) != 1 || leases[0].State != DHCP_LEASE_BOUND {
+		t.Errorf("leases = %+v; want one bound", leases)
+	}
+}
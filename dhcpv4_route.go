@@ -0,0 +1,204 @@
+// @@
+// @ Author       : Eacher
+// @ Date         : 2026-10-20 16:30:26
+// @ LastEditTime : 2026-10-20 16:30:26
+// @ LastEditors  : Eacher
+// @ --------------------------------------------------------------------------------<
+// @ Description  : DHCPv4 路由选项 33/121/249
+// @ --------------------------------------------------------------------------------<
+// @ FilePath     : /20yyq/packet/dhcpv4_route.go
+// @@
+package packet
+
+import (
+	"errors"
+)
+
+/*
+5.8. Static Route Option
+	This option specifies a list of static routes that the client should
+	install in its routing cache.  If multiple routes to the same
+	destination are specified, they are listed in descending order of
+	priority.
+
+	The routes consist of a list of IP address pairs.  The first address
+	is the destination address, and the second address is the router for
+	the destination.
+
+	The default route (0.0.0.0) is an illegal destination for a static
+	route.
+
+	The code for this option is 33.  The minimum length of this option is
+	8, and the length MUST be a multiple of 8.
+
+	Code   Len         Destination 1           Router 1
+	+-----+-----+-----+-----+-----+-----+-----+-----+-----+-----+
+	|  33 |  n  |  d1 |  d2 |  d3 |  d4 |  r1 |  r2 |  r3 |  r4 |
+	+-----+-----+-----+-----+-----+-----+-----+-----+-----+-----+
+
+RFC 3442  The Classless Static Route Option for DHCPv4
+	In order to minimize the size of the option, the destination of each route
+	is encoded as the subnet mask width followed by the significant octets of the
+	subnet number:
+
+	     Subnet number   Subnet mask      Destination descriptor
+	     0               0                0
+	     10.0.0.0        255.0.0.0        8.10
+	     10.0.0.0        255.255.255.0    24.10.0.0
+	     10.17.0.0       255.255.0.0      16.10.17
+	     10.27.129.0     255.255.255.0    24.10.27.129
+	     10.229.0.128    255.255.255.128  25.10.229.0.128
+	     10.198.122.47   255.255.255.255  32.10.198.122.47
+
+	If the DHCP server returns both a Classless Static Routes option and a Router
+	option, the DHCP client MUST ignore the Router option.  Similarly, if the DHCP
+	server returns both a Classless Static Routes option and a Static Routes option,
+	the DHCP client MUST ignore the Static Routes option.
+
+	Microsoft 客户端使用相同格式的选项 249
+ */
+type DHCP_ROUTE_TYPE uint8
+
+const (
+	DHCP_Static_Route DHCP_ROUTE_TYPE = 33
+	DHCP_Classless_Static_Route DHCP_ROUTE_TYPE = 121
+	DHCP_Classless_Static_Route_MS DHCP_ROUTE_TYPE = 249
+)
+
+var ErrDhcpRouteFormat = errors.New("dhcp: malformed static route option")
+
+// Gateway 为 0.0.0.0 表示目的网络与客户端直连
+type DhcpV4Route struct {
+	Dst 		IPv4
+	PrefixLen 	uint8
+	Gateway 	IPv4
+}
+
+func (r DhcpV4Route) Prefix() IPv4Prefix {
+	return IPv4Prefix{IP: r.Dst, Bits: r.PrefixLen}
+}
+
+// 前缀长度不超过 32 且主机位为零
+func (r DhcpV4Route) Validate() error {
+	if r.PrefixLen > 32 || r.Dst.Uint32() & ^r.Prefix().Mask().Uint32() != 0 {
+		return ErrDhcpRouteFormat
+	}
+	return nil
+}
+
+func EncodeDhcpV4ClasslessRoutes(routes ...DhcpV4Route) ([]byte, error) {
+	var b []byte
+	for _, r := range routes {
+		if err := r.Validate(); err != nil {
+			return nil, err
+		}
+		b = append(append(b, r.PrefixLen), r.Dst[:(r.PrefixLen + 7) / 8]...)
+		b = append(b, r.Gateway[:]...)
+	}
+	return b, nil
+}
+
+func NewDhcpV4ClasslessRoutes(b []byte) ([]DhcpV4Route, error) {
+	var routes []DhcpV4Route
+	for idx := 0; idx < len(b); {
+		r := DhcpV4Route{PrefixLen: b[idx]}
+		if r.PrefixLen > 32 {
+			return nil, ErrDhcpRouteFormat
+		}
+		n := int(r.PrefixLen + 7) / 8
+		if idx + 1 + n + 4 > len(b) {
+			return nil, ErrDhcpRouteFormat
+		}
+		copy(r.Dst[:], b[idx+1:idx+1+n])
+		r.Gateway = IPv4(b[idx+1+n:])
+		if err := r.Validate(); err != nil {
+			return nil, err
+		}
+		routes, idx = append(routes, r), idx + 1 + n + 4
+	}
+	return routes, nil
+}
+
+// 选项 33 的目的地址按地址类别推导前缀, 类别之外仍有主机位时视为主机路由
+func NewDhcpV4StaticRoutes(b []byte) ([]DhcpV4Route, error) {
+	if len(b) % 8 != 0 {
+		return nil, ErrDhcpRouteFormat
+	}
+	routes := make([]DhcpV4Route, 0, len(b) / 8)
+	for idx := 0; idx < len(b); idx += 8 {
+		r := DhcpV4Route{Dst: IPv4(b[idx:]), Gateway: IPv4(b[idx+4:])}
+		if r.Dst == (IPv4{}) {
+			return nil, ErrDhcpRouteFormat
+		}
+		switch {
+		case r.Dst[0] < 0x80:
+			r.PrefixLen = 8
+		case r.Dst[0] < 0xc0:
+			r.PrefixLen = 16
+		default:
+			r.PrefixLen = 24
+		}
+		if r.Validate() != nil {
+			r.PrefixLen = 32
+		}
+		routes = append(routes, r)
+	}
+	return routes, nil
+}
+
+// 选项 121/249, 路由不合法时返回空选项
+func SetDHCPClasslessRoutes(t DHCP_ROUTE_TYPE, routes ...DhcpV4Route) OptionsPacket {
+	b, err := EncodeDhcpV4ClasslessRoutes(routes...)
+	if err != nil || len(b) < 5 || t == DHCP_Static_Route {
+		return OptionsPacket{}
+	}
+	return SetDHCPOption(uint8(t), b)
+}
+
+// 选项 33, 只编码目的地址与网关
+func SetDHCPStaticRoutes(routes ...DhcpV4Route) OptionsPacket {
+	if len(routes) < 1 {
+		return OptionsPacket{}
+	}
+	b := make([]byte, 0, len(routes) * 8)
+	for _, r := range routes {
+		if r.Dst == (IPv4{}) {
+			return OptionsPacket{}
+		}
+		b = append(append(b, r.Dst[:]...), r.Gateway[:]...)
+	}
+	return SetDHCPOption(uint8(DHCP_Static_Route), b)
+}
+
+// 选项 121, 不存在时使用选项 249
+func (dhcp DhcpV4Packet) ClasslessStaticRoutes() ([]DhcpV4Route, bool) {
+	for _, t := range []DHCP_ROUTE_TYPE{DHCP_Classless_Static_Route, DHCP_Classless_Static_Route_MS} {
+		if opp, ok := dhcp.Option(uint8(t)); ok {
+			routes, err := NewDhcpV4ClasslessRoutes(opp.Value)
+			return routes, err == nil && len(routes) > 0
+		}
+	}
+	return nil, false
+}
+
+func (dhcp DhcpV4Packet) StaticRoutes() ([]DhcpV4Route, bool) {
+	if opp, ok := dhcp.Option(uint8(DHCP_Static_Route)); ok {
+		routes, err := NewDhcpV4StaticRoutes(opp.Value)
+		return routes, err == nil && len(routes) > 0
+	}
+	return nil, false
+}
+
+// 客户端应安装的路由: 存在选项 121/249 时忽略选项 3 与 33, 否则为选项 33 与选项 3 生成的缺省路由
+func (dhcp DhcpV4Packet) EffectiveRoutes() []DhcpV4Route {
+	if routes, ok := dhcp.ClasslessStaticRoutes(); ok {
+		return routes
+	}
+	routes, _ := dhcp.StaticRoutes()
+	if routers, ok := dhcp.Routers(); ok {
+		for _, gw := range routers {
+			routes = append(routes, DhcpV4Route{Gateway: gw})
+		}
+	}
+	return routes
+}
@@ -0,0 +1,759 @@
+// @@
+// @ Author       : Eacher
+// @ Date         : 2026-10-20 10:36:05
+// @ LastEditTime : 2026-10-21 14:08:31
+// @ LastEditors  : Eacher
+// @ --------------------------------------------------------------------------------<
+// @ Description  : RFC 2131 DHCPv4 服务端
+// @ --------------------------------------------------------------------------------<
+// @ FilePath     : /20yyq/packet/dhcpv4_server.go
+// @@
+package packet
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	DHCP_SERVER_LEASE_TIME 		= 12 * time.Hour
+	// DHCPOFFER 保留地址的时间
+	DHCP_SERVER_OFFER_TIME 		= time.Minute
+	// 被拒绝或者探测冲突的地址隔离时间
+	DHCP_SERVER_DECLINE_TIME 	= time.Hour
+	DHCP_SERVER_PING_TIMEOUT 	= 500 * time.Millisecond
+	// 一次 DHCPDISCOVER 最多探测的地址数
+	DHCP_SERVER_PING_ATTEMPTS 	= 0x08
+	DHCP_SERVER_EXPIRE_INTERVAL = time.Minute
+	// Serve 同时处理的请求数
+	DHCP_SERVER_MAX_HANDLERS 	= 0x40
+)
+
+var (
+	ErrDhcpNoSubnet 	= errors.New("dhcp: no subnet for request")
+	ErrDhcpNoLeaseStore = errors.New("dhcp: server has no lease store")
+)
+
+// 闭区间地址段
+type DhcpV4Range struct {
+	Start 	IPv4
+	End 	IPv4
+}
+
+func (r DhcpV4Range) Contains(ip IPv4) bool {
+	u := ip.Uint32()
+	return r.Start.Uint32() <= u && u <= r.End.Uint32()
+}
+
+// 静态分配, ClientID 不为空时按选项 61 匹配, 否则按 ChHardware 匹配
+type DhcpV4Reservation struct {
+	HardwareAddr 	HardwareAddr
+	ClientID 		DhcpV4ClientID
+	IP 				IPv4
+	// 覆盖子网中相同 Code 的选项
+	Options 		[]OptionsPacket
+}
+
+func (r DhcpV4Reservation) Match(mac HardwareAddr, clientID DhcpV4ClientID) bool {
+	if len(r.ClientID) > 0 {
+		return bytes.Equal(r.ClientID, clientID)
+	}
+	return r.HardwareAddr == mac
+}
+
+type DhcpV4Subnet struct {
+	Prefix 			IPv4Prefix
+	// 动态地址池, 不含网络地址, 广播地址与 Exclude
+	Ranges 			[]DhcpV4Range
+	Exclude 		[]DhcpV4Range
+	Reservations 	[]DhcpV4Reservation
+	// 下发的选项, 例如 SetDHCPIPv4(DHCP_Router, ...), 选项 1 由 Prefix 生成
+	Options 		[]OptionsPacket
+	// 为零时使用 DhcpV4Server.LeaseTime
+	LeaseTime 		time.Duration
+	// 不为 nil 时为网络启动客户端填写启动文件, 不受参数请求列表限制
+	Boot 			*DhcpV4BootPolicy
+}
+
+func (sn *DhcpV4Subnet) reservation(mac HardwareAddr, clientID DhcpV4ClientID) (DhcpV4Reservation, bool) {
+	for _, r := range sn.Reservations {
+		if r.Match(mac, clientID) {
+			return r, true
+		}
+	}
+	return DhcpV4Reservation{}, false
+}
+
+// 地址是否可以动态分配给 (mac, clientID)
+func (sn *DhcpV4Subnet) allocatable(ip IPv4, mac HardwareAddr, clientID DhcpV4ClientID) bool {
+	if !sn.Prefix.Contains(ip) || ip == sn.Prefix.IP || ip.Uint32() | ^sn.Prefix.Mask().Uint32() == ip.Uint32() {
+		return false
+	}
+	for _, r := range sn.Exclude {
+		if r.Contains(ip) {
+			return false
+		}
+	}
+	for _, r := range sn.Reservations {
+		if r.IP == ip {
+			return r.Match(mac, clientID)
+		}
+	}
+	for _, r := range sn.Ranges {
+		if r.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+	RFC 2131 4.3  DHCP server behavior
+
+	Serve 从 Transport 读取请求, 调用 Handle 并发送应答, Handle 可以直接用于进程内测试
+	Handle 可以并发调用, Conflict 探测期间不持有锁
+	子网选择: GIAddr 不为零时取包含 GIAddr 的子网, 否则取包含 CIAddr 或者 ServerID 的子网
+ */
+type DhcpV4Server struct {
+	Transport 		DhcpV4Transport
+	// 选项 54, 同时作为本地子网的选择依据
+	ServerID 		IPv4
+	Subnets 		[]*DhcpV4Subnet
+	// 为 nil 时使用 NewDhcpV4FileStore(LeaseFile), 重启后保留租约; 只需内存保存时设置为 NewDhcpV4MemoryStore()
+	Store 			DhcpV4LeaseStore
+	// 租约文件路径, Store 与 LeaseFile 均未设置时返回 ErrDhcpNoLeaseStore
+	LeaseFile 		string
+	// 不为 nil 时分配新地址前探测是否已被占用, 参见 NewDhcpV4ArpPing, NewDhcpV4IcmpPing
+	Conflict 		func(ctx context.Context, ip IPv4) (bool, error)
+	// 对不属于本服务端的 INIT-REBOOT 请求回复 DHCPNAK
+	Authoritative 	bool
+	// 应答不含选项 53 的 BOOTP 请求, 分配永久地址 (RFC 1534)
+	Bootp 			bool
+	// Serve 同时处理的请求数上限, 为零时使用 DHCP_SERVER_MAX_HANDLERS
+	MaxHandlers 	int
+	// 对携带选项 80 的 DHCPDISCOVER 直接分配地址并回复 DHCPACK (RFC 4039)
+	RapidCommit 	bool
+	// 对携带选项 145 的客户端在 DHCPACK 中下发 nonce, 用于签名 DHCPFORCERENEW (RFC 6704)
+	ForcerenewNonce bool
+	// 不为 nil 时响应 DHCPLEASEQUERY (RFC 4388), 返回 false 时忽略该请求者 (GIAddr)
+	Leasequery 		func(requestor IPv4) bool
+
+	LeaseTime 		time.Duration
+	OfferTime 		time.Duration
+	DeclineTime 	time.Duration
+
+	// 租约变更回调, t 为触发变更的客户端报文类型, BOOTP 请求时为 0
+	OnLease 		func(t DHCP_Message_Type, r DhcpV4LeaseRecord)
+	// Serve 处理单个报文或者定期 Expire 出错时回调, 出错的报文被丢弃, Serve 继续运行
+	OnError 		func(err error)
+
+	mu 				sync.Mutex
+	leases 			map[IPv4]DhcpV4LeaseRecord
+	replay 			DhcpV4ReplayCounter
+}
+
+// 从 Store 加载租约, Serve 会自动调用
+func (s *DhcpV4Server) Load() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.load()
+}
+
+func (s *DhcpV4Server) load() error {
+	if s.leases != nil {
+		return nil
+	}
+	if s.Store == nil {
+		if s.LeaseFile == "" {
+			return ErrDhcpNoLeaseStore
+		}
+		s.Store = NewDhcpV4FileStore(s.LeaseFile)
+	}
+	list, err := s.Store.Load()
+	if err != nil {
+		return err
+	}
+	s.leases = make(map[IPv4]DhcpV4LeaseRecord, len(list))
+	for _, r := range list {
+		s.leases[r.IP] = r
+	}
+	return nil
+}
+
+func (s *DhcpV4Server) Leases() []DhcpV4LeaseRecord {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := make([]DhcpV4LeaseRecord, 0, len(s.leases))
+	for _, r := range s.leases {
+		list = append(list, r)
+	}
+	return list
+}
+
+// 删除已过期的 OFFERED/DECLINED 记录, BOUND/RELEASED 记录保留以便同一客户端再次获得相同地址
+func (s *DhcpV4Server) Expire(now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for ip, r := range s.leases {
+		if r.Expired(now) && (r.State == DHCP_LEASE_OFFERED || r.State == DHCP_LEASE_DECLINED) {
+			delete(s.leases, ip)
+			if err := s.Store.Delete(ip); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// 处理客户端报文, ok 为 false 时不需要应答, dst 为应答目的地址
+func (s *DhcpV4Server) Handle(ctx context.Context, req DhcpV4Packet, now time.Time) (reply DhcpV4Packet, dst IPv4, ok bool, err error) {
+	if req.Op != DHCP_BOOTREQUEST {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err = s.load(); err != nil {
+		return
+	}
+	t, _ := req.MessageType()
+	if t == DHCP_LEASEQUERY {
+		// 请求者不一定位于某个子网中
+		if reply, ok = s.leasequery(req, now); ok {
+			dst = req.GIAddr
+		}
+		return
+	}
+	sn := s.subnet(req)
+	if sn == nil {
+		err = ErrDhcpNoSubnet
+		return
+	}
+	switch t {
+	case DHCP_DISCOVER:
+		reply, ok, err = s.discover(ctx, sn, req, now)
+	case DHCP_REQUEST:
+		reply, ok, err = s.request(sn, req, now)
+	case DHCP_DECLINE:
+		err = s.decline(req, now)
+	case DHCP_RELEASE:
+		err = s.release(req, now)
+	case DHCP_INFORM:
+		reply, ok = s.reply(sn, req, DHCP_ACK, IPv4{}, 0), true
+	case 0:
+		if s.Bootp {
+			if reply, ok, err = s.bootp(ctx, sn, req, now); ok {
+				dst = dhcpV4ReplyDst(req, reply)
+			}
+		}
+		return
+	}
+	if ok {
+		// RFC 3046 2.2: 回显选项 82, 作为选项区的最后一个选项, 不能溢出到 file/sname
+		agent, hasAgent := req.Option(DHCP_Relay_Agent_Information)
+		// 选项超出客户端可接收的长度时溢出到 file/sname, 预留选项 82 的长度
+		if max, has := req.MaximumMessageSize(); has {
+			limit := int(max)
+			if hasAgent {
+				limit -= len(agent.WireFormat())
+			}
+			reply, _ = reply.Overload(limit)
+		}
+		if hasAgent {
+			reply.Options = append(reply.Options, agent)
+		}
+		dst = dhcpV4ReplyDst(req, reply)
+	}
+	return
+}
+
+// RFC 3527: 选项 82 含 Link Selection 时优先按其选择子网
+func (s *DhcpV4Server) subnet(req DhcpV4Packet) *DhcpV4Subnet {
+	link, _ := req.RelayAgentInfo()
+	selection, _ := link.LinkSelection()
+	for _, ip := range []IPv4{selection, req.GIAddr, req.CIAddr, s.ServerID} {
+		if ip == (IPv4{}) {
+			continue
+		}
+		for _, sn := range s.Subnets {
+			if sn.Prefix.Contains(ip) {
+				return sn
+			}
+		}
+		if ip == selection || ip == req.GIAddr {
+			return nil
+		}
+	}
+	return nil
+}
+
+// RFC 5107: 选项 82 含 Server Identifier Override 时以其作为选项 54
+func (s *DhcpV4Server) serverID(req DhcpV4Packet) IPv4 {
+	info, _ := req.RelayAgentInfo()
+	if ip, ok := info.ServerIDOverride(); ok {
+		return ip
+	}
+	return s.ServerID
+}
+
+/*
+	RFC 2131 4.3.1  DHCPDISCOVER message
+	地址选择顺序: 静态分配, 客户端当前或者上次的地址, 选项 50 请求的地址, 地址池中的空闲地址
+ */
+func (s *DhcpV4Server) discover(ctx context.Context, sn *DhcpV4Subnet, req DhcpV4Packet, now time.Time) (DhcpV4Packet, bool, error) {
+	mac, cid := dhcpV4Client(req)
+	ip, err := s.pick(ctx, sn, req, now)
+	if err != nil || ip == (IPv4{}) {
+		return DhcpV4Packet{}, false, err
+	}
+	// RFC 4039: 双方都支持时跳过 DHCPOFFER/DHCPREQUEST
+	if s.RapidCommit && req.RapidCommit() {
+		dhcp, ok, err := s.bind(DHCP_DISCOVER, sn, req, ip, now)
+		if ok {
+			dhcp.Options = append(dhcp.Options, SetDHCPRapidCommit())
+		}
+		return dhcp, ok, err
+	}
+	r, ok := s.leases[ip]
+	if !ok || !r.Owner(mac, cid) || r.State != DHCP_LEASE_BOUND || r.Expired(now) {
+		r = DhcpV4LeaseRecord{IP: ip, HardwareAddr: mac, ClientID: cid, State: DHCP_LEASE_OFFERED, Expiry: now.Add(s.offerTime()), Updated: now}
+		if err := s.put(DHCP_DISCOVER, r); err != nil {
+			return DhcpV4Packet{}, false, err
+		}
+	}
+	return s.reply(sn, req, DHCP_OFFER, ip, s.leaseTime(sn)), true, nil
+}
+
+/*
+	选择分配给客户端的地址并探测冲突, 没有可用地址时返回零值
+	调用时持有 s.mu, 探测前以 OFFERED 记录 (不写入 Store) 保留地址后释放锁, 探测结束重新加锁
+ */
+func (s *DhcpV4Server) pick(ctx context.Context, sn *DhcpV4Subnet, req DhcpV4Packet, now time.Time) (IPv4, error) {
+	mac, cid := dhcpV4Client(req)
+	if r, ok := sn.reservation(mac, cid); ok {
+		return r.IP, nil
+	}
+	for attempt := 0; ; attempt++ {
+		ip := s.allocate(sn, req, now)
+		if ip == (IPv4{}) {
+			return ip, nil
+		}
+		if r, ok := s.leases[ip]; ok && r.Owner(mac, cid) || s.Conflict == nil {
+			return ip, nil
+		}
+		conflict, err := s.probe(ctx, ip, mac, cid, now)
+		if err != nil || !conflict {
+			return ip, err
+		}
+		if err = s.put(0, DhcpV4LeaseRecord{IP: ip, State: DHCP_LEASE_DECLINED, Expiry: now.Add(s.declineTime())}); err != nil || attempt + 1 >= DHCP_SERVER_PING_ATTEMPTS {
+			return IPv4{}, err
+		}
+	}
+}
+
+func (s *DhcpV4Server) probe(ctx context.Context, ip IPv4, mac HardwareAddr, cid DhcpV4ClientID, now time.Time) (bool, error) {
+	old, had := s.leases[ip]
+	s.leases[ip] = DhcpV4LeaseRecord{IP: ip, HardwareAddr: mac, ClientID: cid, State: DHCP_LEASE_OFFERED, Expiry: now.Add(s.offerTime()), Updated: now}
+	s.mu.Unlock()
+	conflict, err := s.Conflict(ctx, ip)
+	s.mu.Lock()
+	// 探测期间记录未被其它请求修改时恢复原状, 由调用方提交
+	if r, ok := s.leases[ip]; ok && r.State == DHCP_LEASE_OFFERED && r.Owner(mac, cid) && r.Updated.Equal(now) {
+		if had {
+			s.leases[ip] = old
+		} else {
+			delete(s.leases, ip)
+		}
+	}
+	return conflict, err
+}
+
+func (s *DhcpV4Server) allocate(sn *DhcpV4Subnet, req DhcpV4Packet, now time.Time) IPv4 {
+	mac, cid := dhcpV4Client(req)
+	free := func(ip IPv4) bool {
+		if !sn.allocatable(ip, mac, cid) || ip == s.ServerID {
+			return false
+		}
+		r, ok := s.leases[ip]
+		return !ok || r.Owner(mac, cid) && r.State != DHCP_LEASE_DECLINED || r.Expired(now)
+	}
+	for _, r := range s.leases {
+		if r.Owner(mac, cid) && r.State != DHCP_LEASE_DECLINED && free(r.IP) {
+			return r.IP
+		}
+	}
+	if ip, ok := req.RequestedIPAddress(); ok && free(ip) {
+		return ip
+	}
+	// 优先分配从未使用的地址, 其次是最早过期的地址
+	var expired IPv4
+	var oldest time.Time
+	for _, rg := range sn.Ranges {
+		for u := rg.Start.Uint32(); u <= rg.End.Uint32() && u != 0; u++ {
+			ip := NewIPv4FromUint32(u)
+			if !free(ip) {
+				continue
+			}
+			r, ok := s.leases[ip]
+			if !ok {
+				return ip
+			}
+			if expired == (IPv4{}) || r.Expiry.Before(oldest) {
+				expired, oldest = ip, r.Expiry
+			}
+		}
+	}
+	return expired
+}
+
+/*
+	RFC 2131 4.3.2  DHCPREQUEST message
+	SELECTING: 含选项 54 与 50; INIT-REBOOT: 仅含选项 50; RENEWING/REBINDING: 仅填写 CIAddr
+ */
+func (s *DhcpV4Server) request(sn *DhcpV4Subnet, req DhcpV4Packet, now time.Time) (DhcpV4Packet, bool, error) {
+	mac, cid := dhcpV4Client(req)
+	ip, hasRequested := req.RequestedIPAddress()
+	_, selecting := req.Option(uint8(DHCP_Server_Identifier))
+	if selecting {
+		if server, _ := req.ServerIdentifier(); server != s.serverID(req) {
+			// 客户端选择了其它服务端, 释放为其保留的地址
+			for k, r := range s.leases {
+				if r.Owner(mac, cid) && r.State == DHCP_LEASE_OFFERED {
+					delete(s.leases, k)
+					return DhcpV4Packet{}, false, s.Store.Delete(k)
+				}
+			}
+			return DhcpV4Packet{}, false, nil
+		}
+	} else if !hasRequested {
+		ip = req.CIAddr
+	}
+	r, ok := s.leases[ip]
+	switch {
+	case ip == (IPv4{}):
+		return DhcpV4Packet{}, false, nil
+	case !sn.Prefix.Contains(ip):
+		return s.nak(sn, req), true, nil
+	case ok && r.Owner(mac, cid) && r.State != DHCP_LEASE_DECLINED:
+	case ok && !r.Expired(now):
+		return s.nak(sn, req), true, nil
+	case !selecting && !s.Authoritative:
+		// 没有该客户端的记录, 4.3.2 要求保持沉默
+		return DhcpV4Packet{}, false, nil
+	case !sn.allocatable(ip, mac, cid) || ip == s.ServerID:
+		return s.nak(sn, req), true, nil
+	}
+	return s.bind(DHCP_REQUEST, sn, req, ip, now)
+}
+
+// 将 ip 绑定到客户端并生成 DHCPACK, t 为触发绑定的客户端报文类型
+func (s *DhcpV4Server) bind(t DHCP_Message_Type, sn *DhcpV4Subnet, req DhcpV4Packet, ip IPv4, now time.Time) (DhcpV4Packet, bool, error) {
+	mac, cid := dhcpV4Client(req)
+	d := s.leaseTime(sn)
+	r := DhcpV4LeaseRecord{IP: ip, HardwareAddr: mac, ClientID: cid, State: DHCP_LEASE_BOUND, Updated: now, ForcerenewNonce: s.forcerenewNonce(req)}
+	if old, ok := s.leases[ip]; ok && old.Owner(mac, cid) {
+		r.HostName = old.HostName
+	}
+	if d < DHCP_INFINITE_LEASE {
+		r.Expiry = now.Add(d)
+	}
+	if name, ok := req.HostNameOption(); ok {
+		r.HostName = name
+	}
+	if err := s.put(t, r); err != nil {
+		return DhcpV4Packet{}, false, err
+	}
+	dhcp := s.reply(sn, req, DHCP_ACK, ip, d)
+	if r.ForcerenewNonce != nil {
+		dhcp.Options = append(dhcp.Options, s.nonceAuthentication(append([]byte{DHCP_FORCERENEW_NONCE_VALUE}, r.ForcerenewNonce...), now))
+	}
+	return dhcp, true, nil
+}
+
+// RFC 2131 4.3.3  DHCPDECLINE message
+func (s *DhcpV4Server) decline(req DhcpV4Packet, now time.Time) error {
+	server, _ := req.ServerIdentifier()
+	requested, ok := req.RequestedIPAddress()
+	if !ok || server != s.serverID(req) {
+		return nil
+	}
+	mac, cid := dhcpV4Client(req)
+	if r, ok := s.leases[requested]; ok && r.Owner(mac, cid) {
+		return s.put(DHCP_DECLINE, DhcpV4LeaseRecord{IP: r.IP, State: DHCP_LEASE_DECLINED, Expiry: now.Add(s.declineTime())})
+	}
+	return nil
+}
+
+// RFC 2131 4.3.4  DHCPRELEASE message
+func (s *DhcpV4Server) release(req DhcpV4Packet, now time.Time) error {
+	if server, ok := req.ServerIdentifier(); !ok || server != s.serverID(req) {
+		return nil
+	}
+	mac, cid := dhcpV4Client(req)
+	if r, ok := s.leases[req.CIAddr]; ok && r.Owner(mac, cid) && r.State == DHCP_LEASE_BOUND {
+		r.State, r.Expiry, r.Updated = DHCP_LEASE_RELEASED, now, now
+		return s.put(DHCP_RELEASE, r)
+	}
+	return nil
+}
+
+func (s *DhcpV4Server) put(t DHCP_Message_Type, r DhcpV4LeaseRecord) error {
+	s.leases[r.IP] = r
+	if err := s.Store.Put(r); err != nil {
+		return err
+	}
+	if t != 0 && s.OnLease != nil {
+		s.OnLease(t, r)
+	}
+	return nil
+}
+
+/*
+	RFC 2131 4.3.1 Table 3  Fields and options used by DHCP servers
+	lease 为 0 时不含选项 51/58/59 (DHCPINFORM)
+ */
+func (s *DhcpV4Server) reply(sn *DhcpV4Subnet, req DhcpV4Packet, t DHCP_Message_Type, yiaddr IPv4, lease time.Duration) DhcpV4Packet {
+	dhcp := s.header(req, t)
+	dhcp.YIAddr = yiaddr
+	if t == DHCP_ACK {
+		dhcp.CIAddr = req.CIAddr
+	}
+	if lease > 0 {
+		dhcp.Options = append(dhcp.Options, SetDHCPTime(DHCP_IP_Address_Lease, lease))
+		if lease < DHCP_INFINITE_LEASE {
+			dhcp.Options = append(dhcp.Options, SetDHCPTime(DHCP_Renewal_Time, lease / 2), SetDHCPTime(DHCP_Rebinding_Time, lease * 7 / 8))
+		}
+	}
+	dhcp.Options = append(dhcp.Options, s.options(sn, req)...)
+	if sn.Boot != nil {
+		sn.Boot.Apply(req, &dhcp)
+	}
+	return dhcp
+}
+
+// 子网与静态分配的选项, 客户端提供参数请求列表时只返回其请求的选项, 并按列表顺序排列
+func (s *DhcpV4Server) options(sn *DhcpV4Subnet, req DhcpV4Packet) (list []OptionsPacket) {
+	options := append([]OptionsPacket{SetDHCPIPv4(DHCP_Subnet_Mask, sn.Prefix.Mask())}, sn.Options...)
+	mac, cid := dhcpV4Client(req)
+	if r, ok := sn.reservation(mac, cid); ok {
+		for _, opp := range r.Options {
+			options = dhcpV4ReplaceOption(options, opp)
+		}
+	}
+	prl, ok := req.ParameterRequestList()
+	if !ok {
+		return options
+	}
+	for _, code := range prl {
+		for _, opp := range options {
+			if opp.Code == code {
+				list = append(list, opp)
+			}
+		}
+	}
+	return
+}
+
+func (s *DhcpV4Server) nak(sn *DhcpV4Subnet, req DhcpV4Packet) DhcpV4Packet {
+	dhcp := s.header(req, DHCP_NAK)
+	// 4.3.2: 经中继的 DHCPNAK 需设置广播标志
+	if req.GIAddr != (IPv4{}) {
+		dhcp.Flags |= DHCP_BROADCAST_FLAG
+	}
+	return dhcp
+}
+
+func (s *DhcpV4Server) header(req DhcpV4Packet, t DHCP_Message_Type) DhcpV4Packet {
+	dhcp := DhcpV4Packet{
+		Op: DHCP_BOOTREPLY, HardwareType: req.HardwareType, HardwareLen: req.HardwareLen,
+		XID: req.XID, Flags: req.Flags, GIAddr: req.GIAddr, ChHardware: req.ChHardware,
+	}
+	dhcp.Options = append(dhcp.Options, SetDHCPMessage(t), SetDHCPIPv4(DHCP_Server_Identifier, s.serverID(req)))
+	// RFC 6842: 回显客户端标识
+	if opp, ok := req.Option(DHCP_Client_Identifier); ok {
+		dhcp.Options = append(dhcp.Options, opp)
+	}
+	return dhcp
+}
+
+func (s *DhcpV4Server) leaseTime(sn *DhcpV4Subnet) time.Duration {
+	if sn.LeaseTime > 0 {
+		return sn.LeaseTime
+	}
+	if s.LeaseTime > 0 {
+		return s.LeaseTime
+	}
+	return DHCP_SERVER_LEASE_TIME
+}
+
+func (s *DhcpV4Server) offerTime() time.Duration {
+	if s.OfferTime > 0 {
+		return s.OfferTime
+	}
+	return DHCP_SERVER_OFFER_TIME
+}
+
+func (s *DhcpV4Server) declineTime() time.Duration {
+	if s.DeclineTime > 0 {
+		return s.DeclineTime
+	}
+	return DHCP_SERVER_DECLINE_TIME
+}
+
+/*
+	读取并应答请求直到 ctx 结束, 定期调用 Expire
+	每个请求在独立的 goroutine 中处理, 同时处理的请求达到 MaxHandlers 时暂停读取
+	单个请求的错误交给 OnError 后丢弃, 仅在 ctx 结束或者 Transport 读取出错时返回
+ */
+func (s *DhcpV4Server) Serve(ctx context.Context) error {
+	if err := s.Load(); err != nil {
+		return err
+	}
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	handlers := make(chan struct{}, s.maxHandlers())
+	lw := watchLink(ctx, s.Transport)
+	defer lw.Close()
+	next := time.Now().Add(DHCP_SERVER_EXPIRE_INTERVAL)
+	for {
+		lw.setDeadline(next)
+		req, _, err := s.Transport.Receive()
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if !errors.Is(err, os.ErrDeadlineExceeded) {
+				return err
+			}
+		}
+		if now := time.Now(); !now.Before(next) {
+			if err := s.Expire(now); err != nil {
+				s.error(err)
+			}
+			next = now.Add(DHCP_SERVER_EXPIRE_INTERVAL)
+		}
+		if err != nil {
+			continue
+		}
+		select {
+		case handlers <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		wg.Add(1)
+		go func() {
+			defer func() { <-handlers; wg.Done() }()
+			reply, dst, ok, err := s.Handle(ctx, req, time.Now())
+			if err == nil && ok {
+				err = s.Transport.Send(reply, dst)
+			}
+			if err != nil && ctx.Err() == nil && !errors.Is(err, ErrDhcpNoSubnet) {
+				s.error(err)
+			}
+		}()
+	}
+}
+
+func (s *DhcpV4Server) maxHandlers() int {
+	if s.MaxHandlers > 0 {
+		return s.MaxHandlers
+	}
+	return DHCP_SERVER_MAX_HANDLERS
+}
+
+func (s *DhcpV4Server) error(err error) {
+	if s.OnError != nil {
+		s.OnError(err)
+	}
+}
+
+/*
+	RFC 2131 4.1  Constructing and sending DHCP messages
+	GIAddr 不为零发往中继; DHCPNAK 广播; CIAddr 不为零单播到 CIAddr; 广播标志置位时广播; 否则单播到 YIAddr
+ */
+func dhcpV4ReplyDst(req, reply DhcpV4Packet) IPv4 {
+	switch t, _ := reply.MessageType(); {
+	case req.GIAddr != (IPv4{}):
+		return req.GIAddr
+	case t == DHCP_NAK:
+		return IPv4Broadcast
+	case req.CIAddr != (IPv4{}):
+		return req.CIAddr
+	case req.Flags & DHCP_BROADCAST_FLAG != 0 || reply.YIAddr == (IPv4{}):
+		return IPv4Broadcast
+	}
+	return reply.YIAddr
+}
+
+func dhcpV4Client(req DhcpV4Packet) (mac HardwareAddr, clientID DhcpV4ClientID) {
+	copy(mac[:], req.ChHardware[:6])
+	clientID, _ = req.ClientIdentifier()
+	return
+}
+
+func dhcpV4ReplaceOption(list []OptionsPacket, opp OptionsPacket) []OptionsPacket {
+	for i, v := range list {
+		if v.Code == opp.Code {
+			list[i] = opp
+			return list
+		}
+	}
+	return append(list, opp)
+}
+
+/*
+	以服务端地址发送 ARP 请求探测 ip, 仅适用于与服务端直连的子网
+	同一 link 上的探测依次进行, 避免并发的探测互相读取对方的应答或者重置读取期限
+ */
+func NewDhcpV4ArpPing(link FrameReadWriter, mac HardwareAddr, ip IPv4, timeout time.Duration) func(ctx context.Context, target IPv4) (bool, error) {
+	if timeout <= 0 {
+		timeout = DHCP_SERVER_PING_TIMEOUT
+	}
+	busy := make(chan struct{}, 1)
+	return func(ctx context.Context, target IPv4) (bool, error) {
+		select {
+		case busy <- struct{}{}:
+		case <-ctx.Done():
+			return false, ctx.Err()
+		}
+		defer func() { <-busy }()
+		req := ArpPacket{
+			HardwareType: ARP_ETHERNETTYPE, ProtocolType: ETH_P_IP, HardwareLen: 6, IPLen: 4,
+			Operation: ARP_REQUEST, SendHardware: mac, SendIP: ip, TargetIP: target,
+		}
+		if err := link.WriteFrame(req.EthernetFrame(Broadcast)); err != nil {
+			return false, err
+		}
+		lw := watchLink(ctx, link)
+		defer lw.Close()
+		lw.setDeadline(time.Now().Add(timeout))
+		for {
+			b, err := link.ReadFrame()
+			if err != nil {
+				if ctx.Err() != nil {
+					return false, ctx.Err()
+				}
+				if errors.Is(err, os.ErrDeadlineExceeded) {
+					return false, nil
+				}
+				return false, err
+			}
+			if _, arp, ok := NewEthernetArp(b); ok && arp.SendIP == target && arp.SendHardware != mac {
+				return true, nil
+			}
+		}
+	}
+}
+
+// 使用 ICMP Echo 探测, 适用于经中继的子网
+func NewDhcpV4IcmpPing(timeout time.Duration) func(ctx context.Context, target IPv4) (bool, error) {
+	if timeout <= 0 {
+		timeout = DHCP_SERVER_PING_TIMEOUT
+	}
+	return func(ctx context.Context, target IPv4) (bool, error) {
+		return IcmpPing(ctx, target, timeout)
+	}
+}
@@ -0,0 +1,253 @@
+// @@
+// @ Author       : Eacher
+// @ Date         : 2026-10-21 14:08:31
+// @ LastEditTime : 2026-10-21 16:42:27
+// @ LastEditors  : Eacher
+// @ --------------------------------------------------------------------------------<
+// @ Description  : DHCPv4 服务端进程内测试
+// @ --------------------------------------------------------------------------------<
+// @ FilePath     : /20yyq/packet/dhcpv4_server_test.go
+// @@
+package packet
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+var (
+	dhcpV4TestServerMAC = HardwareAddr{0x02, 0, 0, 0, 0, 0x01}
+	dhcpV4TestServerIP 	= IPv4{10, 0, 0, 1}
+)
+
+func newDhcpV4TestSubnet() *DhcpV4Subnet {
+	return &DhcpV4Subnet{
+		Prefix: 	IPv4Prefix{IPv4{10, 0, 0, 0}, 24},
+		Ranges: 	[]DhcpV4Range{{IPv4{10, 0, 0, 10}, IPv4{10, 0, 0, 20}}},
+		Options: 	[]OptionsPacket{SetDHCPIPv4(DHCP_Router, dhcpV4TestServerIP)},
+	}
+}
+
+func newDhcpV4TestRequest(mac HardwareAddr, xid uint32, options ...OptionsPacket) DhcpV4Packet {
+	dhcp := DhcpV4Packet{Op: DHCP_BOOTREQUEST, HardwareType: ARP_ETHERNETTYPE, HardwareLen: 6, XID: xid, Flags: DHCP_BROADCAST_FLAG, Options: options}
+	copy(dhcp.ChHardware[:], mac[:])
+	return dhcp
+}
+
+// 在内存链路上运行 Serve, 返回客户端侧的传输
+func startDhcpV4TestServer(t *testing.T, s *DhcpV4Server) DhcpV4Transport {
+	a, b := NewFramePipe()
+	s.Transport = NewDhcpV4RawTransport(b, dhcpV4TestServerMAC, dhcpV4TestServerIP, DHCP_ServerPort, DHCP_ClientPort)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- s.Serve(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+		a.Close()
+	})
+	return NewDhcpV4RawTransport(a, HardwareAddr{}, IPv4{}, DHCP_ClientPort, DHCP_ServerPort)
+}
+
+// 发送 req 并等待同一 XID 的应答
+func dhcpV4TestExchange(t *testing.T, tr DhcpV4Transport, req DhcpV4Packet, want DHCP_Message_Type) DhcpV4Packet {
+	t.Helper()
+	if err := tr.Send(req, IPv4Broadcast); err != nil {
+		t.Fatal(err)
+	}
+	tr.SetReadDeadline(time.Now().Add(3 * time.Second))
+	for {
+		reply, _, err := tr.Receive()
+		if err != nil {
+			t.Fatalf("no reply to xid %x: %v", req.XID, err)
+		}
+		if reply.XID != req.XID {
+			continue
+		}
+		if mt, _ := reply.MessageType(); mt != want {
+			t.Fatalf("reply to xid %x is type %d; want %d", req.XID, mt, want)
+		}
+		return reply
+	}
+}
+
+func TestDhcpV4ServerDORA(t *testing.T) {
+	mac := HardwareAddr{0x02, 0, 0, 0, 0, 0x10}
+	var mu sync.Mutex
+	var events []DHCP_Message_Type
+	s := &DhcpV4Server{
+		ServerID: 	dhcpV4TestServerIP,
+		Subnets: 	[]*DhcpV4Subnet{newDhcpV4TestSubnet()},
+		Store: 		NewDhcpV4MemoryStore(),
+		LeaseTime: 	time.Hour,
+		OnLease: 	func(t DHCP_Message_Type, r DhcpV4LeaseRecord) { mu.Lock(); events = append(events, t); mu.Unlock() },
+	}
+	tr := startDhcpV4TestServer(t, s)
+
+	offer := dhcpV4TestExchange(t, tr, newDhcpV4TestRequest(mac, 1, SetDHCPMessage(DHCP_DISCOVER)), DHCP_OFFER)
+	if offer.YIAddr != (IPv4{10, 0, 0, 10}) {
+		t.Errorf("OFFER yiaddr = %v; want 10.0.0.10", offer.YIAddr)
+	}
+	if id, _ := offer.ServerIdentifier(); id != dhcpV4TestServerIP {
+		t.Errorf("OFFER server identifier = %v; want %v", id, dhcpV4TestServerIP)
+	}
+	if d, _ := offer.LeaseTime(); d != time.Hour {
+		t.Errorf("OFFER lease time = %v; want 1h", d)
+	}
+	if leases := s.Leases(); len(leases) != 1 || leases[0].State != DHCP_LEASE_OFFERED {
+		t.Errorf("leases after OFFER = %+v; want one offered", leases)
+	}
+
+	ack := dhcpV4TestExchange(t, tr, newDhcpV4TestRequest(mac, 2, SetDHCPMessage(DHCP_REQUEST),
+		SetDHCPIPv4(DHCP_Server_Identifier, dhcpV4TestServerIP), SetDHCPIPv4(DHCP_Requested_IP_Address, offer.YIAddr)), DHCP_ACK)
+	if ack.YIAddr != offer.YIAddr {
+		t.Errorf("ACK yiaddr = %v; want %v", ack.YIAddr, offer.YIAddr)
+	}
+	if routers, _ := ack.IPv4ListOption(DHCP_Router); len(routers) != 1 || routers[0] != dhcpV4TestServerIP {
+		t.Errorf("ACK routers = %v; want [%v]", routers, dhcpV4TestServerIP)
+	}
+	leases := s.Leases()
+	if len(leases) != 1 || leases[0].State != DHCP_LEASE_BOUND || leases[0].HardwareAddr != mac {
+		t.Errorf("leases after ACK = %+v; want one bound to %v", leases, mac)
+	}
+
+	// 其它服务端的地址: 不属于本服务端的 INIT-REBOOT 请求
+	s.Authoritative = true
+	dhcpV4TestExchange(t, tr, newDhcpV4TestRequest(HardwareAddr{0x02, 0, 0, 0, 0, 0x11}, 3, SetDHCPMessage(DHCP_REQUEST),
+		SetDHCPIPv4(DHCP_Requested_IP_Address, IPv4{192, 168, 1, 5})), DHCP_NAK)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(events) != 2 || events[0] != DHCP_DISCOVER || events[1] != DHCP_REQUEST {
+		t.Errorf("OnLease events = %v; want [DISCOVER REQUEST]", events)
+	}
+}
+
+func TestDhcpV4ServerConflict(t *testing.T) {
+	mac := HardwareAddr{0x02, 0, 0, 0, 0, 0x20}
+	var mu sync.Mutex
+	var probed []IPv4
+	s := &DhcpV4Server{
+		ServerID: 	dhcpV4TestServerIP,
+		Subnets: 	[]*DhcpV4Subnet{newDhcpV4TestSubnet()},
+		Store: 		NewDhcpV4MemoryStore(),
+		Conflict: 	func(ctx context.Context, ip IPv4) (bool, error) {
+			mu.Lock()
+			probed = append(probed, ip)
+			mu.Unlock()
+			return ip == IPv4{10, 0, 0, 10} || ip == IPv4{10, 0, 0, 11}, nil
+		},
+	}
+	tr := startDhcpV4TestServer(t, s)
+	offer := dhcpV4TestExchange(t, tr, newDhcpV4TestRequest(mac, 1, SetDHCPMessage(DHCP_DISCOVER)), DHCP_OFFER)
+	if offer.YIAddr != (IPv4{10, 0, 0, 12}) {
+		t.Errorf("OFFER yiaddr = %v; want 10.0.0.12", offer.YIAddr)
+	}
+	mu.Lock()
+	if len(probed) != 3 {
+		t.Errorf("probed %v; want 10.0.0.10, 10.0.0.11, 10.0.0.12", probed)
+	}
+	mu.Unlock()
+	states := map[IPv4]DhcpV4LeaseState{}
+	for _, r := range s.Leases() {
+		states[r.IP] = r.State
+	}
+	want := map[IPv4]DhcpV4LeaseState{{10, 0, 0, 10}: DHCP_LEASE_DECLINED, {10, 0, 0, 11}: DHCP_LEASE_DECLINED, {10, 0, 0, 12}: DHCP_LEASE_OFFERED}
+	for ip, st := range want {
+		if states[ip] != st {
+			t.Errorf("lease %v state = %v; want %v", ip, states[ip], st)
+		}
+	}
+	// 已隔离的地址不再探测
+	offer = dhcpV4TestExchange(t, tr, newDhcpV4TestRequest(HardwareAddr{0x02, 0, 0, 0, 0, 0x21}, 2, SetDHCPMessage(DHCP_DISCOVER)), DHCP_OFFER)
+	if offer.YIAddr != (IPv4{10, 0, 0, 13}) {
+		t.Errorf("second OFFER yiaddr = %v; want 10.0.0.13", offer.YIAddr)
+	}
+}
+
+// 同一链路上并发的 ARP 探测各自收到对应的应答
+func TestDhcpV4ArpPingConcurrent(t *testing.T) {
+	a, b := NewFramePipe()
+	defer a.Close()
+	peer := HardwareAddr{0x02, 0, 0, 0, 0, 0x99}
+	used := IPv4{10, 0, 0, 10}
+	go func() {
+		for {
+			frame, err := b.ReadFrame()
+			if err != nil {
+				return
+			}
+			if _, arp, ok := NewEthernetArp(frame); ok && arp.Operation == ARP_REQUEST && arp.TargetIP == used {
+				reply := ArpPacket{
+					HardwareType: ARP_ETHERNETTYPE, ProtocolType: ETH_P_IP, HardwareLen: 6, IPLen: 4, Operation: ARP_REPLY,
+					SendHardware: peer, SendIP: used, TargetHardware: arp.SendHardware, TargetIP: arp.SendIP,
+				}
+				b.WriteFrame(reply.EthernetFrame(arp.SendHardware))
+			}
+		}
+	}()
+	ping := NewDhcpV4ArpPing(a, dhcpV4TestServerMAC, dhcpV4TestServerIP, 100 * time.Millisecond)
+	targets := []IPv4{{10, 0, 0, 11}, used, {10, 0, 0, 12}, used, {10, 0, 0, 13}, used}
+	results := make([]bool, len(targets))
+	ctx, cancel := context.WithTimeout(context.Background(), 5 * time.Second)
+	defer cancel()
+	var wg sync.WaitGroup
+	for i, ip := range targets {
+		wg.Add(1)
+		go func(i int, ip IPv4) {
+			defer wg.Done()
+			conflict, err := ping(ctx, ip)
+			if err != nil {
+				t.Errorf("ping %v: %v", ip, err)
+			}
+			results[i] = conflict
+		}(i, ip)
+	}
+	wg.Wait()
+	for i, ip := range targets {
+		if results[i] != (ip == used) {
+			t.Errorf("ping %v = %v; want %v", ip, results[i], ip == used)
+		}
+	}
+}
+
+func TestDhcpV4ServerNoLeaseStore(t *testing.T) {
+	s := &DhcpV4Server{ServerID: dhcpV4TestServerIP, Subnets: []*DhcpV4Subnet{newDhcpV4TestSubnet()}}
+	if _, _, _, err := s.Handle(context.Background(), newDhcpV4TestRequest(HardwareAddr{2}, 1, SetDHCPMessage(DHCP_DISCOVER)), time.Now()); err != ErrDhcpNoLeaseStore {
+		t.Errorf("Handle without Store or LeaseFile error = %v; want ErrDhcpNoLeaseStore", err)
+	}
+}
+
+// 未携带选项 145 的客户端没有 nonce, 不能构造 DHCPFORCERENEW
+func TestDhcpV4ServerForcerenewNonce(t *testing.T) {
+	s := &DhcpV4Server{
+		ServerID: 			dhcpV4TestServerIP,
+		Subnets: 			[]*DhcpV4Subnet{newDhcpV4TestSubnet()},
+		Store: 				NewDhcpV4MemoryStore(),
+		ForcerenewNonce: 	true,
+	}
+	tr := startDhcpV4TestServer(t, s)
+	bind := func(mac HardwareAddr, options ...OptionsPacket) IPv4 {
+		offer := dhcpV4TestExchange(t, tr, newDhcpV4TestRequest(mac, 1, append([]OptionsPacket{SetDHCPMessage(DHCP_DISCOVER)}, options...)...), DHCP_OFFER)
+		dhcpV4TestExchange(t, tr, newDhcpV4TestRequest(mac, 2, append([]OptionsPacket{SetDHCPMessage(DHCP_REQUEST),
+			SetDHCPIPv4(DHCP_Server_Identifier, dhcpV4TestServerIP), SetDHCPIPv4(DHCP_Requested_IP_Address, offer.YIAddr)}, options...)...), DHCP_ACK)
+		return offer.YIAddr
+	}
+	plain := bind(HardwareAddr{0x02, 0, 0, 0, 0, 0x50})
+	if _, err := s.ForcerenewPacket(plain, time.Now()); err != ErrDhcpNoForcerenewNonce {
+		t.Errorf("ForcerenewPacket(%v) without nonce error = %v; want ErrDhcpNoForcerenewNonce", plain, err)
+	}
+	capable := bind(HardwareAddr{0x02, 0, 0, 0, 0, 0x51}, SetDHCPForcerenewNonceCapable(DHCP_AUTH_ALGORITHM_HMAC_MD5))
+	dhcp, err := s.ForcerenewPacket(capable, time.Now())
+	if err != nil {
+		t.Fatalf("ForcerenewPacket(%v) error: %v", capable, err)
+	}
+	if _, ok := dhcp.Option(DHCP_Authentication); !ok {
+		t.Errorf("DHCPFORCERENEW for %v is not authenticated", capable)
+	}
+	if _, err := s.ForcerenewPacket(IPv4{10, 0, 0, 99}, time.Now()); err != ErrDhcpNoLease {
+		t.Errorf("ForcerenewPacket(unbound) error = %v; want ErrDhcpNoLease", err)
+	}
+}
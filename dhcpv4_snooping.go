@@ -0,0 +1,385 @@
+// @@
+// @ Author       : Eacher
+// @ Date         : 2026-10-20 22:58:40
+// @ LastEditTime : 2026-10-21 14:41:09
+// @ LastEditors  : Eacher
+// @ --------------------------------------------------------------------------------<
+// @ Description  : DHCP snooping 绑定表, 非法服务端与地址耗尽检测, 动态 ARP 检查
+// @ --------------------------------------------------------------------------------<
+// @ FilePath     : /20yyq/packet/dhcpv4_snooping.go
+// @@
+package packet
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// 同一接口在 STARVATION_WINDOW 内出现超过 STARVATION_THRESHOLD 个不同 ChHardware 的 DHCPDISCOVER 视为地址耗尽攻击
+	DHCP_SNOOP_STARVATION_WINDOW 	= 10 * time.Second
+	DHCP_SNOOP_STARVATION_THRESHOLD = 0x20
+	// 清理过期绑定的间隔
+	DHCP_SNOOP_EXPIRE_INTERVAL 		= time.Minute
+	// 记录客户端最近一次发送 DHCP 报文的接口的时间, 需覆盖 DHCPREQUEST 到 DHCPACK 的间隔
+	DHCP_SNOOP_CLIENT_TIMEOUT 		= time.Minute
+)
+
+type DhcpV4SnoopAlertType uint8
+
+const (
+	// 非信任接口上的 DHCPOFFER/DHCPACK/DHCPNAK 等服务端报文
+	DHCP_SNOOP_ROGUE_SERVER DhcpV4SnoopAlertType = iota + 1
+	// 地址耗尽攻击
+	DHCP_SNOOP_STARVATION
+	// 客户端报文的以太网源地址与 ChHardware 不一致
+	DHCP_SNOOP_CHADDR_MISMATCH
+	// ARP 发送方与绑定表不符
+	DHCP_SNOOP_ARP_INVALID
+)
+
+func (t DhcpV4SnoopAlertType) String() string {
+	switch t {
+	case DHCP_SNOOP_ROGUE_SERVER:
+		return "rogue-server"
+	case DHCP_SNOOP_STARVATION:
+		return "starvation"
+	case DHCP_SNOOP_CHADDR_MISMATCH:
+		return "chaddr-mismatch"
+	case DHCP_SNOOP_ARP_INVALID:
+		return "arp-invalid"
+	}
+	return "unknown"
+}
+
+type DhcpV4SnoopAlert struct {
+	Type 		DhcpV4SnoopAlertType
+	Time 		time.Time
+	Interface 	string
+	VLAN 		uint16
+	// 非法服务端为其 IP 源地址, ARP 为发送方 IP
+	IP 			IPv4
+	// 以太网源地址
+	MAC 		HardwareAddr
+	// 地址耗尽告警时为窗口内不同 ChHardware 的数量
+	Count 		int
+	Ethernet 	EthernetPacket
+	Dhcp 		DhcpV4Packet
+	Arp 		ArpPacket
+}
+
+func (a DhcpV4SnoopAlert) String() string {
+	str := a.Time.Format(time.RFC3339Nano) + " " + a.Type.String() + " Interface: " + a.Interface + fmt.Sprintf(" VLAN: %d", a.VLAN)
+	switch a.Type {
+	case DHCP_SNOOP_STARVATION:
+		str += fmt.Sprintf(" Count: %d", a.Count)
+	case DHCP_SNOOP_CHADDR_MISMATCH:
+		str += " MAC: " + a.MAC.String() + " ChHardware: " + HardwareAddr(a.Dhcp.ChHardware[:6]).String()
+	default:
+		str += " IP: " + a.IP.String() + " MAC: " + a.MAC.String()
+	}
+	return str
+}
+
+// Expiry 为零表示永久 (选项 51 为 0xffffffff 或静态绑定)
+type DhcpV4SnoopBinding struct {
+	MAC 		HardwareAddr
+	IP 			IPv4
+	Expiry 		time.Time
+	Interface 	string
+	VLAN 		uint16
+}
+
+func (b DhcpV4SnoopBinding) Expired(now time.Time) bool {
+	return !b.Expiry.IsZero() && !now.Before(b.Expiry)
+}
+
+type dhcpV4SnoopKey struct {
+	vlan 	uint16
+	mac 	HardwareAddr
+}
+
+type dhcpV4SnoopSeen struct {
+	mac 	HardwareAddr
+	seen 	time.Time
+}
+
+type dhcpV4SnoopClient struct {
+	iface 	string
+	seen 	time.Time
+}
+
+// 一个接口窗口内的 DHCPDISCOVER, alerting 在超过阈值时置位, 窗口内不再有 DHCPDISCOVER 时清除
+type dhcpV4SnoopDiscovers struct {
+	list 		[]dhcpV4SnoopSeen
+	alerting 	bool
+}
+
+/*
+	软件 DHCP snooping, 零值参数使用 DHCP_SNOOP_* 默认值, 可并发调用
+	绑定表以 (VLAN, MAC) 为键, 由信任接口上的 DHCPACK 建立, DHCPNAK/DHCPRELEASE/DHCPDECLINE 删除
+	绑定的接口取该客户端最近一次发送 DHCP 报文的接口
+	各 Handle 返回是否应转发该帧以及产生的告警
+ */
+type DhcpV4Snooper struct {
+	// 连接合法服务端 (或上联) 的接口, 其余接口均为非信任接口
+	Trusted 			map[string]bool
+	StarvationWindow 	time.Duration
+	StarvationThreshold int
+	// 静态地址主机, 用于 ARP 检查
+	Static 				[]DhcpV4SnoopBinding
+	OnAlert 			func(DhcpV4SnoopAlert)
+
+	mu 			sync.Mutex
+	bindings 	map[dhcpV4SnoopKey]DhcpV4SnoopBinding
+	clients 	map[dhcpV4SnoopKey]dhcpV4SnoopClient
+	discovers 	map[string]*dhcpV4SnoopDiscovers
+	expired 	time.Time
+}
+
+/*
+	去除 802.1Q/802.1ad 标签, 返回最内层的 VLAN ID 与不带标签的帧, 未带标签时 vlan 为 0
+ */
+func StripVLAN(b []byte) (vlan uint16, frame []byte) {
+	frame = b
+	for len(frame) >= SizeofEthernetPacket + 4 {
+		t := binary.BigEndian.Uint16(frame[12:14])
+		if t != ETH_P_8021Q && t != ETH_P_8021AD {
+			break
+		}
+		vlan = binary.BigEndian.Uint16(frame[14:16]) & 0x0fff
+		frame = append(append(make([]byte, 0, len(frame) - 4), frame[:12]...), frame[16:]...)
+	}
+	return
+}
+
+// 处理 iface 上收到的以太网帧, 非 DHCP/ARP 帧总是转发
+func (s *DhcpV4Snooper) HandleFrame(iface string, b []byte, ts time.Time) (bool, []DhcpV4SnoopAlert) {
+	vlan, b := StripVLAN(b)
+	if eth, arp, ok := NewEthernetArp(b); ok {
+		return s.HandleArp(iface, vlan, eth, arp, ts)
+	}
+	eth, ip, udp, dhcp, ok := NewDhcpV4Frame(b)
+	if !ok || udp.DstPort != DHCP_ServerPort && udp.DstPort != DHCP_ClientPort {
+		return true, nil
+	}
+	return s.HandleDhcp(iface, vlan, eth, ip, udp, dhcp, ts)
+}
+
+func (s *DhcpV4Snooper) HandleDhcp(iface string, vlan uint16, eth EthernetPacket, ip IPv4Packet, udp DUPPacket, dhcp DhcpV4Packet, ts time.Time) (forward bool, alerts []DhcpV4SnoopAlert) {
+	s.mu.Lock()
+	s.init()
+	alert := func(t DhcpV4SnoopAlertType, count int) {
+		alerts = append(alerts, DhcpV4SnoopAlert{Type: t, Time: ts, Interface: iface, VLAN: vlan, IP: ip.Src, MAC: eth.HeadMAC[1], Count: count, Ethernet: eth, Dhcp: dhcp})
+	}
+	key := dhcpV4SnoopKey{vlan, HardwareAddr(dhcp.ChHardware[:6])}
+	t, _ := dhcp.MessageType()
+	forward = true
+	switch {
+	case dhcp.Op == DHCP_BOOTREPLY:
+		if !s.Trusted[iface] {
+			alert(DHCP_SNOOP_ROGUE_SERVER, 0)
+			forward = false
+			break
+		}
+		switch t {
+		case DHCP_ACK:
+			b := DhcpV4SnoopBinding{MAC: key.mac, IP: dhcp.YIAddr, Interface: s.clients[key].iface, VLAN: vlan}
+			if b.IP == (IPv4{}) {
+				b.IP = dhcp.CIAddr
+			}
+			// DHCPINFORM 的应答不含租期, 不建立绑定
+			if d, ok := dhcp.LeaseTime(); ok && b.IP != (IPv4{}) {
+				if d < DHCP_INFINITE_LEASE {
+					b.Expiry = ts.Add(d)
+				}
+				s.bindings[key] = b
+			}
+		case DHCP_NAK:
+			delete(s.bindings, key)
+		}
+	case dhcp.GIAddr == (IPv4{}) && eth.HeadMAC[1] != key.mac:
+		// 经中继的报文以太网源地址为中继
+		alert(DHCP_SNOOP_CHADDR_MISMATCH, 0)
+		forward = false
+	default:
+		s.clients[key] = dhcpV4SnoopClient{iface, ts}
+		switch t {
+		case DHCP_DISCOVER:
+			if count, starving := s.discover(iface, key.mac, ts); starving {
+				alert(DHCP_SNOOP_STARVATION, count)
+			}
+		case DHCP_RELEASE:
+			if b, ok := s.bindings[key]; ok && (b.Interface == "" || b.Interface == iface) {
+				delete(s.bindings, key)
+			}
+		case DHCP_DECLINE:
+			requested, _ := dhcp.RequestedIPAddress()
+			if b, ok := s.bindings[key]; ok && b.IP == requested {
+				delete(s.bindings, key)
+			}
+		}
+	}
+	s.expire(ts)
+	s.mu.Unlock()
+	s.notify(alerts)
+	return
+}
+
+/*
+	记录 DHCPDISCOVER 并返回窗口内不同 ChHardware 的数量, 每次攻击只在首次超过阈值时 starving 为 true
+	告警只需判断是否超过阈值, 每个接口只保留最近的 阈值 + 1 个, 返回值不超过 阈值 + 1
+ */
+func (s *DhcpV4Snooper) discover(iface string, mac HardwareAddr, ts time.Time) (count int, starving bool) {
+	d := s.discovers[iface]
+	if d == nil {
+		d = &dhcpV4SnoopDiscovers{}
+		s.discovers[iface] = d
+	}
+	window, threshold, list := s.starvationWindow(), s.starvationThreshold(), d.list
+	for len(list) > 0 && ts.Sub(list[0].seen) > window {
+		list = list[1:]
+	}
+	for i, v := range list {
+		if v.mac == mac {
+			list = append(list[:i], list[i+1:]...)
+			break
+		}
+	}
+	if len(list) == 0 {
+		d.alerting = false
+	}
+	if len(list) > threshold {
+		list = list[len(list)-threshold:]
+	}
+	d.list = append(list, dhcpV4SnoopSeen{mac, ts})
+	if starving = len(d.list) > threshold && !d.alerting; starving {
+		d.alerting = true
+	}
+	return len(d.list), starving
+}
+
+/*
+	动态 ARP 检查: 非信任接口上 ARP 发送方 (IP, MAC) 必须与绑定表或 Static 一致, 且以太网源地址与发送方硬件地址相同
+	ARP 探测 (发送方 IP 为零) 总是转发
+ */
+func (s *DhcpV4Snooper) HandleArp(iface string, vlan uint16, eth EthernetPacket, arp ArpPacket, ts time.Time) (forward bool, alerts []DhcpV4SnoopAlert) {
+	if s.Trusted[iface] || arp.SendIP == (IPv4{}) {
+		return true, nil
+	}
+	s.mu.Lock()
+	s.init()
+	forward = eth.HeadMAC[1] == arp.SendHardware && s.valid(iface, vlan, arp.SendHardware, arp.SendIP, ts)
+	if !forward {
+		alerts = append(alerts, DhcpV4SnoopAlert{Type: DHCP_SNOOP_ARP_INVALID, Time: ts, Interface: iface, VLAN: vlan, IP: arp.SendIP, MAC: arp.SendHardware, Ethernet: eth, Arp: arp})
+	}
+	s.expire(ts)
+	s.mu.Unlock()
+	s.notify(alerts)
+	return
+}
+
+func (s *DhcpV4Snooper) valid(iface string, vlan uint16, mac HardwareAddr, ip IPv4, ts time.Time) bool {
+	match := func(b DhcpV4SnoopBinding) bool {
+		return b.MAC == mac && b.IP == ip && b.VLAN == vlan && (b.Interface == "" || b.Interface == iface) && !b.Expired(ts)
+	}
+	if b, ok := s.bindings[dhcpV4SnoopKey{vlan, mac}]; ok && match(b) {
+		return true
+	}
+	for _, b := range s.Static {
+		if match(b) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *DhcpV4Snooper) init() {
+	if s.bindings == nil {
+		s.bindings, s.clients = make(map[dhcpV4SnoopKey]DhcpV4SnoopBinding), make(map[dhcpV4SnoopKey]dhcpV4SnoopClient)
+		s.discovers = make(map[string]*dhcpV4SnoopDiscovers)
+	}
+}
+
+func (s *DhcpV4Snooper) notify(alerts []DhcpV4SnoopAlert) {
+	if s.OnAlert != nil {
+		for _, a := range alerts {
+			s.OnAlert(a)
+		}
+	}
+}
+
+func (s *DhcpV4Snooper) expire(ts time.Time) {
+	if ts.Sub(s.expired) < DHCP_SNOOP_EXPIRE_INTERVAL {
+		return
+	}
+	s.expired = ts
+	for k, b := range s.bindings {
+		if b.Expired(ts) {
+			delete(s.bindings, k)
+		}
+	}
+	for k, c := range s.clients {
+		if ts.Sub(c.seen) > DHCP_SNOOP_CLIENT_TIMEOUT {
+			delete(s.clients, k)
+		}
+	}
+	window := s.starvationWindow()
+	for iface, d := range s.discovers {
+		if len(d.list) == 0 || ts.Sub(d.list[len(d.list)-1].seen) > window {
+			delete(s.discovers, iface)
+		}
+	}
+}
+
+// 当前绑定表, 按 VLAN 与 IP 排序
+func (s *DhcpV4Snooper) Bindings() []DhcpV4SnoopBinding {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := make([]DhcpV4SnoopBinding, 0, len(s.bindings))
+	for _, b := range s.bindings {
+		list = append(list, b)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].VLAN != list[j].VLAN {
+			return list[i].VLAN < list[j].VLAN
+		}
+		return list[i].IP.Uint32() < list[j].IP.Uint32()
+	})
+	return list
+}
+
+// 离线分析 iface 上的抓包文件, 使用抓包时间戳
+func (s *DhcpV4Snooper) ReadPcap(iface string, pr *PcapReader) error {
+	if pr.Header.LinkType != PCAP_LINKTYPE_ETHERNET {
+		return ErrPcapFormat
+	}
+	for {
+		b, ts, err := pr.Next()
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		s.HandleFrame(iface, b, ts)
+	}
+}
+
+func (s *DhcpV4Snooper) starvationWindow() time.Duration {
+	if s.StarvationWindow > 0 {
+		return s.StarvationWindow
+	}
+	return DHCP_SNOOP_STARVATION_WINDOW
+}
+
+func (s *DhcpV4Snooper) starvationThreshold() int {
+	if s.StarvationThreshold > 0 {
+		return s.StarvationThreshold
+	}
+	return DHCP_SNOOP_STARVATION_THRESHOLD
+}
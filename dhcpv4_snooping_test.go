@@ -0,0 +1,70 @@
+// @@
+// @ Author       : Eacher
+// @ Date         : 2026-10-21 14:41:09
+// @ LastEditTime : 2026-10-21 14:41:09
+// @ LastEditors  : Eacher
+// @ --------------------------------------------------------------------------------<
+// @ Description  : DHCP snooping 测试
+// @ --------------------------------------------------------------------------------<
+// @ FilePath     : /20yyq/packet/dhcpv4_snooping_test.go
+// @@
+package packet
+
+import (
+	"testing"
+	"time"
+)
+
+func dhcpV4SnoopDiscover(s *DhcpV4Snooper, iface string, i int, ts time.Time) []DhcpV4SnoopAlert {
+	mac := HardwareAddr{0x02, 0, 0, byte(i >> 16), byte(i >> 8), byte(i)}
+	req := newDhcpV4TestRequest(mac, uint32(i), SetDHCPMessage(DHCP_DISCOVER))
+	_, alerts := s.HandleDhcp(iface, 0, EthernetPacket{HeadMAC: [2]HardwareAddr{Broadcast, mac}, FrameType: ETH_P_IP}, IPv4Packet{}, DUPPacket{}, req, ts)
+	return alerts
+}
+
+func TestDhcpV4SnooperStarvation(t *testing.T) {
+	var alerts []DhcpV4SnoopAlert
+	s := &DhcpV4Snooper{StarvationWindow: 10 * time.Second, StarvationThreshold: 4, OnAlert: func(a DhcpV4SnoopAlert) { alerts = append(alerts, a) }}
+	start := time.Unix(1000, 0)
+	// 持续的攻击只告警一次
+	for i := 0; i < 1000; i++ {
+		dhcpV4SnoopDiscover(s, "eth1", i, start.Add(time.Duration(i) * time.Millisecond))
+	}
+	if len(alerts) != 1 || alerts[0].Type != DHCP_SNOOP_STARVATION || alerts[0].Count != 5 || alerts[0].Interface != "eth1" {
+		t.Fatalf("alerts = %v; want one starvation alert with count 5", alerts)
+	}
+	if n := len(s.discovers["eth1"].list); n > 5 {
+		t.Errorf("discover history = %d entries; want at most 5", n)
+	}
+	// 其它接口单独计数
+	for i := 0; i < 4; i++ {
+		dhcpV4SnoopDiscover(s, "eth2", i, start.Add(time.Second))
+	}
+	if len(alerts) != 1 {
+		t.Errorf("alerts after 4 discovers on eth2 = %d; want 1", len(alerts))
+	}
+	// 窗口内不再有 DHCPDISCOVER 后再次攻击时重新告警
+	later := start.Add(time.Minute)
+	for i := 0; i < 100; i++ {
+		dhcpV4SnoopDiscover(s, "eth1", 5000 + i, later.Add(time.Duration(i) * time.Millisecond))
+	}
+	if len(alerts) != 2 || alerts[1].Type != DHCP_SNOOP_STARVATION {
+		t.Errorf("alerts after second attack = %v; want two starvation alerts", alerts)
+	}
+}
+
+func TestDhcpV4SnooperExpireClients(t *testing.T) {
+	s := &DhcpV4Snooper{StarvationThreshold: 1 << 20}
+	start := time.Unix(1000, 0)
+	for i := 0; i < 1000; i++ {
+		dhcpV4SnoopDiscover(s, "eth1", i, start)
+	}
+	if len(s.clients) != 1000 {
+		t.Fatalf("clients = %d; want 1000", len(s.clients))
+	}
+	// 超过 DHCP_SNOOP_CLIENT_TIMEOUT 后随绑定一起清理
+	dhcpV4SnoopDiscover(s, "eth1", 1 << 20, start.Add(DHCP_SNOOP_CLIENT_TIMEOUT + DHCP_SNOOP_EXPIRE_INTERVAL + time.Second))
+	if len(s.clients) != 1 {
+		t.Errorf("clients after timeout = %d; want 1", len(s.clients))
+	}
+}
@@ -0,0 +1,188 @@
+// @@
+// @ Author       : Eacher
+// @ Date         : 2026-10-20 09:12:40
+// @ LastEditTime : 2026-10-21 15:46:03
+// @ LastEditors  : Eacher
+// @ --------------------------------------------------------------------------------<
+// @ Description  : DHCPv4 报文收发
+// @ --------------------------------------------------------------------------------<
+// @ FilePath     : /20yyq/packet/dhcpv4_transport.go
+// @@
+package packet
+
+import (
+	"encoding/binary"
+	"net"
+	"sync"
+	"time"
+)
+
+var IPv4Broadcast = IPv4{0xff, 0xff, 0xff, 0xff}
+
+// Receive 返回报文及其源 IP 地址
+type DhcpV4Transport interface {
+	Send(dhcp DhcpV4Packet, dst IPv4) error
+	Receive() (DhcpV4Packet, IPv4, error)
+	SetReadDeadline(t time.Time) error
+	Close() error
+}
+
+// 在以太网链路上自行封装 IPv4/UDP, 用于客户端尚未获得地址或服务端需要回复无地址的客户端
+type dhcpV4RawTransport struct {
+	link    FrameReadWriter
+	mac     HardwareAddr
+	ip      IPv4
+	srcPort uint16
+	dstPort uint16
+
+	mu        sync.Mutex
+	id        uint16
+	neighbors map[IPv4]HardwareAddr
+}
+
+/*
+	原始链路传输, ip 为发送源地址, 为零值时使用报文 CIAddr
+	单播目的 MAC 依次取: 发给客户端 (非中继) 的 BOOTREPLY 的 ChHardware, 收到过的该 IP 报文源 MAC, 否则广播
+	发往中继 GIAddr 的 BOOTREPLY 目的端口为 DHCP_ServerPort
+*/
+func NewDhcpV4RawTransport(link FrameReadWriter, mac HardwareAddr, ip IPv4, srcPort, dstPort uint16) DhcpV4Transport {
+	return &dhcpV4RawTransport{link: link, mac: mac, ip: ip, srcPort: srcPort, dstPort: dstPort, neighbors: make(map[IPv4]HardwareAddr)}
+}
+
+func (rt *dhcpV4RawTransport) Send(dhcp DhcpV4Packet, dst IPv4) error {
+	src := rt.ip
+	if src == (IPv4{}) {
+		src = dhcp.CIAddr
+	}
+	rt.mu.Lock()
+	dstMAC, ok := rt.neighbors[dst]
+	rt.id++
+	id := rt.id
+	rt.mu.Unlock()
+	toClient := dhcp.Op == DHCP_BOOTREPLY && (dhcp.GIAddr == (IPv4{}) || dst != dhcp.GIAddr)
+	switch {
+	case dst == IPv4Broadcast || toClient && dhcp.Flags&DHCP_BROADCAST_FLAG != 0:
+		dstMAC = Broadcast
+	case toClient:
+		dstMAC = HardwareAddr(dhcp.ChHardware[:6])
+	case !ok:
+		dstMAC = Broadcast
+	}
+	return rt.link.WriteFrame(dhcp.EthernetFrame(rt.mac, dstMAC, src, dst, rt.srcPort, dhcpV4DstPort(dhcp, dst, rt.dstPort), id))
+}
+
+func (rt *dhcpV4RawTransport) Receive() (DhcpV4Packet, IPv4, error) {
+	for {
+		b, err := rt.link.ReadFrame()
+		if err != nil {
+			return DhcpV4Packet{}, IPv4{}, err
+		}
+		eth, ip, udp, dhcp, ok := NewDhcpV4Frame(b)
+		if !ok || udp.DstPort != rt.srcPort || eth.HeadMAC[1] == rt.mac {
+			continue
+		}
+		if ip.Src != (IPv4{}) {
+			rt.mu.Lock()
+			rt.neighbors[ip.Src] = eth.HeadMAC[1]
+			rt.mu.Unlock()
+		}
+		return dhcp, ip.Src, nil
+	}
+}
+
+func (rt *dhcpV4RawTransport) SetReadDeadline(t time.Time) error {
+	return rt.link.SetReadDeadline(t)
+}
+
+func (rt *dhcpV4RawTransport) Close() error {
+	return rt.link.Close()
+}
+
+type dhcpV4UDPTransport struct {
+	conn *net.UDPConn
+	port int
+	buf  []byte
+}
+
+// 已配置地址后使用普通 UDP 套接字, port 为对端端口
+func NewDhcpV4UDPTransport(conn *net.UDPConn, port int) DhcpV4Transport {
+	return &dhcpV4UDPTransport{conn: conn, port: port, buf: make([]byte, maxFrameLength)}
+}
+
+func (ut *dhcpV4UDPTransport) Send(dhcp DhcpV4Packet, dst IPv4) error {
+	port := int(dhcpV4DstPort(dhcp, dst, uint16(ut.port)))
+	_, err := ut.conn.WriteToUDP(dhcp.WireFormat(), &net.UDPAddr{IP: net.IP(dst[:]), Port: port})
+	return err
+}
+
+func (ut *dhcpV4UDPTransport) Receive() (DhcpV4Packet, IPv4, error) {
+	for {
+		n, addr, err := ut.conn.ReadFromUDP(ut.buf)
+		if err != nil {
+			return DhcpV4Packet{}, IPv4{}, err
+		}
+		dhcp := NewDhcpV4Packet(ut.buf[:n])
+		if dhcp.Op == 0 {
+			continue
+		}
+		var src IPv4
+		if v4 := addr.IP.To4(); v4 != nil {
+			src = IPv4(v4)
+		}
+		return dhcp, src, nil
+	}
+}
+
+func (ut *dhcpV4UDPTransport) SetReadDeadline(t time.Time) error {
+	return ut.conn.SetReadDeadline(t)
+}
+
+func (ut *dhcpV4UDPTransport) Close() error {
+	return ut.conn.Close()
+}
+
+// RFC 2131 4.1: 服务端经中继应答时发往中继的 DHCP_ServerPort
+func dhcpV4DstPort(dhcp DhcpV4Packet, dst IPv4, port uint16) uint16 {
+	if dhcp.Op == DHCP_BOOTREPLY && dhcp.GIAddr != (IPv4{}) && dst == dhcp.GIAddr {
+		return DHCP_ServerPort
+	}
+	return port
+}
+
+/*
+	解析承载 DHCP 报文的以太网帧, 非 IPv4/UDP 帧, 分片或 IPv4 首部校验失败时 ok 为 false
+	不校验 UDP 校验和: 启用校验和卸载时经 AF_PACKET 读到的帧可能带有未填写完成的校验和
+*/
+func NewDhcpV4Frame(b []byte) (eth EthernetPacket, ip IPv4Packet, udp DUPPacket, dhcp DhcpV4Packet, ok bool) {
+	if len(b) < SizeofEthernetPacket+SizeofIPv4Packet+SizeofDUPPacket {
+		return
+	}
+	if eth = NewEthernetPacket(([SizeofEthernetPacket]byte)(b)); eth.FrameType != ETH_P_IP {
+		return
+	}
+	b = b[SizeofEthernetPacket:]
+	ip, next := NewIPv4Packet(b)
+	if next == 0 || ip.Protocol != 0x11 || ip.FragOff != 0 || ip.Flags&0x01 != 0 || len(b) < int(next)+SizeofDUPPacket {
+		return
+	}
+	b = b[next:]
+	udp = NewDUPPacket(([SizeofDUPPacket]byte)(b))
+	if int(udp.Len) < SizeofDUPPacket || int(udp.Len) > len(b) {
+		return
+	}
+	dhcp = NewDhcpV4Packet(b[SizeofDUPPacket:udp.Len])
+	ok = dhcp.Op != 0
+	return
+}
+
+// 封装为 Ethernet + IPv4 + UDP 完整帧, id 为 IPv4 Identification
+func (dhcp DhcpV4Packet) EthernetFrame(srcMAC, dstMAC HardwareAddr, src, dst IPv4, srcPort, dstPort, id uint16) []byte {
+	payload := dhcp.WireFormat()
+	udp := DUPPacket{SrcPort: srcPort, DstPort: dstPort, Len: uint16(SizeofDUPPacket + len(payload))}
+	udpb := append(udp.WireFormat(), payload...)
+	binary.BigEndian.PutUint16(udpb[6:8], DUPCheckSum(src, dst, udpb))
+	ip := IPv4Packet{Version: 4, TotalLen: uint16(SizeofIPv4Packet + len(udpb)), ID: id, TTL: 64, Protocol: 0x11, Src: src, Dst: dst}
+	eth := EthernetPacket{HeadMAC: [2]HardwareAddr{dstMAC, srcMAC}, FrameType: ETH_P_IP}
+	b := append(eth.WireFormat(), ip.WireFormat()...)
+	return append(b, udpb...)
+}
@@ -0,0 +1,309 @@
+// @@
+// @ Author       : Eacher
+// @ Date         : 2026-10-20 23:31:08
+// @ LastEditTime : 2026-10-21 16:23:15
+// @ LastEditors  : Eacher
+// @ --------------------------------------------------------------------------------<
+// @ Description  : 按 RFC 2131 校验 DHCPv4 报文
+// @ --------------------------------------------------------------------------------<
+// @ FilePath     : /20yyq/packet/dhcpv4_validate.go
+// @@
+package packet
+
+import (
+	"strings"
+)
+
+/*
+RFC 2131 4.3.1 Table 3: Fields and options used by DHCP servers
+
+	Field      DHCPOFFER            DHCPACK              DHCPNAK
+	-----      ---------            -------              -------
+	'op'       BOOTREPLY            BOOTREPLY            BOOTREPLY
+	'ciaddr'   0                    'ciaddr' from        0
+	                                DHCPREQUEST or 0
+	'yiaddr'   IP address offered   IP address           0
+	           to client            assigned to client
+	'siaddr'   IP address of next   IP address of next   0
+	           bootstrap server     bootstrap server
+
+	Option                    DHCPOFFER    DHCPACK            DHCPNAK
+	------                    ---------    -------            -------
+	Requested IP address      MUST NOT     MUST NOT           MUST NOT
+	IP address lease time     MUST         MUST (DHCPREQUEST) MUST NOT
+	                                       MUST NOT (DHCPINFORM)
+	DHCP message type         DHCPOFFER    DHCPACK            DHCPNAK
+	Parameter request list    MUST NOT     MUST NOT           MUST NOT
+	Server identifier         MUST         MUST               MUST
+	Maximum message size      MUST NOT     MUST NOT           MUST NOT
+	All others                MAY          MAY                MUST NOT
+
+RFC 2131 4.4.1 Table 5: Fields and options used by DHCP clients
+
+	Field      DHCPDISCOVER  DHCPREQUEST          DHCPDECLINE,
+	           DHCPINFORM                         DHCPRELEASE
+	-----      ------------  -----------          -----------
+	'op'       BOOTREQUEST   BOOTREQUEST          BOOTREQUEST
+	'ciaddr'   0 (DISCOVER)  0 or client's        0 (DECLINE)
+	           client's      network address      client's network
+	           network       (BOUND/RENEW/REBIND) address (RELEASE)
+	           address (INFORM)
+	'yiaddr'   0             0                    0
+	'siaddr'   0             0                    0
+
+	Option                     DHCPDISCOVER  DHCPREQUEST      DHCPDECLINE/
+	                           DHCPINFORM                     DHCPRELEASE
+	------                     ------------  -----------      -----------
+	Requested IP address       MAY (DISCOVER) MUST (in        MUST (DECLINE)
+	                           MUST NOT       SELECTING or    MUST NOT (RELEASE)
+	                           (INFORM)       INIT-REBOOT)
+	                                          MUST NOT (in BOUND
+	                                          or RENEWING)
+	IP address lease time      MAY (DISCOVER) MAY             MUST NOT
+	                           MUST NOT (INFORM)
+	Server identifier          MUST NOT      MUST (after      MUST
+	                                         SELECTING)
+	                                         MUST NOT (after
+	                                         INIT-REBOOT, BOUND,
+	                                         RENEWING or REBINDING)
+	Parameter request list     MAY           MAY              MUST NOT
+	Maximum message size       MAY           MAY              MUST NOT
+ */
+type DhcpV4Violation struct {
+	// 字段名 (例如 "ciaddr") 或选项名
+	Field 	string
+	Reason 	string
+}
+
+func (v DhcpV4Violation) Error() string {
+	return "dhcp: " + v.Field + ": " + v.Reason
+}
+
+// DHCPNAK 允许携带的选项
+var dhcpV4NakOptions = map[uint8]bool{
+	DHCP_Message_Type_Code: true, uint8(DHCP_Server_Identifier): true, uint8(DHCP_Error_Message): true,
+	uint8(DHCP_Vendor_Class_Identifier): true, DHCP_Client_Identifier: true, DHCP_Relay_Agent_Information: true,
+	DHCP_Authentication: true,
+}
+
+/*
+	检查 RFC 2131 的首部与选项规则并返回全部违规项, 合法报文返回 nil
+	不含选项 53 的报文按 BOOTP 处理, 只检查首部
+	DHCPREQUEST 按 4.3.2 由选项 54, 50 与 ciaddr 推断客户端状态
+ */
+func (dhcp DhcpV4Packet) Validate() []DhcpV4Violation {
+	var list []DhcpV4Violation
+	bad := func(field, reason string) {
+		list = append(list, DhcpV4Violation{field, reason})
+	}
+	options := DhcpV4Options(dhcp.Options)
+	has := func(code uint8) bool {
+		_, ok := options.Get(code)
+		return ok
+	}
+	must := func(code uint8, t DHCP_Message_Type) {
+		if !has(code) {
+			bad(DhcpV4OptionName(code), "MUST be present in " + t.String())
+		}
+	}
+	mustNot := func(code uint8, t DHCP_Message_Type) {
+		if has(code) {
+			bad(DhcpV4OptionName(code), "MUST NOT be present in " + t.String())
+		}
+	}
+	zero := func(field string, ip IPv4, t DHCP_Message_Type) {
+		if ip != (IPv4{}) {
+			bad(field, "MUST be 0 in " + t.String())
+		}
+	}
+	nonZero := func(field string, ip IPv4, t DHCP_Message_Type) {
+		if ip == (IPv4{}) {
+			bad(field, "MUST be set in " + t.String())
+		}
+	}
+
+	if dhcp.Op != DHCP_BOOTREQUEST && dhcp.Op != DHCP_BOOTREPLY {
+		bad("op", "MUST be BOOTREQUEST (1) or BOOTREPLY (2)")
+	}
+	if dhcp.HardwareLen > uint8(len(dhcp.ChHardware)) {
+		bad("hlen", "exceeds the 16 byte chaddr field")
+	} else if !dhcpV4Zero(dhcp.ChHardware[dhcp.HardwareLen:]) {
+		bad("chaddr", "has non-zero bytes beyond hlen")
+	}
+	if dhcp.HardwareType == DHCP_Ethernet_TYPE && dhcp.HardwareLen != DHCP_Ethernet_LEN {
+		bad("hlen", "MUST be 6 for Ethernet")
+	}
+	if dhcp.Hops > 16 {
+		bad("hops", "exceeds 16 (RFC 1542 4.1.1)")
+	}
+	list = append(list, dhcpV4ValidateOptions(options)...)
+
+	opp, ok := options.Get(DHCP_Message_Type_Code)
+	if !ok {
+		return list
+	}
+	if len(opp.Value) != 1 || opp.Value[0] < uint8(DHCP_DISCOVER) || opp.Value[0] > uint8(DHCP_LEASEACTIVE) {
+		bad(DhcpV4OptionName(DHCP_Message_Type_Code), "invalid message type")
+		return list
+	}
+	if !dhcp.HasMagicCookie() {
+		bad("vend", "DHCP message without magic cookie")
+	}
+	t := DHCP_Message_Type(opp.Value[0])
+	switch t {
+	case DHCP_OFFER, DHCP_ACK, DHCP_NAK, DHCP_FORCERENEW, DHCP_LEASEUNASSIGNED, DHCP_LEASEUNKNOWN, DHCP_LEASEACTIVE:
+		if dhcp.Op != DHCP_BOOTREPLY {
+			bad("op", "MUST be BOOTREPLY in " + t.String())
+		}
+	default:
+		if dhcp.Op != DHCP_BOOTREQUEST {
+			bad("op", "MUST be BOOTREQUEST in " + t.String())
+		}
+	}
+
+	switch t {
+	case DHCP_OFFER, DHCP_ACK, DHCP_NAK:
+		must(uint8(DHCP_Server_Identifier), t)
+		mustNot(uint8(DHCP_Requested_IP_Address), t)
+		mustNot(DHCP_Parameter_Request_List, t)
+		mustNot(DHCP_Maximum_Message_Size, t)
+	}
+	switch t {
+	case DHCP_OFFER:
+		zero("ciaddr", dhcp.CIAddr, t)
+		nonZero("yiaddr", dhcp.YIAddr, t)
+		must(uint8(DHCP_IP_Address_Lease), t)
+	case DHCP_ACK:
+		// 对 DHCPINFORM 的应答 yiaddr 为 0 且不含租期
+		if dhcp.YIAddr != (IPv4{}) {
+			must(uint8(DHCP_IP_Address_Lease), t)
+		} else if has(uint8(DHCP_IP_Address_Lease)) {
+			bad("yiaddr", "MUST be set when IP address lease time is present")
+		}
+	case DHCP_NAK:
+		zero("ciaddr", dhcp.CIAddr, t)
+		zero("yiaddr", dhcp.YIAddr, t)
+		zero("siaddr", dhcp.SIAddr, t)
+		for _, opp := range options {
+			if !dhcpV4NakOptions[opp.Code] {
+				bad(DhcpV4OptionName(opp.Code), "MUST NOT be present in " + t.String())
+			}
+		}
+		if dhcpV4CString(dhcp.HostName[:]) != "" || dhcpV4CString(dhcp.FileName[:]) != "" {
+			bad("sname/file", "MUST NOT be used in " + t.String())
+		}
+	case DHCP_DISCOVER, DHCP_REQUEST, DHCP_DECLINE, DHCP_RELEASE, DHCP_INFORM:
+		zero("yiaddr", dhcp.YIAddr, t)
+		zero("siaddr", dhcp.SIAddr, t)
+		if dhcp.HardwareLen == 0 || dhcpV4Zero(dhcp.ChHardware[:]) {
+			bad("chaddr", "MUST be set in " + t.String())
+		}
+	}
+	switch t {
+	case DHCP_DISCOVER:
+		zero("ciaddr", dhcp.CIAddr, t)
+		mustNot(uint8(DHCP_Server_Identifier), t)
+	case DHCP_REQUEST:
+		_, requested := options.Get(uint8(DHCP_Requested_IP_Address))
+		switch {
+		case has(uint8(DHCP_Server_Identifier)):
+			// SELECTING
+			zero("ciaddr", dhcp.CIAddr, t)
+			if !requested {
+				bad(DhcpV4OptionName(uint8(DHCP_Requested_IP_Address)), "MUST be present in REQUEST (SELECTING)")
+			}
+		case requested && dhcp.CIAddr != (IPv4{}):
+			bad(DhcpV4OptionName(uint8(DHCP_Requested_IP_Address)), "MUST NOT be present in REQUEST with ciaddr (BOUND/RENEWING/REBINDING)")
+		case !requested && dhcp.CIAddr == (IPv4{}):
+			bad("ciaddr", "REQUEST needs ciaddr (RENEWING/REBINDING) or Requested IP Address (INIT-REBOOT)")
+		}
+	case DHCP_DECLINE:
+		zero("ciaddr", dhcp.CIAddr, t)
+		must(uint8(DHCP_Requested_IP_Address), t)
+		must(uint8(DHCP_Server_Identifier), t)
+	case DHCP_RELEASE:
+		nonZero("ciaddr", dhcp.CIAddr, t)
+		mustNot(uint8(DHCP_Requested_IP_Address), t)
+		must(uint8(DHCP_Server_Identifier), t)
+	case DHCP_INFORM:
+		nonZero("ciaddr", dhcp.CIAddr, t)
+		mustNot(uint8(DHCP_Requested_IP_Address), t)
+		mustNot(uint8(DHCP_Server_Identifier), t)
+	case DHCP_FORCERENEW:
+		must(uint8(DHCP_Server_Identifier), t)
+	}
+	switch t {
+	case DHCP_DECLINE, DHCP_RELEASE, DHCP_INFORM:
+		mustNot(uint8(DHCP_IP_Address_Lease), t)
+	}
+	switch t {
+	case DHCP_DECLINE, DHCP_RELEASE:
+		mustNot(DHCP_Parameter_Request_List, t)
+		mustNot(DHCP_Maximum_Message_Size, t)
+	}
+	return list
+}
+
+// 选项长度与取值
+func dhcpV4ValidateOptions(options DhcpV4Options) []DhcpV4Violation {
+	var list []DhcpV4Violation
+	for _, opp := range options {
+		name, n := DhcpV4OptionName(opp.Code), len(opp.Value)
+		def, _ := LookupDhcpV4Option(opp.Code)
+		switch def.Type {
+		case DHCP_DATA_IPV4:
+			if n != 4 {
+				list = append(list, DhcpV4Violation{name, "length MUST be 4"})
+			}
+		case DHCP_DATA_IPV4_LIST:
+			if n < 4 || n % 4 != 0 {
+				list = append(list, DhcpV4Violation{name, "length MUST be a multiple of 4"})
+			}
+		case DHCP_DATA_DURATION, DHCP_DATA_UINT32, DHCP_DATA_INT32:
+			if n != 4 {
+				list = append(list, DhcpV4Violation{name, "length MUST be 4"})
+			}
+		case DHCP_DATA_UINT8, DHCP_DATA_BOOL, DHCP_DATA_MESSAGE_TYPE:
+			if n != 1 {
+				list = append(list, DhcpV4Violation{name, "length MUST be 1"})
+			}
+		case DHCP_DATA_UINT16:
+			if n != 2 {
+				list = append(list, DhcpV4Violation{name, "length MUST be 2"})
+			}
+		case DHCP_DATA_UINT16_LIST:
+			if n < 2 || n % 2 != 0 {
+				list = append(list, DhcpV4Violation{name, "length MUST be a multiple of 2"})
+			}
+		case DHCP_DATA_FLAG:
+			if n != 0 {
+				list = append(list, DhcpV4Violation{name, "length MUST be 0"})
+			}
+		case DHCP_DATA_STRING, DHCP_DATA_UINT8_LIST, DHCP_DATA_OPTION_CODES, DHCP_DATA_CLIENT_ID:
+			if n < 1 {
+				list = append(list, DhcpV4Violation{name, "length MUST be at least 1"})
+			}
+		}
+	}
+	if size, ok := options.Get(DHCP_Maximum_Message_Size); ok && len(size.Value) == 2 && uint16(size.Value[0]) << 8 | uint16(size.Value[1]) < 576 {
+		list = append(list, DhcpV4Violation{DhcpV4OptionName(DHCP_Maximum_Message_Size), "MUST be at least 576"})
+	}
+	// RFC 2131 4.4.5: T1 < T2 < 租期
+	lease, t1, t2 := dhcpV4OptionSeconds(options, uint8(DHCP_IP_Address_Lease)), dhcpV4OptionSeconds(options, uint8(DHCP_Renewal_Time)), dhcpV4OptionSeconds(options, uint8(DHCP_Rebinding_Time))
+	if t1 > 0 && t2 > 0 && t1 >= t2 {
+		list = append(list, DhcpV4Violation{DhcpV4OptionName(uint8(DHCP_Renewal_Time)), "MUST be less than " + DhcpV4OptionName(uint8(DHCP_Rebinding_Time))})
+	}
+	for _, code := range []uint8{uint8(DHCP_Renewal_Time), uint8(DHCP_Rebinding_Time)} {
+		if v := dhcpV4OptionSeconds(options, code); lease > 0 && v > lease {
+			list = append(list, DhcpV4Violation{DhcpV4OptionName(code), "exceeds " + strings.ToLower(DhcpV4OptionName(uint8(DHCP_IP_Address_Lease)))})
+		}
+	}
+	return list
+}
+
+func dhcpV4OptionSeconds(options DhcpV4Options, code uint8) uint32 {
+	if opp, ok := options.Get(code); ok && len(opp.Value) == 4 {
+		return uint32(opp.Value[0]) << 24 | uint32(opp.Value[1]) << 16 | uint32(opp.Value[2]) << 8 | uint32(opp.Value[3])
+	}
+	return 0
+}
@@ -0,0 +1,560 @@
+// @@
+// @ Author       : Eacher
+// @ Date         : 2026-10-20 18:31:07
+// @ LastEditTime : 2026-10-20 18:31:07
+// @ LastEditors  : Eacher
+// @ --------------------------------------------------------------------------------<
+// @ Description  : 厂商选项 43/124/125 与按厂商类别注册的子选项结构
+// @ --------------------------------------------------------------------------------<
+// @ FilePath     : /20yyq/packet/dhcpv4_vendor.go
+// @@
+package packet
+
+import (
+	"encoding/binary"
+	"errors"
+	"strings"
+	"sync"
+)
+
+/*
+8.4. Vendor Specific Information
+	This option is used by clients and servers to exchange vendor-
+	specific information.  The information is an opaque object of n
+	octets, presumably interpreted by vendor-specific code on the clients
+	and servers.  The definition of this information is vendor specific.
+	The vendor is indicated in the vendor class identifier option.
+
+	If a vendor potentially encodes more than one item of information in
+	this option, then the vendor SHOULD encode the option using
+	"Encapsulated vendor-specific options" as described below:
+
+	The Encapsulated vendor-specific options field SHOULD be encoded as a
+	sequence of code/length/value fields of identical syntax to the DHCP
+	options field with the following exceptions:
+
+	1) There SHOULD NOT be a "magic cookie" field in the encapsulated
+	   vendor-specific extensions field.
+
+	2) Codes other than 0 or 255 MAY be redefined by the vendor within
+	   the encapsulated vendor-specific extensions field, but SHOULD
+	   conform to the tag-length-value syntax defined in section 2.
+
+	3) Code 255 (END), if present, signifies the end of the
+	   encapsulated vendor extensions, not the end of the vendor
+	   extensions field. If no code 255 is present, then the end of the
+	   enclosing vendor-specific information field is taken as the end
+	   of the encapsulated vendor-specific extensions field.
+
+	The code for this option is 43 and its minimum length is 1.
+
+	Code   Len   Vendor-specific information
+	+-----+-----+-----+-----+---
+	|  43 |  n  |  i1 |  i2 | ...
+	+-----+-----+-----+-----+---
+
+RFC 3925  Vendor-Identifying Vendor Options for DHCPv4
+
+	                      1 1 1 1 1 1
+	  0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5
+	 +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+	 |  option-code  |  option-len   |
+	 +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+	 |      enterprise-number1       |
+	 |                               |
+	 +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+	 |   data-len1   |               |
+	 +-+-+-+-+-+-+-+-+               |
+	 /      vendor-class-data1       /    option-data1 (125)
+	 +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+	 |      enterprise-number2       |
+	 ...
+
+	选项 124 的 vendor-class-data 为若干 (len, opaque) 项
+	选项 125 的 option-data 为 (code, len, value) 子选项, 没有 pad/end
+	超过 255 bytes 时按 RFC 3396 拆分为多个实例
+ */
+const (
+	DHCP_Vendor_Specific_Information 	= 43
+	DHCP_VI_Vendor_Class 				= 124
+	DHCP_VI_Vendor_Specific_Information = 125
+)
+
+// 子选项 Value 的解释方式
+type DHCP_VENDOR_VALUE_TYPE uint8
+
+const (
+	DHCP_VENDOR_BINARY DHCP_VENDOR_VALUE_TYPE = iota
+	DHCP_VENDOR_STRING
+	DHCP_VENDOR_IPV4
+	DHCP_VENDOR_IPV4_LIST
+	DHCP_VENDOR_UINT8
+	DHCP_VENDOR_UINT16
+	DHCP_VENDOR_UINT32
+	// Value 为嵌套的子选项, 结构由 Nested 描述
+	DHCP_VENDOR_NESTED
+)
+
+var ErrDhcpVendorFormat = errors.New("dhcp: malformed vendor option")
+
+// 厂商子选项列表, 与 OptionsPacket 编码相同
+type DhcpV4VendorOptions []OptionsPacket
+
+// 选项 43 的 Value, 按 RFC 2132 8.4 跳过 pad 并在 end 处结束
+func NewDhcpV4VendorOptions(b []byte) DhcpV4VendorOptions {
+	return DhcpV4VendorOptions(NewOptionsPacket(b))
+}
+
+// 没有 pad/end 的子选项, 截断时返回错误
+func decodeSubOptions(b []byte) (list DhcpV4VendorOptions, err error) {
+	for idx := 0; idx < len(b); {
+		if idx + SizeofOptionsPacket > len(b) {
+			return nil, ErrDhcpVendorFormat
+		}
+		next := idx + SizeofOptionsPacket + int(b[idx+1])
+		if next > len(b) {
+			return nil, ErrDhcpVendorFormat
+		}
+		value := make([]byte, b[idx+1])
+		copy(value, b[idx+SizeofOptionsPacket:next])
+		list, idx = append(list, OptionsPacket{b[idx], b[idx+1], value}), next
+	}
+	return
+}
+
+// 子选项 Value 不能超过 255 bytes, Code 为 0 的子选项被忽略
+func (o DhcpV4VendorOptions) Encode() ([]byte, error) {
+	var b []byte
+	for _, opp := range o {
+		if opp.Code == 0 {
+			continue
+		}
+		if len(opp.Value) > 255 {
+			return nil, ErrDhcpVendorFormat
+		}
+		b = append(append(b, opp.Code, uint8(len(opp.Value))), opp.Value...)
+	}
+	return b, nil
+}
+
+func (o DhcpV4VendorOptions) Get(code uint8) ([]byte, bool) {
+	if opp, ok := DhcpV4Options(o).Get(code); ok {
+		return opp.Value, true
+	}
+	return nil, false
+}
+
+func SetDHCPVendorSubOption(code uint8, b []byte) OptionsPacket {
+	if code == 0 || code == 255 || len(b) > 255 {
+		return OptionsPacket{}
+	}
+	return OptionsPacket{code, uint8(len(b)), b}
+}
+
+// 选项 43
+func SetDHCPVendorSpecific(subs ...OptionsPacket) OptionsPacket {
+	b, err := DhcpV4VendorOptions(subs).Encode()
+	if err != nil || len(b) < 1 {
+		return OptionsPacket{}
+	}
+	return SetDHCPOption(DHCP_Vendor_Specific_Information, b)
+}
+
+// 选项 43 的子选项, class 为空时按本报文的选项 60 查找结构, 找到时校验子选项
+func (dhcp DhcpV4Packet) VendorSpecific(class string) (DhcpV4VendorOptions, bool) {
+	opp, ok := dhcp.Option(DHCP_Vendor_Specific_Information)
+	if !ok || len(opp.Value) < 1 {
+		return nil, false
+	}
+	if class == "" {
+		class, _ = dhcp.VendorClassIdentifier()
+	}
+	o := NewDhcpV4VendorOptions(opp.Value)
+	if s, ok := LookupDhcpV4VendorClass(class); ok && s.Validate(o) != nil {
+		return nil, false
+	}
+	return o, len(o) > 0
+}
+
+// 选项 124 的一个厂商
+type DhcpV4VIVendorClass struct {
+	Enterprise 	uint32
+	Data 		[][]byte
+}
+
+func NewDhcpV4VIVendorClass(b []byte) (list []DhcpV4VIVendorClass, err error) {
+	for idx := 0; idx < len(b); {
+		if idx + 5 > len(b) || idx + 5 + int(b[idx+4]) > len(b) {
+			return nil, ErrDhcpVendorFormat
+		}
+		vc, data := DhcpV4VIVendorClass{Enterprise: binary.BigEndian.Uint32(b[idx:])}, b[idx+5:idx+5+int(b[idx+4])]
+		for i := 0; i < len(data); {
+			if i + 1 + int(data[i]) > len(data) {
+				return nil, ErrDhcpVendorFormat
+			}
+			vc.Data, i = append(vc.Data, append([]byte{}, data[i+1:i+1+int(data[i])]...)), i + 1 + int(data[i])
+		}
+		list, idx = append(list, vc), idx + 5 + int(b[idx+4])
+	}
+	return
+}
+
+func EncodeDhcpV4VIVendorClass(list ...DhcpV4VIVendorClass) ([]byte, error) {
+	var b []byte
+	for _, vc := range list {
+		var data []byte
+		for _, d := range vc.Data {
+			if len(d) > 255 {
+				return nil, ErrDhcpVendorFormat
+			}
+			data = append(append(data, uint8(len(d))), d...)
+		}
+		if len(data) > 255 {
+			return nil, ErrDhcpVendorFormat
+		}
+		b = binary.BigEndian.AppendUint32(b, vc.Enterprise)
+		b = append(append(b, uint8(len(data))), data...)
+	}
+	return b, nil
+}
+
+func SetDHCPVIVendorClass(list ...DhcpV4VIVendorClass) OptionsPacket {
+	b, err := EncodeDhcpV4VIVendorClass(list...)
+	if err != nil || len(b) < 1 {
+		return OptionsPacket{}
+	}
+	return SetDHCPOption(DHCP_VI_Vendor_Class, b)
+}
+
+func (dhcp DhcpV4Packet) VIVendorClass() ([]DhcpV4VIVendorClass, bool) {
+	if opp, ok := dhcp.Option(DHCP_VI_Vendor_Class); ok {
+		list, err := NewDhcpV4VIVendorClass(opp.Value)
+		return list, err == nil && len(list) > 0
+	}
+	return nil, false
+}
+
+// 选项 125 的一个厂商
+type DhcpV4VIVendorInfo struct {
+	Enterprise 	uint32
+	Options 	DhcpV4VendorOptions
+}
+
+func NewDhcpV4VIVendorInfo(b []byte) (list []DhcpV4VIVendorInfo, err error) {
+	for idx := 0; idx < len(b); {
+		if idx + 5 > len(b) || idx + 5 + int(b[idx+4]) > len(b) {
+			return nil, ErrDhcpVendorFormat
+		}
+		vi := DhcpV4VIVendorInfo{Enterprise: binary.BigEndian.Uint32(b[idx:])}
+		if vi.Options, err = decodeSubOptions(b[idx+5:idx+5+int(b[idx+4])]); err != nil {
+			return nil, err
+		}
+		list, idx = append(list, vi), idx + 5 + int(b[idx+4])
+	}
+	return
+}
+
+func EncodeDhcpV4VIVendorInfo(list ...DhcpV4VIVendorInfo) ([]byte, error) {
+	var b []byte
+	for _, vi := range list {
+		data, err := vi.Options.Encode()
+		if err != nil || len(data) > 255 {
+			return nil, ErrDhcpVendorFormat
+		}
+		b = binary.BigEndian.AppendUint32(b, vi.Enterprise)
+		b = append(append(b, uint8(len(data))), data...)
+	}
+	return b, nil
+}
+
+func SetDHCPVIVendorInfo(list ...DhcpV4VIVendorInfo) OptionsPacket {
+	b, err := EncodeDhcpV4VIVendorInfo(list...)
+	if err != nil || len(b) < 1 {
+		return OptionsPacket{}
+	}
+	return SetDHCPOption(DHCP_VI_Vendor_Specific_Information, b)
+}
+
+func (dhcp DhcpV4Packet) VIVendorInfo() ([]DhcpV4VIVendorInfo, bool) {
+	if opp, ok := dhcp.Option(DHCP_VI_Vendor_Specific_Information); ok {
+		list, err := NewDhcpV4VIVendorInfo(opp.Value)
+		return list, err == nil && len(list) > 0
+	}
+	return nil, false
+}
+
+// 指定企业号的子选项, 注册了结构时校验子选项
+func (dhcp DhcpV4Packet) VIVendorInfoFor(enterprise uint32) (DhcpV4VendorOptions, bool) {
+	list, _ := dhcp.VIVendorInfo()
+	for _, vi := range list {
+		if vi.Enterprise != enterprise {
+			continue
+		}
+		if s, ok := LookupDhcpV4VendorEnterprise(enterprise); ok && s.Validate(vi.Options) != nil {
+			return nil, false
+		}
+		return vi.Options, true
+	}
+	return nil, false
+}
+
+type DhcpV4VendorSubOption struct {
+	Name 	string
+	Type 	DHCP_VENDOR_VALUE_TYPE
+	// Type 为 DHCP_VENDOR_NESTED 时的子选项结构
+	Nested 	*DhcpV4VendorSchema
+}
+
+// 厂商子选项结构, 未列出的 Code 按 DHCP_VENDOR_BINARY 处理
+type DhcpV4VendorSchema struct {
+	Name 		string
+	SubOptions 	map[uint8]DhcpV4VendorSubOption
+}
+
+func (s DhcpV4VendorSchema) SubOption(code uint8) DhcpV4VendorSubOption {
+	if sub, ok := s.SubOptions[code]; ok {
+		return sub
+	}
+	return DhcpV4VendorSubOption{Type: DHCP_VENDOR_BINARY}
+}
+
+// 按子选项类型检查长度
+func (s DhcpV4VendorSchema) Validate(o DhcpV4VendorOptions) error {
+	for _, opp := range o {
+		if _, err := s.Value(opp); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/*
+	按结构转换子选项的 Value:
+	DHCP_VENDOR_BINARY []byte, DHCP_VENDOR_STRING string, DHCP_VENDOR_IPV4 IPv4, DHCP_VENDOR_IPV4_LIST []IPv4
+	DHCP_VENDOR_UINT8 uint8, DHCP_VENDOR_UINT16 uint16, DHCP_VENDOR_UINT32 uint32, DHCP_VENDOR_NESTED DhcpV4VendorOptions
+ */
+func (s DhcpV4VendorSchema) Value(opp OptionsPacket) (interface{}, error) {
+	sub, b := s.SubOption(opp.Code), opp.Value
+	switch sub.Type {
+	case DHCP_VENDOR_STRING:
+		return strings.TrimRight(string(b), "\x00"), nil
+	case DHCP_VENDOR_IPV4:
+		if len(b) == 4 {
+			return IPv4(b), nil
+		}
+	case DHCP_VENDOR_IPV4_LIST:
+		if len(b) > 0 && len(b) % 4 == 0 {
+			list := make([]IPv4, len(b) / 4)
+			for i := range list {
+				list[i] = IPv4(b[i*4:])
+			}
+			return list, nil
+		}
+	case DHCP_VENDOR_UINT8:
+		if len(b) == 1 {
+			return b[0], nil
+		}
+	case DHCP_VENDOR_UINT16:
+		if len(b) == 2 {
+			return binary.BigEndian.Uint16(b), nil
+		}
+	case DHCP_VENDOR_UINT32:
+		if len(b) == 4 {
+			return binary.BigEndian.Uint32(b), nil
+		}
+	case DHCP_VENDOR_NESTED:
+		nested, err := decodeSubOptions(b)
+		if err == nil && sub.Nested != nil {
+			err = sub.Nested.Validate(nested)
+		}
+		if err == nil {
+			return nested, nil
+		}
+	default:
+		return b, nil
+	}
+	return nil, ErrDhcpVendorFormat
+}
+
+// Value 的逆过程, v 的类型与 Value 返回的类型一致
+func (s DhcpV4VendorSchema) Encode(code uint8, v interface{}) (OptionsPacket, error) {
+	var b []byte
+	switch sub := s.SubOption(code); {
+	case sub.Type == DHCP_VENDOR_BINARY:
+		b, _ = v.([]byte)
+	case sub.Type == DHCP_VENDOR_STRING:
+		str, _ := v.(string)
+		b = []byte(str)
+	case sub.Type == DHCP_VENDOR_IPV4:
+		if ip, ok := v.(IPv4); ok {
+			b = ip[:]
+		}
+	case sub.Type == DHCP_VENDOR_IPV4_LIST:
+		list, _ := v.([]IPv4)
+		for _, ip := range list {
+			b = append(b, ip[:]...)
+		}
+	case sub.Type == DHCP_VENDOR_UINT8:
+		if n, ok := v.(uint8); ok {
+			b = []byte{n}
+		}
+	case sub.Type == DHCP_VENDOR_UINT16:
+		if n, ok := v.(uint16); ok {
+			b = binary.BigEndian.AppendUint16(nil, n)
+		}
+	case sub.Type == DHCP_VENDOR_UINT32:
+		if n, ok := v.(uint32); ok {
+			b = binary.BigEndian.AppendUint32(nil, n)
+		}
+	case sub.Type == DHCP_VENDOR_NESTED:
+		if nested, ok := v.(DhcpV4VendorOptions); ok {
+			b, _ = nested.Encode()
+		}
+	}
+	opp := SetDHCPVendorSubOption(code, b)
+	if _, err := s.Value(opp); err != nil || opp.Code == 0 {
+		return OptionsPacket{}, ErrDhcpVendorFormat
+	}
+	return opp, nil
+}
+
+var dhcpV4VendorSchemas = struct {
+	sync.RWMutex
+	class 		map[string]DhcpV4VendorSchema
+	enterprise 	map[uint32]DhcpV4VendorSchema
+}{class: map[string]DhcpV4VendorSchema{}, enterprise: map[uint32]DhcpV4VendorSchema{}}
+
+// 注册选项 43 的子选项结构, class 按前缀匹配选项 60, 如 "PXEClient" 匹配 "PXEClient:Arch:00000:UNDI:002001"
+func RegisterDhcpV4VendorClass(class string, s DhcpV4VendorSchema) {
+	dhcpV4VendorSchemas.Lock()
+	dhcpV4VendorSchemas.class[class] = s
+	dhcpV4VendorSchemas.Unlock()
+}
+
+// 注册选项 125 中企业号的子选项结构
+func RegisterDhcpV4VendorEnterprise(enterprise uint32, s DhcpV4VendorSchema) {
+	dhcpV4VendorSchemas.Lock()
+	dhcpV4VendorSchemas.enterprise[enterprise] = s
+	dhcpV4VendorSchemas.Unlock()
+}
+
+// 最长前缀匹配
+func LookupDhcpV4VendorClass(class string) (s DhcpV4VendorSchema, ok bool) {
+	if class == "" {
+		return
+	}
+	dhcpV4VendorSchemas.RLock()
+	defer dhcpV4VendorSchemas.RUnlock()
+	match := -1
+	for k, v := range dhcpV4VendorSchemas.class {
+		if len(k) > match && strings.HasPrefix(class, k) {
+			s, ok, match = v, true, len(k)
+		}
+	}
+	return
+}
+
+func LookupDhcpV4VendorEnterprise(enterprise uint32) (s DhcpV4VendorSchema, ok bool) {
+	dhcpV4VendorSchemas.RLock()
+	s, ok = dhcpV4VendorSchemas.enterprise[enterprise]
+	dhcpV4VendorSchemas.RUnlock()
+	return
+}
+
+/*
+	Preboot Execution Environment (PXE) Specification 2.1, 2.4 DHCP Options
+	选项 60 以 "PXEClient" 开头时选项 43 的子选项
+ */
+const (
+	DHCP_VENDOR_CLASS_PXE = "PXEClient"
+
+	PXE_MTFTP_IP 				= 1
+	PXE_MTFTP_CPORT 			= 2
+	PXE_MTFTP_SPORT 			= 3
+	PXE_MTFTP_TMOUT 			= 4
+	PXE_MTFTP_DELAY 			= 5
+	PXE_DISCOVERY_CONTROL 		= 6
+	PXE_DISCOVERY_MCAST_ADDR 	= 7
+	PXE_BOOT_SERVERS 			= 8
+	PXE_BOOT_MENU 				= 9
+	PXE_MENU_PROMPT 			= 10
+	PXE_BOOT_ITEM 				= 71
+)
+
+/*
+	TR-069 Amendment 6, 3.1 ACS Discovery
+	选项 60 为 "dslforum.org" 时选项 43 的子选项, 均为字符串
+
+	TR-111 2.2.1 Device-Gateway Association
+	选项 125 中企业号 3561 (Broadband Forum) 的子选项
+ */
+const (
+	DHCP_VENDOR_CLASS_TR069 = "dslforum.org"
+	DHCP_ENTERPRISE_BBF 	= 3561
+
+	TR069_ACS_URL 					= 1
+	TR069_PROVISIONING_CODE 		= 2
+	TR069_RETRY_MINIMUM_WAIT 		= 3
+	TR069_RETRY_INTERVAL_MULTIPLIER = 4
+
+	TR111_DEVICE_MANUFACTURER_OUI 	= 1
+	TR111_DEVICE_SERIAL_NUMBER 		= 2
+	TR111_DEVICE_PRODUCT_CLASS 		= 3
+	TR111_GATEWAY_MANUFACTURER_OUI 	= 4
+	TR111_GATEWAY_SERIAL_NUMBER 	= 5
+	TR111_GATEWAY_PRODUCT_CLASS 	= 6
+)
+
+var (
+	DhcpV4VendorSchemaPXE = DhcpV4VendorSchema{Name: "pxe", SubOptions: map[uint8]DhcpV4VendorSubOption{
+		PXE_MTFTP_IP: {Name: "mtftp-ip", Type: DHCP_VENDOR_IPV4},
+		PXE_MTFTP_CPORT: {Name: "mtftp-cport", Type: DHCP_VENDOR_UINT16},
+		PXE_MTFTP_SPORT: {Name: "mtftp-sport", Type: DHCP_VENDOR_UINT16},
+		PXE_MTFTP_TMOUT: {Name: "mtftp-tmout", Type: DHCP_VENDOR_UINT8},
+		PXE_MTFTP_DELAY: {Name: "mtftp-delay", Type: DHCP_VENDOR_UINT8},
+		PXE_DISCOVERY_CONTROL: {Name: "discovery-control", Type: DHCP_VENDOR_UINT8},
+		PXE_DISCOVERY_MCAST_ADDR: {Name: "discovery-mcast-addr", Type: DHCP_VENDOR_IPV4},
+		PXE_BOOT_SERVERS: {Name: "boot-servers", Type: DHCP_VENDOR_BINARY},
+		PXE_BOOT_MENU: {Name: "boot-menu", Type: DHCP_VENDOR_BINARY},
+		PXE_MENU_PROMPT: {Name: "menu-prompt", Type: DHCP_VENDOR_BINARY},
+		PXE_BOOT_ITEM: {Name: "boot-item", Type: DHCP_VENDOR_UINT32},
+	}}
+
+	DhcpV4VendorSchemaTR069 = DhcpV4VendorSchema{Name: "tr069", SubOptions: map[uint8]DhcpV4VendorSubOption{
+		TR069_ACS_URL: {Name: "acs-url", Type: DHCP_VENDOR_STRING},
+		TR069_PROVISIONING_CODE: {Name: "provisioning-code", Type: DHCP_VENDOR_STRING},
+		TR069_RETRY_MINIMUM_WAIT: {Name: "cwmp-retry-minimum-wait-interval", Type: DHCP_VENDOR_STRING},
+		TR069_RETRY_INTERVAL_MULTIPLIER: {Name: "cwmp-retry-interval-multiplier", Type: DHCP_VENDOR_STRING},
+	}}
+
+	DhcpV4VendorSchemaTR111 = DhcpV4VendorSchema{Name: "tr111", SubOptions: map[uint8]DhcpV4VendorSubOption{
+		TR111_DEVICE_MANUFACTURER_OUI: {Name: "device-manufacturer-oui", Type: DHCP_VENDOR_STRING},
+		TR111_DEVICE_SERIAL_NUMBER: {Name: "device-serial-number", Type: DHCP_VENDOR_STRING},
+		TR111_DEVICE_PRODUCT_CLASS: {Name: "device-product-class", Type: DHCP_VENDOR_STRING},
+		TR111_GATEWAY_MANUFACTURER_OUI: {Name: "gateway-manufacturer-oui", Type: DHCP_VENDOR_STRING},
+		TR111_GATEWAY_SERIAL_NUMBER: {Name: "gateway-serial-number", Type: DHCP_VENDOR_STRING},
+		TR111_GATEWAY_PRODUCT_CLASS: {Name: "gateway-product-class", Type: DHCP_VENDOR_STRING},
+	}}
+)
+
+func init() {
+	RegisterDhcpV4VendorClass(DHCP_VENDOR_CLASS_PXE, DhcpV4VendorSchemaPXE)
+	RegisterDhcpV4VendorClass(DHCP_VENDOR_CLASS_TR069, DhcpV4VendorSchemaTR069)
+	RegisterDhcpV4VendorEnterprise(DHCP_ENTERPRISE_BBF, DhcpV4VendorSchemaTR111)
+}
+
+// TR-069 ACS 地址, 用于 CPE 读取服务端下发的选项 43
+func (o DhcpV4VendorOptions) ACSURL() (string, bool) {
+	if b, ok := o.Get(TR069_ACS_URL); ok && len(b) > 0 {
+		return strings.TrimRight(string(b), "\x00"), true
+	}
+	return "", false
+}
+
+func SetDHCPTR069ACSURL(url string) OptionsPacket {
+	opp, _ := DhcpV4VendorSchemaTR069.Encode(TR069_ACS_URL, url)
+	if opp.Code == 0 || len(opp.Value) < 1 {
+		return OptionsPacket{}
+	}
+	return SetDHCPVendorSpecific(opp)
+}
@@ -0,0 +1,361 @@
+// @@
+// @ Author       : Eacher
+// @ Date         : 2026-10-20 23:06:12
+// @ LastEditTime : 2026-10-21 16:51:38
+// @ LastEditors  : Eacher
+// @ --------------------------------------------------------------------------------<
+// @ Description  : RFC 8415 DHCPv6 客户端/服务端与中继报文
+// @ --------------------------------------------------------------------------------<
+// @ FilePath     : /20yyq/packet/dhcpv6.go
+// @@
+package packet
+
+import (
+	"encoding/binary"
+	"errors"
+	"strconv"
+)
+
+const (
+	DHCPV6_ClientPort 	= 0x0222
+	DHCPV6_ServerPort 	= 0x0223
+
+	SizeofDhcpV6Packet 		= 0x04
+	SizeofDhcpV6RelayPacket = 0x22
+	SizeofDhcpV6Option 		= 0x04
+
+	// RFC 8415 7.6 中继嵌套层数上限
+	DHCPV6_HOP_COUNT_LIMIT 	= 0x08
+)
+
+var (
+	// All_DHCP_Relay_Agents_and_Servers ff02::1:2
+	DHCPV6_AllRelayAgentsAndServers = IPv6{0xff, 0x02, 14: 0x01, 15: 0x02}
+	// All_DHCP_Servers ff05::1:3
+	DHCPV6_AllServers 				= IPv6{0xff, 0x05, 14: 0x01, 15: 0x03}
+)
+
+var (
+	ErrDhcpV6Packet = errors.New("dhcpv6: malformed packet")
+	ErrDhcpV6Option = errors.New("dhcpv6: malformed option")
+)
+
+/*
+RFC 8415 7.3  DHCP Message Types
+	SOLICIT (1)  ADVERTISE (2)  REQUEST (3)  CONFIRM (4)  RENEW (5)  REBIND (6)
+	REPLY (7)  RELEASE (8)  DECLINE (9)  RECONFIGURE (10)  INFORMATION-REQUEST (11)
+	RELAY-FORW (12)  RELAY-REPL (13)
+ */
+type DHCPV6_Message_Type uint8
+
+const (
+	DHCPV6_SOLICIT DHCPV6_Message_Type = iota + 1
+	DHCPV6_ADVERTISE
+	DHCPV6_REQUEST
+	DHCPV6_CONFIRM
+	DHCPV6_RENEW
+	DHCPV6_REBIND
+	DHCPV6_REPLY
+	DHCPV6_RELEASE
+	DHCPV6_DECLINE
+	DHCPV6_RECONFIGURE
+	DHCPV6_INFORMATION_REQUEST
+	DHCPV6_RELAY_FORW
+	DHCPV6_RELAY_REPL
+)
+
+var dhcpV6MessageNames = [...]string{
+	DHCPV6_SOLICIT: "SOLICIT", DHCPV6_ADVERTISE: "ADVERTISE", DHCPV6_REQUEST: "REQUEST", DHCPV6_CONFIRM: "CONFIRM",
+	DHCPV6_RENEW: "RENEW", DHCPV6_REBIND: "REBIND", DHCPV6_REPLY: "REPLY", DHCPV6_RELEASE: "RELEASE",
+	DHCPV6_DECLINE: "DECLINE", DHCPV6_RECONFIGURE: "RECONFIGURE", DHCPV6_INFORMATION_REQUEST: "INFORMATION-REQUEST",
+	DHCPV6_RELAY_FORW: "RELAY-FORW", DHCPV6_RELAY_REPL: "RELAY-REPL",
+}
+
+func (t DHCPV6_Message_Type) String() string {
+	if int(t) < len(dhcpV6MessageNames) && dhcpV6MessageNames[t] != "" {
+		return dhcpV6MessageNames[t]
+	}
+	return "UNKNOWN(" + strconv.Itoa(int(t)) + ")"
+}
+
+func (t DHCPV6_Message_Type) IsRelay() bool {
+	return t == DHCPV6_RELAY_FORW || t == DHCPV6_RELAY_REPL
+}
+
+/*
+RFC 8415 8  Client/Server Message Formats
+	 0                   1                   2                   3
+	 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
+	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+	|    msg-type   |               transaction-id                  |
+	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+	|                                                               |
+	.                            options                            .
+	.                 (variable number and length)                  .
+	|                                                               |
+	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+
+	TransactionID 只使用低 24 位
+ */
+type DhcpV6Packet struct {
+	MessageType 	DHCPV6_Message_Type
+	TransactionID 	uint32
+	Options 		DhcpV6Options
+}
+
+func NewDhcpV6Packet(b []byte) (dhcp DhcpV6Packet, err error) {
+	if len(b) < SizeofDhcpV6Packet || DHCPV6_Message_Type(b[0]).IsRelay() {
+		return dhcp, ErrDhcpV6Packet
+	}
+	dhcp.MessageType = DHCPV6_Message_Type(b[0])
+	dhcp.TransactionID = binary.BigEndian.Uint32(b[0:4]) & 0xffffff
+	dhcp.Options, err = NewDhcpV6Options(b[SizeofDhcpV6Packet:])
+	return
+}
+
+// 选项超过 65535 字节时返回 nil
+func (dhcp DhcpV6Packet) WireFormat() []byte {
+	options := dhcp.Options.WireFormat()
+	if options == nil {
+		return nil
+	}
+	b := binary.BigEndian.AppendUint32(nil, dhcp.TransactionID & 0xffffff)
+	b[0] = uint8(dhcp.MessageType)
+	return append(b, options...)
+}
+
+/*
+RFC 8415 9  Relay Agent/Server Message Formats
+	 0                   1                   2                   3
+	 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
+	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+	|    msg-type   |   hop-count   |                               |
+	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+                               |
+	|                                                               |
+	|                         link-address                          |
+	|                                                               |
+	|                               +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+	|                               |                               |
+	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+                               |
+	|                                                               |
+	|                         peer-address                          |
+	|                                                               |
+	|                               +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+	|                               |                               |
+	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+                               |
+	.                                                               .
+	.            options (variable number and length)   ....        .
+	|                                                               |
+	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+
+	被中继的报文位于 Relay Message 选项 (9)
+ */
+type DhcpV6RelayPacket struct {
+	MessageType 	DHCPV6_Message_Type
+	HopCount 		uint8
+	LinkAddr 		IPv6
+	PeerAddr 		IPv6
+	Options 		DhcpV6Options
+}
+
+func NewDhcpV6RelayPacket(b []byte) (relay DhcpV6RelayPacket, err error) {
+	if len(b) < SizeofDhcpV6RelayPacket || !DHCPV6_Message_Type(b[0]).IsRelay() {
+		return relay, ErrDhcpV6Packet
+	}
+	relay.MessageType, relay.HopCount = DHCPV6_Message_Type(b[0]), b[1]
+	relay.LinkAddr, relay.PeerAddr = *(*IPv6)(b[2:18]), *(*IPv6)(b[18:34])
+	relay.Options, err = NewDhcpV6Options(b[SizeofDhcpV6RelayPacket:])
+	return
+}
+
+/*
+	中继代理封装收到的报文, msg 为收到的完整 DHCPv6 报文 (客户端报文或下游的 RELAY-FORW)
+	hop 为收到的 RELAY-FORW 的 HopCount + 1, 来自客户端时为 0
+ */
+func NewDhcpV6RelayForward(hop uint8, link, peer IPv6, msg []byte, options ...DhcpV6Option) DhcpV6RelayPacket {
+	relay := DhcpV6RelayPacket{MessageType: DHCPV6_RELAY_FORW, HopCount: hop, LinkAddr: link, PeerAddr: peer}
+	for _, opt := range options {
+		relay.Options.Set(opt)
+	}
+	relay.Options.Set(SetDHCPV6RelayMessage(msg))
+	return relay
+}
+
+// 选项超过 65535 字节时返回 nil
+func (relay DhcpV6RelayPacket) WireFormat() []byte {
+	options := relay.Options.WireFormat()
+	if options == nil {
+		return nil
+	}
+	b := make([]byte, SizeofDhcpV6RelayPacket)
+	b[0], b[1] = uint8(relay.MessageType), relay.HopCount
+	*(*IPv6)(b[2:18]), *(*IPv6)(b[18:34]) = relay.LinkAddr, relay.PeerAddr
+	return append(b, options...)
+}
+
+// Relay Message 选项中的原始报文
+func (relay DhcpV6RelayPacket) RelayMessage() ([]byte, bool) {
+	if opt, ok := relay.Options.Get(DHCPV6_Relay_Message); ok && len(opt.Value) > 0 {
+		return opt.Value, true
+	}
+	return nil, false
+}
+
+/*
+	逐层解开 Relay Message, 返回最内层的客户端/服务端报文与途经的中继 (由外向内)
+	超过 DHCPV6_HOP_COUNT_LIMIT 层时返回错误
+ */
+func (relay DhcpV6RelayPacket) Inner() (DhcpV6Packet, []DhcpV6RelayPacket, error) {
+	path := []DhcpV6RelayPacket{relay}
+	for len(path) <= DHCPV6_HOP_COUNT_LIMIT {
+		msg, ok := path[len(path)-1].RelayMessage()
+		if !ok {
+			return DhcpV6Packet{}, path, ErrDhcpV6Packet
+		}
+		if !DHCPV6_Message_Type(msg[0]).IsRelay() {
+			dhcp, err := NewDhcpV6Packet(msg)
+			return dhcp, path, err
+		}
+		next, err := NewDhcpV6RelayPacket(msg)
+		if err != nil {
+			return DhcpV6Packet{}, path, err
+		}
+		path = append(path, next)
+	}
+	return DhcpV6Packet{}, path, ErrDhcpV6Packet
+}
+
+/*
+RFC 8415 21.1  Format of DHCP Options
+	 0                   1                   2                   3
+	 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
+	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+	|          option-code          |           option-len          |
+	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+	|                          option-data                          |
+	|                      (option-len octets)                      |
+	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+
+	没有 PAD/END, 选项可以嵌套 (IA_NA, IAADDR, Relay Message 等)
+ */
+type DhcpV6Option struct {
+	Code 		DHCPV6_Option_Code
+	Value 		[]byte
+}
+
+// option-len 为 16 位, Value 超过 65535 字节时返回 nil
+func (opt DhcpV6Option) WireFormat() []byte {
+	if len(opt.Value) > 0xffff {
+		return nil
+	}
+	return opt.append(make([]byte, 0, SizeofDhcpV6Option + len(opt.Value)))
+}
+
+func (opt DhcpV6Option) append(b []byte) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(opt.Code))
+	b = binary.BigEndian.AppendUint16(b, uint16(len(opt.Value)))
+	return append(b, opt.Value...)
+}
+
+// 保持报文顺序的选项集合, 同 Code 可以出现多次 (例如多个 IA_NA)
+type DhcpV6Options []DhcpV6Option
+
+// 选项长度越界时返回 ErrDhcpV6Option
+func NewDhcpV6Options(b []byte) (list DhcpV6Options, err error) {
+	for idx := 0; idx < len(b); {
+		if idx + SizeofDhcpV6Option > len(b) {
+			return nil, ErrDhcpV6Option
+		}
+		next := idx + SizeofDhcpV6Option + int(binary.BigEndian.Uint16(b[idx+2:idx+4]))
+		if next > len(b) {
+			return nil, ErrDhcpV6Option
+		}
+		opt := DhcpV6Option{DHCPV6_Option_Code(binary.BigEndian.Uint16(b[idx:idx+2])), nil}
+		opt.Value = append(make([]byte, 0, next - idx - SizeofDhcpV6Option), b[idx+SizeofDhcpV6Option:next]...)
+		list, idx = append(list, opt), next
+	}
+	return
+}
+
+// 任一选项超过 65535 字节时返回 nil, 没有选项时返回空切片
+func (o DhcpV6Options) WireFormat() []byte {
+	b := []byte{}
+	for _, opt := range o {
+		if len(opt.Value) > 0xffff {
+			return nil
+		}
+		b = opt.append(b)
+	}
+	return b
+}
+
+/*
+	嵌套选项 (IA_NA, IAADDR 等) 的内容, 不检查长度:
+	内层选项超长时外层选项必然超长, 由外层的 WireFormat 返回 nil
+*/
+func (o DhcpV6Options) encode() []byte {
+	var b []byte
+	for _, opt := range o {
+		b = opt.append(b)
+	}
+	return b
+}
+
+func (o DhcpV6Options) Get(code DHCPV6_Option_Code) (DhcpV6Option, bool) {
+	for _, opt := range o {
+		if opt.Code == code {
+			return opt, true
+		}
+	}
+	return DhcpV6Option{}, false
+}
+
+func (o DhcpV6Options) All(code DHCPV6_Option_Code) []DhcpV6Option {
+	var list []DhcpV6Option
+	for _, opt := range o {
+		if opt.Code == code {
+			list = append(list, opt)
+		}
+	}
+	return list
+}
+
+// 替换已有的同 Code 选项并保持其位置, 否则追加到末尾, Code 为 0 的选项被忽略
+func (o *DhcpV6Options) Set(opt DhcpV6Option) {
+	if opt.Code == 0 {
+		return
+	}
+	for i, v := range *o {
+		if v.Code == opt.Code {
+			(*o)[i] = opt
+			return
+		}
+	}
+	*o = append(*o, opt)
+}
+
+// 追加选项, 用于可重复的选项 (IA_NA, IAADDR 等), Code 为 0 的选项被忽略
+func (o *DhcpV6Options) Add(opt DhcpV6Option) {
+	if opt.Code != 0 {
+		*o = append(*o, opt)
+	}
+}
+
+// 删除所有 Code 相同的选项
+func (o *DhcpV6Options) Del(code DHCPV6_Option_Code) {
+	list := (*o)[:0]
+	for _, opt := range *o {
+		if opt.Code != code {
+			list = append(list, opt)
+		}
+	}
+	*o = list
+}
+
+func (o DhcpV6Options) Codes() []DHCPV6_Option_Code {
+	codes := make([]DHCPV6_Option_Code, len(o))
+	for i, opt := range o {
+		codes[i] = opt.Code
+	}
+	return codes
+}
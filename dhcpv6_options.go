@@ -0,0 +1,517 @@
+// @@
+// @ Author       : Eacher
+// @ Date         : 2026-10-20 23:06:12
+// @ LastEditTime : 2026-10-21 16:51:38
+// @ LastEditors  : Eacher
+// @ --------------------------------------------------------------------------------<
+// @ Description  : RFC 8415 DHCPv6 类型化选项
+// @ --------------------------------------------------------------------------------<
+// @ FilePath     : /20yyq/packet/dhcpv6_options.go
+// @@
+package packet
+
+import (
+	"encoding/binary"
+	"strconv"
+	"time"
+)
+
+type DHCPV6_Option_Code uint16
+
+const (
+	DHCPV6_Client_Identifier 	DHCPV6_Option_Code = 1
+	DHCPV6_Server_Identifier 	DHCPV6_Option_Code = 2
+	DHCPV6_IA_NA 				DHCPV6_Option_Code = 3
+	DHCPV6_IA_TA 				DHCPV6_Option_Code = 4
+	DHCPV6_IA_Address 			DHCPV6_Option_Code = 5
+	DHCPV6_Option_Request 		DHCPV6_Option_Code = 6
+	DHCPV6_Preference 			DHCPV6_Option_Code = 7
+	DHCPV6_Elapsed_Time 		DHCPV6_Option_Code = 8
+	DHCPV6_Relay_Message 		DHCPV6_Option_Code = 9
+	DHCPV6_Status_Code 			DHCPV6_Option_Code = 13
+	DHCPV6_Rapid_Commit 		DHCPV6_Option_Code = 14
+	DHCPV6_Interface_ID 		DHCPV6_Option_Code = 18
+	DHCPV6_Reconfigure_Message 	DHCPV6_Option_Code = 19
+	// RFC 3646
+	DHCPV6_DNS_Servers 			DHCPV6_Option_Code = 23
+	DHCPV6_Domain_List 			DHCPV6_Option_Code = 24
+	DHCPV6_IA_PD 				DHCPV6_Option_Code = 25
+	DHCPV6_IA_Prefix 			DHCPV6_Option_Code = 26
+)
+
+/*
+RFC 8415 21.13  Status Code Option
+	 0                   1                   2                   3
+	 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
+	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+	|       OPTION_STATUS_CODE      |         option-len            |
+	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+	|          status-code          |                               |
+	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+                               |
+	.                                                               .
+	.                        status-message                         .
+	.                                                               .
+	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+ */
+type DHCPV6_Status uint16
+
+const (
+	DHCPV6_STATUS_SUCCESS DHCPV6_Status = iota
+	DHCPV6_STATUS_UNSPEC_FAIL
+	DHCPV6_STATUS_NO_ADDRS_AVAIL
+	DHCPV6_STATUS_NO_BINDING
+	DHCPV6_STATUS_NOT_ON_LINK
+	DHCPV6_STATUS_USE_MULTICAST
+	DHCPV6_STATUS_NO_PREFIX_AVAIL
+)
+
+var dhcpV6StatusNames = [...]string{
+	"Success", "UnspecFail", "NoAddrsAvail", "NoBinding", "NotOnLink", "UseMulticast", "NoPrefixAvail",
+}
+
+func (s DHCPV6_Status) String() string {
+	if int(s) < len(dhcpV6StatusNames) {
+		return dhcpV6StatusNames[s]
+	}
+	return "Status(" + strconv.Itoa(int(s)) + ")"
+}
+
+type DhcpV6StatusCode struct {
+	Code 	DHCPV6_Status
+	// UTF-8 文本, 可以为空
+	Message string
+}
+
+func (s DhcpV6StatusCode) String() string {
+	if s.Message == "" {
+		return s.Code.String()
+	}
+	return s.Code.String() + ": " + s.Message
+}
+
+func SetDHCPV6StatusCode(code DHCPV6_Status, message string) DhcpV6Option {
+	return DhcpV6Option{DHCPV6_Status_Code, append(binary.BigEndian.AppendUint16(nil, uint16(code)), message...)}
+}
+
+func (o DhcpV6Options) StatusCode() (DhcpV6StatusCode, bool) {
+	if opt, ok := o.Get(DHCPV6_Status_Code); ok && len(opt.Value) >= 2 {
+		return DhcpV6StatusCode{DHCPV6_Status(binary.BigEndian.Uint16(opt.Value)), string(opt.Value[2:])}, true
+	}
+	return DhcpV6StatusCode{}, false
+}
+
+/*
+RFC 8415 21.2/21.3  Client Identifier / Server Identifier Option
+	option-data 为 DUID, 长度 2-130 bytes
+ */
+func SetDHCPV6ClientID(duid DUID) DhcpV6Option {
+	if duid.Validate() != nil {
+		return DhcpV6Option{}
+	}
+	return DhcpV6Option{DHCPV6_Client_Identifier, append([]byte(nil), duid...)}
+}
+
+func SetDHCPV6ServerID(duid DUID) DhcpV6Option {
+	if duid.Validate() != nil {
+		return DhcpV6Option{}
+	}
+	return DhcpV6Option{DHCPV6_Server_Identifier, append([]byte(nil), duid...)}
+}
+
+func (o DhcpV6Options) ClientID() (DUID, bool) {
+	return o.duid(DHCPV6_Client_Identifier)
+}
+
+func (o DhcpV6Options) ServerID() (DUID, bool) {
+	return o.duid(DHCPV6_Server_Identifier)
+}
+
+func (o DhcpV6Options) duid(code DHCPV6_Option_Code) (DUID, bool) {
+	if opt, ok := o.Get(code); ok && DUID(opt.Value).Validate() == nil {
+		return DUID(opt.Value), true
+	}
+	return nil, false
+}
+
+// 以秒为单位的 32 位时间, 0xffffffff 表示无限 (与 DHCP_INFINITE_LEASE 相同)
+func dhcpSeconds(d time.Duration) uint32 {
+	if d <= 0 {
+		return 0
+	}
+	if d >= DHCP_INFINITE_LEASE {
+		return 0xffffffff
+	}
+	return uint32(d / time.Second)
+}
+
+/*
+RFC 8415 21.4  Identity Association for Non-temporary Addresses Option
+	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+	|          OPTION_IA_NA         |          option-len           |
+	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+	|                        IAID (4 octets)                        |
+	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+	|                              T1                               |
+	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+	|                              T2                               |
+	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+	.                         IA_NA-options                         .
+	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+
+RFC 8415 21.5  IA_TA 只有 IAID 与 IA_TA-options
+RFC 8415 21.21 IA_PD 与 IA_NA 格式相同, 内含 IAPREFIX
+ */
+type DhcpV6IA struct {
+	// DHCPV6_IA_NA, DHCPV6_IA_TA 或 DHCPV6_IA_PD
+	Code 		DHCPV6_Option_Code
+	IAID 		uint32
+	// IA_TA 没有 T1/T2
+	T1 			time.Duration
+	T2 			time.Duration
+	Options 	DhcpV6Options
+}
+
+func NewDhcpV6IA(opt DhcpV6Option) (ia DhcpV6IA, err error) {
+	size := 12
+	switch opt.Code {
+	case DHCPV6_IA_NA, DHCPV6_IA_PD:
+	case DHCPV6_IA_TA:
+		size = 4
+	default:
+		return ia, ErrDhcpV6Option
+	}
+	if len(opt.Value) < size {
+		return ia, ErrDhcpV6Option
+	}
+	ia.Code, ia.IAID = opt.Code, binary.BigEndian.Uint32(opt.Value[0:4])
+	if size > 4 {
+		ia.T1 = time.Duration(binary.BigEndian.Uint32(opt.Value[4:8])) * time.Second
+		ia.T2 = time.Duration(binary.BigEndian.Uint32(opt.Value[8:12])) * time.Second
+	}
+	ia.Options, err = NewDhcpV6Options(opt.Value[size:])
+	return
+}
+
+func (ia DhcpV6IA) Encode() []byte {
+	b := binary.BigEndian.AppendUint32(nil, ia.IAID)
+	if ia.Code != DHCPV6_IA_TA {
+		b = binary.BigEndian.AppendUint32(b, dhcpSeconds(ia.T1))
+		b = binary.BigEndian.AppendUint32(b, dhcpSeconds(ia.T2))
+	}
+	return append(b, ia.Options.encode()...)
+}
+
+func SetDHCPV6IA(ia DhcpV6IA) DhcpV6Option {
+	if ia.Code != DHCPV6_IA_NA && ia.Code != DHCPV6_IA_TA && ia.Code != DHCPV6_IA_PD {
+		return DhcpV6Option{}
+	}
+	return DhcpV6Option{ia.Code, ia.Encode()}
+}
+
+// IA_NA/IA_TA 中的地址, 无法解析的 IAADDR 被忽略
+func (ia DhcpV6IA) Addresses() []DhcpV6IAAddress {
+	var list []DhcpV6IAAddress
+	for _, opt := range ia.Options.All(DHCPV6_IA_Address) {
+		if addr, err := NewDhcpV6IAAddress(opt.Value); err == nil {
+			list = append(list, addr)
+		}
+	}
+	return list
+}
+
+// IA_PD 中的前缀, 无法解析的 IAPREFIX 被忽略
+func (ia DhcpV6IA) Prefixes() []DhcpV6IAPrefix {
+	var list []DhcpV6IAPrefix
+	for _, opt := range ia.Options.All(DHCPV6_IA_Prefix) {
+		if prefix, err := NewDhcpV6IAPrefix(opt.Value); err == nil {
+			list = append(list, prefix)
+		}
+	}
+	return list
+}
+
+// 报文中 code 类型的全部 IA, 无法解析的被忽略
+func (o DhcpV6Options) IA(code DHCPV6_Option_Code) []DhcpV6IA {
+	var list []DhcpV6IA
+	for _, opt := range o.All(code) {
+		if ia, err := NewDhcpV6IA(opt); err == nil {
+			list = append(list, ia)
+		}
+	}
+	return list
+}
+
+func (o DhcpV6Options) IANA() []DhcpV6IA {
+	return o.IA(DHCPV6_IA_NA)
+}
+
+func (o DhcpV6Options) IATA() []DhcpV6IA {
+	return o.IA(DHCPV6_IA_TA)
+}
+
+func (o DhcpV6Options) IAPD() []DhcpV6IA {
+	return o.IA(DHCPV6_IA_PD)
+}
+
+/*
+RFC 8415 21.6  IA Address Option
+	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+	|          OPTION_IAADDR        |          option-len           |
+	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+	|                         IPv6-address                          |
+	|                          (16 octets)                          |
+	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+	|                      preferred-lifetime                       |
+	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+	|                        valid-lifetime                         |
+	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+	.                        IAaddr-options                         .
+	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+ */
+type DhcpV6IAAddress struct {
+	IP 					IPv6
+	PreferredLifetime 	time.Duration
+	ValidLifetime 		time.Duration
+	Options 			DhcpV6Options
+}
+
+func NewDhcpV6IAAddress(b []byte) (addr DhcpV6IAAddress, err error) {
+	if len(b) < 24 {
+		return addr, ErrDhcpV6Option
+	}
+	addr.IP = *(*IPv6)(b[0:16])
+	addr.PreferredLifetime = time.Duration(binary.BigEndian.Uint32(b[16:20])) * time.Second
+	addr.ValidLifetime = time.Duration(binary.BigEndian.Uint32(b[20:24])) * time.Second
+	addr.Options, err = NewDhcpV6Options(b[24:])
+	return
+}
+
+func (addr DhcpV6IAAddress) Encode() []byte {
+	b := append(make([]byte, 0, 24), addr.IP[:]...)
+	b = binary.BigEndian.AppendUint32(b, dhcpSeconds(addr.PreferredLifetime))
+	b = binary.BigEndian.AppendUint32(b, dhcpSeconds(addr.ValidLifetime))
+	return append(b, addr.Options.encode()...)
+}
+
+func SetDHCPV6IAAddress(addr DhcpV6IAAddress) DhcpV6Option {
+	return DhcpV6Option{DHCPV6_IA_Address, addr.Encode()}
+}
+
+/*
+RFC 8415 21.22  IA Prefix Option
+	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+	|        OPTION_IAPREFIX        |          option-len           |
+	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+	|                      preferred-lifetime                       |
+	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+	|                        valid-lifetime                         |
+	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+	| prefix-length |                                               |
+	+-+-+-+-+-+-+-+-+          IPv6-prefix                          |
+	|                           (16 octets)                         |
+	|               +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+	|               |                                               .
+	+-+-+-+-+-+-+-+-+                                               .
+	.                       IAprefix-options                        .
+	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+ */
+type DhcpV6IAPrefix struct {
+	PreferredLifetime 	time.Duration
+	ValidLifetime 		time.Duration
+	Bits 				uint8
+	Prefix 				IPv6
+	Options 			DhcpV6Options
+}
+
+func NewDhcpV6IAPrefix(b []byte) (prefix DhcpV6IAPrefix, err error) {
+	if len(b) < 25 || b[8] > 128 {
+		return prefix, ErrDhcpV6Option
+	}
+	prefix.PreferredLifetime = time.Duration(binary.BigEndian.Uint32(b[0:4])) * time.Second
+	prefix.ValidLifetime = time.Duration(binary.BigEndian.Uint32(b[4:8])) * time.Second
+	prefix.Bits, prefix.Prefix = b[8], *(*IPv6)(b[9:25])
+	prefix.Options, err = NewDhcpV6Options(b[25:])
+	return
+}
+
+func (prefix DhcpV6IAPrefix) Encode() []byte {
+	b := binary.BigEndian.AppendUint32(make([]byte, 0, 25), dhcpSeconds(prefix.PreferredLifetime))
+	b = binary.BigEndian.AppendUint32(b, dhcpSeconds(prefix.ValidLifetime))
+	b = append(append(b, prefix.Bits), prefix.Prefix[:]...)
+	return append(b, prefix.Options.encode()...)
+}
+
+func (prefix DhcpV6IAPrefix) String() string {
+	return prefix.Prefix.String() + "/" + strconv.Itoa(int(prefix.Bits))
+}
+
+func SetDHCPV6IAPrefix(prefix DhcpV6IAPrefix) DhcpV6Option {
+	if prefix.Bits > 128 {
+		return DhcpV6Option{}
+	}
+	return DhcpV6Option{DHCPV6_IA_Prefix, prefix.Encode()}
+}
+
+/*
+RFC 8415 21.7  Option Request Option
+	option-data 为 16 位选项代码列表
+ */
+func SetDHCPV6OptionRequest(codes ...DHCPV6_Option_Code) DhcpV6Option {
+	if len(codes) < 1 {
+		return DhcpV6Option{}
+	}
+	b := make([]byte, 0, len(codes) * 2)
+	for _, c := range codes {
+		b = binary.BigEndian.AppendUint16(b, uint16(c))
+	}
+	return DhcpV6Option{DHCPV6_Option_Request, b}
+}
+
+func (o DhcpV6Options) OptionRequest() ([]DHCPV6_Option_Code, bool) {
+	opt, ok := o.Get(DHCPV6_Option_Request)
+	if !ok || len(opt.Value) < 2 || len(opt.Value) % 2 != 0 {
+		return nil, false
+	}
+	codes := make([]DHCPV6_Option_Code, len(opt.Value) / 2)
+	for i := range codes {
+		codes[i] = DHCPV6_Option_Code(binary.BigEndian.Uint16(opt.Value[i*2:]))
+	}
+	return codes, true
+}
+
+/*
+RFC 8415 21.8  Preference Option
+	服务端优先级, 255 表示客户端应立即选择该服务端
+ */
+func SetDHCPV6Preference(pref uint8) DhcpV6Option {
+	return DhcpV6Option{DHCPV6_Preference, []byte{pref}}
+}
+
+func (o DhcpV6Options) Preference() (uint8, bool) {
+	if opt, ok := o.Get(DHCPV6_Preference); ok && len(opt.Value) == 1 {
+		return opt.Value[0], true
+	}
+	return 0, false
+}
+
+/*
+RFC 8415 21.9  Elapsed Time Option
+	以 1/100 秒为单位, 0xffff 表示大于等于 655.35 秒
+ */
+func SetDHCPV6ElapsedTime(d time.Duration) DhcpV6Option {
+	v := d / (10 * time.Millisecond)
+	if v > 0xffff {
+		v = 0xffff
+	} else if v < 0 {
+		v = 0
+	}
+	return DhcpV6Option{DHCPV6_Elapsed_Time, binary.BigEndian.AppendUint16(nil, uint16(v))}
+}
+
+func (o DhcpV6Options) ElapsedTime() (time.Duration, bool) {
+	if opt, ok := o.Get(DHCPV6_Elapsed_Time); ok && len(opt.Value) == 2 {
+		return time.Duration(binary.BigEndian.Uint16(opt.Value)) * 10 * time.Millisecond, true
+	}
+	return 0, false
+}
+
+// RFC 8415 21.10  Relay Message Option
+func SetDHCPV6RelayMessage(msg []byte) DhcpV6Option {
+	if len(msg) < SizeofDhcpV6Packet {
+		return DhcpV6Option{}
+	}
+	return DhcpV6Option{DHCPV6_Relay_Message, append([]byte(nil), msg...)}
+}
+
+// RFC 8415 21.14  Rapid Commit Option, 长度为 0
+func SetDHCPV6RapidCommit() DhcpV6Option {
+	return DhcpV6Option{DHCPV6_Rapid_Commit, []byte{}}
+}
+
+func (o DhcpV6Options) RapidCommit() bool {
+	opt, ok := o.Get(DHCPV6_Rapid_Commit)
+	return ok && len(opt.Value) == 0
+}
+
+// RFC 8415 21.18  Interface-Id Option, 由中继代理设置, 服务端在 RELAY-REPL 中原样返回
+func SetDHCPV6InterfaceID(id []byte) DhcpV6Option {
+	if len(id) < 1 {
+		return DhcpV6Option{}
+	}
+	return DhcpV6Option{DHCPV6_Interface_ID, append([]byte(nil), id...)}
+}
+
+func (o DhcpV6Options) InterfaceID() ([]byte, bool) {
+	if opt, ok := o.Get(DHCPV6_Interface_ID); ok && len(opt.Value) > 0 {
+		return opt.Value, true
+	}
+	return nil, false
+}
+
+// RFC 8415 21.19  Reconfigure Message Option, msg 为 DHCPV6_RENEW, DHCPV6_REBIND 或 DHCPV6_INFORMATION_REQUEST
+func SetDHCPV6ReconfigureMessage(msg DHCPV6_Message_Type) DhcpV6Option {
+	if msg != DHCPV6_RENEW && msg != DHCPV6_REBIND && msg != DHCPV6_INFORMATION_REQUEST {
+		return DhcpV6Option{}
+	}
+	return DhcpV6Option{DHCPV6_Reconfigure_Message, []byte{uint8(msg)}}
+}
+
+func (o DhcpV6Options) ReconfigureMessage() (DHCPV6_Message_Type, bool) {
+	if opt, ok := o.Get(DHCPV6_Reconfigure_Message); ok && len(opt.Value) == 1 {
+		return DHCPV6_Message_Type(opt.Value[0]), true
+	}
+	return 0, false
+}
+
+/*
+RFC 3646 3  DNS Recursive Name Server option
+	option-data 为 16 bytes 地址列表
+ */
+func SetDHCPV6DNSServers(servers ...IPv6) DhcpV6Option {
+	if len(servers) < 1 {
+		return DhcpV6Option{}
+	}
+	b := make([]byte, 0, len(servers) * 16)
+	for _, ip := range servers {
+		b = append(b, ip[:]...)
+	}
+	return DhcpV6Option{DHCPV6_DNS_Servers, b}
+}
+
+func (o DhcpV6Options) DNSServers() ([]IPv6, bool) {
+	opt, ok := o.Get(DHCPV6_DNS_Servers)
+	if !ok || len(opt.Value) < 16 || len(opt.Value) % 16 != 0 {
+		return nil, false
+	}
+	list := make([]IPv6, len(opt.Value) / 16)
+	for i := range list {
+		list[i] = *(*IPv6)(opt.Value[i*16:])
+	}
+	return list, true
+}
+
+/*
+RFC 3646 4  Domain Search List option
+	RFC 8415 10: 域名使用 RFC 1035 编码, 不使用压缩
+ */
+func SetDHCPV6DomainList(names ...string) DhcpV6Option {
+	var b []byte
+	var err error
+	for _, name := range names {
+		if b, err = appendDomainName(b, name, nil, false); err != nil {
+			return DhcpV6Option{}
+		}
+	}
+	if len(b) < 1 {
+		return DhcpV6Option{}
+	}
+	return DhcpV6Option{DHCPV6_Domain_List, b}
+}
+
+func (o DhcpV6Options) DomainList() ([]string, bool) {
+	if opt, ok := o.Get(DHCPV6_Domain_List); ok {
+		names, err := DecodeDomainSearch(opt.Value)
+		return names, err == nil && len(names) > 0
+	}
+	return nil, false
+}
@@ -0,0 +1,55 @@
+// @@
+// @ Author       : Eacher
+// @ Date         : 2026-10-21 16:51:38
+// @ LastEditTime : 2026-10-21 16:51:38
+// @ LastEditors  : Eacher
+// @ --------------------------------------------------------------------------------<
+// @ Description  : DHCPv6 报文编解码测试
+// @ --------------------------------------------------------------------------------<
+// @ FilePath     : /20yyq/packet/dhcpv6_test.go
+// @@
+package packet
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDhcpV6PacketRoundTrip(t *testing.T) {
+	ia := DhcpV6IA{Code: DHCPV6_IA_NA, IAID: 7, T1: time.Hour, T2: 2 * time.Hour}
+	ia.Options.Add(SetDHCPV6IAAddress(DhcpV6IAAddress{IP: IPv6{0x20, 0x01, 0x0d, 0xb8, 15: 5}, PreferredLifetime: time.Hour, ValidLifetime: 2 * time.Hour}))
+	dhcp := DhcpV6Packet{MessageType: DHCPV6_REPLY, TransactionID: 0x123456}
+	dhcp.Options.Add(SetDHCPV6IA(ia))
+	dhcp.Options.Add(SetDHCPV6Preference(255))
+	got, err := NewDhcpV6Packet(dhcp.WireFormat())
+	if err != nil || !reflect.DeepEqual(got, dhcp) {
+		t.Errorf("NewDhcpV6Packet(WireFormat()) = %+v, %v; want %+v", got, err, dhcp)
+	}
+	if b := (DhcpV6Packet{MessageType: DHCPV6_SOLICIT}).WireFormat(); len(b) != SizeofDhcpV6Packet {
+		t.Errorf("WireFormat() without options = % x; want %d bytes", b, SizeofDhcpV6Packet)
+	}
+}
+
+// option-len 只有 16 位, 超长的选项不能被截断
+func TestDhcpV6OptionTooLong(t *testing.T) {
+	long := DhcpV6Option{DHCPV6_Relay_Message, make([]byte, 0x10000)}
+	if b := long.WireFormat(); b != nil {
+		t.Errorf("WireFormat() of %d byte option = %d bytes; want nil", len(long.Value), len(b))
+	}
+	if b := (DhcpV6Option{DHCPV6_Relay_Message, make([]byte, 0xffff)}).WireFormat(); len(b) != SizeofDhcpV6Option + 0xffff {
+		t.Errorf("WireFormat() of 65535 byte option = %d bytes; want %d", len(b), SizeofDhcpV6Option + 0xffff)
+	}
+	if b := (DhcpV6Packet{MessageType: DHCPV6_REPLY, Options: DhcpV6Options{long}}).WireFormat(); b != nil {
+		t.Errorf("DhcpV6Packet.WireFormat() with long option = %d bytes; want nil", len(b))
+	}
+	relay := NewDhcpV6RelayForward(0, IPv6{}, IPv6{}, make([]byte, 0x10000))
+	if b := relay.WireFormat(); b != nil {
+		t.Errorf("DhcpV6RelayPacket.WireFormat() with long relay message = %d bytes; want nil", len(b))
+	}
+	// 嵌套的超长选项使外层选项超长
+	ia := DhcpV6IA{Code: DHCPV6_IA_NA, IAID: 1, Options: DhcpV6Options{long}}
+	if b := (DhcpV6Packet{MessageType: DHCPV6_REPLY, Options: DhcpV6Options{SetDHCPV6IA(ia)}}).WireFormat(); b != nil {
+		t.Errorf("DhcpV6Packet.WireFormat() with long nested option = %d bytes; want nil", len(b))
+	}
+}
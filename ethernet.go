@@ -1,7 +1,7 @@
 // @@
 // @ Author       : Eacher
 // @ Date         : 2023-07-13 14:02:39
-// @ LastEditTime : 2023-09-15 10:32:23
+// @ LastEditTime : 2026-10-21 15:02:44
 // @ LastEditors  : Eacher
 // @ --------------------------------------------------------------------------------<
 // @ Description  : 
@@ -11,23 +11,40 @@
 package packet
 
 import (
-	_ "net"
+	"net"
+	"errors"
 	"unsafe"
+	"strings"
+	"strconv"
+	"encoding/json"
 	"encoding/binary"
 )
 
 const (
 	SizeofEthernetPacket = 0x0e
+
+	ETH_P_IP 	= 0x0800
+	ETH_P_ARP 	= 0x0806
+	ETH_P_RARP 	= 0x8035
+	// IEEE 802.1Q 与 802.1ad VLAN 标签
+	ETH_P_8021Q 	= 0x8100
+	ETH_P_8021AD 	= 0x88a8
 )
 
 type HardwareAddr [6]byte 
 type IPv4 [4]byte 
+type IPv6 [16]byte 
 
 const hexDigit = "0123456789abcdef"
 const maxIPv4StringLen = len("255.255.255.255")
 
 var Broadcast = HardwareAddr{0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
 
+var (
+	ErrIPv4Format 	= errors.New("packet: malformed IPv4 address")
+	ErrMACFormat 	= errors.New("packet: malformed MAC address")
+)
+
 /*
 
  //来源 https://www.rfc-editor.org/rfc/rfc1071 [Page 6]
@@ -110,6 +127,107 @@ func (v4 IPv4) String() string {
 	return string(b[:n])
 }
 
+// 点分十进制, 不接受 IPv6 与 IPv4-mapped IPv6 写法
+func ParseIPv4(s string) (IPv4, error) {
+	if ip := net.ParseIP(s).To4(); ip != nil && !strings.Contains(s, ":") {
+		return IPv4(ip), nil
+	}
+	return IPv4{}, ErrIPv4Format
+}
+
+// 接受 net.ParseMAC 支持的 48 位地址写法, 例如 00:11:22:33:44:55, 00-11-22-33-44-55, 0011.2233.4455
+func ParseMAC(s string) (HardwareAddr, error) {
+	if mac, err := net.ParseMAC(s); err == nil && len(mac) == 6 {
+		return HardwareAddr(mac), nil
+	}
+	return HardwareAddr{}, ErrMACFormat
+}
+
+func (h HardwareAddr) MarshalText() ([]byte, error) {
+	return []byte(h.String()), nil
+}
+
+func (h *HardwareAddr) UnmarshalText(b []byte) (err error) {
+	*h, err = ParseMAC(string(b))
+	return
+}
+
+// 兼容以字节数组保存的旧 JSON (例如 MarshalText 之前的租约文件)
+func (h *HardwareAddr) UnmarshalJSON(b []byte) error {
+	return unmarshalJSONText(b, (*[6]byte)(h), h)
+}
+
+func (v4 IPv4) MarshalText() ([]byte, error) {
+	return []byte(v4.String()), nil
+}
+
+func (v4 *IPv4) UnmarshalText(b []byte) (err error) {
+	*v4, err = ParseIPv4(string(b))
+	return
+}
+
+// 兼容以字节数组保存的旧 JSON
+func (v4 *IPv4) UnmarshalJSON(b []byte) error {
+	return unmarshalJSONText(b, (*[4]byte)(v4), v4)
+}
+
+func unmarshalJSONText(b []byte, array interface{}, text interface{ UnmarshalText([]byte) error }) error {
+	var s string
+	switch b = []byte(strings.TrimSpace(string(b))); {
+	case string(b) == "null":
+		return nil
+	case len(b) > 0 && b[0] == '[':
+		return json.Unmarshal(b, array)
+	}
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	return text.UnmarshalText([]byte(s))
+}
+
+func (v6 IPv6) String() string {
+	return net.IP(v6[:]).String()
+}
+
+func (v4 IPv4) Uint32() uint32 {
+	return binary.BigEndian.Uint32(v4[:])
+}
+
+func NewIPv4FromUint32(u uint32) (v4 IPv4) {
+	binary.BigEndian.PutUint32(v4[:], u)
+	return
+}
+
+// 地址前缀, 例如 192.168.1.0/24
+type IPv4Prefix struct {
+	IP 		IPv4
+	Bits 	uint8
+}
+
+func (p IPv4Prefix) Mask() IPv4 {
+	if p.Bits >= 32 {
+		return IPv4{0xff, 0xff, 0xff, 0xff}
+	}
+	return NewIPv4FromUint32(^(uint32(0xffffffff) >> p.Bits))
+}
+
+// 清除主机位, 例如 10.0.0.1/24 为 10.0.0.0/24
+func (p IPv4Prefix) Masked() IPv4Prefix {
+	if p.Bits > 32 {
+		p.Bits = 32
+	}
+	return IPv4Prefix{NewIPv4FromUint32(p.IP.Uint32() & p.Mask().Uint32()), p.Bits}
+}
+
+func (p IPv4Prefix) Contains(ip IPv4) bool {
+	mask := p.Mask().Uint32()
+	return ip.Uint32() & mask == p.IP.Uint32() & mask
+}
+
+func (p IPv4Prefix) String() string {
+	return p.IP.String() + "/" + strconv.Itoa(int(p.Bits))
+}
+
 /*
     Ethernet transmission layer (not necessarily accessible to the user):
 	6.byte  48.bit: Ethernet address of destination
@@ -135,3 +253,25 @@ func (eth EthernetPacket) WireFormat() []byte {
 	binary.BigEndian.PutUint16(b[12:14], eth.FrameType)
 	return b[:]
 }
+
+/*
+	JSON 格式: {"dst": "ff:ff:ff:ff:ff:ff", "src": "00:11:22:33:44:55", "type": 2054}
+ */
+type ethernetJSON struct {
+	Dst 	HardwareAddr 	`json:"dst"`
+	Src 	HardwareAddr 	`json:"src"`
+	Type 	uint16 			`json:"type"`
+}
+
+func (eth EthernetPacket) MarshalJSON() ([]byte, error) {
+	return json.Marshal(ethernetJSON{eth.HeadMAC[0], eth.HeadMAC[1], eth.FrameType})
+}
+
+func (eth *EthernetPacket) UnmarshalJSON(b []byte) error {
+	var v ethernetJSON
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	eth.HeadMAC, eth.FrameType = [2]HardwareAddr{v.Dst, v.Src}, v.Type
+	return nil
+}
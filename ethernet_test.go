@@ -0,0 +1,31 @@
+// @@
+// @ Author       : Eacher
+// @ Date         : 2026-10-21 16:07:39
+// @ LastEditTime : 2026-10-21 16:07:39
+// @ LastEditors  : Eacher
+// @ --------------------------------------------------------------------------------<
+// @ Description  : EthernetPacket JSON 测试
+// @ --------------------------------------------------------------------------------<
+// @ FilePath     : /20yyq/packet/ethernet_test.go
+// @@
+package packet
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestEthernetPacketJSON(t *testing.T) {
+	eth := EthernetPacket{HeadMAC: [2]HardwareAddr{Broadcast, {0x00, 0x11, 0x22, 0x33, 0x44, 0x55}}, FrameType: ETH_P_ARP}
+	b, err := json.Marshal(eth)
+	if want := `{"dst":"ff:ff:ff:ff:ff:ff","src":"00:11:22:33:44:55","type":2054}`; err != nil || string(b) != want {
+		t.Fatalf("Marshal() = %s, %v; want %s", b, err, want)
+	}
+	var got EthernetPacket
+	if err := json.Unmarshal(b, &got); err != nil || got != eth {
+		t.Errorf("Unmarshal(%s) = %+v, %v; want %+v", b, got, err, eth)
+	}
+	if err := json.Unmarshal([]byte(`{"dst":"ff:ff:ff","src":"00:11:22:33:44:55","type":2054}`), &got); err == nil {
+		t.Errorf("Unmarshal() with short MAC succeeded")
+	}
+}
@@ -0,0 +1,114 @@
+// @@
+// @ Author       : Eacher
+// @ Date         : 2026-10-20 10:36:05
+// @ LastEditTime : 2026-10-20 10:36:05
+// @ LastEditors  : Eacher
+// @ --------------------------------------------------------------------------------<
+// @ Description  : ICMP Echo 报文与 Ping
+// @ --------------------------------------------------------------------------------<
+// @ FilePath     : /20yyq/packet/icmp.go
+// @@
+package packet
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"os"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	SizeofIcmpPacket = 0x08
+
+	ICMP_ECHO_REPLY 	= 0x00
+	ICMP_ECHO_REQUEST 	= 0x08
+)
+
+/*
+	RFC 792  Echo or Echo Reply Message
+
+    0                   1                   2                   3
+    0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
+   +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+   |     Type      |     Code      |          Checksum             |
+   +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+   |           Identifier          |        Sequence Number        |
+   +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+   |     Data ...
+   +-+-+-+-+-
+ */
+type IcmpPacket struct {
+	Type 		uint8
+	Code 		uint8
+	CheckSum 	uint16
+	ID 			uint16
+	Seq 		uint16
+	Data 		[]byte
+}
+
+func NewIcmpPacket(b []byte) (icmp IcmpPacket, ok bool) {
+	if len(b) < SizeofIcmpPacket {
+		return
+	}
+	icmp.Type, icmp.Code = b[0], b[1]
+	icmp.CheckSum = binary.BigEndian.Uint16(b[2:4])
+	icmp.ID = binary.BigEndian.Uint16(b[4:6])
+	icmp.Seq = binary.BigEndian.Uint16(b[6:8])
+	icmp.Data = append([]byte(nil), b[SizeofIcmpPacket:]...)
+	return icmp, true
+}
+
+// 校验和由 WireFormat 计算, 忽略 CheckSum 字段
+func (icmp IcmpPacket) WireFormat() []byte {
+	b := make([]byte, SizeofIcmpPacket + len(icmp.Data))
+	b[0], b[1] = icmp.Type, icmp.Code
+	binary.BigEndian.PutUint16(b[4:6], icmp.ID)
+	binary.BigEndian.PutUint16(b[6:8], icmp.Seq)
+	copy(b[SizeofIcmpPacket:], icmp.Data)
+	*(*uint16)(unsafe.Pointer(&b[2])) = CheckSum(b)
+	return b
+}
+
+/*
+	使用非特权 ICMP 套接字 (net.ipv4.ping_group_range) 向 ip 发送一次 Echo 请求
+	在 timeout 内收到应答返回 true, 超时返回 false
+ */
+func IcmpPing(ctx context.Context, ip IPv4, timeout time.Duration) (bool, error) {
+	fd, err := unix.Socket(unix.AF_INET, unix.SOCK_DGRAM|unix.SOCK_NONBLOCK|unix.SOCK_CLOEXEC, unix.IPPROTO_ICMP)
+	if err != nil {
+		return false, err
+	}
+	file := os.NewFile(uintptr(fd), "icmp")
+	defer file.Close()
+	if err = unix.Connect(fd, &unix.SockaddrInet4{Addr: ip}); err != nil {
+		return false, err
+	}
+	// 内核会改写 ID 为套接字端口, 仅按 Seq 与 Data 匹配
+	req := IcmpPacket{Type: ICMP_ECHO_REQUEST, Seq: uint16(time.Now().UnixNano()), Data: ip[:]}
+	if _, err = file.Write(req.WireFormat()); err != nil {
+		return false, err
+	}
+	lw := watchLink(ctx, file)
+	defer lw.Close()
+	lw.setDeadline(time.Now().Add(timeout))
+	buf := make([]byte, 0x200)
+	for {
+		n, err := file.Read(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return false, ctx.Err()
+			}
+			if errors.Is(err, os.ErrDeadlineExceeded) {
+				return false, nil
+			}
+			return false, err
+		}
+		if icmp, ok := NewIcmpPacket(buf[:n]); ok && icmp.Type == ICMP_ECHO_REPLY && icmp.Seq == req.Seq {
+			return true, nil
+		}
+	}
+}
@@ -1,7 +1,7 @@
 // @@
 // @ Author       : Eacher
 // @ Date         : 2023-09-15 15:48:53
-// @ LastEditTime : 2023-09-15 15:50:09
+// @ LastEditTime : 2026-10-19 16:05:12
 // @ LastEditors  : Eacher
 // @ --------------------------------------------------------------------------------<
 // @ Description  : 
@@ -10,6 +10,18 @@
 // @@
 package packet
 
+import (
+	"time"
+)
+
 type Attrs interface {
 	WireFormat() []byte
 }
+
+// 以帧为单位读写链路层数据, 每次 ReadFrame 返回一个完整的以太网帧
+type FrameReadWriter interface {
+	ReadFrame() ([]byte, error)
+	WriteFrame(b []byte) error
+	SetReadDeadline(t time.Time) error
+	Close() error
+}
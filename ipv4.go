@@ -1,7 +1,7 @@
 // @@
 // @ Author       	: Eacher
 // @ Date         	: 2023-07-13 15:20:40
-// @ LastEditTime   : 2023-10-13 17:17:40
+// @ LastEditTime   : 2026-10-21 00:36:27
 // @ LastEditors    : Eacher
 // @ --------------------------------------------------------------------------------<
 // @ Description  	:
@@ -12,6 +12,7 @@ package packet
 
 import (
 	"encoding/binary"
+	"encoding/json"
 	"fmt"
 	"runtime"
 	"unsafe"
@@ -154,7 +155,8 @@ func (ipv4 IPv4Packet) WireFormat() []byte {
 	}
 	*(*IPv4)(b[12:16]) = ipv4.Src
 	*(*IPv4)(b[16:20]) = ipv4.Dst
-	binary.BigEndian.PutUint16(b[10:12], CheckSum(b))
+	// CheckSum 按本机字节序累加, 结果按本机字节序写回即为网络字节序
+	*(*uint16)(unsafe.Pointer(&b[10])) = CheckSum(b)
 	return b
 }
 
@@ -166,3 +168,48 @@ func (ipv4 IPv4Packet) String() string {
 	)
 	return str
 }
+
+/*
+	JSON 格式, options 为 "01:02:03:04" 形式的原始字节:
+	{"version": 4, "ihl": 20, "tos": 0, "total_len": 84, "id": 4660, "flags": 2, "frag_off": 0,
+	 "ttl": 64, "protocol": 1, "checksum": 0, "src": "192.168.1.1", "dst": "192.168.1.2"}
+	checksum 仅用于记录, WireFormat 时重新计算
+ */
+type ipv4JSON struct {
+	Version 	uint8 	`json:"version"`
+	IHL 		uint8 	`json:"ihl"`
+	TOS 		uint8 	`json:"tos"`
+	TotalLen 	uint16 	`json:"total_len"`
+	ID 			uint16 	`json:"id"`
+	Flags 		uint8 	`json:"flags"`
+	FragOff 	uint16 	`json:"frag_off"`
+	TTL 		uint8 	`json:"ttl"`
+	Protocol 	uint8 	`json:"protocol"`
+	Checksum 	uint16 	`json:"checksum"`
+	Src 		IPv4 	`json:"src"`
+	Dst 		IPv4 	`json:"dst"`
+	Options 	string 	`json:"options,omitempty"`
+}
+
+func (ipv4 IPv4Packet) MarshalJSON() ([]byte, error) {
+	return json.Marshal(ipv4JSON{
+		ipv4.Version, ipv4.IHL, ipv4.TOS, ipv4.TotalLen, ipv4.ID, ipv4.Flags, ipv4.FragOff,
+		ipv4.TTL, ipv4.Protocol, ipv4.checksum, ipv4.Src, ipv4.Dst, hexColon(ipv4.Options),
+	})
+}
+
+func (ipv4 *IPv4Packet) UnmarshalJSON(b []byte) error {
+	var v ipv4JSON
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	options, err := parseHexColon(v.Options)
+	if err != nil {
+		return err
+	}
+	*ipv4 = IPv4Packet{
+		v.Version, v.TOS, v.TotalLen, v.ID, v.FragOff, v.TTL, v.Protocol, v.Checksum, v.Src, v.Dst,
+		v.Flags, v.IHL, options,
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+// @@
+// @ Author       : Eacher
+// @ Date         : 2026-10-21 16:07:39
+// @ LastEditTime : 2026-10-21 16:07:39
+// @ LastEditors  : Eacher
+// @ --------------------------------------------------------------------------------<
+// @ Description  : IPv4Packet JSON 测试
+// @ --------------------------------------------------------------------------------<
+// @ FilePath     : /20yyq/packet/ipv4_test.go
+// @@
+package packet
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestIPv4PacketJSON(t *testing.T) {
+	tests := []IPv4Packet{
+		{Version: 4, TTL: 64, Protocol: 0x11, Src: IPv4{10, 0, 0, 1}, Dst: IPv4Broadcast, TotalLen: 328, ID: 7, Flags: 2},
+		{Version: 4, TTL: 1, Protocol: 0x02, Src: IPv4{10, 0, 0, 1}, Dst: IPv4{224, 0, 0, 22}, TotalLen: 40, Options: []byte{0x94, 0x04, 0, 0}},
+	}
+	for _, tt := range tests {
+		// 经 NewIPv4Packet 解析以填写 IHL 与校验和
+		ip, _ := NewIPv4Packet(tt.WireFormat())
+		b, err := json.Marshal(ip)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got IPv4Packet
+		if err := json.Unmarshal(b, &got); err != nil || !reflect.DeepEqual(got, ip) {
+			t.Errorf("Unmarshal(%s) = %+v, %v; want %+v", b, got, err, ip)
+			continue
+		}
+		if !bytes.Equal(got.WireFormat(), ip.WireFormat()) {
+			t.Errorf("WireFormat() after JSON = % x; want % x", got.WireFormat(), ip.WireFormat())
+		}
+	}
+	var got IPv4Packet
+	if err := json.Unmarshal([]byte(`{"version":4,"options":"zz"}`), &got); err == nil {
+		t.Errorf("Unmarshal() with malformed options succeeded")
+	}
+}
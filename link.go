@@ -0,0 +1,204 @@
+// @@
+// @ Author       : Eacher
+// @ Date         : 2026-10-19 16:05:12
+// @ LastEditTime : 2026-10-20 09:12:40
+// @ LastEditors  : Eacher
+// @ --------------------------------------------------------------------------------<
+// @ Description  : AF_PACKET 链路与内存链路
+// @ --------------------------------------------------------------------------------<
+// @ FilePath     : /20yyq/packet/link.go
+// @@
+package packet
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// 内存链路单向最大缓存帧数, 超出后丢弃, 与真实链路行为一致
+	framePipeBuffer = 0x100
+	// 以太网帧最大 bytes 长度 (含 VLAN 标签)
+	maxFrameLength = 0x10000
+)
+
+type packetLink struct {
+	file *os.File
+	buf  []byte
+}
+
+// 打开绑定到 ifindex 网卡的 AF_PACKET 原始套接字, proto 为以太网帧类型, 例如 ETH_P_ARP
+func NewPacketLink(ifindex int, proto uint16) (FrameReadWriter, error) {
+	fd, err := unix.Socket(unix.AF_PACKET, unix.SOCK_RAW|unix.SOCK_NONBLOCK|unix.SOCK_CLOEXEC, int(htons(proto)))
+	if err != nil {
+		return nil, err
+	}
+	if err = unix.Bind(fd, &unix.SockaddrLinklayer{Protocol: htons(proto), Ifindex: ifindex}); err != nil {
+		unix.Close(fd)
+		return nil, err
+	}
+	return &packetLink{file: os.NewFile(uintptr(fd), "packet"), buf: make([]byte, maxFrameLength)}, nil
+}
+
+func (pl *packetLink) ReadFrame() ([]byte, error) {
+	n, err := pl.file.Read(pl.buf)
+	if err != nil {
+		return nil, err
+	}
+	b := make([]byte, n)
+	copy(b, pl.buf[:n])
+	return b, nil
+}
+
+func (pl *packetLink) WriteFrame(b []byte) error {
+	_, err := pl.file.Write(b)
+	return err
+}
+
+func (pl *packetLink) SetReadDeadline(t time.Time) error {
+	return pl.file.SetReadDeadline(t)
+}
+
+func (pl *packetLink) Close() error {
+	return pl.file.Close()
+}
+
+type framePipe struct {
+	rx     <-chan []byte
+	tx     chan<- []byte
+	closed chan struct{}
+	once   *sync.Once
+
+	mu       sync.Mutex
+	deadline time.Time
+	notify   chan struct{}
+}
+
+// 创建一对互联的内存链路, 一端写入的帧从另一端读出, 任意一端 Close 后两端均关闭
+func NewFramePipe() (FrameReadWriter, FrameReadWriter) {
+	ab, ba := make(chan []byte, framePipeBuffer), make(chan []byte, framePipeBuffer)
+	closed, once := make(chan struct{}), &sync.Once{}
+	a := &framePipe{rx: ba, tx: ab, closed: closed, once: once, notify: make(chan struct{})}
+	b := &framePipe{rx: ab, tx: ba, closed: closed, once: once, notify: make(chan struct{})}
+	return a, b
+}
+
+func (fp *framePipe) ReadFrame() ([]byte, error) {
+	for {
+		fp.mu.Lock()
+		deadline, notify := fp.deadline, fp.notify
+		fp.mu.Unlock()
+		timer := &time.Timer{}
+		if !deadline.IsZero() {
+			d := time.Until(deadline)
+			if d <= 0 {
+				return nil, os.ErrDeadlineExceeded
+			}
+			timer = time.NewTimer(d)
+		}
+		select {
+		case b := <-fp.rx:
+			stopTimer(timer)
+			return b, nil
+		case <-fp.closed:
+			stopTimer(timer)
+			return nil, io.ErrClosedPipe
+		case <-timer.C:
+			return nil, os.ErrDeadlineExceeded
+		case <-notify:
+			stopTimer(timer)
+		}
+	}
+}
+
+func (fp *framePipe) WriteFrame(b []byte) error {
+	frame := make([]byte, len(b))
+	copy(frame, b)
+	select {
+	case <-fp.closed:
+		return io.ErrClosedPipe
+	default:
+	}
+	select {
+	case fp.tx <- frame:
+	default:
+	}
+	return nil
+}
+
+func (fp *framePipe) SetReadDeadline(t time.Time) error {
+	fp.mu.Lock()
+	fp.deadline = t
+	close(fp.notify)
+	fp.notify = make(chan struct{})
+	fp.mu.Unlock()
+	return nil
+}
+
+func (fp *framePipe) Close() error {
+	fp.once.Do(func() { close(fp.closed) })
+	return nil
+}
+
+type readDeadliner interface {
+	SetReadDeadline(t time.Time) error
+}
+
+// ctx 结束时打断阻塞中的读取, 之后设置的任何 deadline 都立即超时
+type linkWatcher struct {
+	link   readDeadliner
+	mu     sync.Mutex
+	done   bool
+	closed bool
+	stop   chan struct{}
+}
+
+func watchLink(ctx context.Context, link readDeadliner) *linkWatcher {
+	lw := &linkWatcher{link: link, stop: make(chan struct{})}
+	go func() {
+		select {
+		case <-ctx.Done():
+			lw.mu.Lock()
+			if !lw.closed {
+				lw.done = true
+				lw.link.SetReadDeadline(time.Unix(1, 0))
+			}
+			lw.mu.Unlock()
+		case <-lw.stop:
+		}
+	}()
+	return lw
+}
+
+func (lw *linkWatcher) setDeadline(t time.Time) {
+	lw.mu.Lock()
+	if lw.done {
+		t = time.Unix(1, 0)
+	}
+	lw.link.SetReadDeadline(t)
+	lw.mu.Unlock()
+}
+
+func (lw *linkWatcher) Close() {
+	lw.mu.Lock()
+	lw.closed = true
+	lw.link.SetReadDeadline(time.Time{})
+	lw.mu.Unlock()
+	close(lw.stop)
+}
+
+func stopTimer(t *time.Timer) {
+	if t.C != nil {
+		t.Stop()
+	}
+}
+
+func htons(v uint16) uint16 {
+	return ipv4NativeEndian.Uint16(binary.BigEndian.AppendUint16(nil, v))
+}
@@ -1,7 +1,7 @@
 // @@
 // @ Author       : Eacher
 // @ Date         : 2023-07-01 15:20:41
-// @ LastEditTime : 2023-09-20 09:44:57
+// @ LastEditTime : 2026-10-21 00:52:18
 // @ LastEditors  : Eacher
 // @ --------------------------------------------------------------------------------<
 // @ Description  : 
@@ -13,6 +13,7 @@ package packet
 import (
 	"unsafe"
 	"syscall"
+	"encoding/json"
 )
 
 const (
@@ -207,3 +208,196 @@ func (nla NlAttr) WireFormat() []byte {
 	copy(b[SizeofNlAttr:], nla.Data)
 	return b
 }
+
+/*
+	JSON 格式, 字段名与 linux/netlink.h, linux/rtnetlink.h 一致, Data 为 "01:02:03:04" 形式的原始字节:
+	NlMsghdr 		{"len": 32, "type": 16, "flags": 0, "seq": 1, "pid": 0}
+	NlMsgerr 		{"error": -1, "msg": NlMsghdr}
+	NetlinkMessage 	{"header": NlMsghdr, "data": "00:00:01:00"}
+	IfInfomsg 		{"family": 0, "type": 1, "index": 2, "flags": 69699, "change": 0}
+	IfAddrmsg 		{"family": 2, "prefixlen": 24, "flags": 128, "scope": 0, "index": 2}
+	RtMsg 			{"family": 2, "dst_len": 24, "src_len": 0, "tos": 0, "table": 254,
+					 "protocol": 2, "scope": 253, "type": 1, "flags": 0}
+	RtAttr, NlAttr 	{"len": 8, "type": 1, "data": "c0:a8:01:01"}, len 为 0 时按 data 长度计算
+ */
+type nlMsghdrJSON struct {
+	Len 	uint32 	`json:"len"`
+	Type 	uint16 	`json:"type"`
+	Flags 	uint16 	`json:"flags"`
+	Seq 	uint32 	`json:"seq"`
+	Pid 	uint32 	`json:"pid"`
+}
+
+func (hdr NlMsghdr) MarshalJSON() ([]byte, error) {
+	return json.Marshal(nlMsghdrJSON{hdr.Len, hdr.Type, hdr.Flags, hdr.Seq, hdr.Pid})
+}
+
+func (hdr *NlMsghdr) UnmarshalJSON(b []byte) error {
+	var v nlMsghdrJSON
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	*hdr = NlMsghdr{v.Len, v.Type, v.Flags, v.Seq, v.Pid}
+	return nil
+}
+
+type nlMsgerrJSON struct {
+	Error 	int32 		`json:"error"`
+	Msg 	NlMsghdr 	`json:"msg"`
+}
+
+func (nlmsge NlMsgerr) MarshalJSON() ([]byte, error) {
+	return json.Marshal(nlMsgerrJSON{nlmsge.Error, nlmsge.Msg})
+}
+
+func (nlmsge *NlMsgerr) UnmarshalJSON(b []byte) error {
+	var v nlMsgerrJSON
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	*nlmsge = NlMsgerr{v.Error, v.Msg}
+	return nil
+}
+
+type netlinkMessageJSON struct {
+	Header 	*NlMsghdr 	`json:"header"`
+	Data 	string 		`json:"data"`
+}
+
+func (nlmsg NetlinkMessage) MarshalJSON() ([]byte, error) {
+	return json.Marshal(netlinkMessageJSON{nlmsg.Header, hexColon(nlmsg.Data)})
+}
+
+func (nlmsg *NetlinkMessage) UnmarshalJSON(b []byte) error {
+	var v netlinkMessageJSON
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	data, err := parseHexColon(v.Data)
+	if err != nil {
+		return err
+	}
+	if v.Header == nil {
+		v.Header = &NlMsghdr{Len: uint32(SizeofNlMsghdr + len(data))}
+	}
+	*nlmsg = NetlinkMessage{v.Header, data}
+	return nil
+}
+
+type ifInfomsgJSON struct {
+	Family 	uint8 	`json:"family"`
+	Type 	uint16 	`json:"type"`
+	Index 	int32 	`json:"index"`
+	Flags 	uint32 	`json:"flags"`
+	Change 	uint32 	`json:"change"`
+}
+
+func (info IfInfomsg) MarshalJSON() ([]byte, error) {
+	return json.Marshal(ifInfomsgJSON{info.Family, info.Type, info.Index, info.Flags, info.Change})
+}
+
+func (info *IfInfomsg) UnmarshalJSON(b []byte) error {
+	var v ifInfomsgJSON
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	*info = IfInfomsg{Family: v.Family, Type: v.Type, Index: v.Index, Flags: v.Flags, Change: v.Change}
+	return nil
+}
+
+type ifAddrmsgJSON struct {
+	Family 		uint8 	`json:"family"`
+	Prefixlen 	uint8 	`json:"prefixlen"`
+	Flags 		uint8 	`json:"flags"`
+	Scope 		uint8 	`json:"scope"`
+	Index 		uint32 	`json:"index"`
+}
+
+func (addr IfAddrmsg) MarshalJSON() ([]byte, error) {
+	return json.Marshal(ifAddrmsgJSON{addr.Family, addr.Prefixlen, addr.Flags, addr.Scope, addr.Index})
+}
+
+func (addr *IfAddrmsg) UnmarshalJSON(b []byte) error {
+	var v ifAddrmsgJSON
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	*addr = IfAddrmsg{v.Family, v.Prefixlen, v.Flags, v.Scope, v.Index}
+	return nil
+}
+
+type rtMsgJSON struct {
+	Family 		uint8 	`json:"family"`
+	Dst_len 	uint8 	`json:"dst_len"`
+	Src_len 	uint8 	`json:"src_len"`
+	Tos 		uint8 	`json:"tos"`
+	Table 		uint8 	`json:"table"`
+	Protocol 	uint8 	`json:"protocol"`
+	Scope 		uint8 	`json:"scope"`
+	Type 		uint8 	`json:"type"`
+	Flags 		uint32 	`json:"flags"`
+}
+
+func (rtmsg RtMsg) MarshalJSON() ([]byte, error) {
+	return json.Marshal(rtMsgJSON(rtmsg))
+}
+
+func (rtmsg *RtMsg) UnmarshalJSON(b []byte) error {
+	var v rtMsgJSON
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	*rtmsg = RtMsg(v)
+	return nil
+}
+
+type attrJSON struct {
+	Len 	uint16 	`json:"len"`
+	Type 	uint16 	`json:"type"`
+	Data 	string 	`json:"data"`
+}
+
+func unmarshalAttrJSON(b []byte, size int) (length, typ uint16, data []byte, err error) {
+	var v attrJSON
+	if err = json.Unmarshal(b, &v); err != nil {
+		return
+	}
+	if data, err = parseHexColon(v.Data); err == nil && v.Len == 0 {
+		v.Len = uint16(size + len(data))
+	}
+	return v.Len, v.Type, data, err
+}
+
+func (rta RtAttr) MarshalJSON() ([]byte, error) {
+	var v attrJSON
+	if rta.RtAttr != nil {
+		v.Len, v.Type = rta.Len, rta.Type
+	}
+	v.Data = hexColon(rta.Data)
+	return json.Marshal(v)
+}
+
+func (rta *RtAttr) UnmarshalJSON(b []byte) error {
+	length, typ, data, err := unmarshalAttrJSON(b, SizeofRtAttr)
+	if err == nil {
+		*rta = RtAttr{&syscall.RtAttr{Len: length, Type: typ}, data}
+	}
+	return err
+}
+
+func (nla NlAttr) MarshalJSON() ([]byte, error) {
+	var v attrJSON
+	if nla.NlAttr != nil {
+		v.Len, v.Type = nla.Len, nla.Type
+	}
+	v.Data = hexColon(nla.Data)
+	return json.Marshal(v)
+}
+
+func (nla *NlAttr) UnmarshalJSON(b []byte) error {
+	length, typ, data, err := unmarshalAttrJSON(b, SizeofNlAttr)
+	if err == nil {
+		*nla = NlAttr{&syscall.NlAttr{Len: length, Type: typ}, data}
+	}
+	return err
+}
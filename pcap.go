@@ -0,0 +1,149 @@
+// @@
+// @ Author       : Eacher
+// @ Date         : 2026-10-19 18:10:05
+// @ LastEditTime : 2026-10-19 18:10:05
+// @ LastEditors  : Eacher
+// @ --------------------------------------------------------------------------------<
+// @ Description  : libpcap 抓包文件读取
+// @ --------------------------------------------------------------------------------<
+// @ FilePath     : /20yyq/packet/pcap.go
+// @@
+package packet
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"time"
+)
+
+/*
+	来源 https://wiki.wireshark.org/Development/LibpcapFileFormat
+
+	typedef struct pcap_hdr_s {
+		guint32 magic_number;   // magic number
+		guint16 version_major;  // major version number
+		guint16 version_minor;  // minor version number
+		gint32  thiszone;       // GMT to local correction
+		guint32 sigfigs;        // accuracy of timestamps
+		guint32 snaplen;        // max length of captured packets, in octets
+		guint32 network;        // data link type
+	} pcap_hdr_t;
+
+	typedef struct pcaprec_hdr_s {
+		guint32 ts_sec;         // timestamp seconds
+		guint32 ts_usec;        // timestamp microseconds (nanoseconds for 0xa1b23c4d)
+		guint32 incl_len;       // number of octets of packet saved in file
+		guint32 orig_len;       // actual length of packet
+	} pcaprec_hdr_t;
+*/
+const (
+	SizeofPcapHeader       = 0x18
+	SizeofPcapRecordHeader = 0x10
+
+	PCAP_LINKTYPE_ETHERNET = 0x01
+
+	pcapMagicMicro = 0xa1b2c3d4
+	pcapMagicNano  = 0xa1b23c4d
+)
+
+var ErrPcapFormat = errors.New("pcap: invalid file header")
+
+type PcapHeader struct {
+	VersionMajor uint16
+	VersionMinor uint16
+	ThisZone     int32
+	SigFigs      uint32
+	SnapLen      uint32
+	LinkType     uint32
+}
+
+type PcapReader struct {
+	Header PcapHeader
+
+	r     io.Reader
+	order binary.ByteOrder
+	nano  bool
+	hdr   [SizeofPcapRecordHeader]byte
+}
+
+func NewPcapReader(r io.Reader) (*PcapReader, error) {
+	var b [SizeofPcapHeader]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		return nil, err
+	}
+	pr := &PcapReader{r: r}
+	switch {
+	case binary.LittleEndian.Uint32(b[:4]) == pcapMagicMicro:
+		pr.order = binary.LittleEndian
+	case binary.BigEndian.Uint32(b[:4]) == pcapMagicMicro:
+		pr.order = binary.BigEndian
+	case binary.LittleEndian.Uint32(b[:4]) == pcapMagicNano:
+		pr.order, pr.nano = binary.LittleEndian, true
+	case binary.BigEndian.Uint32(b[:4]) == pcapMagicNano:
+		pr.order, pr.nano = binary.BigEndian, true
+	default:
+		return nil, ErrPcapFormat
+	}
+	pr.Header.VersionMajor = pr.order.Uint16(b[4:6])
+	pr.Header.VersionMinor = pr.order.Uint16(b[6:8])
+	pr.Header.ThisZone = int32(pr.order.Uint32(b[8:12]))
+	pr.Header.SigFigs = pr.order.Uint32(b[12:16])
+	pr.Header.SnapLen = pr.order.Uint32(b[16:20])
+	pr.Header.LinkType = pr.order.Uint32(b[20:24])
+	return pr, nil
+}
+
+// 读取下一个报文, 文件结束返回 io.EOF
+func (pr *PcapReader) Next() ([]byte, time.Time, error) {
+	if _, err := io.ReadFull(pr.r, pr.hdr[:]); err != nil {
+		return nil, time.Time{}, err
+	}
+	sec, frac := pr.order.Uint32(pr.hdr[0:4]), pr.order.Uint32(pr.hdr[4:8])
+	length := pr.order.Uint32(pr.hdr[8:12])
+	if length > maxFrameLength {
+		return nil, time.Time{}, ErrPcapFormat
+	}
+	b := make([]byte, length)
+	if _, err := io.ReadFull(pr.r, b); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, time.Time{}, err
+	}
+	if !pr.nano {
+		frac *= 1000
+	}
+	return b, time.Unix(int64(sec), int64(frac)), nil
+}
+
+type PcapWriter struct {
+	w io.Writer
+}
+
+// 写入微秒精度小端序文件头
+func NewPcapWriter(w io.Writer, linkType uint32) (*PcapWriter, error) {
+	var b [SizeofPcapHeader]byte
+	binary.LittleEndian.PutUint32(b[0:4], pcapMagicMicro)
+	binary.LittleEndian.PutUint16(b[4:6], 2)
+	binary.LittleEndian.PutUint16(b[6:8], 4)
+	binary.LittleEndian.PutUint32(b[16:20], maxFrameLength)
+	binary.LittleEndian.PutUint32(b[20:24], linkType)
+	if _, err := w.Write(b[:]); err != nil {
+		return nil, err
+	}
+	return &PcapWriter{w: w}, nil
+}
+
+func (pw *PcapWriter) Write(b []byte, ts time.Time) error {
+	var hdr [SizeofPcapRecordHeader]byte
+	binary.LittleEndian.PutUint32(hdr[0:4], uint32(ts.Unix()))
+	binary.LittleEndian.PutUint32(hdr[4:8], uint32(ts.Nanosecond()/1000))
+	binary.LittleEndian.PutUint32(hdr[8:12], uint32(len(b)))
+	binary.LittleEndian.PutUint32(hdr[12:16], uint32(len(b)))
+	if _, err := pw.w.Write(hdr[:]); err != nil {
+		return err
+	}
+	_, err := pw.w.Write(b)
+	return err
+}
@@ -1,7 +1,7 @@
 // @@
 // @ Author       : Eacher
 // @ Date         : 2023-07-14 08:11:29
-// @ LastEditTime : 2023-09-04 09:40:14
+// @ LastEditTime : 2026-10-21 12:09:43
 // @ LastEditors  : Eacher
 // @ --------------------------------------------------------------------------------<
 // @ Description  : 
@@ -11,6 +11,7 @@
 package packet
 
 import (
+	"encoding/json"
 	"encoding/binary"
 )
 
@@ -136,7 +137,8 @@ func (tcp TCPPacket) WireFormat() []byte {
 	binary.BigEndian.PutUint32(b[4:8], tcp.Sequence)
 	binary.BigEndian.PutUint32(b[8:12], tcp.AckNum)
 	var tmp uint16
-	tmp = uint16(tcp.DataOffset >> 2)
+	// 首部第 13 字节的高 4 位, 以 32 位字为单位
+	tmp = uint16(tcp.DataOffset >> 2) << 12
 	if tcp.URG {
 		tmp |= 0b0000000000100000
 	}
@@ -161,3 +163,58 @@ func (tcp TCPPacket) WireFormat() []byte {
 	binary.BigEndian.PutUint16(b[18:20], tcp.UrgentPtr)
 	return b
 }
+
+/*
+	JSON 格式, 标志位展开为布尔值, options 为 "01:02:03:04" 形式的原始字节:
+	{"src_port": 80, "dst_port": 52100, "sequence": 1, "ack_num": 1, "data_offset": 20, "reserved": 0,
+	 "urg": false, "ack": true, "psh": false, "rst": false, "syn": false, "fin": false,
+	 "window": 65535, "checksum": 4660, "urgent_ptr": 0}
+ */
+type tcpJSON struct {
+	SrcPort 	uint16 	`json:"src_port"`
+	DstPort 	uint16 	`json:"dst_port"`
+	Sequence 	uint32 	`json:"sequence"`
+	AckNum 		uint32 	`json:"ack_num"`
+	DataOffset 	uint8 	`json:"data_offset"`
+	Reserved 	uint8 	`json:"reserved"`
+	URG 		bool 	`json:"urg"`
+	ACK 		bool 	`json:"ack"`
+	PSH 		bool 	`json:"psh"`
+	RST 		bool 	`json:"rst"`
+	SYN 		bool 	`json:"syn"`
+	FIN 		bool 	`json:"fin"`
+	Window 		uint16 	`json:"window"`
+	CheckSum 	uint16 	`json:"checksum"`
+	UrgentPtr 	uint16 	`json:"urgent_ptr"`
+	Options 	string 	`json:"options,omitempty"`
+}
+
+func (tcp TCPPacket) MarshalJSON() ([]byte, error) {
+	return json.Marshal(tcpJSON{
+		tcp.SrcPort, tcp.DstPort, tcp.Sequence, tcp.AckNum, tcp.DataOffset, tcp.Reserved,
+		tcp.URG, tcp.ACK, tcp.PSH, tcp.RST, tcp.SYN, tcp.FIN,
+		tcp.Window, tcp.CheckSum, tcp.UrgentPtr, hexColon(tcp.Options),
+	})
+}
+
+func (tcp *TCPPacket) UnmarshalJSON(b []byte) error {
+	var v tcpJSON
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	options, err := parseHexColon(v.Options)
+	if err != nil {
+		return err
+	}
+	*tcp = TCPPacket{
+		SrcPort: v.SrcPort, DstPort: v.DstPort, Sequence: v.Sequence, AckNum: v.AckNum,
+		Window: v.Window, CheckSum: v.CheckSum, UrgentPtr: v.UrgentPtr,
+		DataOffset: v.DataOffset, Reserved: v.Reserved,
+		URG: v.URG, ACK: v.ACK, PSH: v.PSH, RST: v.RST, SYN: v.SYN, FIN: v.FIN, Options: options,
+	}
+	// 与 NewTCPPacket 解析的首部第 13, 14 字节一致
+	if wire := tcp.WireFormat(); wire != nil {
+		tcp.orgBites = binary.BigEndian.Uint16(wire[12:14])
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+// @@
+// @ Author       : Eacher
+// @ Date         : 2026-10-21 16:07:39
+// @ LastEditTime : 2026-10-21 16:07:39
+// @ LastEditors  : Eacher
+// @ --------------------------------------------------------------------------------<
+// @ Description  : TCPPacket JSON 测试
+// @ --------------------------------------------------------------------------------<
+// @ FilePath     : /20yyq/packet/tcp_test.go
+// @@
+package packet
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestTCPPacketJSON(t *testing.T) {
+	tests := []TCPPacket{
+		{SrcPort: 80, DstPort: 1234, Sequence: 9, AckNum: 10, DataOffset: 20, ACK: true, Window: 100, CheckSum: 5},
+		{SrcPort: 443, DstPort: 5555, Sequence: 1, DataOffset: 24, SYN: true, Reserved: 2, Window: 0xffff, Options: []byte{2, 4, 5, 0xb4}},
+		{SrcPort: 22, DstPort: 2222, DataOffset: 20, URG: true, PSH: true, RST: true, FIN: true, UrgentPtr: 3},
+	}
+	for _, tt := range tests {
+		wire := tt.WireFormat()
+		tcp, _ := NewTCPPacket(wire)
+		b, err := json.Marshal(tcp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got TCPPacket
+		if err := json.Unmarshal(b, &got); err != nil || !reflect.DeepEqual(got, tcp) {
+			t.Errorf("Unmarshal(%s) = %+v, %v; want %+v", b, got, err, tcp)
+			continue
+		}
+		// 首部长度与标志位保持不变
+		if !bytes.Equal(got.WireFormat(), wire) {
+			t.Errorf("WireFormat() after JSON = % x; want % x", got.WireFormat(), wire)
+		}
+	}
+	var got TCPPacket
+	if err := json.Unmarshal([]byte(`{"src_port":1,"options":"0g"}`), &got); err == nil {
+		t.Errorf("Unmarshal() with malformed options succeeded")
+	}
+}
@@ -0,0 +1,511 @@
+// @@
+// @ Author       : Eacher
+// @ Date         : 2026-10-20 20:05:42
+// @ LastEditTime : 2026-10-21 10:58:40
+// @ LastEditors  : Eacher
+// @ --------------------------------------------------------------------------------<
+// @ Description  : RFC 1350 TFTP 报文, 选项协商与分块传输
+// @ --------------------------------------------------------------------------------<
+// @ FilePath     : /20yyq/packet/tftp.go
+// @@
+package packet
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+RFC 1350  THE TFTP PROTOCOL (REVISION 2)
+
+	          2 bytes    string   1 byte     string   1 byte
+	          -----------------------------------------------
+	RRQ/WRQ  | 01/02 |  Filename  |   0  |    Mode    |   0  |
+	          -----------------------------------------------
+
+	          2 bytes    2 bytes       n bytes
+	          ---------------------------------
+	DATA     | 03    |   Block #  |    Data    |
+	          ---------------------------------
+
+	          2 bytes    2 bytes
+	          -------------------
+	ACK      | 04    |   Block #  |
+	          --------------------
+
+	          2 bytes  2 bytes        string    1 byte
+	          ----------------------------------------
+	ERROR    | 05    |  ErrorCode |   ErrMsg   |   0  |
+	          ----------------------------------------
+
+RFC 2347  TFTP Option Extension
+
+	+-------+---~~---+---+---~~---+---+---~~---+---+---~~---+---+-->
+	|  opc  |filename| 0 |  mode  | 0 |  opt1  | 0 | value1 | 0 | <
+	+-------+---~~---+---+---~~---+---+---~~---+---+---~~---+---+-->
+
+	+-------+---~~---+---+---~~---+---+---~~---+---+---~~---+---+
+	|  opc  |  opt1  | 0 | value1 | 0 |  optN  | 0 | valueN | 0 |
+	+-------+---~~---+---+---~~---+---+---~~---+---+---~~---+---+
+	OACK opc 为 6
+
+	RFC 2348 blksize 8-65464, RFC 2349 timeout 1-255 秒与 tsize, RFC 7440 windowsize 1-65535
+	每个传输使用新的端口 (TID), 数据块小于 blksize 时传输结束
+ */
+type TFTP_OPCODE uint16
+
+const (
+	TFTP_RRQ TFTP_OPCODE = iota + 1
+	TFTP_WRQ
+	TFTP_DATA
+	TFTP_ACK
+	TFTP_ERROR
+	TFTP_OACK
+)
+
+type TFTP_ERROR_CODE uint16
+
+const (
+	TFTP_ERR_NOT_DEFINED TFTP_ERROR_CODE = iota
+	TFTP_ERR_FILE_NOT_FOUND
+	TFTP_ERR_ACCESS_VIOLATION
+	TFTP_ERR_DISK_FULL
+	TFTP_ERR_ILLEGAL_OPERATION
+	TFTP_ERR_UNKNOWN_TID
+	TFTP_ERR_FILE_EXISTS
+	TFTP_ERR_NO_SUCH_USER
+	TFTP_ERR_OPTION_NEGOTIATION
+)
+
+const (
+	TFTP_PORT = 69
+
+	TFTP_MODE_NETASCII 	= "netascii"
+	TFTP_MODE_OCTET 	= "octet"
+
+	TFTP_OPT_BLKSIZE 	= "blksize"
+	TFTP_OPT_TIMEOUT 	= "timeout"
+	TFTP_OPT_TSIZE 		= "tsize"
+	TFTP_OPT_WINDOWSIZE = "windowsize"
+
+	TFTP_BLOCK_SIZE 	= 512
+	TFTP_MIN_BLOCK_SIZE = 8
+	TFTP_MAX_BLOCK_SIZE = 65464
+	TFTP_MAX_WINDOW 	= 65535
+	TFTP_TIMEOUT 		= time.Second
+	TFTP_RETRIES 		= 5
+)
+
+var (
+	ErrTftpFormat 	= errors.New("tftp: malformed packet")
+	ErrTftpTimeout 	= errors.New("tftp: timeout")
+	ErrTftpSize 	= errors.New("tftp: transfer size mismatch")
+)
+
+// 对端发送的 ERROR 报文
+type TftpError struct {
+	Code 	TFTP_ERROR_CODE
+	Msg 	string
+}
+
+func (e TftpError) Error() string {
+	return "tftp: error " + strconv.Itoa(int(e.Code)) + ": " + e.Msg
+}
+
+// 选项名称不区分大小写, 保持请求中的顺序
+type TftpOption struct {
+	Name 	string
+	Value 	string
+}
+
+type TftpPacket struct {
+	Opcode 		TFTP_OPCODE
+	// RRQ/WRQ
+	FileName 	string
+	Mode 		string
+	// RRQ/WRQ/OACK
+	Options 	[]TftpOption
+	// DATA/ACK
+	Block 		uint16
+	Data 		[]byte
+	// ERROR
+	ErrorCode 	TFTP_ERROR_CODE
+	ErrorMsg 	string
+}
+
+func NewTftpPacket(b []byte) (tftp TftpPacket, ok bool) {
+	if len(b) < 4 {
+		return
+	}
+	tftp.Opcode = TFTP_OPCODE(binary.BigEndian.Uint16(b))
+	switch tftp.Opcode {
+	case TFTP_RRQ, TFTP_WRQ, TFTP_OACK:
+		fields := bytes.Split(b[2:], []byte{0})
+		// 以 0 结尾时最后一项为空
+		if len(fields) < 2 || len(fields[len(fields)-1]) != 0 {
+			return TftpPacket{}, false
+		}
+		if fields = fields[:len(fields)-1]; tftp.Opcode != TFTP_OACK {
+			if len(fields) < 2 || len(fields[0]) == 0 {
+				return TftpPacket{}, false
+			}
+			tftp.FileName, tftp.Mode, fields = string(fields[0]), strings.ToLower(string(fields[1])), fields[2:]
+		}
+		if len(fields) % 2 != 0 {
+			return TftpPacket{}, false
+		}
+		for i := 0; i < len(fields); i += 2 {
+			tftp.Options = append(tftp.Options, TftpOption{strings.ToLower(string(fields[i])), string(fields[i+1])})
+		}
+	case TFTP_DATA:
+		tftp.Block, tftp.Data = binary.BigEndian.Uint16(b[2:]), append([]byte(nil), b[4:]...)
+	case TFTP_ACK:
+		tftp.Block = binary.BigEndian.Uint16(b[2:])
+	case TFTP_ERROR:
+		tftp.ErrorCode, tftp.ErrorMsg = TFTP_ERROR_CODE(binary.BigEndian.Uint16(b[2:])), strings.TrimRight(string(b[4:]), "\x00")
+	default:
+		return TftpPacket{}, false
+	}
+	return tftp, true
+}
+
+func (tftp TftpPacket) WireFormat() []byte {
+	b := binary.BigEndian.AppendUint16(make([]byte, 0, 4 + len(tftp.Data)), uint16(tftp.Opcode))
+	switch tftp.Opcode {
+	case TFTP_RRQ, TFTP_WRQ, TFTP_OACK:
+		if tftp.Opcode != TFTP_OACK {
+			b = append(append(append(append(b, tftp.FileName...), 0), tftp.Mode...), 0)
+		}
+		for _, o := range tftp.Options {
+			b = append(append(append(append(b, o.Name...), 0), o.Value...), 0)
+		}
+	case TFTP_DATA:
+		b = append(binary.BigEndian.AppendUint16(b, tftp.Block), tftp.Data...)
+	case TFTP_ACK:
+		b = binary.BigEndian.AppendUint16(b, tftp.Block)
+	case TFTP_ERROR:
+		b = append(append(binary.BigEndian.AppendUint16(b, uint16(tftp.ErrorCode)), tftp.ErrorMsg...), 0)
+	}
+	return b
+}
+
+func (tftp TftpPacket) Option(name string) (string, bool) {
+	for _, o := range tftp.Options {
+		if strings.EqualFold(o.Name, name) {
+			return o.Value, true
+		}
+	}
+	return "", false
+}
+
+// 一次传输协商后的参数
+type tftpParams struct {
+	blockSize 	int
+	windowSize 	int
+	timeout 	time.Duration
+	tsize 		int64
+	hasTsize 	bool
+}
+
+func defaultTftpParams(timeout time.Duration) tftpParams {
+	return tftpParams{blockSize: TFTP_BLOCK_SIZE, windowSize: 1, timeout: timeout, tsize: -1}
+}
+
+// 解析 OACK 或请求中的选项, 数值超出范围时返回错误
+func (p *tftpParams) parse(opts []TftpOption) error {
+	for _, o := range opts {
+		n, err := strconv.ParseInt(o.Value, 10, 64)
+		if err != nil {
+			return ErrTftpFormat
+		}
+		switch strings.ToLower(o.Name) {
+		case TFTP_OPT_BLKSIZE:
+			if n < TFTP_MIN_BLOCK_SIZE || n > TFTP_MAX_BLOCK_SIZE {
+				return ErrTftpFormat
+			}
+			p.blockSize = int(n)
+		case TFTP_OPT_TIMEOUT:
+			if n < 1 || n > 255 {
+				return ErrTftpFormat
+			}
+			p.timeout = time.Duration(n) * time.Second
+		case TFTP_OPT_TSIZE:
+			if n < 0 {
+				return ErrTftpFormat
+			}
+			p.tsize, p.hasTsize = n, true
+		case TFTP_OPT_WINDOWSIZE:
+			if n < 1 || n > TFTP_MAX_WINDOW {
+				return ErrTftpFormat
+			}
+			p.windowSize = int(n)
+		}
+	}
+	return nil
+}
+
+/*
+	一次传输的 UDP 套接字, peer 为对端 TID
+	客户端在收到第一个应答前 peer 为服务端的 69 端口, 之后固定为应答的源地址
+	来自其他地址的报文回复 TFTP_ERR_UNKNOWN_TID 后丢弃
+ */
+type tftpConn struct {
+	conn 	net.PacketConn
+	peer 	net.Addr
+	locked 	bool
+	retries int
+	params 	tftpParams
+	buf 	[]byte
+	lw 		*linkWatcher
+}
+
+func (c *tftpConn) send(p TftpPacket) error {
+	_, err := c.conn.WriteTo(p.WireFormat(), c.peer)
+	return err
+}
+
+func (c *tftpConn) sendError(code TFTP_ERROR_CODE, msg string) {
+	c.send(TftpPacket{Opcode: TFTP_ERROR, ErrorCode: code, ErrorMsg: msg})
+}
+
+// 读取下一个来自 peer 的报文, 超时返回 os.ErrDeadlineExceeded
+func (c *tftpConn) receive(ctx context.Context) (TftpPacket, error) {
+	c.lw.setDeadline(time.Now().Add(c.params.timeout))
+	for {
+		n, addr, err := c.conn.ReadFrom(c.buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return TftpPacket{}, ctx.Err()
+			}
+			return TftpPacket{}, err
+		}
+		if c.locked && addr.String() != c.peer.String() {
+			c.conn.WriteTo(TftpPacket{Opcode: TFTP_ERROR, ErrorCode: TFTP_ERR_UNKNOWN_TID, ErrorMsg: "unknown transfer id"}.WireFormat(), addr)
+			continue
+		}
+		p, ok := NewTftpPacket(c.buf[:n])
+		if !ok {
+			continue
+		}
+		c.peer, c.locked = addr, true
+		if p.Opcode == TFTP_ERROR {
+			return p, TftpError{p.ErrorCode, p.ErrorMsg}
+		}
+		return p, nil
+	}
+}
+
+// 发送 p 并等待 match 返回 true 的应答, 超时重发, 最多重发 retries 次
+func (c *tftpConn) exchange(ctx context.Context, p TftpPacket, match func(TftpPacket) bool) (TftpPacket, error) {
+	for try := 0; try <= c.retries; try++ {
+		if err := c.send(p); err != nil {
+			return TftpPacket{}, err
+		}
+		for {
+			reply, err := c.receive(ctx)
+			if errors.Is(err, os.ErrDeadlineExceeded) {
+				break
+			}
+			if err != nil {
+				return reply, err
+			}
+			if match(reply) {
+				return reply, nil
+			}
+		}
+	}
+	return TftpPacket{}, ErrTftpTimeout
+}
+
+/*
+	RFC 7440 发送方: 连续发送 windowsize 个数据块后等待 ACK
+	ACK 确认窗口内的部分数据块时从下一个数据块开始新的窗口, 过期的 ACK 被忽略 (避免 Sorcerer's Apprentice)
+	超时重发整个窗口
+ */
+func (c *tftpConn) sendData(ctx context.Context, r io.Reader) (n int64, err error) {
+	var window [][]byte
+	base, eof, try := uint16(1), false, 0
+	for {
+		for !eof && len(window) < c.params.windowSize {
+			b := make([]byte, c.params.blockSize)
+			m, err := io.ReadFull(r, b)
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				eof = true
+			} else if err != nil {
+				c.sendError(TFTP_ERR_NOT_DEFINED, err.Error())
+				return n, err
+			}
+			window = append(window, b[:m])
+		}
+		if len(window) == 0 {
+			return n, nil
+		}
+		for i, b := range window {
+			if err = c.send(TftpPacket{Opcode: TFTP_DATA, Block: base + uint16(i), Data: b}); err != nil {
+				return n, err
+			}
+		}
+		for {
+			p, err := c.receive(ctx)
+			if errors.Is(err, os.ErrDeadlineExceeded) {
+				if try++; try > c.retries {
+					return n, ErrTftpTimeout
+				}
+				break
+			}
+			if err != nil {
+				return n, err
+			}
+			if p.Opcode != TFTP_ACK {
+				continue
+			}
+			// 块号按 uint16 回绕
+			k := int(p.Block - base + 1)
+			if k < 1 || k > len(window) {
+				continue
+			}
+			for _, b := range window[:k] {
+				n += int64(len(b))
+			}
+			window, base, try = window[k:], base + uint16(k), 0
+			break
+		}
+	}
+}
+
+/*
+	RFC 7440 接收方: 每收到 windowsize 个连续数据块或最后一个数据块时回复 ACK
+	收到乱序数据块时回复最后一个连续数据块的 ACK, 同一个缺口只回复一次
+	first 不为 nil 时为已收到的第一个数据块 (没有选项协商的读请求)
+ */
+func (c *tftpConn) receiveData(ctx context.Context, w io.Writer, first *TftpPacket) (n int64, err error) {
+	expect, count, try, gap := uint16(1), 0, 0, false
+	for {
+		var p TftpPacket
+		if first != nil {
+			p, first = *first, nil
+		} else if p, err = c.receive(ctx); errors.Is(err, os.ErrDeadlineExceeded) {
+			if try++; try > c.retries {
+				return n, ErrTftpTimeout
+			}
+			count, gap = 0, false
+			if err = c.send(TftpPacket{Opcode: TFTP_ACK, Block: expect - 1}); err != nil {
+				return n, err
+			}
+			continue
+		} else if err != nil {
+			return n, err
+		}
+		if p.Opcode != TFTP_DATA {
+			continue
+		}
+		if p.Block != expect {
+			if !gap {
+				count, gap = 0, true
+				c.send(TftpPacket{Opcode: TFTP_ACK, Block: expect - 1})
+			}
+			continue
+		}
+		if _, err = w.Write(p.Data); err != nil {
+			c.sendError(TFTP_ERR_DISK_FULL, err.Error())
+			return n, err
+		}
+		n, expect, try, gap = n + int64(len(p.Data)), expect + 1, 0, false
+		last := len(p.Data) < c.params.blockSize
+		if count++; last || count >= c.params.windowSize {
+			count = 0
+			if err = c.send(TftpPacket{Opcode: TFTP_ACK, Block: p.Block}); err != nil {
+				return n, err
+			}
+		}
+		if last {
+			return n, nil
+		}
+	}
+}
+
+// netascii 发送: "\n" 转换为 "\r\n", "\r" 转换为 "\r\0"
+type netasciiReader struct {
+	r 		*bufio.Reader
+	pending int
+}
+
+func newNetasciiReader(r io.Reader) io.Reader {
+	return &netasciiReader{r: bufio.NewReader(r), pending: -1}
+}
+
+func (nr *netasciiReader) Read(p []byte) (n int, err error) {
+	for n < len(p) {
+		if nr.pending >= 0 {
+			p[n], nr.pending, n = byte(nr.pending), -1, n + 1
+			continue
+		}
+		c, err := nr.r.ReadByte()
+		if err != nil {
+			if n > 0 {
+				return n, nil
+			}
+			return 0, err
+		}
+		switch c {
+		case '\n':
+			p[n], nr.pending = '\r', '\n'
+		case '\r':
+			p[n], nr.pending = '\r', 0
+		default:
+			p[n] = c
+		}
+		n++
+	}
+	return n, nil
+}
+
+// netascii 接收: 还原 "\r\n" 与 "\r\0"
+type netasciiWriter struct {
+	w 	io.Writer
+	cr 	bool
+}
+
+func (nw *netasciiWriter) Write(p []byte) (int, error) {
+	out := make([]byte, 0, len(p) + 1)
+	for _, c := range p {
+		if nw.cr {
+			nw.cr = false
+			switch c {
+			case '\n':
+				out = append(out, '\n')
+				continue
+			case 0:
+				out = append(out, '\r')
+				continue
+			}
+			out = append(out, '\r')
+		}
+		if c == '\r' {
+			nw.cr = true
+			continue
+		}
+		out = append(out, c)
+	}
+	_, err := nw.w.Write(out)
+	return len(p), err
+}
+
+// 数据结束时仍未配对的 "\r" 原样写出, 不关闭 w
+func (nw *netasciiWriter) Close() error {
+	if !nw.cr {
+		return nil
+	}
+	nw.cr = false
+	_, err := nw.w.Write([]byte{'\r'})
+	return err
+}
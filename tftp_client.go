@@ -0,0 +1,165 @@
+// @@
+// @ Author       : Eacher
+// @ Date         : 2026-10-20 20:05:42
+// @ LastEditTime : 2026-10-21 10:58:40
+// @ LastEditors  : Eacher
+// @ --------------------------------------------------------------------------------<
+// @ Description  : TFTP 客户端
+// @ --------------------------------------------------------------------------------<
+// @ FilePath     : /20yyq/packet/tftp_client.go
+// @@
+package packet
+
+import (
+	"context"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+/*
+	每次 Get/Put 使用新的 UDP 端口, 参数为零时使用缺省值
+	BlockSize/WindowSize 不为零时请求对应选项, 服务端拒绝选项时按 RFC 1350 缺省值传输
+ */
+type TftpClient struct {
+	// 服务端地址, Port 为 0 时使用 TFTP_PORT
+	Addr 		*net.UDPAddr
+	// TFTP_MODE_OCTET 或 TFTP_MODE_NETASCII, 为空时使用 TFTP_MODE_OCTET
+	Mode 		string
+	BlockSize 	int
+	WindowSize 	int
+	Timeout 	time.Duration
+	Retries 	int
+}
+
+func (c *TftpClient) mode() string {
+	if c.Mode == "" {
+		return TFTP_MODE_OCTET
+	}
+	return c.Mode
+}
+
+func (c *TftpClient) timeout() time.Duration {
+	if c.Timeout <= 0 {
+		return TFTP_TIMEOUT
+	}
+	return c.Timeout
+}
+
+func (c *TftpClient) retries() int {
+	if c.Retries <= 0 {
+		return TFTP_RETRIES
+	}
+	return c.Retries
+}
+
+func (c *TftpClient) dial() (*tftpConn, error) {
+	conn, err := net.ListenUDP("udp", nil)
+	if err != nil {
+		return nil, err
+	}
+	addr := *c.Addr
+	if addr.Port == 0 {
+		addr.Port = TFTP_PORT
+	}
+	return &tftpConn{conn: conn, peer: &addr, retries: c.retries(), params: defaultTftpParams(c.timeout()), buf: make([]byte, TFTP_MAX_BLOCK_SIZE + 4)}, nil
+}
+
+// tsize 小于 0 时不请求传输大小
+func (c *TftpClient) request(op TFTP_OPCODE, name string, tsize int64) TftpPacket {
+	p := TftpPacket{Opcode: op, FileName: name, Mode: c.mode()}
+	if c.BlockSize > 0 {
+		p.Options = append(p.Options, TftpOption{TFTP_OPT_BLKSIZE, strconv.Itoa(c.BlockSize)})
+	}
+	if c.Timeout >= time.Second {
+		p.Options = append(p.Options, TftpOption{TFTP_OPT_TIMEOUT, strconv.Itoa(int(c.Timeout / time.Second))})
+	}
+	if tsize >= 0 && c.mode() == TFTP_MODE_OCTET {
+		p.Options = append(p.Options, TftpOption{TFTP_OPT_TSIZE, strconv.FormatInt(tsize, 10)})
+	}
+	if c.WindowSize > 0 {
+		p.Options = append(p.Options, TftpOption{TFTP_OPT_WINDOWSIZE, strconv.Itoa(c.WindowSize)})
+	}
+	return p
+}
+
+// RFC 2347: OACK 只能包含请求中的选项
+func (c *tftpConn) accept(req, oack TftpPacket) error {
+	for _, o := range oack.Options {
+		if _, ok := req.Option(o.Name); !ok {
+			return ErrTftpFormat
+		}
+	}
+	return c.params.parse(oack.Options)
+}
+
+// 读取服务端文件写入 w, 返回接收的字节数, 服务端通告 tsize 时校验长度
+func (c *TftpClient) Get(ctx context.Context, name string, w io.Writer) (int64, error) {
+	tc, err := c.dial()
+	if err != nil {
+		return 0, err
+	}
+	defer tc.conn.Close()
+	tc.lw = watchLink(ctx, tc.conn)
+	defer tc.lw.Close()
+	req := c.request(TFTP_RRQ, name, 0)
+	reply, err := tc.exchange(ctx, req, func(p TftpPacket) bool {
+		return p.Opcode == TFTP_OACK || p.Opcode == TFTP_DATA && p.Block == 1
+	})
+	if err != nil {
+		return 0, err
+	}
+	if c.mode() == TFTP_MODE_NETASCII {
+		w = &netasciiWriter{w: w}
+	}
+	var first *TftpPacket
+	if reply.Opcode == TFTP_OACK {
+		if err = tc.accept(req, reply); err != nil {
+			tc.sendError(TFTP_ERR_OPTION_NEGOTIATION, "unexpected option")
+			return 0, err
+		}
+		// ACK 0 确认 OACK, 丢失时由 receiveData 超时重发
+		if err = tc.send(TftpPacket{Opcode: TFTP_ACK}); err != nil {
+			return 0, err
+		}
+	} else {
+		first = &reply
+	}
+	n, err := tc.receiveData(ctx, w, first)
+	if nw, ok := w.(*netasciiWriter); ok && err == nil {
+		err = nw.Close()
+	}
+	if err == nil && tc.params.hasTsize && c.mode() == TFTP_MODE_OCTET && n != tc.params.tsize {
+		err = ErrTftpSize
+	}
+	return n, err
+}
+
+// 读取 r 写入服务端文件, size 为已知长度 (用于 tsize), 未知时为 -1
+func (c *TftpClient) Put(ctx context.Context, name string, r io.Reader, size int64) (int64, error) {
+	tc, err := c.dial()
+	if err != nil {
+		return 0, err
+	}
+	defer tc.conn.Close()
+	tc.lw = watchLink(ctx, tc.conn)
+	defer tc.lw.Close()
+	req := c.request(TFTP_WRQ, name, size)
+	reply, err := tc.exchange(ctx, req, func(p TftpPacket) bool {
+		return p.Opcode == TFTP_OACK || p.Opcode == TFTP_ACK && p.Block == 0
+	})
+	if err != nil {
+		return 0, err
+	}
+	if reply.Opcode == TFTP_OACK {
+		if err = tc.accept(req, reply); err != nil {
+			tc.sendError(TFTP_ERR_OPTION_NEGOTIATION, "unexpected option")
+			return 0, err
+		}
+	}
+	if c.mode() == TFTP_MODE_NETASCII {
+		r = newNetasciiReader(r)
+	}
+	return tc.sendData(ctx, r)
+}
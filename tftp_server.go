@@ -0,0 +1,337 @@
+// @@
+// @ Author       : Eacher
+// @ Date         : 2026-10-20 20:05:42
+// @ LastEditTime : 2026-10-21 15:31:52
+// @ LastEditors  : Eacher
+// @ --------------------------------------------------------------------------------<
+// @ Description  : 基于 io/fs 的 TFTP 服务端
+// @ --------------------------------------------------------------------------------<
+// @ FilePath     : /20yyq/packet/tftp_server.go
+// @@
+package packet
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+/*
+	Serve 从 Conn (通常监听 TFTP_PORT) 读取 RRQ/WRQ, 每个传输使用新的 UDP 端口并在独立的 goroutine 中完成
+	文件名按 '/' 分隔 ('\' 视为 '/'), 去除开头的 '/' 与 ".." 后在 FS 中打开
+ */
+type TftpServer struct {
+	Conn 			net.PacketConn
+	// 读请求的文件系统, 为 nil 时拒绝读请求, 例如 os.DirFS("/srv/tftp")
+	FS 				fs.FS
+	// 写请求, 为 nil 时拒绝写请求, 参见 NewTftpDirCreate
+	// 传输成功时调用 Close, 失败时如果实现了 TftpAborter 则调用 Abort 丢弃已写入的数据, 否则调用 Close
+	Create 			func(name string) (io.WriteCloser, error)
+	Timeout 		time.Duration
+	Retries 		int
+	// 协商 blksize/windowsize 的上限, 为零时使用 TFTP_MAX_BLOCK_SIZE/TFTP_MAX_WINDOW
+	MaxBlockSize 	int
+	MaxWindowSize 	int
+
+	// 每个传输结束后调用, n 为传输的字节数
+	OnTransfer 		func(req TftpPacket, peer net.Addr, n int64, err error)
+
+	wg 				sync.WaitGroup
+}
+
+// 写请求失败时丢弃已写入的数据
+type TftpAborter interface {
+	Abort() error
+}
+
+func (s *TftpServer) timeout() time.Duration {
+	if s.Timeout <= 0 {
+		return TFTP_TIMEOUT
+	}
+	return s.Timeout
+}
+
+func (s *TftpServer) retries() int {
+	if s.Retries <= 0 {
+		return TFTP_RETRIES
+	}
+	return s.Retries
+}
+
+func (s *TftpServer) maxBlockSize() int {
+	if s.MaxBlockSize < TFTP_MIN_BLOCK_SIZE || s.MaxBlockSize > TFTP_MAX_BLOCK_SIZE {
+		return TFTP_MAX_BLOCK_SIZE
+	}
+	return s.MaxBlockSize
+}
+
+func (s *TftpServer) maxWindowSize() int {
+	if s.MaxWindowSize < 1 || s.MaxWindowSize > TFTP_MAX_WINDOW {
+		return TFTP_MAX_WINDOW
+	}
+	return s.MaxWindowSize
+}
+
+// ctx 结束时等待进行中的传输退出后返回
+func (s *TftpServer) Serve(ctx context.Context) error {
+	lw := watchLink(ctx, s.Conn)
+	defer lw.Close()
+	defer s.wg.Wait()
+	buf := make([]byte, maxFrameLength)
+	for {
+		n, addr, err := s.Conn.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+		req, ok := NewTftpPacket(buf[:n])
+		if !ok || req.Opcode != TFTP_RRQ && req.Opcode != TFTP_WRQ {
+			continue
+		}
+		s.wg.Add(1)
+		go func(peer net.Addr) {
+			defer s.wg.Done()
+			n, err := s.handle(ctx, req, peer)
+			if s.OnTransfer != nil {
+				s.OnTransfer(req, peer, n, err)
+			}
+		}(addr)
+	}
+}
+
+func (s *TftpServer) handle(ctx context.Context, req TftpPacket, peer net.Addr) (int64, error) {
+	var laddr *net.UDPAddr
+	if ua, ok := s.Conn.LocalAddr().(*net.UDPAddr); ok {
+		laddr = &net.UDPAddr{IP: ua.IP, Zone: ua.Zone}
+	}
+	conn, err := net.ListenUDP("udp", laddr)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	tc := &tftpConn{conn: conn, peer: peer, locked: true, retries: s.retries(), params: defaultTftpParams(s.timeout()), buf: make([]byte, TFTP_MAX_BLOCK_SIZE + 4)}
+	tc.lw = watchLink(ctx, conn)
+	defer tc.lw.Close()
+	if req.Mode != TFTP_MODE_OCTET && req.Mode != TFTP_MODE_NETASCII {
+		tc.sendError(TFTP_ERR_ILLEGAL_OPERATION, "unsupported mode")
+		return 0, ErrTftpFormat
+	}
+	name, ok := tftpFileName(req.FileName)
+	if !ok {
+		tc.sendError(TFTP_ERR_ACCESS_VIOLATION, "invalid file name")
+		return 0, fs.ErrInvalid
+	}
+	if req.Opcode == TFTP_RRQ {
+		return s.read(ctx, tc, req, name)
+	}
+	return s.write(ctx, tc, req, name)
+}
+
+func (s *TftpServer) read(ctx context.Context, tc *tftpConn, req TftpPacket, name string) (int64, error) {
+	if s.FS == nil {
+		tc.sendError(TFTP_ERR_ACCESS_VIOLATION, "read not allowed")
+		return 0, fs.ErrPermission
+	}
+	f, err := s.FS.Open(name)
+	if err != nil {
+		tc.sendError(tftpError(err))
+		return 0, err
+	}
+	defer f.Close()
+	size := int64(-1)
+	if st, err := f.Stat(); err == nil {
+		if st.IsDir() {
+			tc.sendError(TFTP_ERR_FILE_NOT_FOUND, "is a directory")
+			return 0, fs.ErrNotExist
+		}
+		if req.Mode == TFTP_MODE_OCTET && st.Mode().IsRegular() {
+			size = st.Size()
+		}
+	}
+	if oack := s.negotiate(tc, req, size); len(oack) > 0 {
+		_, err = tc.exchange(ctx, TftpPacket{Opcode: TFTP_OACK, Options: oack}, func(p TftpPacket) bool {
+			return p.Opcode == TFTP_ACK && p.Block == 0
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+	var r io.Reader = f
+	if req.Mode == TFTP_MODE_NETASCII {
+		r = newNetasciiReader(r)
+	}
+	return tc.sendData(ctx, r)
+}
+
+func (s *TftpServer) write(ctx context.Context, tc *tftpConn, req TftpPacket, name string) (int64, error) {
+	if s.Create == nil {
+		tc.sendError(TFTP_ERR_ACCESS_VIOLATION, "write not allowed")
+		return 0, fs.ErrPermission
+	}
+	w, err := s.Create(name)
+	if err != nil {
+		tc.sendError(tftpError(err))
+		return 0, err
+	}
+	reply := TftpPacket{Opcode: TFTP_ACK}
+	if oack := s.negotiate(tc, req, -1); len(oack) > 0 {
+		reply = TftpPacket{Opcode: TFTP_OACK, Options: oack}
+	}
+	// 重发 ACK 0/OACK 直到收到第一个数据块
+	first, err := tc.exchange(ctx, reply, func(p TftpPacket) bool {
+		return p.Opcode == TFTP_DATA && p.Block == 1
+	})
+	if err != nil {
+		tftpAbort(w)
+		return 0, err
+	}
+	var dst io.Writer = w
+	if req.Mode == TFTP_MODE_NETASCII {
+		dst = &netasciiWriter{w: w}
+	}
+	n, err := tc.receiveData(ctx, dst, &first)
+	if nw, ok := dst.(*netasciiWriter); ok && err == nil {
+		err = nw.Close()
+	}
+	// 长度不符时不能提交文件
+	if err == nil && tc.params.hasTsize && n != tc.params.tsize {
+		err = ErrTftpSize
+	}
+	if err != nil {
+		tftpAbort(w)
+		return n, err
+	}
+	return n, w.Close()
+}
+
+func tftpAbort(w io.WriteCloser) {
+	if a, ok := w.(TftpAborter); ok {
+		a.Abort()
+		return
+	}
+	w.Close()
+}
+
+/*
+	按服务端上限接受请求中的选项并更新 tc.params, 返回 OACK 的选项, 无法识别的选项被忽略
+	size 为读请求的文件长度, 未知时为 -1 (不回复 tsize)
+ */
+func (s *TftpServer) negotiate(tc *tftpConn, req TftpPacket, size int64) (oack []TftpOption) {
+	for _, o := range req.Options {
+		n, err := strconv.ParseInt(o.Value, 10, 64)
+		if err != nil {
+			continue
+		}
+		switch o.Name {
+		case TFTP_OPT_BLKSIZE:
+			if n < TFTP_MIN_BLOCK_SIZE {
+				continue
+			}
+			if n > int64(s.maxBlockSize()) {
+				n = int64(s.maxBlockSize())
+			}
+			tc.params.blockSize = int(n)
+		case TFTP_OPT_TIMEOUT:
+			if n < 1 || n > 255 {
+				continue
+			}
+			tc.params.timeout = time.Duration(n) * time.Second
+		case TFTP_OPT_TSIZE:
+			if req.Opcode == TFTP_RRQ {
+				if n = size; n < 0 {
+					continue
+				}
+			} else if n < 0 || req.Mode != TFTP_MODE_OCTET {
+				continue
+			}
+			tc.params.tsize, tc.params.hasTsize = n, true
+		case TFTP_OPT_WINDOWSIZE:
+			if n < 1 {
+				continue
+			}
+			if n > int64(s.maxWindowSize()) {
+				n = int64(s.maxWindowSize())
+			}
+			tc.params.windowSize = int(n)
+		default:
+			continue
+		}
+		oack = append(oack, TftpOption{o.Name, strconv.FormatInt(n, 10)})
+	}
+	return
+}
+
+func tftpFileName(name string) (string, bool) {
+	name = strings.TrimPrefix(path.Clean("/" + strings.ReplaceAll(name, "\\", "/")), "/")
+	return name, name != "" && fs.ValidPath(name)
+}
+
+// 不向客户端暴露本地路径
+func tftpError(err error) (TFTP_ERROR_CODE, string) {
+	var pe *fs.PathError
+	if errors.As(err, &pe) {
+		err = pe.Err
+	}
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return TFTP_ERR_FILE_NOT_FOUND, err.Error()
+	case errors.Is(err, fs.ErrPermission):
+		return TFTP_ERR_ACCESS_VIOLATION, err.Error()
+	case errors.Is(err, fs.ErrExist):
+		return TFTP_ERR_FILE_EXISTS, err.Error()
+	}
+	return TFTP_ERR_NOT_DEFINED, err.Error()
+}
+
+/*
+	在 dir 下创建文件用于写请求, 文件已存在时返回 fs.ErrExist, 不创建子目录
+	数据先写入同目录下的临时文件, Close 时才以硬链接的方式提交为目标文件, 失败或 Abort 时删除临时文件
+	因此中断的传输不会留下不完整的文件, 客户端可以重试
+*/
+func NewTftpDirCreate(dir string) func(name string) (io.WriteCloser, error) {
+	return func(name string) (io.WriteCloser, error) {
+		name = filepath.Join(dir, filepath.FromSlash(name))
+		if _, err := os.Lstat(name); err == nil {
+			return nil, &fs.PathError{Op: "create", Path: name, Err: fs.ErrExist}
+		}
+		f, err := os.CreateTemp(filepath.Dir(name), "." + filepath.Base(name) + ".tftp-*")
+		if err != nil {
+			return nil, err
+		}
+		return &tftpDirFile{File: f, name: name}, nil
+	}
+}
+
+type tftpDirFile struct {
+	*os.File
+	name 	string
+}
+
+func (f *tftpDirFile) Close() error {
+	defer os.Remove(f.File.Name())
+	err := f.File.Chmod(0644)
+	if cerr := f.File.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	// 硬链接在目标已存在时失败, 与 O_EXCL 的语义相同
+	return os.Link(f.File.Name(), f.name)
+}
+
+func (f *tftpDirFile) Abort() error {
+	f.File.Close()
+	return os.Remove(f.File.Name())
+}
@@ -0,0 +1,246 @@
+// @@
+// @ Author       : Eacher
+// @ Date         : 2026-10-21 10:58:40
+// @ LastEditTime : 2026-10-21 15:31:52
+// @ LastEditors  : Eacher
+// @ --------------------------------------------------------------------------------<
+// @ Description  : TFTP 客户端与服务端回环测试
+// @ --------------------------------------------------------------------------------<
+// @ FilePath     : /20yyq/packet/tftp_test.go
+// @@
+package packet
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// 在 127.0.0.1 上启动服务端, 写请求保存到临时目录
+func newTftpTestServer(t *testing.T, files fstest.MapFS) (*net.UDPAddr, string) {
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Skip("loopback udp:", err)
+	}
+	dir := t.TempDir()
+	s := &TftpServer{Conn: conn, FS: files, Create: NewTftpDirCreate(dir), MaxBlockSize: 1428, MaxWindowSize: 8}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- s.Serve(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+		conn.Close()
+	})
+	return conn.LocalAddr().(*net.UDPAddr), dir
+}
+
+func tftpTestData(n int) []byte {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte(i * 7 + i / 251)
+	}
+	return b
+}
+
+func TestTftpLoopback(t *testing.T) {
+	text := []byte("line 1\nline 2\r\nbare \r cr\n\x00end\r")
+	files := fstest.MapFS{
+		"small.txt": 	{Data: []byte("hello")},
+		"big.bin": 		{Data: tftpTestData(100000)},
+		"exact.bin": 	{Data: tftpTestData(1428 * 4)},
+		"empty.bin": 	{Data: []byte{}},
+		"text.txt": 	{Data: text},
+	}
+	addr, dir := newTftpTestServer(t, files)
+	tests := []struct {
+		name 	string
+		file 	string
+		client 	TftpClient
+	}{
+		{"default", "small.txt", TftpClient{}},
+		{"default big", "big.bin", TftpClient{}},
+		{"empty", "empty.bin", TftpClient{}},
+		{"blksize", "big.bin", TftpClient{BlockSize: 1024}},
+		{"blksize capped", "big.bin", TftpClient{BlockSize: 9000}},
+		{"windowsize", "big.bin", TftpClient{BlockSize: 1428, WindowSize: 4}},
+		{"windowsize capped", "exact.bin", TftpClient{BlockSize: 1428, WindowSize: 64}},
+		{"netascii", "text.txt", TftpClient{Mode: TFTP_MODE_NETASCII}},
+		{"netascii window", "text.txt", TftpClient{Mode: TFTP_MODE_NETASCII, BlockSize: 8, WindowSize: 3}},
+	}
+	for i, tt := range tests {
+		tt.client.Addr = addr
+		want := files[tt.file].Data
+		ctx, cancel := context.WithTimeout(context.Background(), 10 * time.Second)
+		var got bytes.Buffer
+		if _, err := tt.client.Get(ctx, tt.file, &got); err != nil {
+			t.Errorf("%s: Get(%q) error: %v", tt.name, tt.file, err)
+		} else if !bytes.Equal(got.Bytes(), want) {
+			t.Errorf("%s: Get(%q) = %d bytes %q; want %d bytes", tt.name, tt.file, got.Len(), tftpTruncate(got.Bytes()), len(want))
+		}
+		size := int64(len(want))
+		if tt.client.Mode == TFTP_MODE_NETASCII {
+			size = -1
+		}
+		name := strconv.Itoa(i) + "-" + tt.file
+		if _, err := tt.client.Put(ctx, name, bytes.NewReader(want), size); err != nil {
+			t.Errorf("%s: Put(%q) error: %v", tt.name, name, err)
+		} else if b, err := os.ReadFile(filepath.Join(dir, name)); err != nil || !bytes.Equal(b, want) {
+			t.Errorf("%s: Put(%q) stored %d bytes %q, %v; want %d bytes", tt.name, name, len(b), tftpTruncate(b), err, len(want))
+		}
+		cancel()
+	}
+}
+
+func tftpTruncate(b []byte) []byte {
+	if len(b) > 32 {
+		return b[:32]
+	}
+	return b
+}
+
+func TestTftpLoopbackErrors(t *testing.T) {
+	addr, _ := newTftpTestServer(t, fstest.MapFS{"a.txt": {Data: []byte("a")}})
+	c := TftpClient{Addr: addr, Timeout: time.Second}
+	ctx, cancel := context.WithTimeout(context.Background(), 10 * time.Second)
+	defer cancel()
+	var te TftpError
+	if _, err := c.Get(ctx, "missing.txt", io.Discard); !errors.As(err, &te) || te.Code != TFTP_ERR_FILE_NOT_FOUND {
+		t.Errorf("Get(missing) error = %v; want file not found", err)
+	}
+	if _, err := c.Put(ctx, "x.txt", bytes.NewReader([]byte("x")), 1); err != nil {
+		t.Fatalf("Put(x.txt) error: %v", err)
+	}
+	if _, err := c.Put(ctx, "x.txt", bytes.NewReader([]byte("x")), 1); !errors.As(err, &te) || te.Code != TFTP_ERR_FILE_EXISTS {
+		t.Errorf("Put(x.txt) again error = %v; want file exists", err)
+	}
+}
+
+// 失败的写请求不留下文件, 客户端可以重试
+func TestTftpPutAbort(t *testing.T) {
+	addr, dir := newTftpTestServer(t, nil)
+	c := TftpClient{Addr: addr, Timeout: time.Second}
+	ctx, cancel := context.WithTimeout(context.Background(), 10 * time.Second)
+	defer cancel()
+	// tsize 与实际长度不符
+	c.Put(ctx, "y.bin", bytes.NewReader(tftpTestData(3000)), 5000)
+	for i := 0; ; i++ {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) == 0 {
+			break
+		}
+		if i == 50 {
+			t.Fatalf("files after failed Put: %v; want none", entries)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	want := tftpTestData(3000)
+	if _, err := c.Put(ctx, "y.bin", bytes.NewReader(want), int64(len(want))); err != nil {
+		t.Fatalf("Put(y.bin) retry error: %v", err)
+	}
+	if b, err := os.ReadFile(filepath.Join(dir, "y.bin")); err != nil || !bytes.Equal(b, want) {
+		t.Errorf("stored %d bytes, %v; want %d bytes", len(b), err, len(want))
+	}
+	if fi, err := os.Stat(filepath.Join(dir, "y.bin")); err != nil || fi.Mode().Perm() != 0644 {
+		t.Errorf("stored file mode = %v, %v; want 0644", fi.Mode(), err)
+	}
+}
+
+// 直接发送 RRQ/WRQ 检查服务端 OACK 中协商的选项
+func TestTftpServerNegotiate(t *testing.T) {
+	addr, _ := newTftpTestServer(t, fstest.MapFS{"big.bin": {Data: tftpTestData(100000)}})
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Skip("loopback udp:", err)
+	}
+	defer conn.Close()
+	tests := []struct {
+		name 	string
+		req 	TftpPacket
+		want 	[]TftpOption
+	}{
+		{"rrq", TftpPacket{Opcode: TFTP_RRQ, FileName: "big.bin", Mode: TFTP_MODE_OCTET, Options: []TftpOption{
+			{"BLKSIZE", "9000"}, {"tsize", "0"}, {"windowsize", "64"}, {"unknown", "1"},
+		}}, []TftpOption{{"blksize", "1428"}, {"tsize", "100000"}, {"windowsize", "8"}}},
+		{"rrq small", TftpPacket{Opcode: TFTP_RRQ, FileName: "/big.bin", Mode: TFTP_MODE_OCTET, Options: []TftpOption{
+			{"blksize", "4"}, {"timeout", "3"}, {"windowsize", "2"},
+		}}, []TftpOption{{"timeout", "3"}, {"windowsize", "2"}}},
+		{"rrq netascii", TftpPacket{Opcode: TFTP_RRQ, FileName: "big.bin", Mode: TFTP_MODE_NETASCII, Options: []TftpOption{
+			{"tsize", "0"}, {"blksize", "1024"},
+		}}, []TftpOption{{"blksize", "1024"}}},
+		{"wrq", TftpPacket{Opcode: TFTP_WRQ, FileName: "new.bin", Mode: TFTP_MODE_OCTET, Options: []TftpOption{
+			{"tsize", "12345"}, {"blksize", "65464"},
+		}}, []TftpOption{{"tsize", "12345"}, {"blksize", "1428"}}},
+	}
+	buf := make([]byte, 1500)
+	for _, tt := range tests {
+		if _, err := conn.WriteTo(tt.req.WireFormat(), addr); err != nil {
+			t.Fatal(err)
+		}
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		n, peer, err := conn.ReadFrom(buf)
+		if err != nil {
+			t.Fatalf("%s: no reply: %v", tt.name, err)
+		}
+		p, ok := NewTftpPacket(buf[:n])
+		if !ok || p.Opcode != TFTP_OACK || !reflect.DeepEqual(p.Options, tt.want) {
+			t.Errorf("%s: reply = %+v; want OACK %v", tt.name, p, tt.want)
+		}
+		if peer.String() == addr.String() {
+			t.Errorf("%s: reply from listening port %v; want new TID", tt.name, peer)
+		}
+		// 结束本次传输
+		conn.WriteTo(TftpPacket{Opcode: TFTP_ERROR, ErrorCode: TFTP_ERR_NOT_DEFINED, ErrorMsg: "done"}.WireFormat(), peer)
+	}
+}
+
+func TestTftpNetascii(t *testing.T) {
+	tests := []struct {
+		in 		string
+		wire 	string
+	}{
+		{"", ""},
+		{"abc", "abc"},
+		{"a\nb", "a\r\nb"},
+		{"a\rb", "a\r\x00b"},
+		{"a\r\nb\n", "a\r\x00\r\nb\r\n"},
+		{"end\r", "end\r\x00"},
+	}
+	for _, tt := range tests {
+		wire, err := io.ReadAll(newNetasciiReader(bytes.NewReader([]byte(tt.in))))
+		if err != nil || string(wire) != tt.wire {
+			t.Errorf("netasciiReader(%q) = %q, %v; want %q", tt.in, wire, err, tt.wire)
+		}
+		// 逐字节写入, 覆盖跨数据块的 "\r"
+		var out bytes.Buffer
+		nw := &netasciiWriter{w: &out}
+		for i := range wire {
+			nw.Write(wire[i:i+1])
+		}
+		if nw.Close(); out.String() != tt.in {
+			t.Errorf("netasciiWriter(%q) = %q; want %q", tt.wire, out.String(), tt.in)
+		}
+	}
+	// 以单独的 "\r" 结尾的数据在 Close 时写出
+	var out bytes.Buffer
+	nw := &netasciiWriter{w: &out}
+	nw.Write([]byte("tail\r"))
+	if out.String() != "tail" {
+		t.Errorf("netasciiWriter before Close = %q; want %q", out.String(), "tail")
+	}
+	if err := nw.Close(); err != nil || out.String() != "tail\r" {
+		t.Errorf("netasciiWriter after Close = %q, %v; want %q", out.String(), err, "tail\r")
+	}
+}
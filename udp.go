@@ -1,7 +1,7 @@
 // @@
 // @ Author       : Eacher
 // @ Date         : 2023-07-13 16:56:05
-// @ LastEditTime : 2023-09-04 09:40:16
+// @ LastEditTime : 2026-10-21 00:33:05
 // @ LastEditors  : Eacher
 // @ --------------------------------------------------------------------------------<
 // @ Description  : 
@@ -11,6 +11,7 @@
 package packet
 
 import (
+	"unsafe"
 	"encoding/binary"
 )
 
@@ -19,10 +20,10 @@ const (
 )
 
 type DUPPacket struct {
-	SrcPort 	uint16
-	DstPort 	uint16
-	Len  		uint16
-	CheckSum 	uint16
+	SrcPort 	uint16 	`json:"src_port"`
+	DstPort 	uint16 	`json:"dst_port"`
+	Len  		uint16 	`json:"len"`
+	CheckSum 	uint16 	`json:"checksum"`
 }
 
 // 14.byte  EthernetPacket
@@ -44,3 +45,17 @@ func (udp DUPPacket) WireFormat() []byte {
 	binary.BigEndian.PutUint16(b[6:8], udp.CheckSum)
 	return b[:]
 }
+
+// 计算含 IPv4 伪首部的校验和, b 为 CheckSum 字段置零的 UDP 首部与负载, 返回值可直接赋给 CheckSum 字段
+func DUPCheckSum(src, dst IPv4, b []byte) uint16 {
+	pseudo := make([]byte, 12, 12 + len(b))
+	*(*IPv4)(pseudo[0:4]) = src
+	*(*IPv4)(pseudo[4:8]) = dst
+	pseudo[9] = 0x11
+	binary.BigEndian.PutUint16(pseudo[10:12], uint16(len(b)))
+	sum := CheckSum(append(pseudo, b...))
+	if sum == 0 {
+		return 0xffff
+	}
+	return binary.BigEndian.Uint16((*[2]byte)(unsafe.Pointer(&sum))[:])
+}